@@ -0,0 +1,51 @@
+// Command nexposetest runs a fake Nexpose API server for local development.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/nexposetest"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "The address on which to serve the fake Nexpose API.")
+	fixtures := flag.String("fixtures", "", "A JSON file of scans, sites and assets to serve.")
+	scans := flag.Int("scans", 0, "The number of finished scans to generate, ending now.")
+	sites := flag.Int("sites", 1, "The number of sites across which generated scans are spread.")
+	interval := flag.Duration("interval", time.Hour, "The time between the ends of generated scans.")
+	latency := flag.Duration("latency", 0, "A delay applied to every response.")
+	username := flag.String("username", "", "A username required with basic authentication.")
+	password := flag.String("password", "", "A password required with basic authentication.")
+	flag.Parse()
+
+	server := nexposetest.New()
+	if *fixtures != "" {
+		f, err := os.Open(*fixtures)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = server.LoadFixtures(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *scans > 0 {
+		server.AddScans(nexposetest.GenerateScans(1, *scans, *sites, time.Now(), *interval)...)
+		for site := 1; site <= *sites; site = site + 1 {
+			server.AddSites(nexposetest.Site{ID: site, Name: fmt.Sprintf("Site %d", site)})
+		}
+	}
+	server.SetLatency(*latency)
+	if *username != "" || *password != "" {
+		server.RequireBasicAuth(*username, *password)
+	}
+
+	log.Printf("serving fake nexpose api on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,326 @@
+// Command notifierctl operates the notifier from the command line. It reads the
+// same environment settings as the service, so it acts on the same Nexpose
+// instance, storage and destinations.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	v1 "github.com/asecurityteam/nexpose-scan-notifier/pkg/handlers/v1"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/producer"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/service"
+	"github.com/asecurityteam/settings"
+)
+
+const usage = `Usage: notifierctl <command> [flags]
+
+Commands:
+  scans      List recent completed scans from Nexpose, without producing them.
+  watermark  Show the stored timestamp, or change it with "set" or "reset".
+  run        Run the notification once, or preview it with -dry-run.
+  replay     Produce the completed scans in a time range again.
+
+Run "notifierctl <command> -h" for the flags of a command.
+`
+
+type command func(ctx context.Context, svc *service.Service, args []string, stdout io.Writer, stderr io.Writer) error
+
+var commands = map[string]command{
+	"scans":     scansCommand,
+	"watermark": watermarkCommand,
+	"run":       runCommand,
+	"replay":    replayCommand,
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Environ(), os.Stdout, os.Stderr))
+}
+
+// run executes a command, returning the exit code.
+func run(ctx context.Context, args []string, env []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	// logs go to stderr, leaving stdout for the output of the command
+	ctx = logevent.NewContext(ctx, logevent.New(logevent.Config{Level: "INFO", Output: stderr}))
+	source, err := settings.NewEnvSource(env)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+	svc, err := service.New(ctx, source)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+
+	switch err = cmd(ctx, svc, args[1:], stdout, stderr); err {
+	case nil:
+		return 0
+	case flag.ErrHelp:
+		return 2
+	default:
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("notifierctl "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+// parseTime parses an optional RFC3339 timestamp flag.
+func parseTime(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s must be an RFC3339 timestamp, got %q", name, value)
+	}
+	return ts, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// scansCommand lists completed scans, newest first.
+func scansCommand(ctx context.Context, svc *service.Service, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("scans", stderr)
+	rawSince := flags.String("since", "", "List scans completed after this RFC3339 timestamp, instead of using -lookback.")
+	lookback := flags.Duration("lookback", 24*time.Hour, "List scans completed within this duration.")
+	limit := flags.Int("limit", 0, "The maximum number of scans to list, keeping the oldest, or 0 for no limit.")
+	asJSON := flags.Bool("json", false, "Print each scan as a line of JSON, as it would be produced.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	since, err := parseTime("since", *rawSince)
+	if err != nil {
+		return err
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-1 * *lookback)
+	}
+
+	query := domain.ScanQuery{Since: since, Limit: *limit}
+	result, err := svc.ReadOnlyScanFetcher().FetchScans(ctx, query)
+	if err != nil {
+		return err
+	}
+	scans, err := domain.ReadScans(result.Reader())
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(scans, func(left, right int) bool {
+		return scans[left].EndTime.After(scans[right].EndTime)
+	})
+
+	if *asJSON {
+		ndjson := &producer.NDJSON{Writer: stdout}
+		for _, scan := range scans {
+			if err := ndjson.Produce(ctx, scan); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "SCAN ID\tSITE ID\tTYPE\tNAME\tSTART TIME\tEND TIME")
+	for _, scan := range scans {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", scan.ScanID, scan.SiteID, scan.ScanType, scan.ScanName,
+			scan.StartTime.Format(time.RFC3339), scan.EndTime.Format(time.RFC3339))
+	}
+	if result.Truncated {
+		fmt.Fprintf(stderr, "more than %d scans matched, only the oldest are listed\n", svc.NexposeClient.ScanLimit(query))
+	}
+	return table.Flush()
+}
+
+// watermarkCommand shows, sets or resets the stored timestamp.
+func watermarkCommand(ctx context.Context, svc *service.Service, args []string, stdout io.Writer, stderr io.Writer) error {
+	action := "show"
+	if len(args) > 0 && (args[0] == "show" || args[0] == "set" || args[0] == "reset") {
+		action, args = args[0], args[1:]
+	}
+	flags := newFlagSet("watermark "+action, stderr)
+	var timestamp *string
+	if action == "set" {
+		timestamp = flags.String("timestamp", "", "The new RFC3339 timestamp of the last processed scan.")
+	}
+	var actor, reason *string
+	if action != "show" {
+		actor = flags.String("actor", os.Getenv("USER"), "Who is changing the timestamp.")
+		reason = flags.String("reason", "", "Why the timestamp is being changed.")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var output v1.WatermarkOutput
+	var err error
+	switch action {
+	case "show":
+		output, err = svc.WatermarkHandler.Fetch(ctx)
+	case "set":
+		output, err = svc.WatermarkHandler.Set(ctx, v1.WatermarkSetInput{Timestamp: *timestamp, Actor: *actor, Reason: *reason})
+	case "reset":
+		output, err = svc.WatermarkHandler.Reset(ctx, v1.WatermarkResetInput{Actor: *actor, Reason: *reason})
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, output)
+}
+
+// runCommand runs the notification once, optionally targeted at some scans or as a
+// dry run, and prints its output.
+func runCommand(ctx context.Context, svc *service.Service, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("run", stderr)
+	since := flags.String("since", "", "Produce scans completed after this RFC3339 timestamp, instead of the stored timestamp.")
+	until := flags.String("until", "", "Produce only scans completed at or before this RFC3339 timestamp.")
+	sites := flags.String("sites", "", "Comma separated site IDs to which the run is limited.")
+	types := flags.String("types", "", "Comma separated scan types to which the run is limited.")
+	maxScans := flags.Int("max-scans", 0, "The maximum number of scans to produce, instead of the configured limit.")
+	dryRun := flags.Bool("dry-run", false, "List the scans which would be produced, without producing them or changing storage.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	svc.NotificationHandler.Source = service.RunSourceCLI
+	output, err := svc.NotificationHandler.Handle(ctx, v1.NotificationInput{
+		Since:     *since,
+		Until:     *until,
+		SiteIDs:   splitList(*sites),
+		ScanTypes: splitList(*types),
+		MaxScans:  *maxScans,
+		DryRun:    *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, output)
+}
+
+// splitList splits a comma separated flag, returning nil if it is empty.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// replayCommand produces the completed scans in a time range to their routed
+// destinations again, oldest first, without changing the stored timestamp.
+func replayCommand(ctx context.Context, svc *service.Service, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("replay", stderr)
+	rawSince := flags.String("since", "", "Replay scans completed at or after this RFC3339 timestamp.")
+	rawUntil := flags.String("until", "", "Replay scans completed at or before this RFC3339 timestamp. Defaults to now.")
+	dryRun := flags.Bool("dry-run", false, "Print the scans which would be replayed, without producing them.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	since, err := parseTime("since", *rawSince)
+	if err != nil {
+		return err
+	}
+	if since.IsZero() {
+		return fmt.Errorf("-since is required")
+	}
+	until, err := parseTime("until", *rawUntil)
+	if err != nil {
+		return err
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if until.Before(since) {
+		return fmt.Errorf("-until must not be before -since")
+	}
+
+	var p domain.Producer = svc.Router
+	if *dryRun {
+		p = &producer.NDJSON{Writer: stdout}
+	}
+	// each fetch returns at most the configured maximum number of scans, keeping the
+	// oldest, so the range is replayed a page at a time until a fetch is not truncated;
+	// scan queries exclude scans completed at the timestamp itself
+	fetcher := svc.ReadOnlyScanFetcher()
+	query := domain.ScanQuery{Since: since.Add(-time.Nanosecond)}
+	replayed := 0
+	for {
+		result, err := fetcher.FetchScans(ctx, query)
+		if err != nil {
+			return fmt.Errorf("replayed %d scans, failed to fetch scans: %s", replayed, err.Error())
+		}
+		last, done, err := replayScans(ctx, p, result.Reader(), until, &replayed)
+		if err != nil {
+			return err
+		}
+		if done || !result.Truncated {
+			break
+		}
+		// a truncated result never separates scans which completed at the same time, so
+		// the next page starts after the last scan replayed
+		if !last.After(query.Since) {
+			return fmt.Errorf("replayed %d scans, more scans matched than could be fetched after %s",
+				replayed, query.Since.Format(time.RFC3339Nano))
+		}
+		query.Since = last
+	}
+	if !*dryRun {
+		fmt.Fprintf(stdout, "replayed %d scans\n", replayed)
+	}
+	return nil
+}
+
+// replayScans produces the scans of a reader, oldest first, counting them in replayed.
+// It returns the end time of the last scan produced, and whether a scan completed after
+// until was found, since no later scan can be in the range.
+func replayScans(ctx context.Context, p domain.Producer, reader domain.ScanReader, until time.Time,
+	replayed *int) (time.Time, bool, error) {
+	defer reader.Close()
+	var last time.Time
+	for {
+		scan, err := reader.Next()
+		if err == io.EOF {
+			return last, false, nil
+		}
+		if err != nil {
+			return last, false, fmt.Errorf("replayed %d scans, failed to read scans: %s", *replayed, err.Error())
+		}
+		if scan.EndTime.After(until) {
+			return last, true, nil
+		}
+		if err := p.Produce(ctx, scan); err != nil {
+			return last, false, fmt.Errorf("replayed %d scans, failed on scan %s: %s", *replayed, scan.ScanID, err.Error())
+		}
+		*replayed = *replayed + 1
+		last = scan.EndTime
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/nexposetest"
+	"github.com/stretchr/testify/require"
+)
+
+// notifierctl runs a command against a fake Nexpose server, with in-memory storage and
+// scans produced to a file.
+type notifierctl struct {
+	env    []string
+	output string
+}
+
+func newNotifierctl(t *testing.T, fake *nexposetest.Server) (*notifierctl, func()) {
+	dir, err := ioutil.TempDir("", "notifierctl")
+	require.NoError(t, err)
+	server := httptest.NewServer(fake)
+	output := filepath.Join(dir, "scans.ndjson")
+	closeFn := func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+	return &notifierctl{
+		env: []string{
+			"NEXPOSE_ENDPOINT=" + server.URL,
+			"NEXPOSE_SCANBLOCKLIST=BadScan1",
+			"STORAGE_TYPE=MEMORY",
+			"OUTPUT_TYPE=FILE",
+			"OUTPUT_FILE_PATH=" + output,
+		},
+		output: output,
+	}, closeFn
+}
+
+func (n *notifierctl) run(args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(context.Background(), args, n.env, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func (n *notifierctl) produced(t *testing.T) []string {
+	content, err := ioutil.ReadFile(n.output)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	var scanIDs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var payload struct {
+			ScanID string `json:"scanID"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &payload))
+		scanIDs = append(scanIDs, payload.ScanID)
+	}
+	return scanIDs
+}
+
+func TestRun_Usage(t *testing.T) {
+	ctl := &notifierctl{}
+	code, _, stderr := ctl.run()
+	require.Equal(t, 2, code)
+	require.Contains(t, stderr, "Usage: notifierctl")
+
+	code, _, stderr = ctl.run("deploy")
+	require.Equal(t, 2, code)
+	require.Contains(t, stderr, `unknown command "deploy"`)
+}
+
+func TestRun_InvalidSettings(t *testing.T) {
+	ctl := &notifierctl{env: []string{"STORAGE_TYPE=FILE", "NEXPOSE_SCANBLOCKLIST=BadScan1"}}
+	code, _, stderr := ctl.run("scans")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "unknown storage type FILE")
+}
+
+func TestScansCommand(t *testing.T) {
+	end := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	fake := nexposetest.New()
+	fake.AddScans(nexposetest.GenerateScans(1, 3, 1, end, time.Hour)...)
+	fake.AddScans(nexposetest.GenerateScans(4, 1, 1, end.Add(-48*time.Hour), time.Hour)...)
+	ctl, closeFn := newNotifierctl(t, fake)
+	defer closeFn()
+
+	code, stdout, _ := ctl.run("scans")
+	require.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 4)
+	require.True(t, strings.HasPrefix(lines[0], "SCAN ID"))
+	require.True(t, strings.HasPrefix(lines[1], "3 "))
+	require.True(t, strings.HasPrefix(lines[3], "1 "))
+
+	code, stdout, _ = ctl.run("scans", "-since", end.Add(-72*time.Hour).Format(time.RFC3339), "-json")
+	require.Equal(t, 0, code)
+	require.Len(t, strings.Split(strings.TrimSpace(stdout), "\n"), 4)
+	require.Contains(t, stdout, `"scanID":"4"`)
+
+	code, _, stderr := ctl.run("scans", "-limit", "2")
+	require.Equal(t, 0, code)
+	require.Contains(t, stderr, "more than 2 scans matched")
+
+	ctl.env = append(ctl.env, "NEXPOSE_MAXSCANS=1")
+	code, _, stderr = ctl.run("scans")
+	require.Equal(t, 0, code)
+	require.Contains(t, stderr, "more than 1 scans matched")
+
+	code, _, stderr = ctl.run("scans", "-since", "yesterday")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "-since must be an RFC3339 timestamp")
+	require.Nil(t, ctl.produced(t))
+}
+
+func TestWatermarkCommand(t *testing.T) {
+	ctl, closeFn := newNotifierctl(t, nexposetest.New())
+	defer closeFn()
+
+	code, stdout, _ := ctl.run("watermark")
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{}`, stdout)
+
+	code, _, _ = ctl.run("watermark", "set", "-timestamp", "2019-06-01T12:00:00Z", "-actor", "alice")
+	require.Equal(t, 1, code)
+
+	// each run has its own in-memory storage, so the change is only visible in its output
+	code, stdout, _ = ctl.run("watermark", "set", "-timestamp", "2019-06-01T12:00:00Z",
+		"-actor", "alice", "-reason", "replaying an outage")
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{"timestamp": "2019-06-01T12:00:00Z"}`, stdout)
+
+	code, stdout, _ = ctl.run("watermark", "reset", "-actor", "alice", "-reason", "starting over")
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{}`, stdout)
+
+	code, _, _ = ctl.run("watermark", "-h")
+	require.Equal(t, 2, code)
+}
+
+func TestRunCommand(t *testing.T) {
+	end := time.Now().Add(-time.Hour).UTC()
+	fake := nexposetest.New()
+	fake.AddScans(nexposetest.GenerateScans(1, 2, 1, end, time.Minute)...)
+	ctl, closeFn := newNotifierctl(t, fake)
+	defer closeFn()
+
+	var output struct {
+		Response []struct {
+			ScanID string `json:"scanID"`
+		} `json:"response"`
+		MoreRemaining bool `json:"moreRemaining"`
+	}
+	code, stdout, _ := ctl.run("run", "-dry-run")
+	require.Equal(t, 0, code)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	require.Len(t, output.Response, 2)
+	require.Nil(t, ctl.produced(t))
+
+	code, stdout, _ = ctl.run("run", "-dry-run", "-max-scans", "1", "-sites", "1,2", "-types", "scheduled")
+	require.Equal(t, 0, code)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	require.Len(t, output.Response, 1)
+	require.True(t, output.MoreRemaining)
+
+	code, _, stderr := ctl.run("run", "-since", "yesterday")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "invalid value for since")
+
+	code, stdout, _ = ctl.run("run")
+	require.Equal(t, 0, code)
+	output.MoreRemaining = false
+	require.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	require.Len(t, output.Response, 2)
+	require.False(t, output.MoreRemaining)
+	require.Equal(t, []string{"1", "2"}, ctl.produced(t))
+}
+
+func TestReplayCommand(t *testing.T) {
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := nexposetest.New()
+	fake.AddScans(nexposetest.GenerateScans(1, 5, 1, end, time.Hour)...)
+	ctl, closeFn := newNotifierctl(t, fake)
+	defer closeFn()
+
+	// scans 1 to 5 end at 08:00 to 12:00
+	code, stdout, _ := ctl.run("replay", "-since", "2019-06-01T09:00:00Z", "-until", "2019-06-01T11:00:00Z", "-dry-run")
+	require.Equal(t, 0, code)
+	require.Len(t, strings.Split(strings.TrimSpace(stdout), "\n"), 3)
+	require.Nil(t, ctl.produced(t))
+
+	code, stdout, _ = ctl.run("replay", "-since", "2019-06-01T09:00:00Z", "-until", "2019-06-01T11:00:00Z")
+	require.Equal(t, 0, code)
+	require.Equal(t, "replayed 3 scans\n", stdout)
+	require.Equal(t, []string{"2", "3", "4"}, ctl.produced(t))
+
+	// a range with more scans than may be fetched at once is replayed a page at a time
+	paged := &notifierctl{env: append(ctl.env, "NEXPOSE_MAXSCANS=2"), output: ctl.output}
+	require.NoError(t, os.Remove(ctl.output))
+	code, stdout, _ = paged.run("replay", "-since", "2019-06-01T08:00:00Z", "-until", "2019-06-01T12:00:00Z")
+	require.Equal(t, 0, code)
+	require.Equal(t, "replayed 5 scans\n", stdout)
+	require.Equal(t, []string{"1", "2", "3", "4", "5"}, paged.produced(t))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "missing since",
+			args:     []string{"replay"},
+			expected: "-since is required",
+		},
+		{
+			name:     "invalid until",
+			args:     []string{"replay", "-since", "2019-06-01T09:00:00Z", "-until", "noon"},
+			expected: "-until must be an RFC3339 timestamp",
+		},
+		{
+			name:     "until before since",
+			args:     []string{"replay", "-since", "2019-06-01T09:00:00Z", "-until", "2019-06-01T08:00:00Z"},
+			expected: "-until must not be before -since",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := ctl.run(tt.args...)
+			require.Equal(t, 1, code)
+			require.Contains(t, stderr, tt.expected)
+		})
+	}
+}
@@ -4,6 +4,7 @@ go 1.12
 
 require (
 	github.com/asecurityteam/component-httpclient v0.2.0 // indirect
+	github.com/asecurityteam/logevent v0.0.0-20190225122144-b32737d8d51c
 	github.com/asecurityteam/runhttp v0.0.0-20190611212819-e67777b27ba7
 	github.com/asecurityteam/serverfull v0.1.0
 	github.com/asecurityteam/settings v0.1.0
@@ -12,4 +13,5 @@ require (
 	github.com/go-chi/chi v3.3.4+incompatible // indirect
 	github.com/golang/mock v0.0.0-20190508161146-9fa652df1129
 	github.com/stretchr/testify v1.3.0
+	gopkg.in/yaml.v2 v2.2.2
 )
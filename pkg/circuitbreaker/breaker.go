@@ -0,0 +1,179 @@
+// Package circuitbreaker stops calls to an external dependency which is failing, so that
+// a struggling dependency is not kept under load and callers fail fast.
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	// StateClosed allows all calls through to the dependency.
+	StateClosed = "closed"
+	// StateOpen rejects all calls without contacting the dependency.
+	StateOpen = "open"
+	// StateHalfOpen allows a limited number of trial calls through to the dependency
+	// to decide whether it has recovered.
+	StateHalfOpen = "half-open"
+)
+
+// stateValues are reported as a gauge so that the state can be graphed and alerted on.
+var stateValues = map[string]float64{
+	StateClosed:   0,
+	StateHalfOpen: 1,
+	StateOpen:     2,
+}
+
+// OpenError is returned for calls which are rejected because the circuit breaker is open.
+type OpenError struct {
+	Dependency string
+}
+
+func (e OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.Dependency)
+}
+
+// Breaker tracks the outcome of calls to a single dependency. It opens after
+// FailureThreshold consecutive failures, rejecting calls until OpenTimeout has
+// passed. It then allows up to HalfOpenRequests trial calls, closing again once
+// they all succeed, or re-opening on the first failure.
+type Breaker struct {
+	Dependency       string
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenRequests int
+	LogFn            domain.LogFn
+	StatFn           domain.StatFn
+
+	lock      sync.Mutex
+	state     string
+	failures  int
+	trials    int
+	successes int
+	openedAt  time.Time
+}
+
+// Allow returns an OpenError if a call to the dependency should not be made.
+// Every allowed call must be followed by a call to Record with its outcome, or
+// to Abandon if it was cancelled before its outcome was known.
+func (b *Breaker) Allow(ctx context.Context) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.currentState() == StateOpen {
+		if time.Since(b.openedAt) < b.OpenTimeout {
+			b.StatFn(ctx).Count("circuitbreaker.rejected", 1, "dependency:"+b.Dependency)
+			return OpenError{Dependency: b.Dependency}
+		}
+		b.transition(ctx, StateHalfOpen)
+	}
+	if b.currentState() == StateHalfOpen {
+		if b.trials >= b.halfOpenRequests() {
+			b.StatFn(ctx).Count("circuitbreaker.rejected", 1, "dependency:"+b.Dependency)
+			return OpenError{Dependency: b.Dependency}
+		}
+		b.trials = b.trials + 1
+	}
+	return nil
+}
+
+// Record updates the circuit breaker with the outcome of an allowed call.
+func (b *Breaker) Record(ctx context.Context, success bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.currentState() {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures = b.failures + 1
+		if b.failures >= b.FailureThreshold {
+			b.transition(ctx, StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.transition(ctx, StateOpen)
+			return
+		}
+		b.successes = b.successes + 1
+		if b.successes >= b.halfOpenRequests() {
+			b.transition(ctx, StateClosed)
+		}
+	default:
+		// calls which began before the circuit breaker opened are ignored
+	}
+}
+
+// Abandon releases an allowed call which was cancelled by the caller, without
+// counting it as a success or a failure of the dependency.
+func (b *Breaker) Abandon(ctx context.Context) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.currentState() == StateHalfOpen && b.trials > b.successes {
+		// allow another trial call in place of the abandoned one
+		b.trials = b.trials - 1
+	}
+}
+
+// Rejecting returns true if the circuit breaker is open and would reject a call
+// made now without contacting the dependency.
+func (b *Breaker) Rejecting() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.currentState() == StateOpen && time.Since(b.openedAt) < b.OpenTimeout
+}
+
+// CircuitBreakerState returns the current state of the circuit breaker.
+func (b *Breaker) CircuitBreakerState() domain.CircuitBreakerState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return domain.CircuitBreakerState{Dependency: b.Dependency, State: b.currentState()}
+}
+
+func (b *Breaker) currentState() string {
+	if b.state == "" {
+		return StateClosed
+	}
+	return b.state
+}
+
+func (b *Breaker) halfOpenRequests() int {
+	if b.HalfOpenRequests < 1 {
+		return 1
+	}
+	return b.HalfOpenRequests
+}
+
+// transition moves the circuit breaker to a new state, resetting its counters.
+// The caller must hold the lock.
+func (b *Breaker) transition(ctx context.Context, state string) {
+	previous := b.currentState()
+	b.state = state
+	b.failures = 0
+	b.trials = 0
+	b.successes = 0
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+
+	logger := b.LogFn(ctx)
+	changed := logs.CircuitBreakerStateChanged{
+		Dependency: b.Dependency,
+		Previous:   previous,
+		Current:    state,
+	}
+	if state == StateOpen {
+		logger.Warn(changed)
+	} else {
+		logger.Info(changed)
+	}
+	b.StatFn(ctx).Gauge("circuitbreaker.state", stateValues[state], "dependency:"+b.Dependency)
+}
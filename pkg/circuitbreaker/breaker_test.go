@@ -0,0 +1,148 @@
+package circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreaker(openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		Dependency:       "nexpose",
+		FailureThreshold: 2,
+		OpenTimeout:      openTimeout,
+		HalfOpenRequests: 2,
+		LogFn:            testLogFn,
+		StatFn:           testStatFn,
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	require.Equal(t, "circuit breaker for nexpose is open", OpenError{Dependency: "nexpose"}.Error())
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	ctx := context.Background()
+	breaker := newTestBreaker(time.Hour)
+	require.Equal(t, StateClosed, breaker.CircuitBreakerState().State)
+
+	// a success resets the count of consecutive failures
+	require.Nil(t, breaker.Allow(ctx))
+	breaker.Record(ctx, false)
+	require.Nil(t, breaker.Allow(ctx))
+	breaker.Record(ctx, true)
+	require.Nil(t, breaker.Allow(ctx))
+	breaker.Record(ctx, false)
+	require.Equal(t, StateClosed, breaker.CircuitBreakerState().State)
+
+	require.Nil(t, breaker.Allow(ctx))
+	breaker.Record(ctx, false)
+	require.Equal(t, StateOpen, breaker.CircuitBreakerState().State)
+	require.Equal(t, OpenError{Dependency: "nexpose"}, breaker.Allow(ctx))
+}
+
+func TestBreakerRejecting(t *testing.T) {
+	ctx := context.Background()
+	breaker := newTestBreaker(time.Millisecond)
+	require.False(t, breaker.Rejecting())
+	for offset := 0; offset < breaker.FailureThreshold; offset = offset + 1 {
+		require.Nil(t, breaker.Allow(ctx))
+		breaker.Record(ctx, false)
+	}
+	require.True(t, breaker.Rejecting())
+
+	// a trial request would be allowed once the open timeout has passed, even
+	// before the circuit breaker moves to half open
+	time.Sleep(2 * time.Millisecond)
+	require.False(t, breaker.Rejecting())
+	require.Equal(t, StateOpen, breaker.CircuitBreakerState().State)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		outcomes []bool
+		expected string
+	}{
+		{
+			name:     "closes after trial requests succeed",
+			outcomes: []bool{true, true},
+			expected: StateClosed,
+		},
+		{
+			name:     "stays half open until all trial requests succeed",
+			outcomes: []bool{true},
+			expected: StateHalfOpen,
+		},
+		{
+			name:     "re-opens when a trial request fails",
+			outcomes: []bool{true, false},
+			expected: StateOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := newTestBreaker(time.Millisecond)
+			for offset := 0; offset < breaker.FailureThreshold; offset = offset + 1 {
+				require.Nil(t, breaker.Allow(ctx))
+				breaker.Record(ctx, false)
+			}
+			require.Equal(t, StateOpen, breaker.CircuitBreakerState().State)
+			time.Sleep(2 * time.Millisecond)
+
+			// only the configured number of trial requests are allowed
+			require.Nil(t, breaker.Allow(ctx))
+			require.Equal(t, StateHalfOpen, breaker.CircuitBreakerState().State)
+			require.Nil(t, breaker.Allow(ctx))
+			require.Error(t, breaker.Allow(ctx))
+
+			for _, success := range tt.outcomes {
+				breaker.Record(ctx, success)
+			}
+			require.Equal(t, tt.expected, breaker.CircuitBreakerState().State)
+		})
+	}
+}
+
+func TestBreakerIgnoresOutcomesWhileOpen(t *testing.T) {
+	ctx := context.Background()
+	breaker := newTestBreaker(time.Hour)
+	for offset := 0; offset < breaker.FailureThreshold; offset = offset + 1 {
+		require.Nil(t, breaker.Allow(ctx))
+		breaker.Record(ctx, false)
+	}
+	breaker.Record(ctx, true)
+	require.Equal(t, StateOpen, breaker.CircuitBreakerState().State)
+}
+
+func TestBreakerAbandon(t *testing.T) {
+	ctx := context.Background()
+	breaker := newTestBreaker(time.Millisecond)
+
+	// abandoned calls are neither failures nor successes
+	for offset := 0; offset < breaker.FailureThreshold; offset = offset + 1 {
+		require.Nil(t, breaker.Allow(ctx))
+		breaker.Abandon(ctx)
+	}
+	require.Equal(t, StateClosed, breaker.CircuitBreakerState().State)
+	for offset := 0; offset < breaker.FailureThreshold; offset = offset + 1 {
+		require.Nil(t, breaker.Allow(ctx))
+		breaker.Record(ctx, false)
+	}
+	require.Equal(t, StateOpen, breaker.CircuitBreakerState().State)
+	time.Sleep(2 * time.Millisecond)
+
+	// an abandoned trial request makes room for another
+	require.Nil(t, breaker.Allow(ctx))
+	require.Nil(t, breaker.Allow(ctx))
+	require.Error(t, breaker.Allow(ctx))
+	breaker.Abandon(ctx)
+	require.Nil(t, breaker.Allow(ctx))
+	breaker.Record(ctx, true)
+	breaker.Record(ctx, true)
+	require.Equal(t, StateClosed, breaker.CircuitBreakerState().State)
+}
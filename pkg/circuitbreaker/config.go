@@ -0,0 +1,64 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// Config holds configuration for the circuit breaker protecting a dependency. It is
+// intended to be nested within the configuration of the client for that dependency.
+type Config struct {
+	FailureThreshold int           `description:"Consecutive failures before the circuit breaker opens, or 0 to disable it."`
+	OpenTimeout      time.Duration `description:"How long the circuit breaker stays open before allowing trial requests."`
+	HalfOpenRequests int           `description:"Successful trial requests required to close the circuit breaker again."`
+}
+
+// Name is used by the settings library and will add a "CIRCUITBREAKER_"
+// prefix to Config environment variables
+func (c *Config) Name() string {
+	return "CircuitBreaker"
+}
+
+// DefaultConfig returns the default circuit breaker configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		FailureThreshold: 5,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 1,
+	}
+}
+
+// New constructs a Breaker for the named dependency from a config. A nil Breaker
+// is returned when the circuit breaker is disabled.
+func New(dependency string, c *Config) (*Breaker, error) {
+	if c == nil || c.FailureThreshold == 0 {
+		return nil, nil
+	}
+	if c.FailureThreshold < 0 {
+		return nil, fmt.Errorf("%s circuit breaker failure threshold must not be negative, got %d",
+			dependency, c.FailureThreshold)
+	}
+	if c.OpenTimeout <= 0 {
+		return nil, fmt.Errorf("%s circuit breaker open timeout must be positive, got %s",
+			dependency, c.OpenTimeout)
+	}
+	return &Breaker{
+		Dependency:       dependency,
+		FailureThreshold: c.FailureThreshold,
+		OpenTimeout:      c.OpenTimeout,
+		HalfOpenRequests: c.HalfOpenRequests,
+		LogFn:            domain.LoggerFromContext,
+		StatFn:           domain.StatFromContext,
+	}, nil
+}
+
+// Wrap returns transport protected by breaker, or transport unchanged if breaker is nil.
+func Wrap(transport http.RoundTripper, breaker *Breaker) http.RoundTripper {
+	if breaker == nil {
+		return transport
+	}
+	return &Transport{Wrapped: transport, Breaker: breaker}
+}
@@ -0,0 +1,75 @@
+package circuitbreaker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	config := Config{}
+	require.Equal(t, "CircuitBreaker", config.Name())
+}
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig()
+	require.Equal(t, 5, config.FailureThreshold)
+	require.Equal(t, time.Minute, config.OpenTimeout)
+	require.Equal(t, 1, config.HalfOpenRequests)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		expectNil bool
+		expectErr bool
+	}{
+		{
+			name:   "enabled",
+			config: DefaultConfig(),
+		},
+		{
+			name:      "no config",
+			expectNil: true,
+		},
+		{
+			name:      "disabled",
+			config:    &Config{OpenTimeout: time.Minute},
+			expectNil: true,
+		},
+		{
+			name:      "negative failure threshold",
+			config:    &Config{FailureThreshold: -1, OpenTimeout: time.Minute},
+			expectErr: true,
+		},
+		{
+			name:      "missing open timeout",
+			config:    &Config{FailureThreshold: 5},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker, err := New("nexpose", tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			if tt.expectNil {
+				require.Nil(t, breaker)
+				require.Equal(t, http.DefaultTransport, Wrap(http.DefaultTransport, breaker))
+				return
+			}
+			require.Equal(t, "nexpose", breaker.Dependency)
+			require.Equal(t, tt.config.FailureThreshold, breaker.FailureThreshold)
+			require.NotNil(t, breaker.LogFn)
+			require.NotNil(t, breaker.StatFn)
+			require.IsType(t, &Transport{}, Wrap(http.DefaultTransport, breaker))
+		})
+	}
+}
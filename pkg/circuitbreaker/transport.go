@@ -0,0 +1,30 @@
+package circuitbreaker
+
+import (
+	"net/http"
+)
+
+// Transport is an http.RoundTripper which protects the wrapped transport with a
+// circuit breaker. Transport errors and server error responses count as failures,
+// unless the request was cancelled.
+type Transport struct {
+	Wrapped http.RoundTripper
+	Breaker *Breaker
+}
+
+// RoundTrip makes the request unless the circuit breaker is open.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := t.Breaker.Allow(ctx); err != nil {
+		return nil, err
+	}
+	res, err := t.Wrapped.RoundTrip(req)
+	if ctx.Err() != nil {
+		// a request cancelled by the caller, such as a crawl which stopped early,
+		// says nothing about the health of the dependency
+		t.Breaker.Abandon(ctx)
+		return res, err
+	}
+	t.Breaker.Record(ctx, err == nil && res.StatusCode < http.StatusInternalServerError)
+	return res, err
+}
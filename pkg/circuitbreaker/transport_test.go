@@ -0,0 +1,97 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name      string
+		response  *http.Response
+		err       error
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "success",
+			response: &http.Response{StatusCode: http.StatusOK},
+			expected: StateClosed,
+		},
+		{
+			name:     "client error response is not a failure",
+			response: &http.Response{StatusCode: http.StatusNotFound},
+			expected: StateClosed,
+		},
+		{
+			name:     "server error response is a failure",
+			response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+			expected: StateOpen,
+		},
+		{
+			name:      "transport error is a failure",
+			err:       fmt.Errorf("HTTPError"),
+			expected:  StateOpen,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			mockRT.EXPECT().RoundTrip(gomock.Any()).Return(tt.response, tt.err)
+			breaker := newTestBreaker(time.Hour)
+			breaker.FailureThreshold = 1
+			transport := &Transport{Wrapped: mockRT, Breaker: breaker}
+
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost", http.NoBody)
+			_, err := transport.RoundTrip(req)
+			require.Equal(t, tt.expectErr, err != nil)
+			require.Equal(t, tt.expected, breaker.CircuitBreakerState().State)
+		})
+	}
+}
+
+func TestTransportRejectsWhileOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRT := NewMockRoundTripper(ctrl)
+	breaker := newTestBreaker(time.Hour)
+	breaker.state = StateOpen
+	breaker.openedAt = time.Now()
+	transport := &Transport{Wrapped: mockRT, Breaker: breaker}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", http.NoBody)
+	_, err := transport.RoundTrip(req)
+	require.Equal(t, OpenError{Dependency: "nexpose"}, err)
+}
+
+func TestTransportIgnoresCancelledRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, req.Context().Err()
+	})
+	breaker := newTestBreaker(time.Hour)
+	breaker.FailureThreshold = 1
+	transport := &Transport{Wrapped: mockRT, Breaker: breaker}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", http.NoBody)
+	_, err := transport.RoundTrip(req.WithContext(ctx))
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, StateClosed, breaker.CircuitBreakerState().State)
+}
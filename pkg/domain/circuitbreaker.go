@@ -0,0 +1,13 @@
+package domain
+
+// CircuitBreakerState describes the state of the circuit breaker protecting an
+// external dependency.
+type CircuitBreakerState struct {
+	Dependency string
+	State      string
+}
+
+// CircuitBreakerStater reports the state of a circuit breaker.
+type CircuitBreakerStater interface {
+	CircuitBreakerState() CircuitBreakerState
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// InvalidInput is used to indicate that a request was rejected because one of
+// its fields failed validation.
+type InvalidInput struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidInput) Error() string {
+	return fmt.Sprintf("invalid value for %s: %s", e.Field, e.Reason)
+}
+
+// RunFailure is used to indicate that a stage of a notification run failed for
+// a reason which is not described by a more specific error.
+type RunFailure struct {
+	Stage  string
+	Reason string
+}
+
+func (e RunFailure) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Stage, e.Reason)
+}
+
+// AuthenticationFailure is used to indicate that a dependency rejected the
+// credentials of the service. Stage is the stage of the run which failed, if any.
+type AuthenticationFailure struct {
+	Stage      string
+	Dependency string
+	Reason     string
+}
+
+func (e AuthenticationFailure) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("%s rejected the credentials of the service: %s", e.Dependency, e.Reason)
+	}
+	return fmt.Sprintf("%s failed: %s rejected the credentials of the service: %s", e.Stage, e.Dependency, e.Reason)
+}
+
+// UpstreamUnavailable is used to indicate that a dependency could not be reached
+// or failed to handle a request. Stage is the stage of the run which failed, if any.
+type UpstreamUnavailable struct {
+	Stage      string
+	Dependency string
+	Reason     string
+}
+
+func (e UpstreamUnavailable) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("%s is unavailable: %s", e.Dependency, e.Reason)
+	}
+	return fmt.Sprintf("%s failed: %s is unavailable: %s", e.Stage, e.Dependency, e.Reason)
+}
+
+// RateLimited is used to indicate that a dependency rejected a request because
+// too many requests were made. RetryAfter is how long the dependency asked the
+// service to wait, or zero if it did not say. Stage is the stage of the run
+// which failed, if any.
+type RateLimited struct {
+	Stage      string
+	Dependency string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e RateLimited) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("%s rate limited the service: %s", e.Dependency, e.Reason)
+	}
+	return fmt.Sprintf("%s failed: %s rate limited the service: %s", e.Stage, e.Dependency, e.Reason)
+}
+
+// Conflict is used to indicate that a dependency rejected a change because it
+// conflicts with the current state of a resource. Stage is the stage of the run
+// which failed, if any.
+type Conflict struct {
+	Stage      string
+	Dependency string
+	Reason     string
+}
+
+func (e Conflict) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("%s rejected a conflicting change: %s", e.Dependency, e.Reason)
+	}
+	return fmt.Sprintf("%s failed: %s rejected a conflicting change: %s", e.Stage, e.Dependency, e.Reason)
+}
+
+// InvalidConfiguration is used to indicate that a dependency rejected a request
+// because the service is configured incorrectly, such as with an endpoint or
+// table which does not exist. Stage is the stage of the run which failed, if any.
+type InvalidConfiguration struct {
+	Stage      string
+	Dependency string
+	Reason     string
+}
+
+func (e InvalidConfiguration) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("%s is misconfigured: %s", e.Dependency, e.Reason)
+	}
+	return fmt.Sprintf("%s failed: %s is misconfigured: %s", e.Stage, e.Dependency, e.Reason)
+}
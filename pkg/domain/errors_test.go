@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidInput(t *testing.T) {
+	e := InvalidInput{Field: "reason", Reason: "must not be empty"}
+	require.Equal(t, "invalid value for reason: must not be empty", e.Error())
+}
+
+func TestSiteNotFound(t *testing.T) {
+	e := SiteNotFound{SiteID: "11"}
+	require.Equal(t, "site 11 not found", e.Error())
+}
+
+func TestRunFailure(t *testing.T) {
+	e := RunFailure{Stage: "produce", Reason: "context canceled"}
+	require.Equal(t, "produce failed: context canceled", e.Error())
+}
+
+func TestDependencyErrors(t *testing.T) {
+	tc := []struct {
+		Name     string
+		Err      error
+		Expected string
+	}{
+		{
+			Name:     "authentication failure",
+			Err:      AuthenticationFailure{Dependency: "nexpose", Reason: "401"},
+			Expected: "nexpose rejected the credentials of the service: 401",
+		},
+		{
+			Name:     "authentication failure with stage",
+			Err:      AuthenticationFailure{Stage: "fetchScans", Dependency: "nexpose", Reason: "401"},
+			Expected: "fetchScans failed: nexpose rejected the credentials of the service: 401",
+		},
+		{
+			Name:     "upstream unavailable",
+			Err:      UpstreamUnavailable{Dependency: "producer", Reason: "503"},
+			Expected: "producer is unavailable: 503",
+		},
+		{
+			Name:     "upstream unavailable with stage",
+			Err:      UpstreamUnavailable{Stage: "produce", Dependency: "producer", Reason: "503"},
+			Expected: "produce failed: producer is unavailable: 503",
+		},
+		{
+			Name:     "rate limited",
+			Err:      RateLimited{Dependency: "dynamodb", Reason: "throttled"},
+			Expected: "dynamodb rate limited the service: throttled",
+		},
+		{
+			Name:     "rate limited with stage",
+			Err:      RateLimited{Stage: "storeTimestamp", Dependency: "dynamodb", Reason: "throttled"},
+			Expected: "storeTimestamp failed: dynamodb rate limited the service: throttled",
+		},
+		{
+			Name:     "conflict",
+			Err:      Conflict{Dependency: "dynamodb", Reason: "condition failed"},
+			Expected: "dynamodb rejected a conflicting change: condition failed",
+		},
+		{
+			Name:     "conflict with stage",
+			Err:      Conflict{Stage: "storeTimestamp", Dependency: "dynamodb", Reason: "condition failed"},
+			Expected: "storeTimestamp failed: dynamodb rejected a conflicting change: condition failed",
+		},
+		{
+			Name:     "invalid configuration",
+			Err:      InvalidConfiguration{Dependency: "dynamodb", Reason: "no table"},
+			Expected: "dynamodb is misconfigured: no table",
+		},
+		{
+			Name:     "invalid configuration with stage",
+			Err:      InvalidConfiguration{Stage: "fetchTimestamp", Dependency: "dynamodb", Reason: "no table"},
+			Expected: "fetchTimestamp failed: dynamodb is misconfigured: no table",
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.Expected, tt.Err.Error())
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Event is produced about the health of the notifier or of Nexpose, rather than
+// for a completed scan. Detail holds the fields specific to the type of event, and
+// must be serializable as JSON.
+type Event struct {
+	ID     string
+	Type   string
+	Time   time.Time
+	Detail interface{}
+}
+
+// EventProducer is used to produce events other than completed scans.
+type EventProducer interface {
+	ProduceEvent(ctx context.Context, event Event) error
+}
+
+// NewEventID returns a stable identifier for an event, derived from its type and
+// the keys which identify the occurrence it describes. Producing the same event
+// again, such as on a later run after a failure, results in the same identifier.
+func NewEventID(eventType string, keys ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{eventType}, keys...), "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// NotifierHealth tracks the recent outcomes of notification runs, so that an event
+// can be produced once when the notifier becomes degraded and once when it recovers.
+type NotifierHealth struct {
+	ConsecutiveFailures int
+	LastError           string
+	LastSuccess         time.Time
+	// DegradedSince is when the notifier was first seen to be degraded, and is zero
+	// while it is healthy.
+	DegradedSince time.Time
+	// Reasons lists why the notifier is degraded.
+	Reasons []string
+	// Alerted is set once the degraded event has been produced, until the recovered
+	// event has been produced.
+	Alerted bool
+}
+
+// HealthFetcher retrieves the health of the notifier. The zero value is returned
+// when none is stored.
+type HealthFetcher interface {
+	FetchHealth(context.Context) (NotifierHealth, error)
+}
+
+// HealthStorer persists the health of the notifier.
+type HealthStorer interface {
+	StoreHealth(context.Context, NotifierHealth) error
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEventID(t *testing.T) {
+	eventID := NewEventID("notifier.degraded", "2019-06-01T00:00:00Z")
+	require.Len(t, eventID, 64)
+	require.Equal(t, eventID, NewEventID("notifier.degraded", "2019-06-01T00:00:00Z"))
+	require.NotEqual(t, eventID, NewEventID("notifier.recovered", "2019-06-01T00:00:00Z"))
+	require.NotEqual(t, eventID, NewEventID("notifier.degraded", "2019-06-02T00:00:00Z"))
+}
@@ -1,8 +1,40 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"time"
+)
 
 // The Producer interface is used to produce completed scans onto a queue.
 type Producer interface {
 	Produce(ctx context.Context, scan CompletedScan) error
 }
+
+// DeadLetter represents a scan which could not be produced to a destination after
+// every attempt, kept so that it can be replayed later.
+type DeadLetter struct {
+	Destination string
+	Scan        CompletedScan
+	Reason      string
+	Attempts    int
+	FailedAt    time.Time
+}
+
+// DeadLetterStorer persists scans which could not be produced to a destination.
+type DeadLetterStorer interface {
+	StoreDeadLetter(context.Context, DeadLetter) error
+}
+
+// ScanDeadLettered is returned by a Producer which could not produce a scan, but
+// stored it as a dead letter instead, so that delivery may continue with the next
+// scan as though it had been produced.
+type ScanDeadLettered struct {
+	Destination string
+	ScanID      string
+	Reason      string
+}
+
+func (e ScanDeadLettered) Error() string {
+	return fmt.Sprintf("scan %s was stored as a dead letter for %s: %s", e.ScanID, e.Destination, e.Reason)
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RunRecord is the history of a single notification run.
+type RunRecord struct {
+	RunID           string
+	Source          string
+	StartTime       time.Time
+	EndTime         time.Time
+	Fetched         int
+	Produced        int
+	WatermarkBefore time.Time
+	WatermarkAfter  time.Time
+	Partial         bool
+	Targeted        bool
+	DryRun          bool
+	Error           string
+}
+
+// RunQuery describes which runs to fetch from the run history.
+type RunQuery struct {
+	// Since excludes runs which started before this time.
+	Since time.Time
+	// Until excludes runs which started at or after this time.
+	Until time.Time
+	// Limit is the maximum number of runs to return.
+	Limit int
+	// Cursor continues from the end of a previous page, if set.
+	Cursor string
+}
+
+// RunPage contains the runs matching a RunQuery, most recent first.
+type RunPage struct {
+	Runs []RunRecord
+	// Cursor continues from the end of this page, and is empty when no runs remain.
+	Cursor string
+}
+
+// RunRecorder persists the history of notification runs.
+type RunRecorder interface {
+	RecordRun(context.Context, RunRecord) error
+}
+
+// RunFetcher retrieves the history of notification runs.
+type RunFetcher interface {
+	FetchRuns(context.Context, RunQuery) (RunPage, error)
+}
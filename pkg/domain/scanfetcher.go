@@ -2,19 +2,204 @@ package domain
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 )
 
-// CompletedScan represents identifiers for a completed Nexpose scan.
+// CompletedScan represents identifiers for a completed Nexpose scan. The site
+// details are only populated when scans are enriched with site metadata, and
+// SiteUnavailable is true when the site of the scan could not be looked up.
 type CompletedScan struct {
-	ScanID    string
-	SiteID    string
-	ScanType  string
-	StartTime time.Time
-	EndTime   time.Time
+	Console         string
+	ScanID          string
+	SiteID          string
+	ScanType        string
+	ScanName        string
+	Status          string
+	StartTime       time.Time
+	EndTime         time.Time
+	Site            Site
+	SiteUnavailable bool
+}
+
+// EventID returns a stable identifier for the event produced for a completed scan,
+// derived from the console, scan ID, status, and end time. Producing the same scan
+// again, such as on a retry or rerun, results in the same identifier, which
+// consumers may use to de-duplicate events.
+func (s CompletedScan) EventID() string {
+	key := strings.Join([]string{
+		s.Console,
+		s.ScanID,
+		strings.ToLower(s.Status),
+		s.EndTime.UTC().Format(time.RFC3339Nano),
+	}, "|")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Site represents the details of the Nexpose site a scan belongs to.
+type Site struct {
+	Name        string
+	Description string
+	Importance  string
+	Tags        []string
+}
+
+// SiteFetcher fetches the details of a Nexpose site.
+type SiteFetcher interface {
+	FetchSite(ctx context.Context, siteID string) (Site, error)
+}
+
+// SiteLister lists the IDs of every Nexpose site.
+type SiteLister interface {
+	ListSites(ctx context.Context) ([]string, error)
+}
+
+// SiteNotFound is returned when a site does not exist.
+type SiteNotFound struct {
+	SiteID string
+}
+
+func (e SiteNotFound) Error() string {
+	return fmt.Sprintf("site %s not found", e.SiteID)
+}
+
+// ScanQuery describes which completed scans to fetch.
+type ScanQuery struct {
+	// Since excludes scans which completed at or before this time.
+	Since time.Time
+	// Limit is the maximum number of scans to return, keeping those which completed
+	// earliest. Zero means no limit.
+	Limit int
+	// Deadline, when set, stops the crawl for scans once it has passed. Zero means
+	// no deadline.
+	Deadline time.Time
+	// Recheck, when not nil, contains the IDs of the scans to re-check for having
+	// finished late instead of those tracked as in flight, which are then neither
+	// fetched nor returned. Nil re-checks the tracked scans.
+	Recheck []string
+}
+
+// ScanResult contains the completed scans matching a ScanQuery.
+type ScanResult struct {
+	// Scans contains the completed scans held in memory, in no particular order.
+	Scans []CompletedScan
+	// Spilled reads the completed scans instead of Scans when they were spilled to
+	// a file while crawling, and must be closed once read. It is nil otherwise.
+	Spilled ScanReader
+	// Truncated is true when more scans matched the query than were returned.
+	Truncated bool
+	// Incomplete is true when the crawl stopped at the query's deadline. Since scans are
+	// crawled most recent first, the earliest may not have been fetched, so no scans
+	// are returned, and the query should be made again.
+	Incomplete bool
+	// Pages is the number of pages of scans fetched.
+	Pages int
+	// Skipped counts the scans which were fetched but not returned.
+	Skipped SkippedScans
+	// InFlight contains the IDs of every scan still in flight, which must be stored
+	// with an InFlightScanStorer once the returned scans have been produced. It is
+	// nil when in-flight scans are not tracked or have not changed.
+	InFlight []string
+}
+
+// Reader returns a reader of the completed scans in ascending order by end time,
+// which must be closed once read.
+func (r ScanResult) Reader() ScanReader {
+	if r.Spilled != nil {
+		return r.Spilled
+	}
+	scans := append([]CompletedScan(nil), r.Scans...)
+	sort.SliceStable(scans, func(left, right int) bool {
+		return scans[left].EndTime.Before(scans[right].EndTime)
+	})
+	return &sliceScanReader{scans: scans}
+}
+
+// ScanReader reads completed scans one at a time, in ascending order by end time.
+type ScanReader interface {
+	// Len returns the total number of scans the reader returns.
+	Len() int
+	// Next returns the next scan, or io.EOF once every scan has been read.
+	Next() (CompletedScan, error)
+	// Close releases any resources held by the reader.
+	Close() error
+}
+
+// ReadScans reads every remaining scan from a reader into memory, then closes it.
+func ReadScans(reader ScanReader) ([]CompletedScan, error) {
+	defer reader.Close()
+	scans := make([]CompletedScan, 0, reader.Len())
+	for {
+		scan, err := reader.Next()
+		if err == io.EOF {
+			return scans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+}
+
+type sliceScanReader struct {
+	scans []CompletedScan
+	next  int
+}
+
+func (r *sliceScanReader) Len() int {
+	return len(r.scans)
+}
+
+func (r *sliceScanReader) Next() (CompletedScan, error) {
+	if r.next >= len(r.scans) {
+		return CompletedScan{}, io.EOF
+	}
+	scan := r.scans[r.next]
+	r.next = r.next + 1
+	return scan, nil
+}
+
+func (r *sliceScanReader) Close() error {
+	return nil
+}
+
+// SkippedScans counts the scans which were not returned by a ScanFetcher, by the
+// reason they were skipped.
+type SkippedScans struct {
+	// NotFinished scans do not have a status of "finished".
+	NotFinished int
+	// Blocklisted scans have a name in the blocklist.
+	Blocklisted int
+	// NotSettled scans ended within the settle window.
+	NotSettled int
+	// Malformed scans could not be parsed.
+	Malformed int
+	// OutOfRange scans completed at or before the time scans were fetched since.
+	OutOfRange int
 }
 
 // ScanFetcher fetchs scans completed from the provided time until now.
 type ScanFetcher interface {
-	FetchScans(context.Context, time.Time) ([]CompletedScan, error)
+	FetchScans(context.Context, ScanQuery) (ScanResult, error)
+}
+
+// QuarantinedScan represents a scan record which could not be parsed, kept for
+// later inspection.
+type QuarantinedScan struct {
+	ScanID        string
+	SiteID        string
+	Reason        string
+	Record        string
+	QuarantinedAt time.Time
+}
+
+// ScanQuarantiner persists scan records which could not be parsed.
+type ScanQuarantiner interface {
+	QuarantineScan(context.Context, QuarantinedScan) error
 }
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletedScanEventID(t *testing.T) {
+	endTime := time.Date(2019, 05, 24, 12, 30, 00, 00, time.UTC)
+	scan := CompletedScan{
+		Console:   "nexpose.example.com",
+		ScanID:    "1",
+		SiteID:    "11",
+		ScanName:  "Weekly Scan",
+		Status:    "finished",
+		StartTime: endTime.Add(-1 * time.Hour),
+		EndTime:   endTime,
+	}
+	eventID := scan.EventID()
+	require.Len(t, eventID, 64)
+
+	tests := []struct {
+		name   string
+		modify func(CompletedScan) CompletedScan
+		same   bool
+	}{
+		{
+			name:   "same scan",
+			modify: func(s CompletedScan) CompletedScan { return s },
+			same:   true,
+		},
+		{
+			name: "enriched or renamed scan",
+			modify: func(s CompletedScan) CompletedScan {
+				s.ScanName = "Renamed Scan"
+				s.Site = Site{Name: "Site 11"}
+				return s
+			},
+			same: true,
+		},
+		{
+			name: "end time in another location",
+			modify: func(s CompletedScan) CompletedScan {
+				s.EndTime = s.EndTime.In(time.FixedZone("UTC+10", 10*60*60))
+				return s
+			},
+			same: true,
+		},
+		{
+			name: "status case",
+			modify: func(s CompletedScan) CompletedScan {
+				s.Status = "Finished"
+				return s
+			},
+			same: true,
+		},
+		{
+			name: "other console",
+			modify: func(s CompletedScan) CompletedScan {
+				s.Console = "other.example.com"
+				return s
+			},
+		},
+		{
+			name: "other scan",
+			modify: func(s CompletedScan) CompletedScan {
+				s.ScanID = "2"
+				return s
+			},
+		},
+		{
+			name: "other status",
+			modify: func(s CompletedScan) CompletedScan {
+				s.Status = "stopped"
+				return s
+			},
+		},
+		{
+			name: "other end time",
+			modify: func(s CompletedScan) CompletedScan {
+				s.EndTime = s.EndTime.Add(time.Nanosecond)
+				return s
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := tt.modify(scan).EventID()
+			if tt.same {
+				require.Equal(t, eventID, actual)
+				return
+			}
+			require.NotEqual(t, eventID, actual)
+		})
+	}
+}
+
+func TestScanResultReader(t *testing.T) {
+	endTime := time.Date(2019, 05, 24, 12, 30, 00, 00, time.UTC)
+	result := ScanResult{Scans: []CompletedScan{
+		{ScanID: "3", EndTime: endTime.Add(time.Hour)},
+		{ScanID: "1", EndTime: endTime},
+		{ScanID: "4", EndTime: endTime.Add(time.Hour)},
+		{ScanID: "2", EndTime: endTime},
+	}}
+
+	// scans are read in ascending order by end time, keeping their order on ties
+	reader := result.Reader()
+	require.Equal(t, 4, reader.Len())
+	scans, err := ReadScans(reader)
+	require.NoError(t, err)
+	var scanIDs []string
+	for _, scan := range scans {
+		scanIDs = append(scanIDs, scan.ScanID)
+	}
+	require.Equal(t, []string{"1", "2", "3", "4"}, scanIDs)
+	require.Equal(t, "3", result.Scans[0].ScanID)
+
+	// a spilled result is read from its reader
+	spilled := ScanResult{Spilled: reader}
+	require.Equal(t, reader, spilled.Reader())
+	_, err = reader.Next()
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SiteScanState tracks the most recent completed scan of a site, and whether the
+// site is overdue for a scan.
+type SiteScanState struct {
+	SiteID      string
+	LastScanID  string
+	LastScanEnd time.Time
+	// TrackedSince is when the site was first tracked, from which a site with no
+	// completed scans becomes overdue.
+	TrackedSince time.Time
+	// OverdueSince is when the site was first seen to be overdue, and is zero while
+	// it is not.
+	OverdueSince time.Time
+	// Alerted is set once the overdue event for the site has been produced, until
+	// the site is scanned again.
+	Alerted bool
+}
+
+// SiteScanRecorder tracks the most recent completed scan of each site.
+type SiteScanRecorder interface {
+	RecordSiteScans(context.Context, []CompletedScan) error
+}
+
+// SiteScanFetcher retrieves the tracked state of every site, ordered by site ID.
+type SiteScanFetcher interface {
+	FetchSiteScans(context.Context) ([]SiteScanState, error)
+}
+
+// SiteScanStorer replaces the tracked state of sites. The most recent completed scan
+// of a site is kept if one was recorded after the given state was fetched.
+type SiteScanStorer interface {
+	StoreSiteScans(context.Context, []SiteScanState) error
+}
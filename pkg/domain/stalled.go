@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ActiveScan represents a Nexpose scan which has not yet reached a terminal status.
+type ActiveScan struct {
+	Console   string
+	ScanID    string
+	SiteID    string
+	ScanType  string
+	ScanName  string
+	Status    string
+	StartTime time.Time
+}
+
+// ActiveScanFetcher fetches the scans which are currently active.
+type ActiveScanFetcher interface {
+	FetchActiveScans(context.Context) ([]ActiveScan, error)
+}
+
+// ActiveScanChecker looks up a single scan which is missing from the active scans,
+// since a scan may briefly disappear from them while it is still active.
+type ActiveScanChecker interface {
+	IsScanActive(ctx context.Context, scanID string) (bool, error)
+}
+
+// StalledScan tracks an active scan which has run for longer than allowed.
+type StalledScan struct {
+	ScanID string
+	SiteID string
+	// StalledSince is when the scan was first seen to have run for too long.
+	StalledSince time.Time
+	// Alerted is set once the stalled event for the scan has been produced.
+	Alerted bool
+}
+
+// StalledScanFetcher retrieves the scans which were stalled as of the last check.
+type StalledScanFetcher interface {
+	FetchStalledScans(context.Context) ([]StalledScan, error)
+}
+
+// StalledScanStorer replaces the scans which are stalled.
+type StalledScanStorer interface {
+	StoreStalledScans(context.Context, []StalledScan) error
+}
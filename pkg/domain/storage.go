@@ -16,9 +16,44 @@ type TimestampFetcher interface {
 	FetchTimestamp(context.Context) (time.Time, error)
 }
 
+// TimestampResetter provides a method to remove the timestamp from storage, so that
+// subsequent fetches behave as though no scan has ever been processed.
+type TimestampResetter interface {
+	ResetTimestamp(context.Context) error
+}
+
+// InFlightScanStorer provides a method to persist the IDs of scans which have not yet
+// reached a terminal status.
+type InFlightScanStorer interface {
+	StoreInFlightScans(context.Context, []string) error
+}
+
+// InFlightScanFetcher provides a method to retrieve the IDs of scans which had not yet
+// reached a terminal status when last seen. An empty list is returned when none are stored.
+type InFlightScanFetcher interface {
+	FetchInFlightScans(context.Context) ([]string, error)
+}
+
 // TimestampNotFound is used to indicate that no timestamp value exists in storage.
 type TimestampNotFound struct{}
 
 func (e TimestampNotFound) Error() string {
 	return fmt.Sprintf("no timestamp found in storage")
 }
+
+// WatermarkChange is the audit record of a manual change to the stored timestamp.
+// Previous and Current are zero when no timestamp was or is stored.
+type WatermarkChange struct {
+	Action    string
+	Actor     string
+	Reason    string
+	Previous  time.Time
+	Current   time.Time
+	ChangedAt time.Time
+}
+
+// WatermarkChangeRecorder persists the audit records of manual changes to the
+// stored timestamp.
+type WatermarkChangeRecorder interface {
+	RecordWatermarkChange(context.Context, WatermarkChange) error
+}
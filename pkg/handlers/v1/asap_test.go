@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// asapToken returns an Authorization header with an unsigned token for subject.
+func asapToken(subject string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"test/key"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"test","sub":"` + subject + `","aud":"nexpose-scan-notifier"}`))
+	return "Bearer " + header + "." + claims + ".signature"
+}
+
+func TestASAPSubject(t *testing.T) {
+	tc := []struct {
+		Name          string
+		Authorization string
+		Subject       string
+		Err           error
+	}{
+		{
+			Name:          "subject",
+			Authorization: asapToken("ops-automation"),
+			Subject:       "ops-automation",
+		},
+		{
+			Name:          "not a bearer token",
+			Authorization: "Basic dXNlcjpwYXNz",
+			Err:           domain.InvalidInput{Field: "authorization", Reason: "must be an ASAP bearer token"},
+		},
+		{
+			Name:          "not a JWT",
+			Authorization: "Bearer token",
+			Err:           domain.InvalidInput{Field: "authorization", Reason: "must be an ASAP bearer token"},
+		},
+		{
+			Name:          "no subject",
+			Authorization: asapToken(""),
+			Err:           domain.InvalidInput{Field: "authorization", Reason: "token has no subject"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			subject, err := asapSubject(tt.Authorization)
+			require.Equal(t, tt.Err, err)
+			require.Equal(t, tt.Subject, subject)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// NotificationConfig holds the budget for a single notification run.
+type NotificationConfig struct {
+	MaxScans    int           `description:"The maximum number of scans to produce in a single run, or 0 for no limit."`
+	MaxDuration time.Duration `description:"How long a single run may spend producing scans before stopping, or 0 for no limit."`
+}
+
+// Name is used by the settings library and will add a "NOTIFICATION_"
+// prefix to NotificationConfig environment variables
+func (c *NotificationConfig) Name() string {
+	return "Notification"
+}
+
+// NotificationComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type NotificationComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*NotificationComponent) Settings() *NotificationConfig {
+	return &NotificationConfig{}
+}
+
+// New constructs a NotificationHandler from a config. The scan fetcher, storage,
+// and producer must be set on the result before use.
+func (*NotificationComponent) New(_ context.Context, c *NotificationConfig) (*NotificationHandler, error) {
+	if c.MaxScans < 0 {
+		return nil, fmt.Errorf("notification max scans must not be negative, got %d", c.MaxScans)
+	}
+	if c.MaxDuration < 0 {
+		return nil, fmt.Errorf("notification max duration must not be negative, got %s", c.MaxDuration)
+	}
+	return &NotificationHandler{
+		MaxScans:    c.MaxScans,
+		MaxDuration: c.MaxDuration,
+		LogFn:       domain.LoggerFromContext,
+		StatFn:      domain.StatFromContext,
+	}, nil
+}
+
+// HealthConfig holds the thresholds past which the notifier is degraded.
+type HealthConfig struct {
+	MaxConsecutiveFailures int           `description:"The number of consecutive failed runs after which the notifier is degraded, or 0 to ignore failures."`
+	MaxWatermarkAge        time.Duration `description:"How old the stored timestamp may become before the notifier is degraded, or 0 to ignore its age."`
+}
+
+// Name is used by the settings library and will add a "HEALTH_"
+// prefix to HealthConfig environment variables
+func (c *HealthConfig) Name() string {
+	return "Health"
+}
+
+// HealthComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type HealthComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*HealthComponent) Settings() *HealthConfig {
+	return &HealthConfig{MaxConsecutiveFailures: 3}
+}
+
+// New constructs a HealthMonitor from a config. The storage and event producer
+// must be set on the result before use.
+func (*HealthComponent) New(_ context.Context, c *HealthConfig) (*HealthMonitor, error) {
+	if c.MaxConsecutiveFailures < 0 {
+		return nil, fmt.Errorf("health max consecutive failures must not be negative, got %d", c.MaxConsecutiveFailures)
+	}
+	if c.MaxWatermarkAge < 0 {
+		return nil, fmt.Errorf("health max watermark age must not be negative, got %s", c.MaxWatermarkAge)
+	}
+	return &HealthMonitor{
+		MaxConsecutiveFailures: c.MaxConsecutiveFailures,
+		MaxWatermarkAge:        c.MaxWatermarkAge,
+		LogFn:                  domain.LoggerFromContext,
+	}, nil
+}
+
+// SiteScanConfig holds the maximum intervals between completed scans of sites,
+// past which a site is overdue for a scan.
+type SiteScanConfig struct {
+	DefaultMaxInterval time.Duration `description:"How long a site may go without a completed scan before it is overdue, or 0 to only check sites with their own interval."`
+	MaxIntervals       string        `description:"Comma separated siteID=duration pairs of the maximum interval of individual sites, where 0s never checks a site."`
+}
+
+// Name is used by the settings library and will add a "SITESCAN_"
+// prefix to SiteScanConfig environment variables
+func (c *SiteScanConfig) Name() string {
+	return "SiteScan"
+}
+
+// SiteScanComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type SiteScanComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*SiteScanComponent) Settings() *SiteScanConfig {
+	return &SiteScanConfig{}
+}
+
+// New constructs a SiteScanHandler from a config. The storage and event producer
+// must be set on the result before use.
+func (*SiteScanComponent) New(_ context.Context, c *SiteScanConfig) (*SiteScanHandler, error) {
+	if c.DefaultMaxInterval < 0 {
+		return nil, fmt.Errorf("site scan default max interval must not be negative, got %s", c.DefaultMaxInterval)
+	}
+	maxIntervals, err := parseDurations("site scan max interval", "siteID", c.MaxIntervals)
+	if err != nil {
+		return nil, err
+	}
+	return &SiteScanHandler{
+		DefaultMaxInterval: c.DefaultMaxInterval,
+		MaxIntervals:       maxIntervals,
+		LogFn:              domain.LoggerFromContext,
+	}, nil
+}
+
+// StalledScanConfig holds the maximum runtimes of active scans, past which a scan
+// is stalled.
+type StalledScanConfig struct {
+	DefaultMaxRuntime   time.Duration `description:"How long an active scan may run before it is stalled, or 0 to only check scans with their own maximum runtime."`
+	ScanTypeMaxRuntimes string        `description:"Comma separated scanType=duration pairs of the maximum runtime of scans by their type, where 0s never checks a scan."`
+	SiteMaxRuntimes     string        `description:"Comma separated siteID=duration pairs of the maximum runtime of scans by their site, which take precedence over scan types."`
+}
+
+// Name is used by the settings library and will add a "STALLEDSCAN_"
+// prefix to StalledScanConfig environment variables
+func (c *StalledScanConfig) Name() string {
+	return "StalledScan"
+}
+
+// StalledScanComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type StalledScanComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*StalledScanComponent) Settings() *StalledScanConfig {
+	return &StalledScanConfig{}
+}
+
+// New constructs a StalledScanMonitor from a config. The active scan fetcher,
+// storage and event producer must be set on the result before use.
+func (*StalledScanComponent) New(_ context.Context, c *StalledScanConfig) (*StalledScanMonitor, error) {
+	if c.DefaultMaxRuntime < 0 {
+		return nil, fmt.Errorf("stalled scan default max runtime must not be negative, got %s", c.DefaultMaxRuntime)
+	}
+	scanTypeMaxRuntimes, err := parseDurations("stalled scan max runtime", "scanType", strings.ToLower(c.ScanTypeMaxRuntimes))
+	if err != nil {
+		return nil, err
+	}
+	siteMaxRuntimes, err := parseDurations("stalled scan max runtime", "siteID", c.SiteMaxRuntimes)
+	if err != nil {
+		return nil, err
+	}
+	return &StalledScanMonitor{
+		DefaultMaxRuntime:   c.DefaultMaxRuntime,
+		ScanTypeMaxRuntimes: scanTypeMaxRuntimes,
+		SiteMaxRuntimes:     siteMaxRuntimes,
+		LogFn:               domain.LoggerFromContext,
+	}, nil
+}
+
+// parseDurations parses comma separated key=duration pairs, such as those of a
+// setting which overrides a duration for individual sites.
+func parseDurations(setting string, key string, value string) (map[string]time.Duration, error) {
+	durations := map[string]time.Duration{}
+	if strings.TrimSpace(value) == "" {
+		return durations, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%s must be a %s=duration pair, got %q", setting, key, pair)
+		}
+		if _, ok := durations[parts[0]]; ok {
+			return nil, fmt.Errorf("%s of %s %s is set more than once", setting, key, parts[0])
+		}
+		duration, err := time.ParseDuration(parts[1])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("%s of %s %s is invalid: %q", setting, key, parts[0], parts[1])
+		}
+		durations[parts[0]] = duration
+	}
+	return durations, nil
+}
@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationName(t *testing.T) {
+	notificationConfig := NotificationConfig{}
+	require.Equal(t, "Notification", notificationConfig.Name())
+}
+
+func TestNotificationComponentDefaultConfig(t *testing.T) {
+	component := &NotificationComponent{}
+	config := component.Settings()
+	require.Zero(t, config.MaxScans)
+	require.Zero(t, config.MaxDuration)
+}
+
+func TestNotificationComponentNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *NotificationConfig
+		expectErr bool
+	}{
+		{
+			name:   "no budget",
+			config: &NotificationConfig{},
+		},
+		{
+			name:   "budget",
+			config: &NotificationConfig{MaxScans: 500, MaxDuration: 10 * time.Minute},
+		},
+		{
+			name:      "negative max scans",
+			config:    &NotificationConfig{MaxScans: -1},
+			expectErr: true,
+		},
+		{
+			name:      "negative max duration",
+			config:    &NotificationConfig{MaxDuration: -1 * time.Minute},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := (&NotificationComponent{}).New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.config.MaxScans, handler.MaxScans)
+			require.Equal(t, tt.config.MaxDuration, handler.MaxDuration)
+			require.NotNil(t, handler.LogFn)
+			require.NotNil(t, handler.StatFn)
+		})
+	}
+}
+
+func TestHealthName(t *testing.T) {
+	healthConfig := HealthConfig{}
+	require.Equal(t, "Health", healthConfig.Name())
+}
+
+func TestHealthComponentDefaultConfig(t *testing.T) {
+	component := &HealthComponent{}
+	config := component.Settings()
+	require.Equal(t, 3, config.MaxConsecutiveFailures)
+	require.Zero(t, config.MaxWatermarkAge)
+}
+
+func TestHealthComponentNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *HealthConfig
+		expectErr bool
+	}{
+		{
+			name:   "disabled",
+			config: &HealthConfig{},
+		},
+		{
+			name:   "thresholds",
+			config: &HealthConfig{MaxConsecutiveFailures: 5, MaxWatermarkAge: 48 * time.Hour},
+		},
+		{
+			name:      "negative max consecutive failures",
+			config:    &HealthConfig{MaxConsecutiveFailures: -1},
+			expectErr: true,
+		},
+		{
+			name:      "negative max watermark age",
+			config:    &HealthConfig{MaxWatermarkAge: -1 * time.Hour},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor, err := (&HealthComponent{}).New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.config.MaxConsecutiveFailures, monitor.MaxConsecutiveFailures)
+			require.Equal(t, tt.config.MaxWatermarkAge, monitor.MaxWatermarkAge)
+			require.NotNil(t, monitor.LogFn)
+		})
+	}
+}
+
+func TestSiteScanName(t *testing.T) {
+	siteScanConfig := SiteScanConfig{}
+	require.Equal(t, "SiteScan", siteScanConfig.Name())
+}
+
+func TestSiteScanComponentNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *SiteScanConfig
+		maxIntervals map[string]time.Duration
+		expectErr    bool
+	}{
+		{
+			name:         "defaults",
+			config:       (&SiteScanComponent{}).Settings(),
+			maxIntervals: map[string]time.Duration{},
+		},
+		{
+			name:         "max intervals",
+			config:       &SiteScanConfig{DefaultMaxInterval: 168 * time.Hour, MaxIntervals: "12=24h, 15=0s"},
+			maxIntervals: map[string]time.Duration{"12": 24 * time.Hour, "15": 0},
+		},
+		{
+			name:      "negative default max interval",
+			config:    &SiteScanConfig{DefaultMaxInterval: -1 * time.Hour},
+			expectErr: true,
+		},
+		{
+			name:      "missing duration",
+			config:    &SiteScanConfig{MaxIntervals: "12"},
+			expectErr: true,
+		},
+		{
+			name:      "missing site",
+			config:    &SiteScanConfig{MaxIntervals: "=24h"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid duration",
+			config:    &SiteScanConfig{MaxIntervals: "12=weekly"},
+			expectErr: true,
+		},
+		{
+			name:      "negative duration",
+			config:    &SiteScanConfig{MaxIntervals: "12=-1h"},
+			expectErr: true,
+		},
+		{
+			name:      "duplicate site",
+			config:    &SiteScanConfig{MaxIntervals: "12=24h,12=48h"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := (&SiteScanComponent{}).New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.config.DefaultMaxInterval, handler.DefaultMaxInterval)
+			require.Equal(t, tt.maxIntervals, handler.MaxIntervals)
+			require.NotNil(t, handler.LogFn)
+		})
+	}
+}
+
+func TestStalledScanName(t *testing.T) {
+	stalledScanConfig := StalledScanConfig{}
+	require.Equal(t, "StalledScan", stalledScanConfig.Name())
+}
+
+func TestStalledScanComponentNew(t *testing.T) {
+	tests := []struct {
+		name                string
+		config              *StalledScanConfig
+		scanTypeMaxRuntimes map[string]time.Duration
+		siteMaxRuntimes     map[string]time.Duration
+		expectErr           bool
+	}{
+		{
+			name:                "defaults",
+			config:              (&StalledScanComponent{}).Settings(),
+			scanTypeMaxRuntimes: map[string]time.Duration{},
+			siteMaxRuntimes:     map[string]time.Duration{},
+		},
+		{
+			name:                "max runtimes",
+			config:              &StalledScanConfig{DefaultMaxRuntime: 24 * time.Hour, ScanTypeMaxRuntimes: "Scheduled=48h,Manual=0s", SiteMaxRuntimes: "12=72h"},
+			scanTypeMaxRuntimes: map[string]time.Duration{"scheduled": 48 * time.Hour, "manual": 0},
+			siteMaxRuntimes:     map[string]time.Duration{"12": 72 * time.Hour},
+		},
+		{
+			name:      "negative default max runtime",
+			config:    &StalledScanConfig{DefaultMaxRuntime: -1 * time.Hour},
+			expectErr: true,
+		},
+		{
+			name:      "invalid scan type max runtime",
+			config:    &StalledScanConfig{ScanTypeMaxRuntimes: "Scheduled"},
+			expectErr: true,
+		},
+		{
+			name:      "duplicate scan type",
+			config:    &StalledScanConfig{ScanTypeMaxRuntimes: "Scheduled=48h,scheduled=24h"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid site max runtime",
+			config:    &StalledScanConfig{SiteMaxRuntimes: "12=-1h"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor, err := (&StalledScanComponent{}).New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.config.DefaultMaxRuntime, monitor.DefaultMaxRuntime)
+			require.Equal(t, tt.scanTypeMaxRuntimes, monitor.ScanTypeMaxRuntimes)
+			require.Equal(t, tt.siteMaxRuntimes, monitor.SiteMaxRuntimes)
+			require.NotNil(t, monitor.LogFn)
+		})
+	}
+}
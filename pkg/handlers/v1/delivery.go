@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+// deliveryQueueSize is the number of scans which may wait to be produced to an
+// additional destination before the destination falls behind for the rest of a run.
+const deliveryQueueSize = 100
+
+// delivery produces scans to an additional destination in the background, in the
+// order they are queued, so that a slow or failing destination does not hold back
+// the primary Producer. Once a scan fails, the remaining scans are skipped, and the
+// destination catches up from its stored timestamp on a later run.
+//
+// The scans which ended at or before lateUntil are remembered once they are
+// received, so that the run can tell which scans that finished late the destination
+// missed.
+type delivery struct {
+	scans     chan domain.CompletedScan
+	done      chan struct{}
+	lateUntil time.Time
+
+	// set by the run, which queues scans, or by the background delivery of a
+	// destination catching up, and only read once it is done
+	queued time.Time
+	behind bool
+
+	// set by the background delivery, and only read once it is done
+	err          error
+	deadLettered int
+	received     map[string]bool
+}
+
+// startDelivery begins delivering queued scans to a destination, storing the
+// timestamp of each scan it receives in watermark.
+func (h *NotificationHandler) startDelivery(ctx context.Context, destination Destination, watermark *time.Time,
+	lateUntil time.Time) *delivery {
+	d := &delivery{
+		scans:     make(chan domain.CompletedScan, deliveryQueueSize),
+		done:      make(chan struct{}),
+		lateUntil: lateUntil,
+		received:  make(map[string]bool),
+	}
+	go func() {
+		defer close(d.done)
+		for scan := range d.scans {
+			if d.err != nil {
+				continue
+			}
+			d.deliver(ctx, h, destination, scan, watermark)
+		}
+	}()
+	return d
+}
+
+// startCatchUp begins delivering scans to a destination whose stored timestamp is
+// behind the primary Producer's, or which missed scans that finished late, fetching
+// them with its own query rather than holding back the scans fetched for the
+// primary. No scans are queued to it. Once
+// the deadline of the query has passed, no more scans are produced, except those
+// which ended at the same time as the previous scan.
+func (h *NotificationHandler) startCatchUp(ctx context.Context, destination Destination, watermark *time.Time,
+	lateUntil time.Time, query domain.ScanQuery) *delivery {
+	d := &delivery{
+		done:      make(chan struct{}),
+		lateUntil: lateUntil,
+		received:  make(map[string]bool),
+	}
+	go func() {
+		defer close(d.done)
+		result, err := h.ScanFetcher.FetchScans(ctx, query)
+		if err != nil {
+			h.LogFn(ctx).Error(logs.ScanFetcherFailure{Reason: err.Error()})
+			d.err = withStage(err, stageFetchScans)
+			return
+		}
+		reader := result.Reader()
+		defer reader.Close()
+		d.behind = result.Truncated || result.Incomplete
+		for {
+			scan, err := reader.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				h.LogFn(ctx).Error(logs.ScanFetcherFailure{Reason: err.Error()})
+				d.err = withStage(err, stageFetchScans)
+				return
+			}
+			if !query.Deadline.IsZero() && time.Now().After(query.Deadline) && scan.EndTime.After(d.queued) {
+				d.behind = true
+				return
+			}
+			d.queued = scan.EndTime
+			if d.deliver(ctx, h, destination, scan, watermark); d.err != nil {
+				return
+			}
+		}
+	}()
+	return d
+}
+
+// deliver produces a single scan to the destination, recording its outcome.
+func (d *delivery) deliver(ctx context.Context, h *NotificationHandler, destination Destination,
+	scan domain.CompletedScan, watermark *time.Time) {
+	deadLettered, err := h.produce(ctx, destination, scan, watermark)
+	if deadLettered {
+		d.deadLettered = d.deadLettered + 1
+	}
+	d.err = err
+	if err == nil && !scan.EndTime.After(d.lateUntil) {
+		d.received[scan.ScanID] = true
+	}
+}
+
+// failedDelivery returns a delivery to a destination which failed before any scans
+// could be delivered to it.
+func failedDelivery(err error) *delivery {
+	d := &delivery{done: make(chan struct{}), err: err}
+	close(d.done)
+	return d
+}
+
+// queue adds a scan to the delivery without waiting for the destination, unless
+// the scan ended at the same time as the previous scan queued, since the timestamp
+// stored for the previous scan would exclude it from later runs. A destination
+// whose queue is full falls behind, and receives no more scans during the run.
+func (d *delivery) queue(scan domain.CompletedScan) {
+	if d.behind {
+		return
+	}
+	if scan.EndTime.Equal(d.queued) {
+		d.scans <- scan
+		return
+	}
+	select {
+	case d.scans <- scan:
+		d.queued = scan.EndTime
+	default:
+		d.behind = true
+	}
+}
+
+// finish waits for every queued scan to be delivered, returning the error which
+// stopped the delivery, if any.
+func (d *delivery) finish() error {
+	if d.scans != nil {
+		close(d.scans)
+	}
+	<-d.done
+	return d.err
+}
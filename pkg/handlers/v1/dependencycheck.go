@@ -6,18 +6,43 @@ import (
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 )
 
+// DependencyCheckOutput contains the state of the circuit breakers protecting
+// external dependencies.
+type DependencyCheckOutput struct {
+	CircuitBreakers []circuitBreakerStatus `json:"circuitBreakers"`
+}
+
+type circuitBreakerStatus struct {
+	Dependency string `json:"dependency"`
+	State      string `json:"state"`
+}
+
 // DependencyCheckHandler takes in a domain.DependencyChecker to check external dependencies
 type DependencyCheckHandler struct {
 	DynamoDBDependencyChecker      domain.DependencyChecker
 	NexposeClientDependencyChecker domain.DependencyChecker
+	CircuitBreakers                []domain.CircuitBreakerStater
 }
 
 // Handle makes a call CheckDependencies from DependencyChecker that verifies this
-// app can talk to it's external dependencies
-func (h *DependencyCheckHandler) Handle(ctx context.Context) error {
+// app can talk to it's external dependencies, and reports the state of each circuit
+// breaker. A dependency protected by an open circuit breaker fails the check.
+func (h *DependencyCheckHandler) Handle(ctx context.Context) (DependencyCheckOutput, error) {
 	err := h.DynamoDBDependencyChecker.CheckDependencies(ctx)
 	if err != nil {
-		return err
+		return DependencyCheckOutput{}, err
+	}
+	if err := h.NexposeClientDependencyChecker.CheckDependencies(ctx); err != nil {
+		return DependencyCheckOutput{}, err
+	}
+
+	output := DependencyCheckOutput{CircuitBreakers: make([]circuitBreakerStatus, 0, len(h.CircuitBreakers))}
+	for _, breaker := range h.CircuitBreakers {
+		state := breaker.CircuitBreakerState()
+		output.CircuitBreakers = append(output.CircuitBreakers, circuitBreakerStatus{
+			Dependency: state.Dependency,
+			State:      state.State,
+		})
 	}
-	return h.NexposeClientDependencyChecker.CheckDependencies(ctx)
+	return output, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,7 +23,7 @@ func TestDepCheckHandleSuccess(t *testing.T) {
 		DynamoDBDependencyChecker:      DynamoDBMockDependencyChecker,
 		NexposeClientDependencyChecker: NexposeClientMockDependencyChecker,
 	}
-	err := handler.Handle(context.Background())
+	_, err := handler.Handle(context.Background())
 
 	assert.Nil(t, err)
 }
@@ -39,7 +40,7 @@ func TestDepCheckHandleDBError(t *testing.T) {
 		DynamoDBDependencyChecker:      DynamoDBMockDependencyChecker,
 		NexposeClientDependencyChecker: NexposeClientMockDependencyChecker,
 	}
-	err := handler.Handle(context.Background())
+	_, err := handler.Handle(context.Background())
 
 	assert.NotNil(t, err)
 }
@@ -57,7 +58,40 @@ func TestDepCheckHandleNexposeClientError(t *testing.T) {
 		DynamoDBDependencyChecker:      DynamoDBMockDependencyChecker,
 		NexposeClientDependencyChecker: NexposeClientMockDependencyChecker,
 	}
-	err := handler.Handle(context.Background())
+	_, err := handler.Handle(context.Background())
 
 	assert.NotNil(t, err)
 }
+
+func TestDepCheckHandleCircuitBreakers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	DynamoDBMockDependencyChecker := NewMockDependencyChecker(ctrl)
+	DynamoDBMockDependencyChecker.EXPECT().CheckDependencies(context.Background()).Return(nil)
+	NexposeClientMockDependencyChecker := NewMockDependencyChecker(ctrl)
+	NexposeClientMockDependencyChecker.EXPECT().CheckDependencies(context.Background()).Return(nil)
+	NexposeMockCircuitBreaker := NewMockCircuitBreakerStater(ctrl)
+	NexposeMockCircuitBreaker.EXPECT().CircuitBreakerState().Return(domain.CircuitBreakerState{
+		Dependency: "nexpose",
+		State:      "closed",
+	})
+	ProducerMockCircuitBreaker := NewMockCircuitBreakerStater(ctrl)
+	ProducerMockCircuitBreaker.EXPECT().CircuitBreakerState().Return(domain.CircuitBreakerState{
+		Dependency: "producer",
+		State:      "open",
+	})
+
+	handler := &DependencyCheckHandler{
+		DynamoDBDependencyChecker:      DynamoDBMockDependencyChecker,
+		NexposeClientDependencyChecker: NexposeClientMockDependencyChecker,
+		CircuitBreakers:                []domain.CircuitBreakerStater{NexposeMockCircuitBreaker, ProducerMockCircuitBreaker},
+	}
+	output, err := handler.Handle(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, DependencyCheckOutput{CircuitBreakers: []circuitBreakerStatus{
+		{Dependency: "nexpose", State: "closed"},
+		{Dependency: "producer", State: "open"},
+	}}, output)
+}
@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"reflect"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// The stages of a notification run, named by the errors it returns so that the
+// caller can tell which part of the run failed.
+const (
+	stageFetchTimestamp = "fetchTimestamp"
+	stageFetchScans     = "fetchScans"
+	stageProduce        = "produce"
+	stageStoreTimestamp = "storeTimestamp"
+	stageStoreInFlight  = "storeInFlightScans"
+)
+
+// withStage names the stage of a run in which err occurred. Domain errors keep
+// their type, so that they can be mapped to a response status, and any other
+// error becomes a domain.RunFailure.
+func withStage(err error, stage string) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case domain.AuthenticationFailure:
+		e.Stage = stage
+		return e
+	case domain.UpstreamUnavailable:
+		e.Stage = stage
+		return e
+	case domain.RateLimited:
+		e.Stage = stage
+		return e
+	case domain.Conflict:
+		e.Stage = stage
+		return e
+	case domain.InvalidConfiguration:
+		e.Stage = stage
+		return e
+	case domain.RunFailure:
+		return e
+	}
+	return domain.RunFailure{Stage: stage, Reason: err.Error()}
+}
+
+// ErrorResponse describes a failed request with the fields of a Lambda error, along
+// with the stage of the notification run which failed, which is nil for failures
+// outside of a run.
+type ErrorResponse struct {
+	ErrorMessage string  `json:"errorMessage"`
+	ErrorType    string  `json:"errorType"`
+	Stage        *string `json:"stage"`
+}
+
+// NewErrorResponse describes err, naming its type as a Lambda error does, so that
+// the failure can be returned with its stage rather than leaving the stage to be
+// parsed from the message.
+func NewErrorResponse(err error) ErrorResponse {
+	errType := reflect.TypeOf(err)
+	if errType.Kind() == reflect.Ptr {
+		errType = errType.Elem()
+	}
+	response := ErrorResponse{ErrorMessage: err.Error(), ErrorType: errType.Name()}
+	if stage := errorStage(err); stage != "" {
+		response.Stage = &stage
+	}
+	return response
+}
+
+// errorStage returns the stage of a run named by err, if any.
+func errorStage(err error) string {
+	switch e := err.(type) {
+	case domain.AuthenticationFailure:
+		return e.Stage
+	case domain.UpstreamUnavailable:
+		return e.Stage
+	case domain.RateLimited:
+		return e.Stage
+	case domain.Conflict:
+		return e.Stage
+	case domain.InvalidConfiguration:
+		return e.Stage
+	case domain.RunFailure:
+		return e.Stage
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStage(t *testing.T) {
+	tc := []struct {
+		Name     string
+		Err      error
+		Expected error
+	}{
+		{
+			Name:     "nil",
+			Err:      nil,
+			Expected: nil,
+		},
+		{
+			Name:     "authentication failure",
+			Err:      domain.AuthenticationFailure{Dependency: "nexpose", Reason: "401"},
+			Expected: domain.AuthenticationFailure{Stage: "produce", Dependency: "nexpose", Reason: "401"},
+		},
+		{
+			Name:     "upstream unavailable",
+			Err:      domain.UpstreamUnavailable{Dependency: "nexpose", Reason: "503"},
+			Expected: domain.UpstreamUnavailable{Stage: "produce", Dependency: "nexpose", Reason: "503"},
+		},
+		{
+			Name:     "rate limited",
+			Err:      domain.RateLimited{Dependency: "nexpose", Reason: "429"},
+			Expected: domain.RateLimited{Stage: "produce", Dependency: "nexpose", Reason: "429"},
+		},
+		{
+			Name:     "conflict",
+			Err:      domain.Conflict{Dependency: "dynamodb", Reason: "409"},
+			Expected: domain.Conflict{Stage: "produce", Dependency: "dynamodb", Reason: "409"},
+		},
+		{
+			Name:     "invalid configuration",
+			Err:      domain.InvalidConfiguration{Dependency: "dynamodb", Reason: "no table"},
+			Expected: domain.InvalidConfiguration{Stage: "produce", Dependency: "dynamodb", Reason: "no table"},
+		},
+		{
+			Name:     "run failure",
+			Err:      domain.RunFailure{Stage: "storeTimestamp", Reason: "failed"},
+			Expected: domain.RunFailure{Stage: "storeTimestamp", Reason: "failed"},
+		},
+		{
+			Name:     "other error",
+			Err:      errors.New("failed"),
+			Expected: domain.RunFailure{Stage: "produce", Reason: "failed"},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.Expected, withStage(tt.Err, stageProduce))
+		})
+	}
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	stage := "fetchScans"
+	tc := []struct {
+		Name     string
+		Err      error
+		Expected ErrorResponse
+	}{
+		{
+			Name: "failure of a run stage",
+			Err:  domain.RateLimited{Stage: stage, Dependency: "nexpose", Reason: "429"},
+			Expected: ErrorResponse{
+				ErrorMessage: "fetchScans failed: nexpose rate limited the service: 429",
+				ErrorType:    "RateLimited",
+				Stage:        &stage,
+			},
+		},
+		{
+			Name: "run failure",
+			Err:  domain.RunFailure{Stage: stage, Reason: "unexpected"},
+			Expected: ErrorResponse{
+				ErrorMessage: "fetchScans failed: unexpected",
+				ErrorType:    "RunFailure",
+				Stage:        &stage,
+			},
+		},
+		{
+			Name:     "failure outside of a run",
+			Err:      domain.InvalidInput{Field: "since", Reason: "invalid"},
+			Expected: ErrorResponse{ErrorMessage: domain.InvalidInput{Field: "since", Reason: "invalid"}.Error(), ErrorType: "InvalidInput"},
+		},
+		{
+			Name:     "pointer error",
+			Err:      &json.SyntaxError{},
+			Expected: ErrorResponse{ErrorMessage: (&json.SyntaxError{}).Error(), ErrorType: "SyntaxError"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.Expected, NewErrorResponse(tt.Err))
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	eventTypeNotifierDegraded  = "notifier.degraded"
+	eventTypeNotifierRecovered = "notifier.recovered"
+
+	degradedReasonConsecutiveFailures = "consecutiveFailures"
+	degradedReasonStaleWatermark      = "staleWatermark"
+)
+
+type notifierDegradedDetail struct {
+	Reasons             []string `json:"reasons"`
+	ConsecutiveFailures int      `json:"consecutiveFailures"`
+	LastError           string   `json:"lastError,omitempty"`
+	LastSuccess         string   `json:"lastSuccess,omitempty"`
+	Watermark           string   `json:"watermark,omitempty"`
+	DegradedSince       string   `json:"degradedSince"`
+}
+
+type notifierRecoveredDetail struct {
+	DegradedSince string `json:"degradedSince"`
+	Duration      string `json:"duration"`
+	Watermark     string `json:"watermark,omitempty"`
+}
+
+// HealthMonitor tracks the outcome of each regular notification run, and produces
+// a notifier.degraded event once the notifier has failed MaxConsecutiveFailures
+// runs in a row, or the stored timestamp is older than MaxWatermarkAge. A
+// notifier.recovered event is produced once neither is true. Either threshold is
+// ignored when zero.
+//
+// An event which cannot be produced is retried after the next run, so that an
+// outage of the event producer does not lose the alert.
+type HealthMonitor struct {
+	HealthFetcher          domain.HealthFetcher
+	HealthStorer           domain.HealthStorer
+	TimestampFetcher       domain.TimestampFetcher
+	EventProducer          domain.EventProducer
+	LogFn                  domain.LogFn
+	MaxConsecutiveFailures int
+	MaxWatermarkAge        time.Duration
+}
+
+// Observe updates the health of the notifier with the outcome of a run, producing
+// an event if the notifier became degraded or recovered.
+func (m *HealthMonitor) Observe(ctx context.Context, runErr error) {
+	logger := m.LogFn(ctx)
+	health, err := m.HealthFetcher.FetchHealth(ctx)
+	if err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if runErr == nil {
+		health.ConsecutiveFailures = 0
+		health.LastError = ""
+		health.LastSuccess = now
+	} else {
+		health.ConsecutiveFailures = health.ConsecutiveFailures + 1
+		health.LastError = runErr.Error()
+	}
+	watermark := m.fetchWatermark(ctx)
+
+	var reasons []string
+	if m.MaxConsecutiveFailures > 0 && health.ConsecutiveFailures >= m.MaxConsecutiveFailures {
+		reasons = append(reasons, degradedReasonConsecutiveFailures)
+	}
+	if m.MaxWatermarkAge > 0 && !watermark.IsZero() && now.Sub(watermark) > m.MaxWatermarkAge {
+		reasons = append(reasons, degradedReasonStaleWatermark)
+	}
+
+	switch {
+	case len(reasons) > 0:
+		if health.DegradedSince.IsZero() {
+			health.DegradedSince = now
+			logger.Warn(logs.NotifierDegraded{
+				Reasons:             strings.Join(reasons, ","),
+				ConsecutiveFailures: health.ConsecutiveFailures,
+				LastError:           health.LastError,
+				Watermark:           formatWatermark(watermark),
+			})
+		}
+		health.Reasons = reasons
+		if !health.Alerted {
+			health.Alerted = m.produce(ctx, domain.Event{
+				ID:   domain.NewEventID(eventTypeNotifierDegraded, health.DegradedSince.UTC().Format(time.RFC3339Nano)),
+				Type: eventTypeNotifierDegraded,
+				Time: now,
+				Detail: notifierDegradedDetail{
+					Reasons:             reasons,
+					ConsecutiveFailures: health.ConsecutiveFailures,
+					LastError:           health.LastError,
+					LastSuccess:         formatWatermark(health.LastSuccess),
+					Watermark:           formatWatermark(watermark),
+					DegradedSince:       formatWatermark(health.DegradedSince),
+				},
+			})
+		}
+	case health.Alerted:
+		duration := now.Sub(health.DegradedSince).String()
+		recovered := m.produce(ctx, domain.Event{
+			ID:   domain.NewEventID(eventTypeNotifierRecovered, health.DegradedSince.UTC().Format(time.RFC3339Nano)),
+			Type: eventTypeNotifierRecovered,
+			Time: now,
+			Detail: notifierRecoveredDetail{
+				DegradedSince: formatWatermark(health.DegradedSince),
+				Duration:      duration,
+				Watermark:     formatWatermark(watermark),
+			},
+		})
+		if recovered {
+			logger.Info(logs.NotifierRecovered{
+				DegradedSince: formatWatermark(health.DegradedSince),
+				Duration:      duration,
+			})
+			health.Alerted = false
+			health.DegradedSince = time.Time{}
+			health.Reasons = nil
+		}
+	default:
+		health.DegradedSince = time.Time{}
+		health.Reasons = nil
+	}
+
+	if err := m.HealthStorer.StoreHealth(ctx, health); err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+	}
+}
+
+// fetchWatermark returns the stored timestamp, or the zero time if none is stored
+// or it cannot be fetched.
+func (m *HealthMonitor) fetchWatermark(ctx context.Context) time.Time {
+	ts, err := m.TimestampFetcher.FetchTimestamp(ctx)
+	switch err.(type) {
+	case nil:
+		return ts
+	case domain.TimestampNotFound:
+		return time.Time{}
+	default:
+		m.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return time.Time{}
+	}
+}
+
+// produce sends an event, reporting whether it was produced.
+func (m *HealthMonitor) produce(ctx context.Context, event domain.Event) bool {
+	if err := m.EventProducer.ProduceEvent(ctx, event); err != nil {
+		m.LogFn(ctx).Error(logs.EventFailure{EventType: event.Type, Reason: err.Error()})
+		return false
+	}
+	return true
+}
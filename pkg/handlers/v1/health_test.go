@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// healthMonitorTest wires a HealthMonitor to mocks which keep the stored health
+// between observations.
+type healthMonitorTest struct {
+	monitor   *HealthMonitor
+	health    domain.NotifierHealth
+	watermark time.Time
+	eventErr  error
+	events    []domain.Event
+}
+
+func newHealthMonitorTest(ctrl *gomock.Controller) *healthMonitorTest {
+	test := &healthMonitorTest{}
+	mockHealthFetcher := NewMockHealthFetcher(ctrl)
+	mockHealthStorer := NewMockHealthStorer(ctrl)
+	mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+	mockEventProducer := NewMockEventProducer(ctrl)
+
+	mockHealthFetcher.EXPECT().FetchHealth(gomock.Any()).DoAndReturn(
+		func(context.Context) (domain.NotifierHealth, error) {
+			return test.health, nil
+		}).AnyTimes()
+	mockHealthStorer.EXPECT().StoreHealth(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, health domain.NotifierHealth) error {
+			test.health = health
+			return nil
+		}).AnyTimes()
+	mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).DoAndReturn(
+		func(context.Context) (time.Time, error) {
+			if test.watermark.IsZero() {
+				return time.Time{}, domain.TimestampNotFound{}
+			}
+			return test.watermark, nil
+		}).AnyTimes()
+	mockEventProducer.EXPECT().ProduceEvent(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, event domain.Event) error {
+			if test.eventErr != nil {
+				return test.eventErr
+			}
+			test.events = append(test.events, event)
+			return nil
+		}).AnyTimes()
+
+	test.monitor = &HealthMonitor{
+		HealthFetcher:          mockHealthFetcher,
+		HealthStorer:           mockHealthStorer,
+		TimestampFetcher:       mockTimestampFetcher,
+		EventProducer:          mockEventProducer,
+		LogFn:                  testLogFn,
+		MaxConsecutiveFailures: 3,
+		MaxWatermarkAge:        48 * time.Hour,
+	}
+	return test
+}
+
+func TestHealthMonitor_ConsecutiveFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newHealthMonitorTest(ctrl)
+	ctx := context.Background()
+	runErr := fmt.Errorf("fetchScans failed: nexpose rejected the credentials")
+
+	test.monitor.Observe(ctx, runErr)
+	test.monitor.Observe(ctx, nil)
+	test.monitor.Observe(ctx, runErr)
+	test.monitor.Observe(ctx, runErr)
+	require.Empty(t, test.events)
+	require.Equal(t, 2, test.health.ConsecutiveFailures)
+
+	test.monitor.Observe(ctx, runErr)
+	require.Len(t, test.events, 1)
+	degraded := test.events[0]
+	require.Equal(t, eventTypeNotifierDegraded, degraded.Type)
+	require.Equal(t, domain.NewEventID(eventTypeNotifierDegraded, test.health.DegradedSince.UTC().Format(time.RFC3339Nano)), degraded.ID)
+	detail := degraded.Detail.(notifierDegradedDetail)
+	require.Equal(t, []string{degradedReasonConsecutiveFailures}, detail.Reasons)
+	require.Equal(t, 3, detail.ConsecutiveFailures)
+	require.Equal(t, runErr.Error(), detail.LastError)
+	require.NotEmpty(t, detail.LastSuccess)
+	require.True(t, test.health.Alerted)
+
+	// the degraded event is only produced once
+	test.monitor.Observe(ctx, runErr)
+	require.Len(t, test.events, 1)
+
+	test.monitor.Observe(ctx, nil)
+	require.Len(t, test.events, 2)
+	recovered := test.events[1]
+	require.Equal(t, eventTypeNotifierRecovered, recovered.Type)
+	require.Equal(t, detail.DegradedSince, recovered.Detail.(notifierRecoveredDetail).DegradedSince)
+	require.Equal(t, domain.NotifierHealth{LastSuccess: test.health.LastSuccess}, test.health)
+
+	test.monitor.Observe(ctx, nil)
+	require.Len(t, test.events, 2)
+}
+
+func TestHealthMonitor_StaleWatermark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newHealthMonitorTest(ctrl)
+	ctx := context.Background()
+
+	// no stored timestamp is never stale
+	test.monitor.Observe(ctx, nil)
+	require.Empty(t, test.events)
+
+	test.watermark = time.Now().Add(-47 * time.Hour)
+	test.monitor.Observe(ctx, nil)
+	require.Empty(t, test.events)
+
+	test.watermark = time.Now().Add(-49 * time.Hour)
+	test.monitor.Observe(ctx, nil)
+	require.Len(t, test.events, 1)
+	detail := test.events[0].Detail.(notifierDegradedDetail)
+	require.Equal(t, []string{degradedReasonStaleWatermark}, detail.Reasons)
+	require.Equal(t, test.watermark.Format(time.RFC3339Nano), detail.Watermark)
+
+	test.watermark = time.Now()
+	test.monitor.Observe(ctx, nil)
+	require.Len(t, test.events, 2)
+	require.Equal(t, eventTypeNotifierRecovered, test.events[1].Type)
+}
+
+func TestHealthMonitor_EventFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newHealthMonitorTest(ctrl)
+	test.monitor.MaxConsecutiveFailures = 1
+	ctx := context.Background()
+	runErr := fmt.Errorf("produce failed")
+
+	// an event which cannot be produced is retried with the same ID after the next run
+	test.eventErr = fmt.Errorf("producer error")
+	test.monitor.Observe(ctx, runErr)
+	require.False(t, test.health.Alerted)
+	degradedSince := test.health.DegradedSince
+	require.False(t, degradedSince.IsZero())
+
+	test.eventErr = nil
+	test.monitor.Observe(ctx, runErr)
+	require.True(t, test.health.Alerted)
+	require.Len(t, test.events, 1)
+	require.Equal(t, domain.NewEventID(eventTypeNotifierDegraded, degradedSince.UTC().Format(time.RFC3339Nano)), test.events[0].ID)
+
+	test.eventErr = fmt.Errorf("producer error")
+	test.monitor.Observe(ctx, nil)
+	require.True(t, test.health.Alerted)
+
+	test.eventErr = nil
+	test.monitor.Observe(ctx, nil)
+	require.False(t, test.health.Alerted)
+	require.Len(t, test.events, 2)
+}
+
+func TestHealthMonitor_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newHealthMonitorTest(ctrl)
+	test.monitor.MaxConsecutiveFailures = 0
+	test.monitor.MaxWatermarkAge = 0
+	test.watermark = time.Now().Add(-1000 * time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i = i + 1 {
+		test.monitor.Observe(ctx, fmt.Errorf("fetchScans failed"))
+	}
+	require.Empty(t, test.events)
+	require.Equal(t, 10, test.health.ConsecutiveFailures)
+}
+
+func TestHealthMonitor_StorageFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockHealthFetcher := NewMockHealthFetcher(ctrl)
+	monitor := &HealthMonitor{
+		HealthFetcher:          mockHealthFetcher,
+		LogFn:                  testLogFn,
+		MaxConsecutiveFailures: 1,
+	}
+
+	// nothing is produced or stored when the health cannot be fetched
+	mockHealthFetcher.EXPECT().FetchHealth(gomock.Any()).Return(domain.NotifierHealth{}, fmt.Errorf("dynamodb error"))
+	monitor.Observe(context.Background(), fmt.Errorf("fetchScans failed"))
+}
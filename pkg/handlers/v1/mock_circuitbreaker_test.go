@@ -0,0 +1,48 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: CircuitBreakerStater)
+
+// Package v1 is a generated GoMock package.
+package v1
+
+import (
+	domain "github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockCircuitBreakerStater is a mock of CircuitBreakerStater interface
+type MockCircuitBreakerStater struct {
+	ctrl     *gomock.Controller
+	recorder *MockCircuitBreakerStaterMockRecorder
+}
+
+// MockCircuitBreakerStaterMockRecorder is the mock recorder for MockCircuitBreakerStater
+type MockCircuitBreakerStaterMockRecorder struct {
+	mock *MockCircuitBreakerStater
+}
+
+// NewMockCircuitBreakerStater creates a new mock instance
+func NewMockCircuitBreakerStater(ctrl *gomock.Controller) *MockCircuitBreakerStater {
+	mock := &MockCircuitBreakerStater{ctrl: ctrl}
+	mock.recorder = &MockCircuitBreakerStaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCircuitBreakerStater) EXPECT() *MockCircuitBreakerStaterMockRecorder {
+	return m.recorder
+}
+
+// CircuitBreakerState mocks base method
+func (m *MockCircuitBreakerStater) CircuitBreakerState() domain.CircuitBreakerState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CircuitBreakerState")
+	ret0, _ := ret[0].(domain.CircuitBreakerState)
+	return ret0
+}
+
+// CircuitBreakerState indicates an expected call of CircuitBreakerState
+func (mr *MockCircuitBreakerStaterMockRecorder) CircuitBreakerState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CircuitBreakerState", reflect.TypeOf((*MockCircuitBreakerStater)(nil).CircuitBreakerState))
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: Producer)
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: Producer,EventProducer)
 
 // Package v1 is a generated GoMock package.
 package v1
@@ -47,3 +47,40 @@ func (mr *MockProducerMockRecorder) Produce(arg0, arg1 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Produce", reflect.TypeOf((*MockProducer)(nil).Produce), arg0, arg1)
 }
+
+// MockEventProducer is a mock of EventProducer interface
+type MockEventProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventProducerMockRecorder
+}
+
+// MockEventProducerMockRecorder is the mock recorder for MockEventProducer
+type MockEventProducerMockRecorder struct {
+	mock *MockEventProducer
+}
+
+// NewMockEventProducer creates a new mock instance
+func NewMockEventProducer(ctrl *gomock.Controller) *MockEventProducer {
+	mock := &MockEventProducer{ctrl: ctrl}
+	mock.recorder = &MockEventProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockEventProducer) EXPECT() *MockEventProducerMockRecorder {
+	return m.recorder
+}
+
+// ProduceEvent mocks base method
+func (m *MockEventProducer) ProduceEvent(arg0 context.Context, arg1 domain.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceEvent indicates an expected call of ProduceEvent
+func (mr *MockEventProducerMockRecorder) ProduceEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceEvent", reflect.TypeOf((*MockEventProducer)(nil).ProduceEvent), arg0, arg1)
+}
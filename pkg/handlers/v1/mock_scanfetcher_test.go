@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: ScanFetcher)
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: ScanFetcher,ActiveScanFetcher,ActiveScanChecker,SiteLister)
 
 // Package v1 is a generated GoMock package.
 package v1
@@ -9,7 +9,6 @@ import (
 	domain "github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
-	time "time"
 )
 
 // MockScanFetcher is a mock of ScanFetcher interface
@@ -36,10 +35,10 @@ func (m *MockScanFetcher) EXPECT() *MockScanFetcherMockRecorder {
 }
 
 // FetchScans mocks base method
-func (m *MockScanFetcher) FetchScans(arg0 context.Context, arg1 time.Time) ([]domain.CompletedScan, error) {
+func (m *MockScanFetcher) FetchScans(arg0 context.Context, arg1 domain.ScanQuery) (domain.ScanResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "FetchScans", arg0, arg1)
-	ret0, _ := ret[0].([]domain.CompletedScan)
+	ret0, _ := ret[0].(domain.ScanResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -49,3 +48,117 @@ func (mr *MockScanFetcherMockRecorder) FetchScans(arg0, arg1 interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchScans", reflect.TypeOf((*MockScanFetcher)(nil).FetchScans), arg0, arg1)
 }
+
+// MockActiveScanFetcher is a mock of ActiveScanFetcher interface
+type MockActiveScanFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockActiveScanFetcherMockRecorder
+}
+
+// MockActiveScanFetcherMockRecorder is the mock recorder for MockActiveScanFetcher
+type MockActiveScanFetcherMockRecorder struct {
+	mock *MockActiveScanFetcher
+}
+
+// NewMockActiveScanFetcher creates a new mock instance
+func NewMockActiveScanFetcher(ctrl *gomock.Controller) *MockActiveScanFetcher {
+	mock := &MockActiveScanFetcher{ctrl: ctrl}
+	mock.recorder = &MockActiveScanFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockActiveScanFetcher) EXPECT() *MockActiveScanFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchActiveScans mocks base method
+func (m *MockActiveScanFetcher) FetchActiveScans(arg0 context.Context) ([]domain.ActiveScan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchActiveScans", arg0)
+	ret0, _ := ret[0].([]domain.ActiveScan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchActiveScans indicates an expected call of FetchActiveScans
+func (mr *MockActiveScanFetcherMockRecorder) FetchActiveScans(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchActiveScans", reflect.TypeOf((*MockActiveScanFetcher)(nil).FetchActiveScans), arg0)
+}
+
+// MockActiveScanChecker is a mock of ActiveScanChecker interface
+type MockActiveScanChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockActiveScanCheckerMockRecorder
+}
+
+// MockActiveScanCheckerMockRecorder is the mock recorder for MockActiveScanChecker
+type MockActiveScanCheckerMockRecorder struct {
+	mock *MockActiveScanChecker
+}
+
+// NewMockActiveScanChecker creates a new mock instance
+func NewMockActiveScanChecker(ctrl *gomock.Controller) *MockActiveScanChecker {
+	mock := &MockActiveScanChecker{ctrl: ctrl}
+	mock.recorder = &MockActiveScanCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockActiveScanChecker) EXPECT() *MockActiveScanCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsScanActive mocks base method
+func (m *MockActiveScanChecker) IsScanActive(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsScanActive", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsScanActive indicates an expected call of IsScanActive
+func (mr *MockActiveScanCheckerMockRecorder) IsScanActive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsScanActive", reflect.TypeOf((*MockActiveScanChecker)(nil).IsScanActive), arg0, arg1)
+}
+
+// MockSiteLister is a mock of SiteLister interface
+type MockSiteLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockSiteListerMockRecorder
+}
+
+// MockSiteListerMockRecorder is the mock recorder for MockSiteLister
+type MockSiteListerMockRecorder struct {
+	mock *MockSiteLister
+}
+
+// NewMockSiteLister creates a new mock instance
+func NewMockSiteLister(ctrl *gomock.Controller) *MockSiteLister {
+	mock := &MockSiteLister{ctrl: ctrl}
+	mock.recorder = &MockSiteListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockSiteLister) EXPECT() *MockSiteListerMockRecorder {
+	return m.recorder
+}
+
+// ListSites mocks base method
+func (m *MockSiteLister) ListSites(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSites", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSites indicates an expected call of ListSites
+func (mr *MockSiteListerMockRecorder) ListSites(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSites", reflect.TypeOf((*MockSiteLister)(nil).ListSites), arg0)
+}
@@ -1,11 +1,12 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: TimestampFetcher,TimestampStorer)
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: TimestampFetcher,TimestampStorer,TimestampResetter,WatermarkChangeRecorder,InFlightScanFetcher,InFlightScanStorer,RunRecorder,RunFetcher,HealthFetcher,HealthStorer,SiteScanRecorder,SiteScanFetcher,SiteScanStorer,StalledScanFetcher,StalledScanStorer)
 
 // Package v1 is a generated GoMock package.
 package v1
 
 import (
 	context "context"
+	domain "github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 	time "time"
@@ -85,3 +86,489 @@ func (mr *MockTimestampStorerMockRecorder) StoreTimestamp(arg0, arg1 interface{}
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreTimestamp", reflect.TypeOf((*MockTimestampStorer)(nil).StoreTimestamp), arg0, arg1)
 }
+
+// MockTimestampResetter is a mock of TimestampResetter interface
+type MockTimestampResetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockTimestampResetterMockRecorder
+}
+
+// MockTimestampResetterMockRecorder is the mock recorder for MockTimestampResetter
+type MockTimestampResetterMockRecorder struct {
+	mock *MockTimestampResetter
+}
+
+// NewMockTimestampResetter creates a new mock instance
+func NewMockTimestampResetter(ctrl *gomock.Controller) *MockTimestampResetter {
+	mock := &MockTimestampResetter{ctrl: ctrl}
+	mock.recorder = &MockTimestampResetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTimestampResetter) EXPECT() *MockTimestampResetterMockRecorder {
+	return m.recorder
+}
+
+// ResetTimestamp mocks base method
+func (m *MockTimestampResetter) ResetTimestamp(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetTimestamp", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetTimestamp indicates an expected call of ResetTimestamp
+func (mr *MockTimestampResetterMockRecorder) ResetTimestamp(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetTimestamp", reflect.TypeOf((*MockTimestampResetter)(nil).ResetTimestamp), arg0)
+}
+
+// MockWatermarkChangeRecorder is a mock of WatermarkChangeRecorder interface
+type MockWatermarkChangeRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockWatermarkChangeRecorderMockRecorder
+}
+
+// MockWatermarkChangeRecorderMockRecorder is the mock recorder for MockWatermarkChangeRecorder
+type MockWatermarkChangeRecorderMockRecorder struct {
+	mock *MockWatermarkChangeRecorder
+}
+
+// NewMockWatermarkChangeRecorder creates a new mock instance
+func NewMockWatermarkChangeRecorder(ctrl *gomock.Controller) *MockWatermarkChangeRecorder {
+	mock := &MockWatermarkChangeRecorder{ctrl: ctrl}
+	mock.recorder = &MockWatermarkChangeRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockWatermarkChangeRecorder) EXPECT() *MockWatermarkChangeRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordWatermarkChange mocks base method
+func (m *MockWatermarkChangeRecorder) RecordWatermarkChange(arg0 context.Context, arg1 domain.WatermarkChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordWatermarkChange", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordWatermarkChange indicates an expected call of RecordWatermarkChange
+func (mr *MockWatermarkChangeRecorderMockRecorder) RecordWatermarkChange(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordWatermarkChange", reflect.TypeOf((*MockWatermarkChangeRecorder)(nil).RecordWatermarkChange), arg0, arg1)
+}
+
+// MockInFlightScanFetcher is a mock of InFlightScanFetcher interface
+type MockInFlightScanFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockInFlightScanFetcherMockRecorder
+}
+
+// MockInFlightScanFetcherMockRecorder is the mock recorder for MockInFlightScanFetcher
+type MockInFlightScanFetcherMockRecorder struct {
+	mock *MockInFlightScanFetcher
+}
+
+// NewMockInFlightScanFetcher creates a new mock instance
+func NewMockInFlightScanFetcher(ctrl *gomock.Controller) *MockInFlightScanFetcher {
+	mock := &MockInFlightScanFetcher{ctrl: ctrl}
+	mock.recorder = &MockInFlightScanFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockInFlightScanFetcher) EXPECT() *MockInFlightScanFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchInFlightScans mocks base method
+func (m *MockInFlightScanFetcher) FetchInFlightScans(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchInFlightScans", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchInFlightScans indicates an expected call of FetchInFlightScans
+func (mr *MockInFlightScanFetcherMockRecorder) FetchInFlightScans(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchInFlightScans", reflect.TypeOf((*MockInFlightScanFetcher)(nil).FetchInFlightScans), arg0)
+}
+
+// MockInFlightScanStorer is a mock of InFlightScanStorer interface
+type MockInFlightScanStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockInFlightScanStorerMockRecorder
+}
+
+// MockInFlightScanStorerMockRecorder is the mock recorder for MockInFlightScanStorer
+type MockInFlightScanStorerMockRecorder struct {
+	mock *MockInFlightScanStorer
+}
+
+// NewMockInFlightScanStorer creates a new mock instance
+func NewMockInFlightScanStorer(ctrl *gomock.Controller) *MockInFlightScanStorer {
+	mock := &MockInFlightScanStorer{ctrl: ctrl}
+	mock.recorder = &MockInFlightScanStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockInFlightScanStorer) EXPECT() *MockInFlightScanStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreInFlightScans mocks base method
+func (m *MockInFlightScanStorer) StoreInFlightScans(arg0 context.Context, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreInFlightScans", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreInFlightScans indicates an expected call of StoreInFlightScans
+func (mr *MockInFlightScanStorerMockRecorder) StoreInFlightScans(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreInFlightScans", reflect.TypeOf((*MockInFlightScanStorer)(nil).StoreInFlightScans), arg0, arg1)
+}
+
+// MockRunRecorder is a mock of RunRecorder interface
+type MockRunRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockRunRecorderMockRecorder
+}
+
+// MockRunRecorderMockRecorder is the mock recorder for MockRunRecorder
+type MockRunRecorderMockRecorder struct {
+	mock *MockRunRecorder
+}
+
+// NewMockRunRecorder creates a new mock instance
+func NewMockRunRecorder(ctrl *gomock.Controller) *MockRunRecorder {
+	mock := &MockRunRecorder{ctrl: ctrl}
+	mock.recorder = &MockRunRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRunRecorder) EXPECT() *MockRunRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordRun mocks base method
+func (m *MockRunRecorder) RecordRun(arg0 context.Context, arg1 domain.RunRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordRun", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordRun indicates an expected call of RecordRun
+func (mr *MockRunRecorderMockRecorder) RecordRun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordRun", reflect.TypeOf((*MockRunRecorder)(nil).RecordRun), arg0, arg1)
+}
+
+// MockRunFetcher is a mock of RunFetcher interface
+type MockRunFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockRunFetcherMockRecorder
+}
+
+// MockRunFetcherMockRecorder is the mock recorder for MockRunFetcher
+type MockRunFetcherMockRecorder struct {
+	mock *MockRunFetcher
+}
+
+// NewMockRunFetcher creates a new mock instance
+func NewMockRunFetcher(ctrl *gomock.Controller) *MockRunFetcher {
+	mock := &MockRunFetcher{ctrl: ctrl}
+	mock.recorder = &MockRunFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRunFetcher) EXPECT() *MockRunFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchRuns mocks base method
+func (m *MockRunFetcher) FetchRuns(arg0 context.Context, arg1 domain.RunQuery) (domain.RunPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchRuns", arg0, arg1)
+	ret0, _ := ret[0].(domain.RunPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchRuns indicates an expected call of FetchRuns
+func (mr *MockRunFetcherMockRecorder) FetchRuns(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchRuns", reflect.TypeOf((*MockRunFetcher)(nil).FetchRuns), arg0, arg1)
+}
+
+// MockHealthFetcher is a mock of HealthFetcher interface
+type MockHealthFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthFetcherMockRecorder
+}
+
+// MockHealthFetcherMockRecorder is the mock recorder for MockHealthFetcher
+type MockHealthFetcherMockRecorder struct {
+	mock *MockHealthFetcher
+}
+
+// NewMockHealthFetcher creates a new mock instance
+func NewMockHealthFetcher(ctrl *gomock.Controller) *MockHealthFetcher {
+	mock := &MockHealthFetcher{ctrl: ctrl}
+	mock.recorder = &MockHealthFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHealthFetcher) EXPECT() *MockHealthFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchHealth mocks base method
+func (m *MockHealthFetcher) FetchHealth(arg0 context.Context) (domain.NotifierHealth, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchHealth", arg0)
+	ret0, _ := ret[0].(domain.NotifierHealth)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchHealth indicates an expected call of FetchHealth
+func (mr *MockHealthFetcherMockRecorder) FetchHealth(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchHealth", reflect.TypeOf((*MockHealthFetcher)(nil).FetchHealth), arg0)
+}
+
+// MockHealthStorer is a mock of HealthStorer interface
+type MockHealthStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthStorerMockRecorder
+}
+
+// MockHealthStorerMockRecorder is the mock recorder for MockHealthStorer
+type MockHealthStorerMockRecorder struct {
+	mock *MockHealthStorer
+}
+
+// NewMockHealthStorer creates a new mock instance
+func NewMockHealthStorer(ctrl *gomock.Controller) *MockHealthStorer {
+	mock := &MockHealthStorer{ctrl: ctrl}
+	mock.recorder = &MockHealthStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHealthStorer) EXPECT() *MockHealthStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreHealth mocks base method
+func (m *MockHealthStorer) StoreHealth(arg0 context.Context, arg1 domain.NotifierHealth) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreHealth", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreHealth indicates an expected call of StoreHealth
+func (mr *MockHealthStorerMockRecorder) StoreHealth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreHealth", reflect.TypeOf((*MockHealthStorer)(nil).StoreHealth), arg0, arg1)
+}
+
+// MockSiteScanRecorder is a mock of SiteScanRecorder interface
+type MockSiteScanRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockSiteScanRecorderMockRecorder
+}
+
+// MockSiteScanRecorderMockRecorder is the mock recorder for MockSiteScanRecorder
+type MockSiteScanRecorderMockRecorder struct {
+	mock *MockSiteScanRecorder
+}
+
+// NewMockSiteScanRecorder creates a new mock instance
+func NewMockSiteScanRecorder(ctrl *gomock.Controller) *MockSiteScanRecorder {
+	mock := &MockSiteScanRecorder{ctrl: ctrl}
+	mock.recorder = &MockSiteScanRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockSiteScanRecorder) EXPECT() *MockSiteScanRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordSiteScans mocks base method
+func (m *MockSiteScanRecorder) RecordSiteScans(arg0 context.Context, arg1 []domain.CompletedScan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordSiteScans", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordSiteScans indicates an expected call of RecordSiteScans
+func (mr *MockSiteScanRecorderMockRecorder) RecordSiteScans(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSiteScans", reflect.TypeOf((*MockSiteScanRecorder)(nil).RecordSiteScans), arg0, arg1)
+}
+
+// MockSiteScanFetcher is a mock of SiteScanFetcher interface
+type MockSiteScanFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockSiteScanFetcherMockRecorder
+}
+
+// MockSiteScanFetcherMockRecorder is the mock recorder for MockSiteScanFetcher
+type MockSiteScanFetcherMockRecorder struct {
+	mock *MockSiteScanFetcher
+}
+
+// NewMockSiteScanFetcher creates a new mock instance
+func NewMockSiteScanFetcher(ctrl *gomock.Controller) *MockSiteScanFetcher {
+	mock := &MockSiteScanFetcher{ctrl: ctrl}
+	mock.recorder = &MockSiteScanFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockSiteScanFetcher) EXPECT() *MockSiteScanFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchSiteScans mocks base method
+func (m *MockSiteScanFetcher) FetchSiteScans(arg0 context.Context) ([]domain.SiteScanState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchSiteScans", arg0)
+	ret0, _ := ret[0].([]domain.SiteScanState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchSiteScans indicates an expected call of FetchSiteScans
+func (mr *MockSiteScanFetcherMockRecorder) FetchSiteScans(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSiteScans", reflect.TypeOf((*MockSiteScanFetcher)(nil).FetchSiteScans), arg0)
+}
+
+// MockSiteScanStorer is a mock of SiteScanStorer interface
+type MockSiteScanStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockSiteScanStorerMockRecorder
+}
+
+// MockSiteScanStorerMockRecorder is the mock recorder for MockSiteScanStorer
+type MockSiteScanStorerMockRecorder struct {
+	mock *MockSiteScanStorer
+}
+
+// NewMockSiteScanStorer creates a new mock instance
+func NewMockSiteScanStorer(ctrl *gomock.Controller) *MockSiteScanStorer {
+	mock := &MockSiteScanStorer{ctrl: ctrl}
+	mock.recorder = &MockSiteScanStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockSiteScanStorer) EXPECT() *MockSiteScanStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreSiteScans mocks base method
+func (m *MockSiteScanStorer) StoreSiteScans(arg0 context.Context, arg1 []domain.SiteScanState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreSiteScans", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreSiteScans indicates an expected call of StoreSiteScans
+func (mr *MockSiteScanStorerMockRecorder) StoreSiteScans(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreSiteScans", reflect.TypeOf((*MockSiteScanStorer)(nil).StoreSiteScans), arg0, arg1)
+}
+
+// MockStalledScanFetcher is a mock of StalledScanFetcher interface
+type MockStalledScanFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockStalledScanFetcherMockRecorder
+}
+
+// MockStalledScanFetcherMockRecorder is the mock recorder for MockStalledScanFetcher
+type MockStalledScanFetcherMockRecorder struct {
+	mock *MockStalledScanFetcher
+}
+
+// NewMockStalledScanFetcher creates a new mock instance
+func NewMockStalledScanFetcher(ctrl *gomock.Controller) *MockStalledScanFetcher {
+	mock := &MockStalledScanFetcher{ctrl: ctrl}
+	mock.recorder = &MockStalledScanFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockStalledScanFetcher) EXPECT() *MockStalledScanFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchStalledScans mocks base method
+func (m *MockStalledScanFetcher) FetchStalledScans(arg0 context.Context) ([]domain.StalledScan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchStalledScans", arg0)
+	ret0, _ := ret[0].([]domain.StalledScan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchStalledScans indicates an expected call of FetchStalledScans
+func (mr *MockStalledScanFetcherMockRecorder) FetchStalledScans(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchStalledScans", reflect.TypeOf((*MockStalledScanFetcher)(nil).FetchStalledScans), arg0)
+}
+
+// MockStalledScanStorer is a mock of StalledScanStorer interface
+type MockStalledScanStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStalledScanStorerMockRecorder
+}
+
+// MockStalledScanStorerMockRecorder is the mock recorder for MockStalledScanStorer
+type MockStalledScanStorerMockRecorder struct {
+	mock *MockStalledScanStorer
+}
+
+// NewMockStalledScanStorer creates a new mock instance
+func NewMockStalledScanStorer(ctrl *gomock.Controller) *MockStalledScanStorer {
+	mock := &MockStalledScanStorer{ctrl: ctrl}
+	mock.recorder = &MockStalledScanStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockStalledScanStorer) EXPECT() *MockStalledScanStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreStalledScans mocks base method
+func (m *MockStalledScanStorer) StoreStalledScans(arg0 context.Context, arg1 []domain.StalledScan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreStalledScans", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreStalledScans indicates an expected call of StoreStalledScans
+func (mr *MockStalledScanStorerMockRecorder) StoreStalledScans(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreStalledScans", reflect.TypeOf((*MockStalledScanStorer)(nil).StoreStalledScans), arg0, arg1)
+}
@@ -2,16 +2,54 @@ package v1
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
 )
 
-// Output contains a list of completed Nexpose scans.
+// Output contains a list of completed Nexpose scans. MoreRemaining is true when the
+// run stopped early to stay within its budget, and the handler should be invoked again.
+// Summary describes what the run did.
 type Output struct {
-	Response []scanNotification `json:"response"`
+	Response      []scanNotification `json:"response"`
+	MoreRemaining bool               `json:"moreRemaining"`
+	Summary       runSummary         `json:"summary"`
+}
+
+// runSummary describes a single notification run. Fetched counts the scans returned
+// by the scan fetcher, and Skipped the scans it did not return, along with those
+// excluded by the filters of a targeted run. The watermarks are those of the primary
+// destination, and are omitted when no timestamp was stored or the run was given its
+// own start time. DeadLettered counts the scans stored as dead letters by each
+// additional destination. Partial is true when the run stopped early, or an
+// additional destination failed, fell behind or stored scans as dead letters.
+type runSummary struct {
+	RunID           string         `json:"runID"`
+	Fetched         int            `json:"fetched"`
+	Produced        int            `json:"produced"`
+	Skipped         skippedScans   `json:"skipped"`
+	DeadLettered    map[string]int `json:"deadLettered,omitempty"`
+	PagesFetched    int            `json:"pagesFetched"`
+	WatermarkBefore string         `json:"watermarkBefore,omitempty"`
+	WatermarkAfter  string         `json:"watermarkAfter,omitempty"`
+	Duration        string         `json:"duration"`
+	Partial         bool           `json:"partial"`
+}
+
+// skippedScans counts the scans which were not produced, by the reason they were skipped.
+type skippedScans struct {
+	NotFinished int `json:"notFinished"`
+	Blocklisted int `json:"blocklisted"`
+	NotSettled  int `json:"notSettled"`
+	Malformed   int `json:"malformed"`
+	OutOfRange  int `json:"outOfRange"`
+	Filtered    int `json:"filtered"`
 }
 
 // scanNotification represents a completed scan event.
@@ -23,59 +61,568 @@ type scanNotification struct {
 	EndTime   string `json:"endTime"`
 }
 
+// NotificationInput optionally overrides how a single notification run behaves.
+// The zero value runs exactly as configured.
+//
+// A run with Since, Until, SiteIDs or ScanTypes set is targeted: matching scans are
+// produced only to the handler's Producer, and no timestamps are stored, so regular
+// runs are unaffected. A dry run produces nothing and stores nothing, and returns the
+// scans which would have been produced.
+type NotificationInput struct {
+	Since     string   `json:"since,omitempty"`
+	Until     string   `json:"until,omitempty"`
+	SiteIDs   []string `json:"siteIDs,omitempty"`
+	ScanTypes []string `json:"scanTypes,omitempty"`
+	MaxScans  int      `json:"maxScans,omitempty"`
+	DryRun    bool     `json:"dryRun,omitempty"`
+}
+
+// runOptions are the validated overrides of a single run.
+type runOptions struct {
+	since     time.Time
+	until     time.Time
+	siteIDs   map[string]bool
+	scanTypes map[string]bool
+	maxScans  int
+	dryRun    bool
+}
+
+func (in NotificationInput) options(maxScans int) (runOptions, error) {
+	options := runOptions{maxScans: maxScans, dryRun: in.DryRun}
+	var err error
+	if in.Since != "" {
+		if options.since, err = time.Parse(time.RFC3339Nano, in.Since); err != nil {
+			return runOptions{}, domain.InvalidInput{Field: "since", Reason: err.Error()}
+		}
+	}
+	if in.Until != "" {
+		if options.until, err = time.Parse(time.RFC3339Nano, in.Until); err != nil {
+			return runOptions{}, domain.InvalidInput{Field: "until", Reason: err.Error()}
+		}
+		if !options.since.IsZero() && options.until.Before(options.since) {
+			return runOptions{}, domain.InvalidInput{Field: "until", Reason: "must not be before since"}
+		}
+	}
+	if len(in.SiteIDs) > 0 {
+		options.siteIDs = make(map[string]bool, len(in.SiteIDs))
+		for _, siteID := range in.SiteIDs {
+			options.siteIDs[siteID] = true
+		}
+	}
+	if len(in.ScanTypes) > 0 {
+		options.scanTypes = make(map[string]bool, len(in.ScanTypes))
+		for _, scanType := range in.ScanTypes {
+			options.scanTypes[strings.ToLower(scanType)] = true
+		}
+	}
+	switch {
+	case in.MaxScans < 0:
+		return runOptions{}, domain.InvalidInput{Field: "maxScans", Reason: "must not be negative"}
+	case in.MaxScans > 0:
+		options.maxScans = in.MaxScans
+	}
+	return options, nil
+}
+
+// targeted is true when the run only covers some scans, and so must not store
+// timestamps which would cause regular runs to skip the others.
+func (o runOptions) targeted() bool {
+	return !o.since.IsZero() || !o.until.IsZero() || o.siteIDs != nil || o.scanTypes != nil
+}
+
+func (o runOptions) matches(scan domain.CompletedScan) bool {
+	if !o.until.IsZero() && scan.EndTime.After(o.until) {
+		return false
+	}
+	if o.siteIDs != nil && !o.siteIDs[scan.SiteID] {
+		return false
+	}
+	if o.scanTypes != nil && !o.scanTypes[strings.ToLower(scan.ScanType)] {
+		return false
+	}
+	return true
+}
+
+// primaryDestination names the destination served by the handler's Producer.
+const primaryDestination = "default"
+
+// Destination is an additional destination for completed scans. Each destination
+// stores the timestamp of the last scan it received separately, so that one which
+// is failing does not hold back the others. The IDs of scans which finished late and
+// which the destination missed are tracked by InFlightScanFetcher and
+// InFlightScanStorer when they are set, and re-checked when it catches up.
+type Destination struct {
+	Name                string
+	Producer            domain.Producer
+	TimestampFetcher    domain.TimestampFetcher
+	TimestampStorer     domain.TimestampStorer
+	InFlightScanFetcher domain.InFlightScanFetcher
+	InFlightScanStorer  domain.InFlightScanStorer
+}
+
 // NotificationHandler takes a duration and returns a list of completed scans.
+//
+// The scans which the scan fetcher reports as still in flight are stored by
+// InFlightScanStorer only once every scan has been produced to the Producer and its
+// timestamp stored, so that a failed run does not stop tracking scans which finished
+// late before they are produced.
+//
+// Targeted and dry runs use ReadOnlyScanFetcher and ReadOnlyTimestampFetcher when
+// they are set, which must not record in-flight or quarantined scans or store a
+// bootstrapped timestamp, so that those runs do not change what regular runs produce.
+//
+// Every run is recorded by RunRecorder when it is set, naming Source as what
+// triggered the run. The outcome of every regular run, which is neither targeted
+// nor a dry run, is observed by HealthMonitor when it is set, and the scans it
+// produces are recorded by SiteScanRecorder when it is set. Active scans are
+// checked by StalledScanMonitor after every regular run when it is set.
 type NotificationHandler struct {
-	ScanFetcher      domain.ScanFetcher
-	TimestampFetcher domain.TimestampFetcher
-	TimestampStorer  domain.TimestampStorer
-	Producer         domain.Producer
-	LogFn            domain.LogFn
-	StatFn           domain.StatFn
+	ScanFetcher              domain.ScanFetcher
+	ReadOnlyScanFetcher      domain.ScanFetcher
+	TimestampFetcher         domain.TimestampFetcher
+	ReadOnlyTimestampFetcher domain.TimestampFetcher
+	TimestampStorer          domain.TimestampStorer
+	InFlightScanStorer       domain.InFlightScanStorer
+	Producer                 domain.Producer
+	Destinations             []Destination
+	RunRecorder              domain.RunRecorder
+	Source                   string
+	HealthMonitor            *HealthMonitor
+	SiteScanRecorder         domain.SiteScanRecorder
+	StalledScanMonitor       *StalledScanMonitor
+	LogFn                    domain.LogFn
+	StatFn                   domain.StatFn
+	MaxScans                 int
+	MaxDuration              time.Duration
 }
 
 // Handle queries for completed scans since the last known successfully processed
 // scan timestamp, produces all completed scans to a queue, and returns the list
 // of completed scans.
-func (h *NotificationHandler) Handle(ctx context.Context) (Output, error) {
+//
+// When a budget is configured, only the oldest MaxScans scans are produced, and no
+// further scans are produced once MaxDuration has elapsed. The crawl for scans also
+// stops once MaxDuration has elapsed, in which case no scans are produced. The stored
+// timestamp reflects the scans which were produced, and the output reports that more remain.
+//
+// Scans are also produced to any additional destinations. A destination whose stored
+// timestamp is behind the primary Producer's fetches the scans it missed with its own
+// query, limited by the same budget, rather than holding back the primary. A
+// destination which fails stops receiving scans for the rest of the run without
+// affecting the others, and only a failure of the primary Producer fails the run.
+//
+// The input may override the run, as described by NotificationInput.
+func (h *NotificationHandler) Handle(ctx context.Context, in NotificationInput) (Output, error) {
+	started := time.Now()
+	runID := newRunID()
+	output, err := h.run(ctx, in, runID, started)
+	h.recordRun(ctx, in, runID, started, output, err)
+	if options, optionsErr := in.options(h.MaxScans); optionsErr == nil && !options.dryRun && !options.targeted() {
+		if h.HealthMonitor != nil {
+			h.HealthMonitor.Observe(ctx, err)
+		}
+		if h.StalledScanMonitor != nil {
+			h.StalledScanMonitor.Check(ctx)
+		}
+	}
+	return output, err
+}
+
+func (h *NotificationHandler) run(ctx context.Context, in NotificationInput, runID string, started time.Time) (Output, error) {
 	logger := h.LogFn(ctx)
 	stater := h.StatFn(ctx)
 
-	lastScanTimestamp, err := h.TimestampFetcher.FetchTimestamp(ctx)
-	switch err.(type) {
-	case nil:
-	case domain.TimestampNotFound:
-	default:
-		logger.Error(logs.StorageFailure{Reason: err.Error()})
+	options, err := in.options(h.MaxScans)
+	if err != nil {
 		return Output{}, err
 	}
+	readOnly := options.dryRun || options.targeted()
+	scanFetcher := h.ScanFetcher
+	destinations := append([]Destination{{
+		Name:             primaryDestination,
+		Producer:         h.Producer,
+		TimestampFetcher: h.TimestampFetcher,
+		TimestampStorer:  h.TimestampStorer,
+	}}, h.Destinations...)
+	if readOnly {
+		logger.Info(logs.TargetedRun{
+			Since:     in.Since,
+			Until:     in.Until,
+			SiteIDs:   strings.Join(in.SiteIDs, ","),
+			ScanTypes: strings.Join(in.ScanTypes, ","),
+			MaxScans:  in.MaxScans,
+			DryRun:    in.DryRun,
+		})
+		if h.ReadOnlyScanFetcher != nil {
+			scanFetcher = h.ReadOnlyScanFetcher
+		}
+		if h.ReadOnlyTimestampFetcher != nil {
+			destinations[0].TimestampFetcher = h.ReadOnlyTimestampFetcher
+		}
+		destinations = destinations[:1]
+	}
 
-	scans, err := h.ScanFetcher.FetchScans(ctx, lastScanTimestamp)
+	watermarks := make([]time.Time, len(destinations))
+	delivered := make([]time.Time, len(destinations))
+	failures := make([]error, len(destinations))
+	for offset, destination := range destinations {
+		ts := options.since
+		if ts.IsZero() {
+			ts, err = destination.TimestampFetcher.FetchTimestamp(ctx)
+		}
+		switch err.(type) {
+		case nil:
+		case domain.TimestampNotFound:
+		default:
+			logger.Error(logs.StorageFailure{Reason: err.Error()})
+			return Output{}, withStage(err, stageFetchTimestamp)
+		}
+		watermarks[offset] = ts
+		delivered[offset] = ts
+	}
+	// additional destinations behind the primary Producer, or which missed scans that
+	// finished late, catch up with their own queries, so that they do not hold back
+	// the scans fetched for the primary
+	lastScanTimestamp := watermarks[0]
+	catchingUp := make([]bool, len(destinations))
+	missed := make([][]string, len(destinations))
+	for offset := 1; offset < len(destinations); offset = offset + 1 {
+		catchingUp[offset] = watermarks[offset].Before(lastScanTimestamp)
+		if destinations[offset].InFlightScanFetcher == nil {
+			continue
+		}
+		scanIDs, err := destinations[offset].InFlightScanFetcher.FetchInFlightScans(ctx)
+		if err != nil {
+			logger.Error(logs.StorageFailure{Reason: err.Error()})
+			failures[offset] = withStage(err, stageFetchScans)
+			continue
+		}
+		missed[offset] = append([]string{}, scanIDs...)
+		catchingUp[offset] = catchingUp[offset] || len(scanIDs) > 0
+	}
+
+	// the scans of a targeted run are limited once they are filtered
+	query := domain.ScanQuery{Since: lastScanTimestamp, Limit: options.maxScans}
+	if options.targeted() {
+		query.Limit = 0
+	}
+	if h.MaxDuration > 0 {
+		query.Deadline = started.Add(h.MaxDuration)
+	}
+	result, err := scanFetcher.FetchScans(ctx, query)
 	if err != nil {
 		logger.Error(logs.ScanFetcherFailure{Reason: err.Error()})
-		return Output{}, err
+		return Output{}, withStage(err, stageFetchScans)
 	}
+	// spilled scans are read one at a time rather than loaded into memory
+	reader := result.Reader()
+	defer reader.Close()
+	// a crawl stopped at the deadline returns no scans, and is made again by the next run
+	moreRemaining := result.Truncated || result.Incomplete
+	fetched := reader.Len()
+	matched := 0
+	stopped := false
+	var previousEndTime time.Time
 
-	// sort scans by earliest time completed
-	sort.SliceStable(scans, func(left, right int) bool {
-		return scans[left].EndTime.Before(scans[right].EndTime)
-	})
+	scanNotifications := make([]scanNotification, 0, fetched)
+	var producedScans, lateScans []domain.CompletedScan
 
-	scanNotifications := make([]scanNotification, len(scans))
-	for offset, scan := range scans {
-		// Produce completed scan events to a queue
-		err := h.Producer.Produce(ctx, scan)
+	// additional destinations are delivered in the background, and the run waits for
+	// them to finish before it returns
+	deliveries := make([]*delivery, len(destinations))
+	for offset := 1; offset < len(destinations); offset = offset + 1 {
+		switch {
+		case failures[offset] != nil:
+			deliveries[offset] = failedDelivery(failures[offset])
+		case catchingUp[offset]:
+			lateUntil := lastScanTimestamp
+			if watermarks[offset].After(lateUntil) {
+				lateUntil = watermarks[offset]
+			}
+			catchUp := domain.ScanQuery{
+				Since:    watermarks[offset],
+				Limit:    options.maxScans,
+				Deadline: query.Deadline,
+				Recheck:  missed[offset],
+			}
+			deliveries[offset] = h.startCatchUp(ctx, destinations[offset], &watermarks[offset], lateUntil, catchUp)
+		default:
+			deliveries[offset] = h.startDelivery(ctx, destinations[offset], &watermarks[offset], lastScanTimestamp)
+		}
+	}
+	finished := false
+	finishDeliveries := func() {
+		if finished {
+			return
+		}
+		finished = true
+		for offset := 1; offset < len(destinations); offset = offset + 1 {
+			failures[offset] = deliveries[offset].finish()
+		}
+	}
+	defer finishDeliveries()
+
+	for {
+		// scans are read by earliest time completed
+		scan, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			logger.Error(logs.ProducerFailure{Reason: err.Error()})
-			return Output{}, err
+			logger.Error(logs.ScanFetcherFailure{Reason: err.Error()})
+			return Output{}, withStage(err, stageFetchScans)
+		}
+		// the scans of a targeted run are limited once they are filtered
+		if options.targeted() && !options.matches(scan) {
+			continue
+		}
+		matched = matched + 1
+		if stopped {
+			// the remaining scans are only read to count those which were filtered
+			continue
+		}
+		if options.targeted() && options.maxScans > 0 && matched > options.maxScans {
+			moreRemaining = true
+			stopped = true
+			continue
+		}
+		// scans before the stored timestamp will not be fetched again, so they are
+		// produced regardless of the time budget, as are scans which ended at the same
+		// time as the previous scan, since the stored timestamp excludes them as well
+		if h.MaxDuration > 0 && time.Since(started) >= h.MaxDuration && scan.EndTime.After(lastScanTimestamp) &&
+			scan.EndTime.After(previousEndTime) {
+			moreRemaining = true
+			stopped = true
+			continue
+		}
+		previousEndTime = scan.EndTime
+		if !scan.EndTime.After(lastScanTimestamp) {
+			lateScans = append(lateScans, scan)
+		}
+		produced := false
+		for offset, destination := range destinations {
+			// scans which finished late may end before the primary's stored timestamp,
+			// and are produced to every destination which is not catching up; otherwise
+			// a destination has already received every scan up to the timestamp it
+			// stored before this run
+			if failures[offset] != nil || catchingUp[offset] ||
+				(scan.EndTime.After(lastScanTimestamp) && !scan.EndTime.After(delivered[offset])) {
+				continue
+			}
+			if options.dryRun {
+				produced = true
+				continue
+			}
+			if offset > 0 {
+				deliveries[offset].queue(scan)
+				continue
+			}
+			watermark := &watermarks[offset]
+			if readOnly {
+				watermark = nil
+			}
+			_, failures[offset] = h.produce(ctx, destination, scan, watermark)
+			produced = failures[offset] == nil
+		}
+		if !produced {
+			continue
+		}
+		// emit a statistic of the time between a completed scan and the scan is produced,
+		// which would be skewed by scans produced again or not at all
+		if !readOnly {
+			stater.Timing("scannotificationdelay", time.Since(scan.EndTime))
+		}
+		scanNotifications = append(scanNotifications, completedScanToScanNotification(scan))
+		producedScans = append(producedScans, scan)
+	}
+	finishDeliveries()
+	var deadLettered map[string]int
+	partial := false
+	for offset := 1; offset < len(destinations); offset = offset + 1 {
+		name := destinations[offset].Name
+		if failures[offset] != nil {
+			stater.Count("notification.destination.failed", 1, "destination:"+name)
+			partial = true
 		}
-		if err := h.TimestampStorer.StoreTimestamp(ctx, scan.EndTime); err != nil {
-			return Output{}, err
+		if deliveries[offset].behind {
+			stater.Count("notification.destination.behind", 1, "destination:"+name)
+			partial = true
 		}
-		// emit a statistic of the time between a completed scan and the scan is produced
-		stater.Timing("scannotificationdelay", time.Since(scan.EndTime))
-		scanNotifications[offset] = completedScanToScanNotification(scan)
+		if catchingUp[offset] {
+			stater.Count("notification.destination.catchup", 1, "destination:"+name)
+		}
+		if deliveries[offset].deadLettered > 0 {
+			if deadLettered == nil {
+				deadLettered = make(map[string]int)
+			}
+			deadLettered[name] = deliveries[offset].deadLettered
+			partial = true
+		}
+	}
+
+	if h.SiteScanRecorder != nil && !readOnly && len(producedScans) > 0 {
+		if err := h.SiteScanRecorder.RecordSiteScans(ctx, producedScans); err != nil {
+			logger.Error(logs.StorageFailure{Reason: err.Error()})
+		}
+	}
+	if failures[0] != nil {
+		return Output{}, failures[0]
+	}
+	// the scans which finished late are only stored as missed by the destinations
+	// before the primary stops tracking them
+	for offset := 1; offset < len(destinations) && !readOnly; offset = offset + 1 {
+		// a catch up which completed re-checked every missed scan, and those it did
+		// not return no longer exist or are no longer produced
+		retry := deliveries[offset].err != nil || deliveries[offset].behind ||
+			(!query.Deadline.IsZero() && time.Now().After(query.Deadline))
+		if err := storeMissedScans(ctx, destinations[offset], missed[offset], deliveries[offset], retry,
+			watermarks[offset], lateScans); err != nil {
+			logger.Error(logs.StorageFailure{Reason: err.Error()})
+			return Output{}, withStage(err, stageStoreInFlight)
+		}
+	}
+	if h.InFlightScanStorer != nil && !readOnly && result.InFlight != nil {
+		if err := h.InFlightScanStorer.StoreInFlightScans(ctx, result.InFlight); err != nil {
+			logger.Error(logs.StorageFailure{Reason: err.Error()})
+			return Output{}, withStage(err, stageStoreInFlight)
+		}
+	}
+
+	summary := runSummary{
+		RunID:    runID,
+		Fetched:  fetched,
+		Produced: len(scanNotifications),
+		Skipped: skippedScans{
+			NotFinished: result.Skipped.NotFinished,
+			Blocklisted: result.Skipped.Blocklisted,
+			NotSettled:  result.Skipped.NotSettled,
+			Malformed:   result.Skipped.Malformed,
+			OutOfRange:  result.Skipped.OutOfRange,
+			Filtered:    fetched - matched,
+		},
+		DeadLettered: deadLettered,
+		PagesFetched: result.Pages,
+		Duration:     time.Since(started).String(),
+		Partial:      moreRemaining || partial,
+	}
+	if options.since.IsZero() {
+		summary.WatermarkBefore = formatWatermark(delivered[0])
+		summary.WatermarkAfter = formatWatermark(watermarks[0])
+	}
+
+	if moreRemaining {
+		logger.Info(logs.RunBudgetExhausted{
+			Produced:    len(scanNotifications),
+			Fetched:     matched,
+			MaxScans:    options.maxScans,
+			MaxDuration: h.MaxDuration.String(),
+		})
+		stater.Count("notification.budgetexhausted", 1)
+	}
+	logger.Info(logs.RunCompleted{
+		RunID:           summary.RunID,
+		Fetched:         summary.Fetched,
+		Produced:        summary.Produced,
+		PagesFetched:    summary.PagesFetched,
+		WatermarkBefore: summary.WatermarkBefore,
+		WatermarkAfter:  summary.WatermarkAfter,
+		Duration:        summary.Duration,
+		Partial:         summary.Partial,
+	})
+	return Output{Response: scanNotifications, MoreRemaining: moreRemaining, Summary: summary}, nil
+}
+
+// recordRun adds a run to the run history when a RunRecorder is set. A run which
+// cannot be recorded is logged without failing the run.
+func (h *NotificationHandler) recordRun(ctx context.Context, in NotificationInput, runID string,
+	started time.Time, output Output, runErr error) {
+	if h.RunRecorder == nil {
+		return
+	}
+	run := domain.RunRecord{
+		RunID:     runID,
+		Source:    h.Source,
+		StartTime: started,
+		EndTime:   time.Now(),
+		Fetched:   output.Summary.Fetched,
+		Produced:  output.Summary.Produced,
+		Partial:   output.Summary.Partial,
+		DryRun:    in.DryRun,
+	}
+	if options, err := in.options(h.MaxScans); err == nil {
+		run.Targeted = options.targeted()
+	}
+	// the summary omits watermarks which were not stored
+	run.WatermarkBefore, _ = time.Parse(time.RFC3339Nano, output.Summary.WatermarkBefore)
+	run.WatermarkAfter, _ = time.Parse(time.RFC3339Nano, output.Summary.WatermarkAfter)
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	if err := h.RunRecorder.RecordRun(ctx, run); err != nil {
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+	}
+}
+
+// newRunID returns a random identifier for a notification run.
+func newRunID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// produce sends a scan to a destination, and stores the destination's timestamp
+// unless watermark is nil. A scan which the destination stored as a dead letter is
+// treated as produced, and reported as dead lettered.
+func (h *NotificationHandler) produce(ctx context.Context, destination Destination, scan domain.CompletedScan,
+	watermark *time.Time) (bool, error) {
+	err := destination.Producer.Produce(ctx, scan)
+	_, deadLettered := err.(domain.ScanDeadLettered)
+	if err != nil && !deadLettered {
+		h.LogFn(ctx).Error(logs.ProducerFailure{Destination: destination.Name, Reason: err.Error()})
+		return false, withStage(err, stageProduce)
+	}
+	// scans which finished late may end before the stored timestamp, which
+	// must never move backwards
+	if watermark != nil && !scan.EndTime.Before(*watermark) {
+		if err := destination.TimestampStorer.StoreTimestamp(ctx, scan.EndTime); err != nil {
+			return deadLettered, withStage(err, stageStoreTimestamp)
+		}
+		*watermark = scan.EndTime
+	}
+	return deadLettered, nil
+}
+
+// storeMissedScans stores the IDs of the scans which finished late that a destination
+// has not received, and which it would not fetch again since they ended at or before
+// its stored timestamp, along with those it missed before this run when retry is
+// true. Nothing is stored when the destination does not track the scans it missed,
+// when they could not be fetched, or when they have not changed.
+func storeMissedScans(ctx context.Context, destination Destination, missed []string, d *delivery, retry bool,
+	watermark time.Time, lateScans []domain.CompletedScan) error {
+	if destination.InFlightScanStorer == nil || missed == nil {
+		return nil
+	}
+	pending := make(map[string]bool)
+	for _, scanID := range missed {
+		if retry && !d.received[scanID] {
+			pending[scanID] = true
+		}
+	}
+	for _, scan := range lateScans {
+		if !d.received[scan.ScanID] && !watermark.Before(scan.EndTime) {
+			pending[scan.ScanID] = true
+		}
+	}
+	scanIDs := make([]string, 0, len(pending))
+	for scanID := range pending {
+		scanIDs = append(scanIDs, scanID)
+	}
+	sort.Strings(scanIDs)
+	sort.Strings(missed)
+	if strings.Join(scanIDs, ",") == strings.Join(missed, ",") {
+		return nil
 	}
-	return Output{Response: scanNotifications}, nil
+	return destination.InFlightScanStorer.StoreInFlightScans(ctx, scanIDs)
 }
 
 func completedScanToScanNotification(scan domain.CompletedScan) scanNotification {
@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -113,7 +114,7 @@ func TestHandle(t *testing.T) {
 			ProducerErrs:       nil,
 			StoreTimestampErrs: nil,
 			Output:             Output{},
-			Err:                fmt.Errorf("timestamp fetch error"),
+			Err:                domain.RunFailure{Stage: "fetchTimestamp", Reason: "timestamp fetch error"},
 		},
 		{
 			Name:               "fetch scan error",
@@ -125,7 +126,19 @@ func TestHandle(t *testing.T) {
 			ProducerErrs:       nil,
 			StoreTimestampErrs: nil,
 			Output:             Output{},
-			Err:                fmt.Errorf("fetch scan error"),
+			Err:                domain.RunFailure{Stage: "fetchScans", Reason: "fetch scan error"},
+		},
+		{
+			Name:               "fetch scan rate limited",
+			Timestamp:          ts,
+			FetchTimestampErr:  nil,
+			ExpectFetchScan:    true,
+			Scans:              nil,
+			FetchScanErr:       domain.RateLimited{Dependency: "nexpose", Reason: "429", RetryAfter: time.Minute},
+			ProducerErrs:       nil,
+			StoreTimestampErrs: nil,
+			Output:             Output{},
+			Err:                domain.RateLimited{Stage: "fetchScans", Dependency: "nexpose", Reason: "429", RetryAfter: time.Minute},
 		},
 		{
 			Name:              "producer error",
@@ -150,7 +163,37 @@ func TestHandle(t *testing.T) {
 			ProducerErrs:       []error{nil, fmt.Errorf("producer error")},
 			StoreTimestampErrs: []error{nil},
 			Output:             Output{},
-			Err:                fmt.Errorf("producer error"),
+			Err:                domain.RunFailure{Stage: "produce", Reason: "producer error"},
+		},
+		{
+			Name:              "success with scan before timestamp",
+			Timestamp:         ts,
+			FetchTimestampErr: nil,
+			ExpectFetchScan:   true,
+			Scans: []domain.CompletedScan{
+				{
+					ScanID:    "1",
+					SiteID:    "11",
+					ScanType:  "Scheduled",
+					StartTime: ts.Add(-10 * time.Second),
+					EndTime:   ts.Add(-1 * time.Second),
+				},
+			},
+			FetchScanErr:       nil,
+			ProducerErrs:       []error{nil},
+			StoreTimestampErrs: nil,
+			Output: Output{
+				Response: []scanNotification{
+					{
+						ScanID:    "1",
+						SiteID:    "11",
+						ScanType:  "Scheduled",
+						StartTime: ts.Add(-10 * time.Second).Format(time.RFC3339Nano),
+						EndTime:   ts.Add(-1 * time.Second).Format(time.RFC3339Nano),
+					},
+				},
+			},
+			Err: nil,
 		},
 		{
 			Name:              "store timestamp error",
@@ -169,7 +212,7 @@ func TestHandle(t *testing.T) {
 			ProducerErrs:       []error{nil},
 			StoreTimestampErrs: []error{fmt.Errorf("store timestamp error")},
 			Output:             Output{},
-			Err:                fmt.Errorf("store timestamp error"),
+			Err:                domain.RunFailure{Stage: "storeTimestamp", Reason: "store timestamp error"},
 		},
 	}
 
@@ -191,7 +234,7 @@ func TestHandle(t *testing.T) {
 		t.Run(tt.Name, func(t *testing.T) {
 			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.Timestamp, tt.FetchTimestampErr)
 			if tt.ExpectFetchScan {
-				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), tt.Timestamp).Return(tt.Scans, tt.FetchScanErr)
+				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: tt.Timestamp}).Return(domain.ScanResult{Scans: tt.Scans}, tt.FetchScanErr)
 			}
 			for _, err := range tt.ProducerErrs {
 				mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(err)
@@ -200,8 +243,9 @@ func TestHandle(t *testing.T) {
 				mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(err)
 			}
 
-			output, err := handler.Handle(context.Background())
-			require.Equal(t, tt.Output, output)
+			output, err := handler.Handle(context.Background(), NotificationInput{})
+			require.Equal(t, tt.Output.Response, output.Response)
+			require.Equal(t, tt.Output.MoreRemaining, output.MoreRemaining)
 			require.Equal(t, tt.Err, err)
 		})
 	}
@@ -407,7 +451,7 @@ func TestHandleSortOrder(t *testing.T) {
 	for _, tt := range tc {
 		t.Run(tt.Name, func(t *testing.T) {
 			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.Timestamp, nil)
-			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), tt.Timestamp).Return(tt.Scans, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: tt.Timestamp}).Return(domain.ScanResult{Scans: tt.Scans}, nil)
 			for range tt.Scans {
 				mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil)
 			}
@@ -415,8 +459,1308 @@ func TestHandleSortOrder(t *testing.T) {
 				mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil)
 			}
 
-			output, _ := handler.Handle(context.Background())
-			require.Equal(t, tt.Output, output)
+			output, _ := handler.Handle(context.Background(), NotificationInput{})
+			require.Equal(t, tt.Output.Response, output.Response)
+			require.Equal(t, tt.Output.MoreRemaining, output.MoreRemaining)
+		})
+	}
+}
+
+func TestHandleBudget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ts := time.Now().Add(-1 * time.Hour)
+	late := domain.CompletedScan{
+		ScanID:    "1",
+		SiteID:    "11",
+		ScanType:  "Scheduled",
+		StartTime: ts.Add(-20 * time.Second),
+		EndTime:   ts.Add(-10 * time.Second),
+	}
+	recent := domain.CompletedScan{
+		ScanID:    "2",
+		SiteID:    "11",
+		ScanType:  "Scheduled",
+		StartTime: ts.Add(10 * time.Second),
+		EndTime:   ts.Add(20 * time.Second),
+	}
+	tied := recent
+	tied.ScanID = "3"
+	later := recent
+	later.ScanID = "4"
+	later.EndTime = ts.Add(30 * time.Second)
+	tc := []struct {
+		Name          string
+		MaxScans      int
+		MaxDuration   time.Duration
+		Slow          bool
+		Result        domain.ScanResult
+		ExpectedIDs   []string
+		ExpectedStore int
+		MoreRemaining bool
+	}{
+		{
+			Name:          "within budget",
+			MaxScans:      2,
+			Result:        domain.ScanResult{Scans: []domain.CompletedScan{recent}},
+			ExpectedIDs:   []string{"2"},
+			ExpectedStore: 1,
+		},
+		{
+			Name:          "scan limit reached",
+			MaxScans:      1,
+			Result:        domain.ScanResult{Scans: []domain.CompletedScan{recent}, Truncated: true},
+			ExpectedIDs:   []string{"2"},
+			ExpectedStore: 1,
+			MoreRemaining: true,
+		},
+		{
+			Name:          "duration exhausted",
+			MaxDuration:   time.Nanosecond,
+			Result:        domain.ScanResult{Scans: []domain.CompletedScan{recent, late}},
+			ExpectedIDs:   []string{"1"},
+			MoreRemaining: true,
+		},
+		{
+			Name:          "duration exhausted while crawling",
+			MaxDuration:   time.Minute,
+			Result:        domain.ScanResult{Incomplete: true, Pages: 3},
+			MoreRemaining: true,
+		},
+		{
+			Name:          "duration exhausted between scans which ended together",
+			MaxDuration:   time.Millisecond,
+			Slow:          true,
+			Result:        domain.ScanResult{Scans: []domain.CompletedScan{later, tied, recent}},
+			ExpectedIDs:   []string{"3", "2"},
+			ExpectedStore: 2,
+			MoreRemaining: true,
+		},
+	}
+
+	mockScanFetcher := NewMockScanFetcher(ctrl)
+	mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+	mockTimestampStorer := NewMockTimestampStorer(ctrl)
+	mockProducer := NewMockProducer(ctrl)
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+				StatFn:           MockStatFn,
+				MaxScans:         tt.MaxScans,
+				MaxDuration:      tt.MaxDuration,
+			}
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, query domain.ScanQuery) (domain.ScanResult, error) {
+					require.Equal(t, ts, query.Since)
+					require.Equal(t, tt.MaxScans, query.Limit)
+					// the crawl is bounded by the time budget of the run
+					require.Equal(t, tt.MaxDuration > 0, !query.Deadline.IsZero())
+					return tt.Result, nil
+				})
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(context.Context, domain.CompletedScan) error {
+					if tt.Slow {
+						// exhaust the budget while producing the first scan
+						time.Sleep(tt.MaxDuration)
+					}
+					return nil
+				}).Times(len(tt.ExpectedIDs))
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).Times(tt.ExpectedStore)
+
+			output, err := handler.Handle(context.Background(), NotificationInput{})
+			require.Nil(t, err)
+			var actualIDs []string
+			for _, notification := range output.Response {
+				actualIDs = append(actualIDs, notification.ScanID)
+			}
+			require.Equal(t, tt.ExpectedIDs, actualIDs)
+			require.Equal(t, tt.MoreRemaining, output.MoreRemaining)
+		})
+	}
+}
+
+func TestHandleDestinations(t *testing.T) {
+	ts := time.Now().Add(-1 * time.Hour)
+	scan := func(id string, endTime time.Time) domain.CompletedScan {
+		return domain.CompletedScan{
+			ScanID:    id,
+			SiteID:    "11",
+			ScanType:  "Scheduled",
+			StartTime: endTime.Add(-10 * time.Second),
+			EndTime:   endTime,
+		}
+	}
+	late := scan("1", ts.Add(-10*time.Second))
+	first := scan("2", ts.Add(10*time.Second))
+	second := scan("3", ts.Add(20*time.Second))
+
+	tc := []struct {
+		Name               string
+		PrimaryTimestamp   time.Time
+		SecondaryTimestamp time.Time
+		Scans              []domain.CompletedScan
+		CatchUpErr         error
+		PrimaryIDs         []string
+		PrimaryErr         error
+		SecondaryIDs       []string
+		SecondaryErr       error
+		ExpectedIDs        []string
+		Err                error
+	}{
+		{
+			Name:               "every destination receives every scan",
+			PrimaryTimestamp:   ts,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first},
+			PrimaryIDs:         []string{"2", "3"},
+			SecondaryIDs:       []string{"2", "3"},
+			ExpectedIDs:        []string{"2", "3"},
+		},
+		{
+			Name:               "destination behind the primary catches up",
+			PrimaryTimestamp:   first.EndTime,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first},
+			PrimaryIDs:         []string{"3"},
+			SecondaryIDs:       []string{"2", "3"},
+			ExpectedIDs:        []string{"3"},
+		},
+		{
+			Name:               "destination which cannot catch up does not hold back the primary",
+			PrimaryTimestamp:   first.EndTime,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first},
+			CatchUpErr:         fmt.Errorf("scan fetcher error"),
+			PrimaryIDs:         []string{"3"},
+			ExpectedIDs:        []string{"3"},
+		},
+		{
+			Name:               "destination behind the primary catches up with late scans",
+			PrimaryTimestamp:   second.EndTime,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first, late},
+			PrimaryIDs:         []string{"1"},
+			SecondaryIDs:       []string{"1", "2", "3"},
+			ExpectedIDs:        []string{"1"},
+		},
+		{
+			Name:               "primary behind a destination catches up",
+			PrimaryTimestamp:   ts,
+			SecondaryTimestamp: second.EndTime,
+			Scans:              []domain.CompletedScan{second, first},
+			PrimaryIDs:         []string{"2", "3"},
+			ExpectedIDs:        []string{"2", "3"},
+		},
+		{
+			Name:               "late scans are produced to every destination",
+			PrimaryTimestamp:   ts,
+			SecondaryTimestamp: first.EndTime,
+			Scans:              []domain.CompletedScan{late, first},
+			PrimaryIDs:         []string{"1", "2"},
+			SecondaryIDs:       []string{"1"},
+			ExpectedIDs:        []string{"1", "2"},
+		},
+		{
+			Name:               "failing destination does not hold back the primary",
+			PrimaryTimestamp:   ts,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first},
+			PrimaryIDs:         []string{"2", "3"},
+			SecondaryIDs:       []string{"2"},
+			SecondaryErr:       fmt.Errorf("producer error"),
+			ExpectedIDs:        []string{"2", "3"},
+		},
+		{
+			Name:               "failing primary does not hold back destinations",
+			PrimaryTimestamp:   ts,
+			SecondaryTimestamp: ts,
+			Scans:              []domain.CompletedScan{second, first},
+			PrimaryIDs:         []string{"2"},
+			PrimaryErr:         fmt.Errorf("producer error"),
+			SecondaryIDs:       []string{"2", "3"},
+			Err:                domain.RunFailure{Stage: "produce", Reason: "producer error"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockPrimaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockPrimaryStorer := NewMockTimestampStorer(ctrl)
+			mockPrimaryProducer := NewMockProducer(ctrl)
+			mockSecondaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockSecondaryStorer := NewMockTimestampStorer(ctrl)
+			mockSecondaryProducer := NewMockProducer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockPrimaryFetcher,
+				TimestampStorer:  mockPrimaryStorer,
+				Producer:         mockPrimaryProducer,
+				Destinations: []Destination{{
+					Name:             "datalake",
+					Producer:         mockSecondaryProducer,
+					TimestampFetcher: mockSecondaryFetcher,
+					TimestampStorer:  mockSecondaryStorer,
+				}},
+				StatFn: MockStatFn,
+			}
+
+			// scans which finished late are re-checked by every query
+			fetchScans := func(_ context.Context, query domain.ScanQuery) (domain.ScanResult, error) {
+				var scans []domain.CompletedScan
+				for _, scan := range tt.Scans {
+					if scan.EndTime.After(query.Since) || scan.ScanID == late.ScanID {
+						scans = append(scans, scan)
+					}
+				}
+				return domain.ScanResult{Scans: scans}, nil
+			}
+			mockPrimaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.PrimaryTimestamp, nil)
+			mockSecondaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.SecondaryTimestamp, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: tt.PrimaryTimestamp}).DoAndReturn(fetchScans)
+			if tt.SecondaryTimestamp.Before(tt.PrimaryTimestamp) {
+				catchUp := mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: tt.SecondaryTimestamp})
+				if tt.CatchUpErr != nil {
+					catchUp.Return(domain.ScanResult{}, tt.CatchUpErr)
+				} else {
+					catchUp.DoAndReturn(fetchScans)
+				}
+			}
+
+			var primaryIDs, secondaryIDs []string
+			mockPrimaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					primaryIDs = append(primaryIDs, scan.ScanID)
+					if len(primaryIDs) == len(tt.PrimaryIDs) {
+						return tt.PrimaryErr
+					}
+					return nil
+				}).AnyTimes()
+			mockSecondaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					secondaryIDs = append(secondaryIDs, scan.ScanID)
+					if len(secondaryIDs) == len(tt.SecondaryIDs) {
+						return tt.SecondaryErr
+					}
+					return nil
+				}).AnyTimes()
+			mockPrimaryStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockSecondaryStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			output, err := handler.Handle(context.Background(), NotificationInput{})
+			require.Equal(t, tt.Err, err)
+			require.Equal(t, tt.PrimaryIDs, primaryIDs)
+			require.Equal(t, tt.SecondaryIDs, secondaryIDs)
+			var actualIDs []string
+			for _, notification := range output.Response {
+				actualIDs = append(actualIDs, notification.ScanID)
+			}
+			require.Equal(t, tt.ExpectedIDs, actualIDs)
+		})
+	}
+}
+
+func TestHandleMissedLateScans(t *testing.T) {
+	ts := time.Now().Add(-1 * time.Hour)
+	late := domain.CompletedScan{ScanID: "1", SiteID: "11", ScanType: "Scheduled", EndTime: ts.Add(-10 * time.Second)}
+	first := domain.CompletedScan{ScanID: "2", SiteID: "11", ScanType: "Scheduled", EndTime: ts.Add(10 * time.Second)}
+
+	tc := []struct {
+		Name         string
+		Tracked      bool
+		Missed       []string
+		CatchUpErr   error
+		SecondaryErr error
+		StoreErr     error
+		SecondaryIDs []string
+		Stored       []string
+		Err          error
+	}{
+		{
+			Name:         "late scan received by every destination is not stored",
+			Tracked:      true,
+			Missed:       []string{},
+			SecondaryIDs: []string{"1", "2"},
+		},
+		{
+			Name:         "late scan which a failing destination missed is stored",
+			Tracked:      true,
+			Missed:       []string{},
+			SecondaryErr: fmt.Errorf("producer error"),
+			SecondaryIDs: []string{"1"},
+			Stored:       []string{"1"},
+		},
+		{
+			Name:         "missed scans are re-checked by the destination",
+			Missed:       []string{"1"},
+			SecondaryIDs: []string{"1", "2"},
+			Stored:       []string{},
+		},
+		{
+			Name:       "missed scans remain when the destination cannot catch up",
+			Missed:     []string{"1"},
+			CatchUpErr: fmt.Errorf("scan fetcher error"),
+		},
+		{
+			Name:         "failure storing missed scans fails the run",
+			Tracked:      true,
+			Missed:       []string{},
+			SecondaryErr: fmt.Errorf("producer error"),
+			StoreErr:     fmt.Errorf("storage error"),
+			SecondaryIDs: []string{"1"},
+			Stored:       []string{"1"},
+			Err:          domain.RunFailure{Stage: "storeInFlightScans", Reason: "storage error"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockPrimaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockPrimaryStorer := NewMockTimestampStorer(ctrl)
+			mockPrimaryProducer := NewMockProducer(ctrl)
+			mockSecondaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockSecondaryStorer := NewMockTimestampStorer(ctrl)
+			mockSecondaryProducer := NewMockProducer(ctrl)
+			mockMissedFetcher := NewMockInFlightScanFetcher(ctrl)
+			mockMissedStorer := NewMockInFlightScanStorer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockPrimaryFetcher,
+				TimestampStorer:  mockPrimaryStorer,
+				Producer:         mockPrimaryProducer,
+				Destinations: []Destination{{
+					Name:                "datalake",
+					Producer:            mockSecondaryProducer,
+					TimestampFetcher:    mockSecondaryFetcher,
+					TimestampStorer:     mockSecondaryStorer,
+					InFlightScanFetcher: mockMissedFetcher,
+					InFlightScanStorer:  mockMissedStorer,
+				}},
+				StatFn: MockStatFn,
+			}
+
+			mockPrimaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockSecondaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockMissedFetcher.EXPECT().FetchInFlightScans(gomock.Any()).Return(tt.Missed, nil)
+			// the late scan is returned by the primary's query while the primary tracks it,
+			// and by a catch up which re-checks it
+			primaryScans := []domain.CompletedScan{first}
+			if tt.Tracked {
+				primaryScans = append(primaryScans, late)
+			}
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: ts}).Return(
+				domain.ScanResult{Scans: primaryScans}, nil)
+			if len(tt.Missed) > 0 {
+				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), domain.ScanQuery{Since: ts, Recheck: tt.Missed}).Return(
+					domain.ScanResult{Scans: []domain.CompletedScan{first, late}}, tt.CatchUpErr)
+			}
+			mockPrimaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockPrimaryStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			var secondaryIDs []string
+			mockSecondaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					secondaryIDs = append(secondaryIDs, scan.ScanID)
+					return tt.SecondaryErr
+				}).AnyTimes()
+			mockSecondaryStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			if tt.Stored != nil {
+				mockMissedStorer.EXPECT().StoreInFlightScans(gomock.Any(), tt.Stored).Return(tt.StoreErr)
+			}
+
+			_, err := handler.Handle(context.Background(), NotificationInput{})
+			require.Equal(t, tt.Err, err)
+			require.Equal(t, tt.SecondaryIDs, secondaryIDs)
+		})
+	}
+}
+
+func TestHandleBackgroundDelivery(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	var scans []domain.CompletedScan
+	for offset := 1; offset <= deliveryQueueSize+2; offset = offset + 1 {
+		scans = append(scans, domain.CompletedScan{
+			ScanID:    fmt.Sprintf("%d", offset),
+			SiteID:    "11",
+			ScanType:  "Scheduled",
+			StartTime: ts,
+			EndTime:   ts.Add(time.Duration(offset) * time.Minute),
+		})
+	}
+
+	tc := []struct {
+		Name         string
+		Scans        []domain.CompletedScan
+		SecondaryIDs int
+		Partial      bool
+	}{
+		{
+			Name:         "destination receives every scan once the primary is done",
+			Scans:        scans[:2],
+			SecondaryIDs: 2,
+		},
+		{
+			Name:         "destination falls behind once its queue is full",
+			Scans:        scans,
+			SecondaryIDs: deliveryQueueSize + 1,
+			Partial:      true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+			mockSecondaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockSecondaryStorer := NewMockTimestampStorer(ctrl)
+			mockSecondaryProducer := NewMockProducer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+				Destinations: []Destination{{
+					Name:             "datalake",
+					Producer:         mockSecondaryProducer,
+					TimestampFetcher: mockSecondaryFetcher,
+					TimestampStorer:  mockSecondaryStorer,
+				}},
+			}
+
+			// the destination is held with the first scan until every scan has been
+			// produced to the primary
+			secondaryStarted := make(chan struct{})
+			primaryDone := make(chan struct{})
+			var primaryIDs int
+			var secondaryIDs int
+			var secondaryWatermark time.Time
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockSecondaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{Scans: tt.Scans}, nil)
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					primaryIDs = primaryIDs + 1
+					if primaryIDs == 2 {
+						<-secondaryStarted
+					}
+					if primaryIDs == len(tt.Scans) {
+						close(primaryDone)
+					}
+					return nil
+				}).Times(len(tt.Scans))
+			mockSecondaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					if secondaryIDs == 0 {
+						close(secondaryStarted)
+					}
+					<-primaryDone
+					secondaryIDs = secondaryIDs + 1
+					return nil
+				}).Times(tt.SecondaryIDs)
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).Times(len(tt.Scans))
+			mockSecondaryStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, timestamp time.Time) error {
+					secondaryWatermark = timestamp
+					return nil
+				}).Times(tt.SecondaryIDs)
+
+			output, err := handler.Handle(context.Background(), NotificationInput{})
+			require.NoError(t, err)
+			require.Len(t, output.Response, len(tt.Scans))
+			require.Equal(t, tt.SecondaryIDs, secondaryIDs)
+			require.Equal(t, tt.Scans[tt.SecondaryIDs-1].EndTime, secondaryWatermark)
+			require.Equal(t, tt.Partial, output.Summary.Partial)
+		})
+	}
+}
+
+func TestHandleInput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ts := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	scans := []domain.CompletedScan{
+		{ScanID: "1", SiteID: "11", ScanType: "Scheduled", EndTime: ts.Add(1 * time.Hour)},
+		{ScanID: "2", SiteID: "12", ScanType: "Manual", EndTime: ts.Add(2 * time.Hour)},
+		{ScanID: "3", SiteID: "11", ScanType: "Agent", EndTime: ts.Add(3 * time.Hour)},
+		{ScanID: "4", SiteID: "11", ScanType: "Scheduled", EndTime: ts.Add(4 * time.Hour)},
+	}
+	tc := []struct {
+		Name            string
+		Input           NotificationInput
+		ExpectedSince   time.Time
+		ExpectedLimit   int
+		ExpectedIDs     []string
+		ExpectedProduce int
+		ExpectedStore   int
+		MoreRemaining   bool
+		ExpectedErr     bool
+	}{
+		{
+			Name:            "no input",
+			Input:           NotificationInput{},
+			ExpectedSince:   ts,
+			ExpectedIDs:     []string{"1", "2", "3", "4"},
+			ExpectedProduce: 4,
+			ExpectedStore:   4,
+		},
+		{
+			Name:            "max scans only lowers the budget",
+			Input:           NotificationInput{MaxScans: 2},
+			ExpectedSince:   ts,
+			ExpectedLimit:   2,
+			ExpectedIDs:     []string{"1", "2", "3", "4"},
+			ExpectedProduce: 4,
+			ExpectedStore:   4,
+		},
+		{
+			Name:          "dry run",
+			Input:         NotificationInput{DryRun: true},
+			ExpectedSince: ts,
+			ExpectedIDs:   []string{"1", "2", "3", "4"},
+		},
+		{
+			Name:            "since and until",
+			Input:           NotificationInput{Since: ts.Add(-24 * time.Hour).Format(time.RFC3339), Until: ts.Add(2 * time.Hour).Format(time.RFC3339)},
+			ExpectedSince:   ts.Add(-24 * time.Hour),
+			ExpectedIDs:     []string{"1", "2"},
+			ExpectedProduce: 2,
+		},
+		{
+			Name:            "site and scan type filters",
+			Input:           NotificationInput{SiteIDs: []string{"11"}, ScanTypes: []string{"scheduled", "AGENT"}},
+			ExpectedSince:   ts,
+			ExpectedIDs:     []string{"1", "3", "4"},
+			ExpectedProduce: 3,
+		},
+		{
+			Name:            "filtered scans limited",
+			Input:           NotificationInput{SiteIDs: []string{"11"}, MaxScans: 2},
+			ExpectedSince:   ts,
+			ExpectedIDs:     []string{"1", "3"},
+			ExpectedProduce: 2,
+			MoreRemaining:   true,
+		},
+		{
+			Name:          "targeted dry run",
+			Input:         NotificationInput{ScanTypes: []string{"Manual"}, DryRun: true},
+			ExpectedSince: ts,
+			ExpectedIDs:   []string{"2"},
+		},
+		{
+			Name:        "invalid since",
+			Input:       NotificationInput{Since: "yesterday"},
+			ExpectedErr: true,
+		},
+		{
+			Name:        "invalid until",
+			Input:       NotificationInput{Until: "tomorrow"},
+			ExpectedErr: true,
+		},
+		{
+			Name:        "until before since",
+			Input:       NotificationInput{Since: ts.Format(time.RFC3339), Until: ts.Add(-time.Hour).Format(time.RFC3339)},
+			ExpectedErr: true,
+		},
+		{
+			Name:        "negative max scans",
+			Input:       NotificationInput{MaxScans: -1},
+			ExpectedErr: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockReadOnlyScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockReadOnlyTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+			mockDestinationProducer := NewMockProducer(ctrl)
+			mockDestinationTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockDestinationTimestampStorer := NewMockTimestampStorer(ctrl)
+			handler := NotificationHandler{
+				LogFn:                    testLogFn,
+				ScanFetcher:              mockScanFetcher,
+				ReadOnlyScanFetcher:      mockReadOnlyScanFetcher,
+				TimestampFetcher:         mockTimestampFetcher,
+				ReadOnlyTimestampFetcher: mockReadOnlyTimestampFetcher,
+				TimestampStorer:          mockTimestampStorer,
+				Producer:                 mockProducer,
+				Destinations: []Destination{{
+					Name:             "datalake",
+					Producer:         mockDestinationProducer,
+					TimestampFetcher: mockDestinationTimestampFetcher,
+					TimestampStorer:  mockDestinationTimestampStorer,
+				}},
+				StatFn: MockStatFn,
+			}
+
+			if !tt.ExpectedErr {
+				readOnly := tt.Input.DryRun || tt.Input.Since != "" || tt.Input.Until != "" ||
+					len(tt.Input.SiteIDs) > 0 || len(tt.Input.ScanTypes) > 0
+				query := domain.ScanQuery{Since: tt.ExpectedSince, Limit: tt.ExpectedLimit}
+				result := domain.ScanResult{Scans: append([]domain.CompletedScan{}, scans...)}
+				if readOnly {
+					if tt.Input.Since == "" {
+						mockReadOnlyTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+					}
+					mockReadOnlyScanFetcher.EXPECT().FetchScans(gomock.Any(), query).Return(result, nil)
+				} else {
+					mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+					mockDestinationTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+					mockScanFetcher.EXPECT().FetchScans(gomock.Any(), query).Return(result, nil)
+					mockDestinationProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).Times(tt.ExpectedProduce)
+					mockDestinationTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).Times(tt.ExpectedStore)
+				}
+				mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).Times(tt.ExpectedProduce)
+				mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).Times(tt.ExpectedStore)
+			}
+
+			output, err := handler.Handle(context.Background(), tt.Input)
+			if tt.ExpectedErr {
+				require.IsType(t, domain.InvalidInput{}, err)
+				return
+			}
+			require.NoError(t, err)
+			var actualIDs []string
+			for _, notification := range output.Response {
+				actualIDs = append(actualIDs, notification.ScanID)
+			}
+			require.Equal(t, tt.ExpectedIDs, actualIDs)
+			require.Equal(t, tt.MoreRemaining, output.MoreRemaining)
+		})
+	}
+}
+
+func TestHandleSummary(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	first := domain.CompletedScan{ScanID: "1", SiteID: "11", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(time.Minute)}
+	second := domain.CompletedScan{ScanID: "2", SiteID: "12", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(2 * time.Minute)}
+	skipped := domain.SkippedScans{NotFinished: 1, Blocklisted: 2, NotSettled: 3, Malformed: 4, OutOfRange: 1}
+
+	tc := []struct {
+		Name         string
+		Input        NotificationInput
+		SecondaryErr error
+		Summary      runSummary
+	}{
+		{
+			Name: "run",
+			Summary: runSummary{
+				Fetched:  2,
+				Produced: 2,
+				Skipped: skippedScans{
+					NotFinished: 1,
+					Blocklisted: 2,
+					NotSettled:  3,
+					Malformed:   4,
+					OutOfRange:  1,
+				},
+				PagesFetched:    3,
+				WatermarkBefore: ts.Format(time.RFC3339Nano),
+				WatermarkAfter:  second.EndTime.Format(time.RFC3339Nano),
+			},
+		},
+		{
+			Name:         "failed destination",
+			SecondaryErr: fmt.Errorf("producer error"),
+			Summary: runSummary{
+				Fetched:  2,
+				Produced: 2,
+				Skipped: skippedScans{
+					NotFinished: 1,
+					Blocklisted: 2,
+					NotSettled:  3,
+					Malformed:   4,
+					OutOfRange:  1,
+				},
+				PagesFetched:    3,
+				WatermarkBefore: ts.Format(time.RFC3339Nano),
+				WatermarkAfter:  second.EndTime.Format(time.RFC3339Nano),
+				Partial:         true,
+			},
+		},
+		{
+			Name:         "dead lettered scans",
+			SecondaryErr: domain.ScanDeadLettered{Destination: "datalake", Reason: "producer error"},
+			Summary: runSummary{
+				Fetched:  2,
+				Produced: 2,
+				Skipped: skippedScans{
+					NotFinished: 1,
+					Blocklisted: 2,
+					NotSettled:  3,
+					Malformed:   4,
+					OutOfRange:  1,
+				},
+				DeadLettered:    map[string]int{"datalake": 2},
+				PagesFetched:    3,
+				WatermarkBefore: ts.Format(time.RFC3339Nano),
+				WatermarkAfter:  second.EndTime.Format(time.RFC3339Nano),
+				Partial:         true,
+			},
+		},
+		{
+			Name:  "targeted dry run",
+			Input: NotificationInput{Since: ts.Format(time.RFC3339Nano), SiteIDs: []string{"12"}, DryRun: true},
+			Summary: runSummary{
+				Fetched:  2,
+				Produced: 1,
+				Skipped: skippedScans{
+					NotFinished: 1,
+					Blocklisted: 2,
+					NotSettled:  3,
+					Malformed:   4,
+					OutOfRange:  1,
+					Filtered:    1,
+				},
+				PagesFetched: 3,
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+			mockSecondaryFetcher := NewMockTimestampFetcher(ctrl)
+			mockSecondaryProducer := NewMockProducer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+				Destinations: []Destination{{
+					Name:             "datalake",
+					Producer:         mockSecondaryProducer,
+					TimestampFetcher: mockSecondaryFetcher,
+					TimestampStorer:  mockTimestampStorer,
+				}},
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil).AnyTimes()
+			mockSecondaryFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil).AnyTimes()
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{
+				Scans:   []domain.CompletedScan{first, second},
+				Pages:   3,
+				Skipped: skipped,
+			}, nil)
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockSecondaryProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(tt.SecondaryErr).AnyTimes()
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			output, err := handler.Handle(context.Background(), tt.Input)
+			require.NoError(t, err)
+			require.Len(t, output.Summary.RunID, 32)
+			_, err = time.ParseDuration(output.Summary.Duration)
+			require.NoError(t, err)
+			output.Summary.RunID = ""
+			output.Summary.Duration = ""
+			require.Equal(t, tt.Summary, output.Summary)
+		})
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	require.NotEqual(t, newRunID(), newRunID())
+}
+
+func TestHandleRecordRun(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "11", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(time.Minute)}
+
+	tc := []struct {
+		Name      string
+		FetchErr  error
+		RecordErr error
+		Run       domain.RunRecord
+		Err       bool
+	}{
+		{
+			Name: "success",
+			Run: domain.RunRecord{
+				Source:          "test",
+				Fetched:         1,
+				Produced:        1,
+				WatermarkBefore: ts,
+				WatermarkAfter:  scan.EndTime,
+			},
+		},
+		{
+			Name:     "failed run",
+			FetchErr: fmt.Errorf("fetch error"),
+			Run: domain.RunRecord{
+				Source: "test",
+				Error:  "fetchScans failed: fetch error",
+			},
+			Err: true,
+		},
+		{
+			Name:      "record error",
+			RecordErr: fmt.Errorf("record error"),
+			Run: domain.RunRecord{
+				Source:          "test",
+				Fetched:         1,
+				Produced:        1,
+				WatermarkBefore: ts,
+				WatermarkAfter:  scan.EndTime,
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+			mockRunRecorder := NewMockRunRecorder(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+				RunRecorder:      mockRunRecorder,
+				Source:           "test",
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{
+				Scans: []domain.CompletedScan{scan},
+			}, tt.FetchErr)
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			var recorded domain.RunRecord
+			mockRunRecorder.EXPECT().RecordRun(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, run domain.RunRecord) error {
+					recorded = run
+					return tt.RecordErr
+				})
+
+			output, err := handler.Handle(context.Background(), NotificationInput{})
+			require.Equal(t, tt.Err, err != nil)
+			require.Len(t, recorded.RunID, 32)
+			if err == nil {
+				require.Equal(t, output.Summary.RunID, recorded.RunID)
+			}
+			require.False(t, recorded.StartTime.IsZero())
+			require.False(t, recorded.EndTime.Before(recorded.StartTime))
+			recorded.RunID = ""
+			recorded.StartTime = time.Time{}
+			recorded.EndTime = time.Time{}
+			require.Equal(t, tt.Run, recorded)
+		})
+	}
+}
+
+func TestHandleHealth(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		Name     string
+		Input    NotificationInput
+		Observed bool
+	}{
+		{
+			Name:     "regular run",
+			Input:    NotificationInput{},
+			Observed: true,
+		},
+		{
+			Name:  "dry run",
+			Input: NotificationInput{DryRun: true},
+		},
+		{
+			Name:  "targeted run",
+			Input: NotificationInput{SiteIDs: []string{"1"}},
+		},
+		{
+			Name:  "invalid input",
+			Input: NotificationInput{Since: "yesterday"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockHealthFetcher := NewMockHealthFetcher(ctrl)
+			mockHealthStorer := NewMockHealthStorer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				HealthMonitor: &HealthMonitor{
+					HealthFetcher:          mockHealthFetcher,
+					HealthStorer:           mockHealthStorer,
+					TimestampFetcher:       mockTimestampFetcher,
+					LogFn:                  testLogFn,
+					MaxConsecutiveFailures: 3,
+				},
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil).AnyTimes()
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{}, fmt.Errorf("fetch error")).AnyTimes()
+			if tt.Observed {
+				mockHealthFetcher.EXPECT().FetchHealth(gomock.Any()).Return(domain.NotifierHealth{}, nil)
+				mockHealthStorer.EXPECT().StoreHealth(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, health domain.NotifierHealth) error {
+						require.Equal(t, 1, health.ConsecutiveFailures)
+						require.Equal(t, "fetchScans failed: fetch error", health.LastError)
+						return nil
+					})
+			}
+
+			_, err := handler.Handle(context.Background(), tt.Input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestHandleSiteScans(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	first := domain.CompletedScan{ScanID: "1", SiteID: "11", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(time.Minute)}
+	second := domain.CompletedScan{ScanID: "2", SiteID: "12", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(2 * time.Minute)}
+
+	tc := []struct {
+		Name     string
+		Input    NotificationInput
+		Recorded []domain.CompletedScan
+	}{
+		{
+			Name:     "regular run",
+			Recorded: []domain.CompletedScan{first, second},
+		},
+		{
+			Name:  "dry run",
+			Input: NotificationInput{DryRun: true},
+		},
+		{
+			Name:  "targeted run",
+			Input: NotificationInput{SiteIDs: []string{"12"}},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+			mockSiteScanRecorder := NewMockSiteScanRecorder(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+				SiteScanRecorder: mockSiteScanRecorder,
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{
+				Scans: []domain.CompletedScan{first, second},
+			}, nil)
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			if tt.Recorded != nil {
+				mockSiteScanRecorder.EXPECT().RecordSiteScans(gomock.Any(), tt.Recorded).Return(fmt.Errorf("storage error"))
+			}
+
+			// a failure to record the scans does not fail the run
+			_, err := handler.Handle(context.Background(), tt.Input)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHandleStalledScans(t *testing.T) {
+	tc := []struct {
+		Name    string
+		Input   NotificationInput
+		Checked bool
+	}{
+		{
+			Name:    "regular run",
+			Checked: true,
+		},
+		{
+			Name:  "dry run",
+			Input: NotificationInput{DryRun: true},
+		},
+		{
+			Name:  "targeted run",
+			Input: NotificationInput{ScanTypes: []string{"Scheduled"}},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockActiveScanFetcher := NewMockActiveScanFetcher(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				StalledScanMonitor: &StalledScanMonitor{
+					ActiveScanFetcher: mockActiveScanFetcher,
+					LogFn:             testLogFn,
+					DefaultMaxRuntime: time.Hour,
+				},
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(time.Now(), nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{}, nil)
+			if tt.Checked {
+				// a failed check does not fail the run
+				mockActiveScanFetcher.EXPECT().FetchActiveScans(gomock.Any()).Return(nil, fmt.Errorf("nexpose error"))
+			}
+
+			_, err := handler.Handle(context.Background(), tt.Input)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHandleInFlightScans(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	late := domain.CompletedScan{ScanID: "1", SiteID: "11", ScanType: "Scheduled", StartTime: ts.Add(-2 * time.Hour), EndTime: ts.Add(-time.Hour)}
+	scan := domain.CompletedScan{ScanID: "2", SiteID: "11", ScanType: "Scheduled", StartTime: ts, EndTime: ts.Add(time.Minute)}
+
+	tc := []struct {
+		Name       string
+		Input      NotificationInput
+		InFlight   []string
+		ProduceErr error
+		StoreErr   error
+		Stored     bool
+		ExpectErr  bool
+	}{
+		{
+			Name:     "stored after a successful run",
+			InFlight: []string{"3"},
+			Stored:   true,
+		},
+		{
+			Name:     "unchanged",
+			InFlight: nil,
+		},
+		{
+			Name:       "not stored when a scan cannot be produced",
+			InFlight:   []string{"3"},
+			ProduceErr: fmt.Errorf("producer error"),
+			ExpectErr:  true,
+		},
+		{
+			Name:     "not stored by a dry run",
+			Input:    NotificationInput{DryRun: true},
+			InFlight: []string{"3"},
+		},
+		{
+			Name:      "error storing",
+			InFlight:  []string{},
+			StoreErr:  fmt.Errorf("storage error"),
+			Stored:    true,
+			ExpectErr: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockInFlightScanStorer := NewMockInFlightScanStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:              testLogFn,
+				StatFn:             MockStatFn,
+				ScanFetcher:        mockScanFetcher,
+				TimestampFetcher:   mockTimestampFetcher,
+				TimestampStorer:    mockTimestampStorer,
+				InFlightScanStorer: mockInFlightScanStorer,
+				Producer:           mockProducer,
+			}
+
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil)
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{
+				Scans:    []domain.CompletedScan{late, scan},
+				InFlight: tt.InFlight,
+			}, nil)
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(tt.ProduceErr).AnyTimes()
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			if tt.Stored {
+				mockInFlightScanStorer.EXPECT().StoreInFlightScans(gomock.Any(), tt.InFlight).Return(tt.StoreErr)
+			}
+
+			_, err := handler.Handle(context.Background(), tt.Input)
+			require.Equal(t, tt.ExpectErr, err != nil)
+			if tt.StoreErr != nil {
+				require.Equal(t, domain.RunFailure{Stage: stageStoreInFlight, Reason: tt.StoreErr.Error()}, err)
+			}
+		})
+	}
+}
+
+// spilledScans is a domain.ScanReader of scans which were spilled while crawling.
+type spilledScans struct {
+	scans  []domain.CompletedScan
+	err    error
+	read   int
+	closed bool
+}
+
+func (r *spilledScans) Len() int {
+	return len(r.scans)
+}
+
+func (r *spilledScans) Next() (domain.CompletedScan, error) {
+	if r.read == len(r.scans) {
+		if r.err != nil {
+			return domain.CompletedScan{}, r.err
+		}
+		return domain.CompletedScan{}, io.EOF
+	}
+	r.read = r.read + 1
+	return r.scans[r.read-1], nil
+}
+
+func (r *spilledScans) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestHandleSpilledScans(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	scans := []domain.CompletedScan{
+		{ScanID: "1", SiteID: "11", StartTime: ts, EndTime: ts.Add(time.Minute)},
+		{ScanID: "2", SiteID: "12", StartTime: ts, EndTime: ts.Add(2 * time.Minute)},
+		{ScanID: "3", SiteID: "11", StartTime: ts, EndTime: ts.Add(3 * time.Minute)},
+		{ScanID: "4", SiteID: "11", StartTime: ts, EndTime: ts.Add(4 * time.Minute)},
+	}
+
+	tc := []struct {
+		Name          string
+		Input         NotificationInput
+		ReadErr       error
+		ExpectedIDs   []string
+		Filtered      int
+		MoreRemaining bool
+		ExpectErr     bool
+	}{
+		{
+			Name:        "scans are produced as they are read",
+			ExpectedIDs: []string{"1", "2", "3", "4"},
+		},
+		{
+			Name:          "remaining scans are read to count those filtered",
+			Input:         NotificationInput{Since: ts.Format(time.RFC3339Nano), SiteIDs: []string{"11"}, MaxScans: 1},
+			ExpectedIDs:   []string{"1"},
+			Filtered:      1,
+			MoreRemaining: true,
+		},
+		{
+			Name:      "read error",
+			ReadErr:   fmt.Errorf("read error"),
+			ExpectErr: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+			mockTimestampStorer := NewMockTimestampStorer(ctrl)
+			mockProducer := NewMockProducer(ctrl)
+
+			handler := NotificationHandler{
+				LogFn:            testLogFn,
+				StatFn:           MockStatFn,
+				ScanFetcher:      mockScanFetcher,
+				TimestampFetcher: mockTimestampFetcher,
+				TimestampStorer:  mockTimestampStorer,
+				Producer:         mockProducer,
+			}
+
+			spilled := &spilledScans{scans: scans, err: tt.ReadErr}
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(ts, nil).AnyTimes()
+			mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).Return(domain.ScanResult{Spilled: spilled}, nil)
+			var producedIDs []string
+			mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, scan domain.CompletedScan) error {
+					producedIDs = append(producedIDs, scan.ScanID)
+					return nil
+				}).AnyTimes()
+			mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			output, err := handler.Handle(context.Background(), tt.Input)
+			require.True(t, spilled.closed)
+			require.Equal(t, len(scans), spilled.read)
+			if tt.ExpectErr {
+				require.Equal(t, domain.RunFailure{Stage: stageFetchScans, Reason: tt.ReadErr.Error()}, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectedIDs, producedIDs)
+			require.Equal(t, len(scans), output.Summary.Fetched)
+			require.Equal(t, tt.Filtered, output.Summary.Skipped.Filtered)
+			require.Equal(t, tt.MoreRemaining, output.MoreRemaining)
 		})
 	}
 }
@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	defaultRunsWindow = 24 * time.Hour
+	maxRunsWindow     = 7 * 24 * time.Hour
+	defaultRunsLimit  = 50
+	maxRunsLimit      = 500
+)
+
+// RunsInput selects a page of the run history. Since and Until are RFC3339
+// timestamps bounding when runs started, defaulting to the last day, and may
+// be at most a week apart. Cursor continues from a previous page.
+type RunsInput struct {
+	Since  string `json:"since"`
+	Until  string `json:"until"`
+	Limit  string `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+// RunsOutput contains a page of the run history, most recent first. Cursor is
+// only set when more runs remain.
+type RunsOutput struct {
+	Runs   []runRecord `json:"runs"`
+	Cursor string      `json:"cursor,omitempty"`
+}
+
+type runRecord struct {
+	RunID           string `json:"runID"`
+	Source          string `json:"source"`
+	StartTime       string `json:"startTime"`
+	EndTime         string `json:"endTime"`
+	Fetched         int    `json:"fetched"`
+	Produced        int    `json:"produced"`
+	WatermarkBefore string `json:"watermarkBefore,omitempty"`
+	WatermarkAfter  string `json:"watermarkAfter,omitempty"`
+	Partial         bool   `json:"partial"`
+	Targeted        bool   `json:"targeted"`
+	DryRun          bool   `json:"dryRun"`
+	Error           string `json:"error,omitempty"`
+}
+
+// RunsHandler queries the history of notification runs.
+type RunsHandler struct {
+	RunFetcher domain.RunFetcher
+	LogFn      domain.LogFn
+}
+
+// Handle returns the runs which started within the requested time range.
+func (h *RunsHandler) Handle(ctx context.Context, in RunsInput) (RunsOutput, error) {
+	query, err := in.query(time.Now())
+	if err != nil {
+		return RunsOutput{}, err
+	}
+	page, err := h.RunFetcher.FetchRuns(ctx, query)
+	if err != nil {
+		if _, ok := err.(domain.InvalidInput); !ok {
+			h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		}
+		return RunsOutput{}, err
+	}
+
+	output := RunsOutput{Runs: make([]runRecord, 0, len(page.Runs)), Cursor: page.Cursor}
+	for _, run := range page.Runs {
+		output.Runs = append(output.Runs, runRecord{
+			RunID:           run.RunID,
+			Source:          run.Source,
+			StartTime:       formatWatermark(run.StartTime),
+			EndTime:         formatWatermark(run.EndTime),
+			Fetched:         run.Fetched,
+			Produced:        run.Produced,
+			WatermarkBefore: formatWatermark(run.WatermarkBefore),
+			WatermarkAfter:  formatWatermark(run.WatermarkAfter),
+			Partial:         run.Partial,
+			Targeted:        run.Targeted,
+			DryRun:          run.DryRun,
+			Error:           run.Error,
+		})
+	}
+	return output, nil
+}
+
+// query validates the input and applies defaults relative to now.
+func (in RunsInput) query(now time.Time) (domain.RunQuery, error) {
+	query := domain.RunQuery{Until: now, Limit: defaultRunsLimit, Cursor: in.Cursor}
+	var err error
+	if in.Until != "" {
+		if query.Until, err = time.Parse(time.RFC3339Nano, in.Until); err != nil {
+			return domain.RunQuery{}, domain.InvalidInput{Field: "until", Reason: err.Error()}
+		}
+	}
+	query.Since = query.Until.Add(-defaultRunsWindow)
+	if in.Since != "" {
+		if query.Since, err = time.Parse(time.RFC3339Nano, in.Since); err != nil {
+			return domain.RunQuery{}, domain.InvalidInput{Field: "since", Reason: err.Error()}
+		}
+	}
+	if !query.Since.Before(query.Until) {
+		return domain.RunQuery{}, domain.InvalidInput{Field: "since", Reason: "must be before until"}
+	}
+	// run history is stored by the hour, and each hour in the range is read separately
+	if query.Until.Sub(query.Since) > maxRunsWindow {
+		return domain.RunQuery{}, domain.InvalidInput{
+			Field:  "since",
+			Reason: "must be at most " + maxRunsWindow.String() + " before until",
+		}
+	}
+	if in.Limit != "" {
+		if query.Limit, err = strconv.Atoi(in.Limit); err != nil {
+			return domain.RunQuery{}, domain.InvalidInput{Field: "limit", Reason: err.Error()}
+		}
+		if query.Limit < 1 || query.Limit > maxRunsLimit {
+			return domain.RunQuery{}, domain.InvalidInput{
+				Field:  "limit",
+				Reason: "must be between 1 and " + strconv.Itoa(maxRunsLimit),
+			}
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunsHandler(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	run := domain.RunRecord{
+		RunID:          "run1",
+		Source:         "http",
+		StartTime:      ts,
+		EndTime:        ts.Add(time.Second),
+		Fetched:        3,
+		Produced:       2,
+		WatermarkAfter: ts.Add(-time.Minute),
+		Partial:        true,
+		Error:          "produce failed: producer error",
+	}
+
+	tc := []struct {
+		Name     string
+		Input    RunsInput
+		Query    domain.RunQuery
+		Page     domain.RunPage
+		FetchErr error
+		Output   RunsOutput
+		Err      bool
+	}{
+		{
+			Name:  "success",
+			Input: RunsInput{Since: ts.Add(-time.Hour).Format(time.RFC3339), Until: ts.Add(time.Hour).Format(time.RFC3339), Limit: "1"},
+			Query: domain.RunQuery{Since: ts.Add(-time.Hour), Until: ts.Add(time.Hour), Limit: 1},
+			Page:  domain.RunPage{Runs: []domain.RunRecord{run}, Cursor: "next"},
+			Output: RunsOutput{
+				Runs: []runRecord{{
+					RunID:          "run1",
+					Source:         "http",
+					StartTime:      ts.Format(time.RFC3339Nano),
+					EndTime:        ts.Add(time.Second).Format(time.RFC3339Nano),
+					Fetched:        3,
+					Produced:       2,
+					WatermarkAfter: ts.Add(-time.Minute).Format(time.RFC3339Nano),
+					Partial:        true,
+					Error:          "produce failed: producer error",
+				}},
+				Cursor: "next",
+			},
+		},
+		{
+			Name:   "default since",
+			Input:  RunsInput{Until: ts.Format(time.RFC3339), Cursor: "next"},
+			Query:  domain.RunQuery{Since: ts.Add(-defaultRunsWindow), Until: ts, Limit: defaultRunsLimit, Cursor: "next"},
+			Output: RunsOutput{Runs: []runRecord{}},
+		},
+		{
+			Name:     "fetch error",
+			Input:    RunsInput{Until: ts.Format(time.RFC3339)},
+			Query:    domain.RunQuery{Since: ts.Add(-defaultRunsWindow), Until: ts, Limit: defaultRunsLimit},
+			FetchErr: fmt.Errorf("fetch error"),
+			Err:      true,
+		},
+		{
+			Name:     "invalid cursor",
+			Input:    RunsInput{Until: ts.Format(time.RFC3339), Cursor: "bad"},
+			Query:    domain.RunQuery{Since: ts.Add(-defaultRunsWindow), Until: ts, Limit: defaultRunsLimit, Cursor: "bad"},
+			FetchErr: domain.InvalidInput{Field: "cursor", Reason: "malformed"},
+			Err:      true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRunFetcher := NewMockRunFetcher(ctrl)
+			handler := RunsHandler{
+				RunFetcher: mockRunFetcher,
+				LogFn:      testLogFn,
+			}
+
+			mockRunFetcher.EXPECT().FetchRuns(gomock.Any(), tt.Query).Return(tt.Page, tt.FetchErr)
+			output, err := handler.Handle(context.Background(), tt.Input)
+			require.Equal(t, tt.Err, err != nil)
+			require.Equal(t, tt.Output, output)
+		})
+	}
+}
+
+func TestRunsInputQuery(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		Name  string
+		Input RunsInput
+		Query domain.RunQuery
+		Field string
+	}{
+		{
+			Name:  "defaults",
+			Input: RunsInput{},
+			Query: domain.RunQuery{Since: now.Add(-defaultRunsWindow), Until: now, Limit: defaultRunsLimit},
+		},
+		{
+			Name:  "since",
+			Input: RunsInput{Since: now.Add(-time.Hour).Format(time.RFC3339)},
+			Query: domain.RunQuery{Since: now.Add(-time.Hour), Until: now, Limit: defaultRunsLimit},
+		},
+		{
+			Name:  "invalid since",
+			Input: RunsInput{Since: "yesterday"},
+			Field: "since",
+		},
+		{
+			Name:  "invalid until",
+			Input: RunsInput{Until: "today"},
+			Field: "until",
+		},
+		{
+			Name:  "since after until",
+			Input: RunsInput{Since: now.Format(time.RFC3339), Until: now.Add(-time.Hour).Format(time.RFC3339)},
+			Field: "since",
+		},
+		{
+			Name:  "widest range",
+			Input: RunsInput{Since: now.Add(-maxRunsWindow).Format(time.RFC3339)},
+			Query: domain.RunQuery{Since: now.Add(-maxRunsWindow), Until: now, Limit: defaultRunsLimit},
+		},
+		{
+			Name:  "range too wide",
+			Input: RunsInput{Since: now.Add(-maxRunsWindow - time.Second).Format(time.RFC3339)},
+			Field: "since",
+		},
+		{
+			Name:  "invalid limit",
+			Input: RunsInput{Limit: "many"},
+			Field: "limit",
+		},
+		{
+			Name:  "limit too small",
+			Input: RunsInput{Limit: "0"},
+			Field: "limit",
+		},
+		{
+			Name:  "limit too large",
+			Input: RunsInput{Limit: "501"},
+			Field: "limit",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			query, err := tt.Input.query(now)
+			if tt.Field != "" {
+				require.IsType(t, domain.InvalidInput{}, err)
+				require.Equal(t, tt.Field, err.(domain.InvalidInput).Field)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.Query, query)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const eventTypeSiteScanOverdue = "site.scan_overdue"
+
+// SiteScansOutput contains the tracked state of every site, ordered by site ID.
+type SiteScansOutput struct {
+	Sites []siteScanStatus `json:"sites"`
+}
+
+type siteScanStatus struct {
+	SiteID       string `json:"siteID"`
+	LastScanID   string `json:"lastScanID,omitempty"`
+	LastScanTime string `json:"lastScanTime,omitempty"`
+	TrackedSince string `json:"trackedSince"`
+	MaxInterval  string `json:"maxInterval,omitempty"`
+	Overdue      bool   `json:"overdue"`
+	OverdueSince string `json:"overdueSince,omitempty"`
+}
+
+type siteScanOverdueDetail struct {
+	SiteID       string `json:"siteID"`
+	LastScanID   string `json:"lastScanID,omitempty"`
+	LastScanTime string `json:"lastScanTime,omitempty"`
+	TrackedSince string `json:"trackedSince"`
+	MaxInterval  string `json:"maxInterval"`
+	OverdueSince string `json:"overdueSince"`
+}
+
+// SiteScanHandler checks whether sites have gone too long without a completed scan,
+// which happens when their schedules silently break. The most recent completed scan
+// of each site is recorded by regular notification runs.
+//
+// A site is overdue once the time since its last completed scan, or since it was
+// first tracked if it has none, is longer than its entry in MaxIntervals, or
+// DefaultMaxInterval if it has no entry. A site with an interval of zero is never
+// overdue. Every site listed by Nexpose, and every site in MaxIntervals, is tracked
+// from the first check, even if it has never been scanned. A check which cannot list
+// the sites still checks the sites already tracked.
+type SiteScanHandler struct {
+	SiteLister         domain.SiteLister
+	SiteScanFetcher    domain.SiteScanFetcher
+	SiteScanStorer     domain.SiteScanStorer
+	EventProducer      domain.EventProducer
+	LogFn              domain.LogFn
+	DefaultMaxInterval time.Duration
+	MaxIntervals       map[string]time.Duration
+}
+
+// Fetch returns the tracked state of every site, as of the last check.
+func (h *SiteScanHandler) Fetch(ctx context.Context) (SiteScansOutput, error) {
+	states, err := h.SiteScanFetcher.FetchSiteScans(ctx)
+	if err != nil {
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return SiteScansOutput{}, err
+	}
+	return h.output(states), nil
+}
+
+// Check finds the sites which are overdue for a scan, producing a site.scan_overdue
+// event once for each site until it is scanned again. An event which cannot be
+// produced is retried by the next check.
+func (h *SiteScanHandler) Check(ctx context.Context) (SiteScansOutput, error) {
+	logger := h.LogFn(ctx)
+	states, err := h.SiteScanFetcher.FetchSiteScans(ctx)
+	if err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+		return SiteScansOutput{}, err
+	}
+
+	now := time.Now()
+	tracked := make(map[string]bool, len(states))
+	for _, state := range states {
+		tracked[state.SiteID] = true
+	}
+	siteIDs, err := h.SiteLister.ListSites(ctx)
+	if err != nil {
+		logger.Error(logs.ScanFetcherFailure{Reason: err.Error()})
+	}
+	for siteID := range h.MaxIntervals {
+		siteIDs = append(siteIDs, siteID)
+	}
+	for _, siteID := range siteIDs {
+		if !tracked[siteID] {
+			tracked[siteID] = true
+			states = append(states, domain.SiteScanState{SiteID: siteID, TrackedSince: now})
+		}
+	}
+	sort.Slice(states, func(left, right int) bool {
+		return states[left].SiteID < states[right].SiteID
+	})
+
+	for offset, state := range states {
+		maxInterval := h.maxInterval(state.SiteID)
+		last := state.LastScanEnd
+		if last.IsZero() {
+			last = state.TrackedSince
+		}
+		if maxInterval == 0 || now.Sub(last) <= maxInterval {
+			state.OverdueSince = time.Time{}
+			state.Alerted = false
+			states[offset] = state
+			continue
+		}
+
+		if state.OverdueSince.IsZero() {
+			state.OverdueSince = now
+			logger.Warn(logs.SiteScanOverdue{
+				SiteID:       state.SiteID,
+				LastScanID:   state.LastScanID,
+				LastScanTime: formatWatermark(state.LastScanEnd),
+				MaxInterval:  maxInterval.String(),
+			})
+		}
+		if !state.Alerted {
+			event := domain.Event{
+				ID:   domain.NewEventID(eventTypeSiteScanOverdue, state.SiteID, last.UTC().Format(time.RFC3339Nano)),
+				Type: eventTypeSiteScanOverdue,
+				Time: now,
+				Detail: siteScanOverdueDetail{
+					SiteID:       state.SiteID,
+					LastScanID:   state.LastScanID,
+					LastScanTime: formatWatermark(state.LastScanEnd),
+					TrackedSince: formatWatermark(state.TrackedSince),
+					MaxInterval:  maxInterval.String(),
+					OverdueSince: formatWatermark(state.OverdueSince),
+				},
+			}
+			if err := h.EventProducer.ProduceEvent(ctx, event); err != nil {
+				logger.Error(logs.EventFailure{EventType: event.Type, Reason: err.Error()})
+			} else {
+				state.Alerted = true
+			}
+		}
+		states[offset] = state
+	}
+
+	if err := h.SiteScanStorer.StoreSiteScans(ctx, states); err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+		return SiteScansOutput{}, err
+	}
+	return h.output(states), nil
+}
+
+func (h *SiteScanHandler) maxInterval(siteID string) time.Duration {
+	if maxInterval, ok := h.MaxIntervals[siteID]; ok {
+		return maxInterval
+	}
+	return h.DefaultMaxInterval
+}
+
+func (h *SiteScanHandler) output(states []domain.SiteScanState) SiteScansOutput {
+	output := SiteScansOutput{Sites: make([]siteScanStatus, 0, len(states))}
+	for _, state := range states {
+		status := siteScanStatus{
+			SiteID:       state.SiteID,
+			LastScanID:   state.LastScanID,
+			LastScanTime: formatWatermark(state.LastScanEnd),
+			TrackedSince: formatWatermark(state.TrackedSince),
+			Overdue:      !state.OverdueSince.IsZero(),
+			OverdueSince: formatWatermark(state.OverdueSince),
+		}
+		if maxInterval := h.maxInterval(state.SiteID); maxInterval > 0 {
+			status.MaxInterval = maxInterval.String()
+		}
+		output.Sites = append(output.Sites, status)
+	}
+	return output
+}
@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteScanHandlerCheck(t *testing.T) {
+	now := time.Now()
+	recent := domain.SiteScanState{SiteID: "1", LastScanID: "10", LastScanEnd: now.Add(-time.Hour), TrackedSince: now.Add(-1000 * time.Hour)}
+	overdue := domain.SiteScanState{SiteID: "2", LastScanID: "20", LastScanEnd: now.Add(-200 * time.Hour), TrackedSince: now.Add(-1000 * time.Hour)}
+	alerted := domain.SiteScanState{
+		SiteID:       "3",
+		LastScanID:   "30",
+		LastScanEnd:  now.Add(-300 * time.Hour),
+		TrackedSince: now.Add(-1000 * time.Hour),
+		OverdueSince: now.Add(-100 * time.Hour),
+		Alerted:      true,
+	}
+	resumed := domain.SiteScanState{
+		SiteID:       "4",
+		LastScanID:   "41",
+		LastScanEnd:  now.Add(-time.Hour),
+		TrackedSince: now.Add(-1000 * time.Hour),
+		OverdueSince: now.Add(-100 * time.Hour),
+		Alerted:      true,
+	}
+	ignored := domain.SiteScanState{SiteID: "5", LastScanEnd: now.Add(-1000 * time.Hour), TrackedSince: now.Add(-1000 * time.Hour)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteScanFetcher := NewMockSiteScanFetcher(ctrl)
+	mockSiteScanStorer := NewMockSiteScanStorer(ctrl)
+	mockEventProducer := NewMockEventProducer(ctrl)
+	mockSiteLister := NewMockSiteLister(ctrl)
+	handler := SiteScanHandler{
+		SiteLister:         mockSiteLister,
+		SiteScanFetcher:    mockSiteScanFetcher,
+		SiteScanStorer:     mockSiteScanStorer,
+		EventProducer:      mockEventProducer,
+		LogFn:              testLogFn,
+		DefaultMaxInterval: 168 * time.Hour,
+		MaxIntervals:       map[string]time.Duration{"5": 0, "6": 24 * time.Hour},
+	}
+
+	var stored []domain.SiteScanState
+	var events []domain.Event
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return([]domain.SiteScanState{recent, overdue, alerted, resumed, ignored}, nil)
+	mockSiteLister.EXPECT().ListSites(gomock.Any()).Return([]string{"1", "6", "7"}, nil)
+	mockEventProducer.EXPECT().ProduceEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event domain.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	mockSiteScanStorer.EXPECT().StoreSiteScans(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, states []domain.SiteScanState) error {
+		stored = states
+		return nil
+	})
+
+	output, err := handler.Check(context.Background())
+	require.NoError(t, err)
+
+	// only the newly overdue site produces an event
+	require.Len(t, events, 1)
+	require.Equal(t, eventTypeSiteScanOverdue, events[0].Type)
+	require.Equal(t, domain.NewEventID(eventTypeSiteScanOverdue, "2", overdue.LastScanEnd.UTC().Format(time.RFC3339Nano)), events[0].ID)
+	detail := events[0].Detail.(siteScanOverdueDetail)
+	require.Equal(t, "2", detail.SiteID)
+	require.Equal(t, "20", detail.LastScanID)
+	require.Equal(t, "168h0m0s", detail.MaxInterval)
+
+	require.Len(t, stored, 7)
+	require.Equal(t, recent, stored[0])
+	require.True(t, stored[1].Alerted)
+	require.False(t, stored[1].OverdueSince.IsZero())
+	require.Equal(t, alerted, stored[2])
+	require.False(t, stored[3].Alerted)
+	require.True(t, stored[3].OverdueSince.IsZero())
+	require.Equal(t, ignored, stored[4])
+	// configured and listed sites are tracked from the first check
+	for offset, siteID := range []string{"6", "7"} {
+		require.Equal(t, siteID, stored[5+offset].SiteID)
+		require.False(t, stored[5+offset].TrackedSince.IsZero())
+		require.False(t, stored[5+offset].Alerted)
+	}
+
+	require.Len(t, output.Sites, 7)
+	require.Equal(t, []bool{false, true, true, false, false, false, false}, []bool{
+		output.Sites[0].Overdue, output.Sites[1].Overdue, output.Sites[2].Overdue,
+		output.Sites[3].Overdue, output.Sites[4].Overdue, output.Sites[5].Overdue,
+		output.Sites[6].Overdue,
+	})
+	require.Empty(t, output.Sites[4].MaxInterval)
+	require.Equal(t, "24h0m0s", output.Sites[5].MaxInterval)
+}
+
+func TestSiteScanHandlerCheckListFailure(t *testing.T) {
+	now := time.Now()
+	overdue := domain.SiteScanState{SiteID: "2", LastScanID: "20", LastScanEnd: now.Add(-200 * time.Hour), TrackedSince: now.Add(-1000 * time.Hour)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteScanFetcher := NewMockSiteScanFetcher(ctrl)
+	mockSiteScanStorer := NewMockSiteScanStorer(ctrl)
+	mockEventProducer := NewMockEventProducer(ctrl)
+	mockSiteLister := NewMockSiteLister(ctrl)
+	handler := SiteScanHandler{
+		SiteLister:         mockSiteLister,
+		SiteScanFetcher:    mockSiteScanFetcher,
+		SiteScanStorer:     mockSiteScanStorer,
+		EventProducer:      mockEventProducer,
+		LogFn:              testLogFn,
+		DefaultMaxInterval: 168 * time.Hour,
+		MaxIntervals:       map[string]time.Duration{"6": 24 * time.Hour},
+	}
+
+	// the sites already tracked are still checked
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return([]domain.SiteScanState{overdue}, nil)
+	mockSiteLister.EXPECT().ListSites(gomock.Any()).Return(nil, fmt.Errorf("nexpose error"))
+	mockEventProducer.EXPECT().ProduceEvent(gomock.Any(), gomock.Any()).Return(nil)
+	mockSiteScanStorer.EXPECT().StoreSiteScans(gomock.Any(), gomock.Any()).Return(nil)
+	output, err := handler.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output.Sites, 2)
+	require.True(t, output.Sites[0].Overdue)
+	require.Equal(t, "6", output.Sites[1].SiteID)
+}
+
+func TestSiteScanHandlerCheckEventFailure(t *testing.T) {
+	now := time.Now()
+	overdue := domain.SiteScanState{SiteID: "2", LastScanID: "20", LastScanEnd: now.Add(-200 * time.Hour), TrackedSince: now.Add(-1000 * time.Hour)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteScanFetcher := NewMockSiteScanFetcher(ctrl)
+	mockSiteScanStorer := NewMockSiteScanStorer(ctrl)
+	mockEventProducer := NewMockEventProducer(ctrl)
+	mockSiteLister := NewMockSiteLister(ctrl)
+	handler := SiteScanHandler{
+		SiteLister:         mockSiteLister,
+		SiteScanFetcher:    mockSiteScanFetcher,
+		SiteScanStorer:     mockSiteScanStorer,
+		EventProducer:      mockEventProducer,
+		LogFn:              testLogFn,
+		DefaultMaxInterval: 168 * time.Hour,
+	}
+
+	// the event is retried by the next check
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return([]domain.SiteScanState{overdue}, nil)
+	mockSiteLister.EXPECT().ListSites(gomock.Any()).Return([]string{"2"}, nil)
+	mockEventProducer.EXPECT().ProduceEvent(gomock.Any(), gomock.Any()).Return(fmt.Errorf("producer error"))
+	mockSiteScanStorer.EXPECT().StoreSiteScans(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, states []domain.SiteScanState) error {
+		require.False(t, states[0].Alerted)
+		require.False(t, states[0].OverdueSince.IsZero())
+		return nil
+	})
+	output, err := handler.Check(context.Background())
+	require.NoError(t, err)
+	require.True(t, output.Sites[0].Overdue)
+}
+
+func TestSiteScanHandlerCheckStorageFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteScanFetcher := NewMockSiteScanFetcher(ctrl)
+	mockSiteScanStorer := NewMockSiteScanStorer(ctrl)
+	mockSiteLister := NewMockSiteLister(ctrl)
+	handler := SiteScanHandler{
+		SiteLister:      mockSiteLister,
+		SiteScanFetcher: mockSiteScanFetcher,
+		SiteScanStorer:  mockSiteScanStorer,
+		LogFn:           testLogFn,
+	}
+
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return(nil, fmt.Errorf("fetch error"))
+	_, err := handler.Check(context.Background())
+	require.Error(t, err)
+
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return([]domain.SiteScanState{{SiteID: "1"}}, nil)
+	mockSiteLister.EXPECT().ListSites(gomock.Any()).Return([]string{"1"}, nil)
+	mockSiteScanStorer.EXPECT().StoreSiteScans(gomock.Any(), gomock.Any()).Return(fmt.Errorf("store error"))
+	_, err = handler.Check(context.Background())
+	require.Error(t, err)
+}
+
+func TestSiteScanHandlerFetch(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteScanFetcher := NewMockSiteScanFetcher(ctrl)
+	handler := SiteScanHandler{
+		SiteScanFetcher:    mockSiteScanFetcher,
+		LogFn:              testLogFn,
+		DefaultMaxInterval: 168 * time.Hour,
+	}
+
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return([]domain.SiteScanState{
+		{SiteID: "1", LastScanID: "10", LastScanEnd: ts, TrackedSince: ts.Add(-time.Hour), OverdueSince: ts.Add(200 * time.Hour), Alerted: true},
+	}, nil)
+	output, err := handler.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SiteScansOutput{Sites: []siteScanStatus{{
+		SiteID:       "1",
+		LastScanID:   "10",
+		LastScanTime: "2019-06-01T00:00:00Z",
+		TrackedSince: "2019-05-31T23:00:00Z",
+		MaxInterval:  "168h0m0s",
+		Overdue:      true,
+		OverdueSince: "2019-06-09T08:00:00Z",
+	}}}, output)
+
+	mockSiteScanFetcher.EXPECT().FetchSiteScans(gomock.Any()).Return(nil, fmt.Errorf("fetch error"))
+	_, err = handler.Fetch(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	eventTypeScanStalled = "scan.stalled"
+
+	pausedScanStatus = "paused"
+)
+
+type scanStalledDetail struct {
+	Console      string `json:"console,omitempty"`
+	ScanID       string `json:"scanID"`
+	SiteID       string `json:"siteID"`
+	ScanType     string `json:"scanType"`
+	ScanName     string `json:"scanName"`
+	Status       string `json:"status"`
+	StartTime    string `json:"startTime"`
+	Runtime      string `json:"runtime"`
+	MaxRuntime   string `json:"maxRuntime"`
+	StalledSince string `json:"stalledSince"`
+}
+
+// StalledScanMonitor finds active scans which have run for longer than allowed,
+// which usually means their scan engine is hung, and produces a scan.stalled event
+// once for each of them. Paused scans are never stalled.
+//
+// The maximum runtime of a scan is its site's entry in SiteMaxRuntimes, or else
+// its scan type's entry in ScanTypeMaxRuntimes, or else DefaultMaxRuntime. A scan
+// with a maximum runtime of zero is never stalled, and nothing is fetched when
+// every maximum runtime is zero.
+//
+// A stalled scan is tracked for as long as it is active, including while it is
+// paused, so that it is alerted only once. A tracked scan which is missing from the
+// active scans is looked up with the ActiveScanChecker, and is forgotten only once
+// it is no longer active. An event which cannot be produced is retried by the next
+// check.
+type StalledScanMonitor struct {
+	ActiveScanFetcher   domain.ActiveScanFetcher
+	ActiveScanChecker   domain.ActiveScanChecker
+	StalledScanFetcher  domain.StalledScanFetcher
+	StalledScanStorer   domain.StalledScanStorer
+	EventProducer       domain.EventProducer
+	LogFn               domain.LogFn
+	DefaultMaxRuntime   time.Duration
+	ScanTypeMaxRuntimes map[string]time.Duration
+	SiteMaxRuntimes     map[string]time.Duration
+}
+
+// Check compares the runtime of each active scan with its maximum runtime,
+// producing an event for any scan which has newly stalled.
+func (m *StalledScanMonitor) Check(ctx context.Context) {
+	if !m.enabled() {
+		return
+	}
+	logger := m.LogFn(ctx)
+	scans, err := m.ActiveScanFetcher.FetchActiveScans(ctx)
+	if err != nil {
+		logger.Error(logs.ScanFetcherFailure{Reason: err.Error()})
+		return
+	}
+	previous, err := m.StalledScanFetcher.FetchStalledScans(ctx)
+	if err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+		return
+	}
+	known := make(map[string]domain.StalledScan, len(previous))
+	for _, state := range previous {
+		known[state.ScanID] = state
+	}
+
+	now := time.Now()
+	stalled := make([]domain.StalledScan, 0, len(previous))
+	active := make(map[string]bool, len(scans))
+	for _, scan := range scans {
+		active[scan.ScanID] = true
+		maxRuntime := m.maxRuntime(scan)
+		runtime := now.Sub(scan.StartTime)
+		if strings.EqualFold(scan.Status, pausedScanStatus) || maxRuntime == 0 || runtime <= maxRuntime {
+			// a tracked scan which is paused keeps its state until it finishes
+			if state, ok := known[scan.ScanID]; ok {
+				stalled = append(stalled, state)
+			}
+			continue
+		}
+
+		state, ok := known[scan.ScanID]
+		if !ok {
+			state = domain.StalledScan{ScanID: scan.ScanID, SiteID: scan.SiteID, StalledSince: now}
+			logger.Warn(logs.ScanStalled{
+				ScanID:     scan.ScanID,
+				SiteID:     scan.SiteID,
+				ScanType:   scan.ScanType,
+				Status:     scan.Status,
+				StartTime:  formatWatermark(scan.StartTime),
+				MaxRuntime: maxRuntime.String(),
+			})
+		}
+		if !state.Alerted {
+			event := domain.Event{
+				ID:   domain.NewEventID(eventTypeScanStalled, scan.Console, scan.ScanID),
+				Type: eventTypeScanStalled,
+				Time: now,
+				Detail: scanStalledDetail{
+					Console:      scan.Console,
+					ScanID:       scan.ScanID,
+					SiteID:       scan.SiteID,
+					ScanType:     scan.ScanType,
+					ScanName:     scan.ScanName,
+					Status:       scan.Status,
+					StartTime:    formatWatermark(scan.StartTime),
+					Runtime:      runtime.Round(time.Second).String(),
+					MaxRuntime:   maxRuntime.String(),
+					StalledSince: formatWatermark(state.StalledSince),
+				},
+			}
+			if err := m.EventProducer.ProduceEvent(ctx, event); err != nil {
+				logger.Error(logs.EventFailure{EventType: event.Type, Reason: err.Error()})
+			} else {
+				state.Alerted = true
+			}
+		}
+		stalled = append(stalled, state)
+	}
+	for _, state := range previous {
+		if active[state.ScanID] {
+			continue
+		}
+		// scans can briefly disappear from the active scans, so a tracked scan is
+		// only forgotten once it is confirmed to be no longer active
+		stillActive, err := m.ActiveScanChecker.IsScanActive(ctx, state.ScanID)
+		if err != nil {
+			logger.Error(logs.ScanFetcherFailure{Reason: err.Error()})
+		}
+		if stillActive || err != nil {
+			stalled = append(stalled, state)
+		}
+	}
+	sort.Slice(stalled, func(left, right int) bool {
+		return stalled[left].ScanID < stalled[right].ScanID
+	})
+
+	// most checks find nothing stalled, so skip the write when nothing has changed
+	if sameStalledScans(previous, stalled) {
+		return
+	}
+	if err := m.StalledScanStorer.StoreStalledScans(ctx, stalled); err != nil {
+		logger.Error(logs.StorageFailure{Reason: err.Error()})
+	}
+}
+
+func (m *StalledScanMonitor) enabled() bool {
+	if m.DefaultMaxRuntime > 0 {
+		return true
+	}
+	for _, maxRuntimes := range []map[string]time.Duration{m.ScanTypeMaxRuntimes, m.SiteMaxRuntimes} {
+		for _, maxRuntime := range maxRuntimes {
+			if maxRuntime > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *StalledScanMonitor) maxRuntime(scan domain.ActiveScan) time.Duration {
+	if maxRuntime, ok := m.SiteMaxRuntimes[scan.SiteID]; ok {
+		return maxRuntime
+	}
+	if maxRuntime, ok := m.ScanTypeMaxRuntimes[strings.ToLower(scan.ScanType)]; ok {
+		return maxRuntime
+	}
+	return m.DefaultMaxRuntime
+}
+
+// sameStalledScans reports whether two sets of stalled scans, ordered by scan ID,
+// track the same scans in the same alert state.
+func sameStalledScans(previous []domain.StalledScan, current []domain.StalledScan) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for offset := range previous {
+		if previous[offset].ScanID != current[offset].ScanID || previous[offset].Alerted != current[offset].Alerted {
+			return false
+		}
+	}
+	return true
+}
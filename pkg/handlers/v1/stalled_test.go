@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledScanMonitorTest wires a StalledScanMonitor to mocks which keep the stored
+// stalled scans between checks.
+type stalledScanMonitorTest struct {
+	monitor  *StalledScanMonitor
+	active   []domain.ActiveScan
+	missing  map[string]bool // scans missing from the active scans which are still active
+	checkErr error
+	stalled  []domain.StalledScan
+	stores   int
+	eventErr error
+	events   []domain.Event
+}
+
+func newStalledScanMonitorTest(ctrl *gomock.Controller) *stalledScanMonitorTest {
+	test := &stalledScanMonitorTest{}
+	mockActiveScanFetcher := NewMockActiveScanFetcher(ctrl)
+	mockActiveScanChecker := NewMockActiveScanChecker(ctrl)
+	mockStalledScanFetcher := NewMockStalledScanFetcher(ctrl)
+	mockStalledScanStorer := NewMockStalledScanStorer(ctrl)
+	mockEventProducer := NewMockEventProducer(ctrl)
+
+	mockActiveScanFetcher.EXPECT().FetchActiveScans(gomock.Any()).DoAndReturn(
+		func(context.Context) ([]domain.ActiveScan, error) {
+			return test.active, nil
+		}).AnyTimes()
+	mockActiveScanChecker.EXPECT().IsScanActive(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, scanID string) (bool, error) {
+			return test.missing[scanID], test.checkErr
+		}).AnyTimes()
+	mockStalledScanFetcher.EXPECT().FetchStalledScans(gomock.Any()).DoAndReturn(
+		func(context.Context) ([]domain.StalledScan, error) {
+			return test.stalled, nil
+		}).AnyTimes()
+	mockStalledScanStorer.EXPECT().StoreStalledScans(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, scans []domain.StalledScan) error {
+			test.stalled = scans
+			test.stores = test.stores + 1
+			return nil
+		}).AnyTimes()
+	mockEventProducer.EXPECT().ProduceEvent(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, event domain.Event) error {
+			if test.eventErr != nil {
+				return test.eventErr
+			}
+			test.events = append(test.events, event)
+			return nil
+		}).AnyTimes()
+
+	test.monitor = &StalledScanMonitor{
+		ActiveScanFetcher:   mockActiveScanFetcher,
+		ActiveScanChecker:   mockActiveScanChecker,
+		StalledScanFetcher:  mockStalledScanFetcher,
+		StalledScanStorer:   mockStalledScanStorer,
+		EventProducer:       mockEventProducer,
+		LogFn:               testLogFn,
+		DefaultMaxRuntime:   24 * time.Hour,
+		ScanTypeMaxRuntimes: map[string]time.Duration{"manual": 0, "scheduled": 48 * time.Hour},
+		SiteMaxRuntimes:     map[string]time.Duration{"12": 72 * time.Hour},
+	}
+	return test
+}
+
+func TestStalledScanMonitor_Check(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newStalledScanMonitorTest(ctrl)
+	ctx := context.Background()
+	now := time.Now()
+
+	hung := domain.ActiveScan{Console: "console", ScanID: "1", SiteID: "10", ScanType: "Scheduled", ScanName: "Weekly", Status: "running", StartTime: now.Add(-49 * time.Hour)}
+	test.active = []domain.ActiveScan{
+		hung,
+		// within the maximum runtime of its scan type
+		{ScanID: "2", SiteID: "10", ScanType: "Scheduled", Status: "running", StartTime: now.Add(-47 * time.Hour)},
+		// within the maximum runtime of its site, which takes precedence
+		{ScanID: "3", SiteID: "12", ScanType: "Scheduled", Status: "running", StartTime: now.Add(-71 * time.Hour)},
+		// never stalled by scan type
+		{ScanID: "4", SiteID: "10", ScanType: "Manual", Status: "running", StartTime: now.Add(-1000 * time.Hour)},
+		// never stalled while paused
+		{ScanID: "5", SiteID: "10", ScanType: "Automated", Status: "paused", StartTime: now.Add(-1000 * time.Hour)},
+	}
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+	event := test.events[0]
+	require.Equal(t, eventTypeScanStalled, event.Type)
+	require.Equal(t, domain.NewEventID(eventTypeScanStalled, "console", "1"), event.ID)
+	detail := event.Detail.(scanStalledDetail)
+	require.Equal(t, "1", detail.ScanID)
+	require.Equal(t, "10", detail.SiteID)
+	require.Equal(t, "Weekly", detail.ScanName)
+	require.Equal(t, "48h0m0s", detail.MaxRuntime)
+	require.Equal(t, "49h0m0s", detail.Runtime)
+	require.Len(t, test.stalled, 1)
+	require.Equal(t, "1", test.stalled[0].ScanID)
+	require.True(t, test.stalled[0].Alerted)
+
+	// the event is only produced once per scan, and nothing is stored when unchanged
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+	require.Equal(t, 1, test.stores)
+
+	// a newly stalled scan is added to those already stalled
+	test.active = append(test.active, domain.ActiveScan{ScanID: "6", SiteID: "12", ScanType: "Manual", Status: "integrating", StartTime: now.Add(-73 * time.Hour)})
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 2)
+	require.Equal(t, "6", test.events[1].Detail.(scanStalledDetail).ScanID)
+	require.Len(t, test.stalled, 2)
+
+	// scans are forgotten once they finish
+	test.active = nil
+	test.monitor.Check(ctx)
+	require.Empty(t, test.stalled)
+	require.Len(t, test.events, 2)
+}
+
+func TestStalledScanMonitor_TrackedWhileActive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newStalledScanMonitorTest(ctrl)
+	ctx := context.Background()
+	hung := domain.ActiveScan{ScanID: "1", SiteID: "10", ScanType: "Automated", Status: "running", StartTime: time.Now().Add(-25 * time.Hour)}
+	test.active = []domain.ActiveScan{hung}
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+	stalledSince := test.stalled[0].StalledSince
+
+	// a stalled scan which is paused and resumed is not alerted again
+	paused := hung
+	paused.Status = "paused"
+	test.active = []domain.ActiveScan{paused}
+	test.monitor.Check(ctx)
+	require.Len(t, test.stalled, 1)
+	test.active = []domain.ActiveScan{hung}
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+
+	// nor is one which is briefly missing from the active scans
+	test.active = nil
+	test.missing = map[string]bool{"1": true}
+	test.monitor.Check(ctx)
+	require.Len(t, test.stalled, 1)
+	test.missing = nil
+	test.checkErr = fmt.Errorf("nexpose error")
+	test.monitor.Check(ctx)
+	require.Len(t, test.stalled, 1)
+	test.checkErr = nil
+	test.active = []domain.ActiveScan{hung}
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+	require.Equal(t, stalledSince, test.stalled[0].StalledSince)
+
+	// the scan is forgotten once it is no longer active
+	test.active = nil
+	test.monitor.Check(ctx)
+	require.Empty(t, test.stalled)
+}
+
+func TestStalledScanMonitor_EventFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	test := newStalledScanMonitorTest(ctrl)
+	ctx := context.Background()
+	test.active = []domain.ActiveScan{{ScanID: "1", SiteID: "10", ScanType: "Automated", Status: "running", StartTime: time.Now().Add(-25 * time.Hour)}}
+
+	// an event which cannot be produced is retried with the same ID by the next check
+	test.eventErr = fmt.Errorf("producer error")
+	test.monitor.Check(ctx)
+	require.Len(t, test.stalled, 1)
+	require.False(t, test.stalled[0].Alerted)
+	stalledSince := test.stalled[0].StalledSince
+
+	test.eventErr = nil
+	test.monitor.Check(ctx)
+	require.Len(t, test.events, 1)
+	require.True(t, test.stalled[0].Alerted)
+	require.Equal(t, stalledSince, test.stalled[0].StalledSince)
+	require.Equal(t, formatWatermark(stalledSince), test.events[0].Detail.(scanStalledDetail).StalledSince)
+}
+
+func TestStalledScanMonitor_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	monitor := &StalledScanMonitor{
+		LogFn:               testLogFn,
+		ScanTypeMaxRuntimes: map[string]time.Duration{"manual": 0},
+	}
+
+	// nothing is fetched when every maximum runtime is zero
+	monitor.Check(context.Background())
+}
+
+func TestStalledScanMonitor_Failures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockActiveScanFetcher := NewMockActiveScanFetcher(ctrl)
+	mockStalledScanFetcher := NewMockStalledScanFetcher(ctrl)
+	monitor := &StalledScanMonitor{
+		ActiveScanFetcher:  mockActiveScanFetcher,
+		StalledScanFetcher: mockStalledScanFetcher,
+		LogFn:              testLogFn,
+		DefaultMaxRuntime:  time.Hour,
+	}
+
+	// nothing is produced or stored when either fetch fails
+	mockActiveScanFetcher.EXPECT().FetchActiveScans(gomock.Any()).Return(nil, fmt.Errorf("nexpose error"))
+	monitor.Check(context.Background())
+
+	mockActiveScanFetcher.EXPECT().FetchActiveScans(gomock.Any()).Return([]domain.ActiveScan{
+		{ScanID: "1", Status: "running", StartTime: time.Now().Add(-2 * time.Hour)},
+	}, nil)
+	mockStalledScanFetcher.EXPECT().FetchStalledScans(gomock.Any()).Return(nil, fmt.Errorf("dynamodb error"))
+	monitor.Check(context.Background())
+}
@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	watermarkActionSet   = "set"
+	watermarkActionReset = "reset"
+)
+
+// WatermarkOutput contains the stored timestamp of the last processed scan.
+// Previous is only populated by calls which change the stored timestamp.
+type WatermarkOutput struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Previous  string `json:"previous,omitempty"`
+}
+
+// WatermarkSetInput contains the new timestamp of the last processed scan,
+// along with who is changing it and why. Requests through the gateway are made
+// by the subject of their verified ASAP token, passed on in Authorization; Actor
+// can only be set by callers within the process, such as notifierctl.
+type WatermarkSetInput struct {
+	Timestamp     string `json:"timestamp"`
+	Reason        string `json:"reason"`
+	Authorization string `json:"authorization"`
+	Actor         string `json:"-"`
+}
+
+// WatermarkResetInput contains who is resetting the stored timestamp and why,
+// in the same way as WatermarkSetInput.
+type WatermarkResetInput struct {
+	Reason        string `json:"reason"`
+	Authorization string `json:"authorization"`
+	Actor         string `json:"-"`
+}
+
+// WatermarkHandler exposes administrative operations on the stored timestamp
+// of the last processed scan. Every change is recorded by the
+// WatermarkChangeRecorder before it is made, so that no change goes unaudited.
+type WatermarkHandler struct {
+	TimestampFetcher        domain.TimestampFetcher
+	TimestampStorer         domain.TimestampStorer
+	TimestampResetter       domain.TimestampResetter
+	WatermarkChangeRecorder domain.WatermarkChangeRecorder
+	LogFn                   domain.LogFn
+}
+
+// Fetch returns the stored timestamp, or an empty output if none is stored.
+func (h *WatermarkHandler) Fetch(ctx context.Context) (WatermarkOutput, error) {
+	ts, err := h.fetchTimestamp(ctx)
+	if err != nil {
+		return WatermarkOutput{}, err
+	}
+	return WatermarkOutput{Timestamp: formatWatermark(ts)}, nil
+}
+
+// Set replaces the stored timestamp. Timestamps in the future are rejected, as they
+// would cause every scan completed before that time to be silently skipped.
+func (h *WatermarkHandler) Set(ctx context.Context, in WatermarkSetInput) (WatermarkOutput, error) {
+	actor, err := watermarkActor(in.Actor, in.Authorization)
+	if err != nil {
+		return WatermarkOutput{}, err
+	}
+	if err := validateWatermarkChange(actor, in.Reason); err != nil {
+		return WatermarkOutput{}, err
+	}
+	ts, err := time.Parse(time.RFC3339Nano, in.Timestamp)
+	if err != nil {
+		return WatermarkOutput{}, domain.InvalidInput{Field: "timestamp", Reason: err.Error()}
+	}
+	if ts.After(time.Now()) {
+		return WatermarkOutput{}, domain.InvalidInput{Field: "timestamp", Reason: "must not be in the future"}
+	}
+
+	previous, err := h.fetchTimestamp(ctx)
+	if err != nil {
+		return WatermarkOutput{}, err
+	}
+	change := domain.WatermarkChange{
+		Action:    watermarkActionSet,
+		Actor:     actor,
+		Reason:    in.Reason,
+		Previous:  previous,
+		Current:   ts,
+		ChangedAt: time.Now(),
+	}
+	if err := h.recordChange(ctx, change); err != nil {
+		return WatermarkOutput{}, err
+	}
+	if err := h.TimestampStorer.StoreTimestamp(ctx, ts); err != nil {
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return WatermarkOutput{}, err
+	}
+
+	output := WatermarkOutput{Timestamp: formatWatermark(ts), Previous: formatWatermark(previous)}
+	h.LogFn(ctx).Info(logs.WatermarkChanged{
+		Action:   watermarkActionSet,
+		Actor:    actor,
+		Reason:   in.Reason,
+		Previous: output.Previous,
+		Current:  output.Timestamp,
+	})
+	return output, nil
+}
+
+// Reset removes the stored timestamp, so that the next notification run behaves
+// as though no scans have been processed.
+func (h *WatermarkHandler) Reset(ctx context.Context, in WatermarkResetInput) (WatermarkOutput, error) {
+	actor, err := watermarkActor(in.Actor, in.Authorization)
+	if err != nil {
+		return WatermarkOutput{}, err
+	}
+	if err := validateWatermarkChange(actor, in.Reason); err != nil {
+		return WatermarkOutput{}, err
+	}
+
+	previous, err := h.fetchTimestamp(ctx)
+	if err != nil {
+		return WatermarkOutput{}, err
+	}
+	change := domain.WatermarkChange{
+		Action:    watermarkActionReset,
+		Actor:     actor,
+		Reason:    in.Reason,
+		Previous:  previous,
+		ChangedAt: time.Now(),
+	}
+	if err := h.recordChange(ctx, change); err != nil {
+		return WatermarkOutput{}, err
+	}
+	if err := h.TimestampResetter.ResetTimestamp(ctx); err != nil {
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return WatermarkOutput{}, err
+	}
+
+	output := WatermarkOutput{Previous: formatWatermark(previous)}
+	h.LogFn(ctx).Info(logs.WatermarkChanged{
+		Action:   watermarkActionReset,
+		Actor:    actor,
+		Reason:   in.Reason,
+		Previous: output.Previous,
+	})
+	return output, nil
+}
+
+// fetchTimestamp returns the stored timestamp, or the zero time if none is stored.
+func (h *WatermarkHandler) fetchTimestamp(ctx context.Context) (time.Time, error) {
+	ts, err := h.TimestampFetcher.FetchTimestamp(ctx)
+	switch err.(type) {
+	case nil:
+		return ts, nil
+	case domain.TimestampNotFound:
+		return time.Time{}, nil
+	default:
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return time.Time{}, err
+	}
+}
+
+// recordChange stores the audit record of a change.
+func (h *WatermarkHandler) recordChange(ctx context.Context, change domain.WatermarkChange) error {
+	if err := h.WatermarkChangeRecorder.RecordWatermarkChange(ctx, change); err != nil {
+		h.LogFn(ctx).Error(logs.StorageFailure{Reason: err.Error()})
+		return err
+	}
+	return nil
+}
+
+// watermarkActor returns who is making a change: the actor given by a caller within
+// the process, or else the subject of the ASAP token the request was made with.
+func watermarkActor(actor string, authorization string) (string, error) {
+	if strings.TrimSpace(actor) != "" || strings.TrimSpace(authorization) == "" {
+		return actor, nil
+	}
+	return asapSubject(authorization)
+}
+
+func validateWatermarkChange(actor string, reason string) error {
+	if strings.TrimSpace(actor) == "" {
+		return domain.InvalidInput{Field: "actor", Reason: "must not be empty"}
+	}
+	if strings.TrimSpace(reason) == "" {
+		return domain.InvalidInput{Field: "reason", Reason: "must not be empty"}
+	}
+	return nil
+}
+
+func formatWatermark(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,302 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermarkHandlerFetch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ts := time.Now().Add(-1 * time.Hour)
+	tc := []struct {
+		Name      string
+		Timestamp time.Time
+		FetchErr  error
+		Output    WatermarkOutput
+		Err       bool
+	}{
+		{
+			Name:      "success",
+			Timestamp: ts,
+			FetchErr:  nil,
+			Output:    WatermarkOutput{Timestamp: ts.Format(time.RFC3339Nano)},
+			Err:       false,
+		},
+		{
+			Name:      "no timestamp found",
+			Timestamp: time.Time{},
+			FetchErr:  domain.TimestampNotFound{},
+			Output:    WatermarkOutput{},
+			Err:       false,
+		},
+		{
+			Name:      "fetch error",
+			Timestamp: time.Time{},
+			FetchErr:  fmt.Errorf("fetch error"),
+			Output:    WatermarkOutput{},
+			Err:       true,
+		},
+	}
+
+	mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+	handler := WatermarkHandler{
+		TimestampFetcher: mockTimestampFetcher,
+		LogFn:            testLogFn,
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.Timestamp, tt.FetchErr)
+			output, err := handler.Fetch(context.Background())
+			require.Equal(t, tt.Output, output)
+			require.Equal(t, tt.Err, err != nil)
+		})
+	}
+}
+
+func TestWatermarkHandlerSet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	previous := time.Now().Add(-2 * time.Hour)
+	ts := time.Now().Add(-1 * time.Hour)
+	tc := []struct {
+		Name         string
+		Input        WatermarkSetInput
+		ExpectFetch  bool
+		FetchErr     error
+		ExpectRecord bool
+		RecordErr    error
+		Actor        string
+		ExpectStore  bool
+		StoreErr     error
+		Output       WatermarkOutput
+		Err          error
+	}{
+		{
+			Name:         "success",
+			Input:        WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: "rewind"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			Actor:        "admin",
+			ExpectStore:  true,
+			Output: WatermarkOutput{
+				Timestamp: ts.Format(time.RFC3339Nano),
+				Previous:  previous.Format(time.RFC3339Nano),
+			},
+		},
+		{
+			Name:         "actor from the ASAP token",
+			Input:        WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Authorization: asapToken("ops-automation"), Reason: "rewind"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			Actor:        "ops-automation",
+			ExpectStore:  true,
+			Output: WatermarkOutput{
+				Timestamp: ts.Format(time.RFC3339Nano),
+				Previous:  previous.Format(time.RFC3339Nano),
+			},
+		},
+		{
+			Name:         "success with no previous timestamp",
+			Input:        WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: "rewind"},
+			ExpectFetch:  true,
+			FetchErr:     domain.TimestampNotFound{},
+			ExpectRecord: true,
+			Actor:        "admin",
+			ExpectStore:  true,
+			Output:       WatermarkOutput{Timestamp: ts.Format(time.RFC3339Nano)},
+		},
+		{
+			Name:  "invalid ASAP token",
+			Input: WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Authorization: "Bearer token", Reason: "rewind"},
+			Err:   domain.InvalidInput{Field: "authorization", Reason: "must be an ASAP bearer token"},
+		},
+		{
+			Name:  "missing reason",
+			Input: WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: " "},
+			Err:   domain.InvalidInput{Field: "reason", Reason: "must not be empty"},
+		},
+		{
+			Name:  "missing actor",
+			Input: WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Reason: "rewind"},
+			Err:   domain.InvalidInput{Field: "actor", Reason: "must not be empty"},
+		},
+		{
+			Name:  "timestamp in the future",
+			Input: WatermarkSetInput{Timestamp: time.Now().Add(time.Hour).Format(time.RFC3339Nano), Actor: "admin", Reason: "skip"},
+			Err:   domain.InvalidInput{Field: "timestamp", Reason: "must not be in the future"},
+		},
+		{
+			Name:        "fetch error",
+			Input:       WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: "rewind"},
+			ExpectFetch: true,
+			FetchErr:    fmt.Errorf("fetch error"),
+			Err:         fmt.Errorf("fetch error"),
+		},
+		{
+			Name:         "audit record error",
+			Input:        WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: "rewind"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			Actor:        "admin",
+			RecordErr:    fmt.Errorf("record error"),
+			Err:          fmt.Errorf("record error"),
+		},
+		{
+			Name:         "store error",
+			Input:        WatermarkSetInput{Timestamp: ts.Format(time.RFC3339Nano), Actor: "admin", Reason: "rewind"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			Actor:        "admin",
+			ExpectStore:  true,
+			StoreErr:     fmt.Errorf("store error"),
+			Err:          fmt.Errorf("store error"),
+		},
+	}
+
+	mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+	mockTimestampStorer := NewMockTimestampStorer(ctrl)
+	mockChangeRecorder := NewMockWatermarkChangeRecorder(ctrl)
+	handler := WatermarkHandler{
+		TimestampFetcher:        mockTimestampFetcher,
+		TimestampStorer:         mockTimestampStorer,
+		WatermarkChangeRecorder: mockChangeRecorder,
+		LogFn:                   testLogFn,
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			if tt.ExpectFetch {
+				mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(previous, tt.FetchErr)
+			}
+			if tt.ExpectRecord {
+				mockChangeRecorder.EXPECT().RecordWatermarkChange(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, change domain.WatermarkChange) error {
+						require.Equal(t, watermarkActionSet, change.Action)
+						require.Equal(t, tt.Actor, change.Actor)
+						require.Equal(t, tt.Input.Reason, change.Reason)
+						require.Equal(t, tt.Input.Timestamp, formatWatermark(change.Current))
+						return tt.RecordErr
+					})
+			}
+			if tt.ExpectStore {
+				expected, _ := time.Parse(time.RFC3339Nano, tt.Input.Timestamp)
+				mockTimestampStorer.EXPECT().StoreTimestamp(gomock.Any(), expected).Return(tt.StoreErr)
+			}
+			output, err := handler.Set(context.Background(), tt.Input)
+			require.Equal(t, tt.Output, output)
+			require.Equal(t, tt.Err, err)
+		})
+	}
+}
+
+func TestWatermarkHandlerSetInvalidTimestamp(t *testing.T) {
+	handler := WatermarkHandler{LogFn: testLogFn}
+	_, err := handler.Set(context.Background(), WatermarkSetInput{Timestamp: "yesterday", Actor: "admin", Reason: "rewind"})
+	require.IsType(t, domain.InvalidInput{}, err)
+}
+
+func TestWatermarkHandlerReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	previous := time.Now().Add(-2 * time.Hour)
+	tc := []struct {
+		Name         string
+		Input        WatermarkResetInput
+		ExpectFetch  bool
+		FetchErr     error
+		ExpectRecord bool
+		RecordErr    error
+		ExpectReset  bool
+		ResetErr     error
+		Output       WatermarkOutput
+		Err          error
+	}{
+		{
+			Name:         "success",
+			Input:        WatermarkResetInput{Actor: "admin", Reason: "start over"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			ExpectReset:  true,
+			Output:       WatermarkOutput{Previous: previous.Format(time.RFC3339Nano)},
+		},
+		{
+			Name:  "missing actor",
+			Input: WatermarkResetInput{Reason: "start over"},
+			Err:   domain.InvalidInput{Field: "actor", Reason: "must not be empty"},
+		},
+		{
+			Name:  "missing reason",
+			Input: WatermarkResetInput{Actor: "admin"},
+			Err:   domain.InvalidInput{Field: "reason", Reason: "must not be empty"},
+		},
+		{
+			Name:        "fetch error",
+			Input:       WatermarkResetInput{Actor: "admin", Reason: "start over"},
+			ExpectFetch: true,
+			FetchErr:    fmt.Errorf("fetch error"),
+			Err:         fmt.Errorf("fetch error"),
+		},
+		{
+			Name:         "audit record error",
+			Input:        WatermarkResetInput{Actor: "admin", Reason: "start over"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			RecordErr:    fmt.Errorf("record error"),
+			Err:          fmt.Errorf("record error"),
+		},
+		{
+			Name:         "reset error",
+			Input:        WatermarkResetInput{Actor: "admin", Reason: "start over"},
+			ExpectFetch:  true,
+			ExpectRecord: true,
+			ExpectReset:  true,
+			ResetErr:     fmt.Errorf("reset error"),
+			Err:          fmt.Errorf("reset error"),
+		},
+	}
+
+	mockTimestampFetcher := NewMockTimestampFetcher(ctrl)
+	mockTimestampResetter := NewMockTimestampResetter(ctrl)
+	mockChangeRecorder := NewMockWatermarkChangeRecorder(ctrl)
+	handler := WatermarkHandler{
+		TimestampFetcher:        mockTimestampFetcher,
+		TimestampResetter:       mockTimestampResetter,
+		WatermarkChangeRecorder: mockChangeRecorder,
+		LogFn:                   testLogFn,
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			if tt.ExpectFetch {
+				mockTimestampFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(previous, tt.FetchErr)
+			}
+			if tt.ExpectRecord {
+				mockChangeRecorder.EXPECT().RecordWatermarkChange(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, change domain.WatermarkChange) error {
+						require.Equal(t, watermarkActionReset, change.Action)
+						require.Equal(t, "admin", change.Actor)
+						require.Equal(t, previous, change.Previous)
+						require.True(t, change.Current.IsZero())
+						return tt.RecordErr
+					})
+			}
+			if tt.ExpectReset {
+				mockTimestampResetter.EXPECT().ResetTimestamp(gomock.Any()).Return(tt.ResetErr)
+			}
+			output, err := handler.Reset(context.Background(), tt.Input)
+			require.Equal(t, tt.Output, output)
+			require.Equal(t, tt.Err, err)
+		})
+	}
+}
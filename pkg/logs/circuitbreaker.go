@@ -0,0 +1,10 @@
+package logs
+
+// CircuitBreakerStateChanged is logged when the circuit breaker protecting a
+// dependency opens, closes, or begins allowing trial requests.
+type CircuitBreakerStateChanged struct {
+	Message    string `logevent:"message,default=circuit-breaker-state-changed"`
+	Dependency string `logevent:"dependency"`
+	Previous   string `logevent:"previous"`
+	Current    string `logevent:"current"`
+}
@@ -8,8 +8,9 @@ type ScanFetcherFailure struct {
 
 // ProducerFailure is logged when the producer fails to put a scan on the queue.
 type ProducerFailure struct {
-	Message string `logevent:"message,default=producer-failure"`
-	Reason  string `logevent:"reason"`
+	Message     string `logevent:"message,default=producer-failure"`
+	Destination string `logevent:"destination"`
+	Reason      string `logevent:"reason"`
 }
 
 // StorageFailure is logged when there is a failure with the storage layer.
@@ -17,3 +18,43 @@ type StorageFailure struct {
 	Message string `logevent:"message,default=storage-failure"`
 	Reason  string `logevent:"reason"`
 }
+
+// MalformedScanQuarantined is logged when a scan record from Nexpose cannot be parsed
+// and is skipped so that other scans can still be processed.
+type MalformedScanQuarantined struct {
+	Message  string `logevent:"message,default=malformed-scan-quarantined"`
+	ScanID   string `logevent:"scanID"`
+	ScanName string `logevent:"scanName"`
+	SiteID   string `logevent:"siteID"`
+	Field    string `logevent:"field"`
+	Reason   string `logevent:"reason"`
+	Record   string `logevent:"record"`
+}
+
+// SiteEnrichmentFailure is logged when the details of a scan's site cannot be looked
+// up, and the scan is produced without them.
+type SiteEnrichmentFailure struct {
+	Message string `logevent:"message,default=site-enrichment-failure"`
+	ScanID  string `logevent:"scanID"`
+	SiteID  string `logevent:"siteID"`
+	Reason  string `logevent:"reason"`
+}
+
+// RouteFailure is logged when a scan cannot be produced to one of the destinations
+// it was routed to.
+type RouteFailure struct {
+	Message     string `logevent:"message,default=route-failure"`
+	ScanID      string `logevent:"scanID"`
+	Destination string `logevent:"destination"`
+	Reason      string `logevent:"reason"`
+}
+
+// ScanDeadLettered is logged when a scan could not be produced to a destination after
+// every attempt, and was stored as a dead letter instead.
+type ScanDeadLettered struct {
+	Message     string `logevent:"message,default=scan-dead-lettered"`
+	ScanID      string `logevent:"scanID"`
+	Destination string `logevent:"destination"`
+	Attempts    int    `logevent:"attempts"`
+	Reason      string `logevent:"reason"`
+}
@@ -0,0 +1,25 @@
+package logs
+
+// NotifierDegraded is logged when the notifier becomes degraded, such as after
+// consecutive failed runs or once the stored timestamp becomes too old.
+type NotifierDegraded struct {
+	Message             string `logevent:"message,default=notifier-degraded"`
+	Reasons             string `logevent:"reasons"`
+	ConsecutiveFailures int    `logevent:"consecutiveFailures"`
+	LastError           string `logevent:"lastError"`
+	Watermark           string `logevent:"watermark"`
+}
+
+// NotifierRecovered is logged when a degraded notifier becomes healthy again.
+type NotifierRecovered struct {
+	Message       string `logevent:"message,default=notifier-recovered"`
+	DegradedSince string `logevent:"degradedSince"`
+	Duration      string `logevent:"duration"`
+}
+
+// EventFailure is logged when an event other than a completed scan cannot be produced.
+type EventFailure struct {
+	Message   string `logevent:"message,default=event-failure"`
+	EventType string `logevent:"eventType"`
+	Reason    string `logevent:"reason"`
+}
@@ -0,0 +1,37 @@
+package logs
+
+// RunBudgetExhausted is logged when a notification run stops before producing every
+// eligible scan in order to stay within its budget.
+type RunBudgetExhausted struct {
+	Message     string `logevent:"message,default=run-budget-exhausted"`
+	Produced    int    `logevent:"produced"`
+	Fetched     int    `logevent:"fetched"`
+	MaxScans    int    `logevent:"maxScans"`
+	MaxDuration string `logevent:"maxDuration"`
+}
+
+// RunCompleted is logged at the end of every successful notification run, summarizing
+// what it did.
+type RunCompleted struct {
+	Message         string `logevent:"message,default=run-completed"`
+	RunID           string `logevent:"runID"`
+	Fetched         int    `logevent:"fetched"`
+	Produced        int    `logevent:"produced"`
+	PagesFetched    int    `logevent:"pagesFetched"`
+	WatermarkBefore string `logevent:"watermarkBefore"`
+	WatermarkAfter  string `logevent:"watermarkAfter"`
+	Duration        string `logevent:"duration"`
+	Partial         bool   `logevent:"partial"`
+}
+
+// TargetedRun is logged when a notification run is invoked with overrides, such as
+// a time range or filters, or as a dry run.
+type TargetedRun struct {
+	Message   string `logevent:"message,default=targeted-run"`
+	Since     string `logevent:"since"`
+	Until     string `logevent:"until"`
+	SiteIDs   string `logevent:"siteIDs"`
+	ScanTypes string `logevent:"scanTypes"`
+	MaxScans  int    `logevent:"maxScans"`
+	DryRun    bool   `logevent:"dryRun"`
+}
@@ -0,0 +1,30 @@
+package logs
+
+// PaginationDrift is logged when the scans returned by Nexpose change between
+// requesting two pages, shifting scans between pages.
+type PaginationDrift struct {
+	Message                string `logevent:"message,default=pagination-drift"`
+	Page                   int    `logevent:"page"`
+	PreviousTotalResources int    `logevent:"previousTotalResources"`
+	TotalResources         int    `logevent:"totalResources"`
+}
+
+// CrawlDeadlineExceeded is logged when the crawl for scans stops at the deadline of
+// the query, before reaching the scans which completed earliest.
+type CrawlDeadlineExceeded struct {
+	Message string `logevent:"message,default=crawl-deadline-exceeded"`
+	Page    int    `logevent:"page"`
+	Pages   int    `logevent:"pages"`
+}
+
+// ScanStalled is logged when an active scan has run for longer than its maximum
+// runtime.
+type ScanStalled struct {
+	Message    string `logevent:"message,default=scan-stalled"`
+	ScanID     string `logevent:"scanID"`
+	SiteID     string `logevent:"siteID"`
+	ScanType   string `logevent:"scanType"`
+	Status     string `logevent:"status"`
+	StartTime  string `logevent:"startTime"`
+	MaxRuntime string `logevent:"maxRuntime"`
+}
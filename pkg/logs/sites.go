@@ -0,0 +1,11 @@
+package logs
+
+// SiteScanOverdue is logged when a site has gone longer than its maximum interval
+// without a completed scan.
+type SiteScanOverdue struct {
+	Message      string `logevent:"message,default=site-scan-overdue"`
+	SiteID       string `logevent:"siteID"`
+	LastScanID   string `logevent:"lastScanID"`
+	LastScanTime string `logevent:"lastScanTime"`
+	MaxInterval  string `logevent:"maxInterval"`
+}
@@ -0,0 +1,20 @@
+package logs
+
+// WatermarkChanged is logged as an audit record whenever the stored timestamp is
+// manually set or reset through the admin API.
+type WatermarkChanged struct {
+	Message  string `logevent:"message,default=watermark-changed"`
+	Action   string `logevent:"action"`
+	Actor    string `logevent:"actor"`
+	Reason   string `logevent:"reason"`
+	Previous string `logevent:"previous"`
+	Current  string `logevent:"current"`
+}
+
+// WatermarkBootstrapped is logged when no timestamp exists in storage and the
+// configured bootstrap policy chooses where to start.
+type WatermarkBootstrapped struct {
+	Message   string `logevent:"message,default=watermark-bootstrapped"`
+	Policy    string `logevent:"policy"`
+	Timestamp string `logevent:"timestamp"`
+}
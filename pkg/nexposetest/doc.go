@@ -0,0 +1,5 @@
+// Package nexposetest provides a fake Nexpose API server for local development
+// and tests. It serves the parts of the Nexpose v3 API used by the notifier,
+// including scans with paging and sorting, sites, tags and assets, and allows
+// tests to inject scans, status changes, latency and errors.
+package nexposetest
@@ -0,0 +1,86 @@
+package nexposetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Fixtures are scans, sites and assets loaded into a fake Nexpose server. Times are
+// formatted as RFC3339.
+type Fixtures struct {
+	Scans  []FixtureScan  `json:"scans"`
+	Sites  []FixtureSite  `json:"sites"`
+	Assets []FixtureAsset `json:"assets"`
+}
+
+// FixtureScan is a scan in a fixtures file.
+type FixtureScan struct {
+	ID        int       `json:"id"`
+	SiteID    int       `json:"siteId"`
+	Name      string    `json:"scanName"`
+	Type      string    `json:"scanType"`
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// FixtureSite is a site in a fixtures file.
+type FixtureSite struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Importance  string `json:"importance"`
+	Tags        []Tag  `json:"tags"`
+}
+
+// FixtureAsset is an asset in a fixtures file.
+type FixtureAsset struct {
+	ID       int    `json:"id"`
+	SiteID   int    `json:"siteId"`
+	IP       string `json:"ip"`
+	HostName string `json:"hostName"`
+}
+
+// LoadFixtures decodes fixtures from JSON and adds them to the server.
+func (s *Server) LoadFixtures(r io.Reader) error {
+	var fixtures Fixtures
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return fmt.Errorf("invalid nexpose fixtures: %s", err.Error())
+	}
+	for _, scan := range fixtures.Scans {
+		s.AddScans(Scan(scan))
+	}
+	for _, site := range fixtures.Sites {
+		s.AddSites(Site(site))
+	}
+	for _, asset := range fixtures.Assets {
+		s.AddAssets(Asset(asset))
+	}
+	return nil
+}
+
+// GenerateScans returns count finished scans spread across sites, one ending every
+// interval up to end. Scan IDs start at firstID, and sites are numbered from 1.
+func GenerateScans(firstID int, count int, sites int, end time.Time, interval time.Duration) []Scan {
+	if sites < 1 {
+		sites = 1
+	}
+	scans := make([]Scan, 0, count)
+	for offset := 0; offset < count; offset = offset + 1 {
+		endTime := end.Add(-time.Duration(count-offset-1) * interval)
+		scans = append(scans, Scan{
+			ID:        firstID + offset,
+			SiteID:    offset%sites + 1,
+			Name:      fmt.Sprintf("Scan %d", firstID+offset),
+			Type:      "Scheduled",
+			Status:    StatusFinished,
+			StartTime: endTime.Add(-interval / 2),
+			EndTime:   endTime,
+		})
+	}
+	return scans
+}
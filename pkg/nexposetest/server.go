@@ -0,0 +1,492 @@
+package nexposetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPageSize = 10  // The page size used by Nexpose when none is requested.
+	maxPageSize     = 500 // The largest page size Nexpose allows.
+)
+
+// failure is an injected error response for requests to paths with a prefix.
+type failure struct {
+	prefix    string
+	status    int
+	remaining int // zero fails every matching request
+}
+
+// Server is a fake Nexpose API server. It is safe for concurrent use, so scans may
+// be changed while a client is paging through them.
+type Server struct {
+	lock     sync.Mutex
+	scans    map[int]Scan
+	sites    map[int]Site
+	assets   map[int]Asset
+	latency  time.Duration
+	failures []*failure
+	requests map[string]int
+	username string
+	password string
+}
+
+// New returns an empty fake Nexpose server, which may be started with
+// httptest.NewServer or http.ListenAndServe.
+func New() *Server {
+	return &Server{
+		scans:    map[int]Scan{},
+		sites:    map[int]Site{},
+		assets:   map[int]Asset{},
+		requests: map[string]int{},
+	}
+}
+
+// AddScans adds scans, replacing any existing scans with the same IDs.
+func (s *Server) AddScans(scans ...Scan) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, scan := range scans {
+		s.scans[scan.ID] = scan
+	}
+}
+
+// SetScanStatus changes the status and end time of a scan, returning false if the
+// scan does not exist.
+func (s *Server) SetScanStatus(id int, status string, endTime time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	scan, ok := s.scans[id]
+	if !ok {
+		return false
+	}
+	scan.Status = status
+	scan.EndTime = endTime
+	s.scans[id] = scan
+	return true
+}
+
+// RemoveScan removes a scan, returning false if the scan does not exist.
+func (s *Server) RemoveScan(id int) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.scans[id]
+	delete(s.scans, id)
+	return ok
+}
+
+// AddSites adds sites, replacing any existing sites with the same IDs.
+func (s *Server) AddSites(sites ...Site) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, site := range sites {
+		s.sites[site.ID] = site
+	}
+}
+
+// AddAssets adds assets, replacing any existing assets with the same IDs.
+func (s *Server) AddAssets(assets ...Asset) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, asset := range assets {
+		s.assets[asset.ID] = asset
+	}
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency = d
+}
+
+// RequireBasicAuth rejects requests without the given credentials with a 401 response.
+func (s *Server) RequireBasicAuth(username string, password string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// FailRequests responds to the next count requests whose path starts with prefix
+// with the given status code, or to every such request if count is 0. Failures are
+// checked in the order they were added.
+func (s *Server) FailRequests(prefix string, status int, count int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = append(s.failures, &failure{prefix: prefix, status: status, remaining: count})
+}
+
+// ClearFailures removes every failure added by FailRequests.
+func (s *Server) ClearFailures() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = nil
+}
+
+// Requests returns the number of requests received for paths starting with prefix.
+func (s *Server) Requests(prefix string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for path, requests := range s.requests {
+		if strings.HasPrefix(path, prefix) {
+			count = count + requests
+		}
+	}
+	return count
+}
+
+// ServeHTTP serves the fake Nexpose API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	latency, status, ok := s.admit(r)
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if status != 0 {
+		writeError(w, status, "injected failure")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "api" || parts[1] != "3" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	parts = parts[2:]
+	switch {
+	case len(parts) == 0:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"links": []interface{}{}})
+	case parts[0] == "scans" && len(parts) == 1:
+		s.listScans(w, r)
+	case parts[0] == "scans" && len(parts) == 2:
+		s.getScan(w, parts[1])
+	case parts[0] == "sites" && len(parts) == 2:
+		s.getSite(w, parts[1])
+	case parts[0] == "sites" && len(parts) == 3 && parts[2] == "tags":
+		s.listSiteTags(w, r, parts[1])
+	case parts[0] == "sites" && len(parts) == 3 && parts[2] == "assets":
+		s.listAssets(w, r, parts[1])
+	case parts[0] == "assets" && len(parts) == 1:
+		s.listAssets(w, r, "")
+	case parts[0] == "assets" && len(parts) == 2:
+		s.getAsset(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// admit records a request and returns the latency to apply, any injected failure
+// status, and whether the request is authenticated.
+func (s *Server) admit(r *http.Request) (time.Duration, int, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[r.URL.Path] = s.requests[r.URL.Path] + 1
+
+	if s.username != "" || s.password != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != s.username || password != s.password {
+			return s.latency, 0, false
+		}
+	}
+	for offset, f := range s.failures {
+		if !strings.HasPrefix(r.URL.Path, f.prefix) {
+			continue
+		}
+		if f.remaining > 0 {
+			f.remaining = f.remaining - 1
+			if f.remaining == 0 {
+				s.failures = append(s.failures[:offset], s.failures[offset+1:]...)
+			}
+		}
+		return s.latency, f.status, true
+	}
+	return s.latency, 0, true
+}
+
+func (s *Server) listScans(w http.ResponseWriter, r *http.Request) {
+	less, err := scanOrder(r.URL.Query()["sort"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	active := r.URL.Query().Get("active")
+
+	s.lock.Lock()
+	scans := make([]Scan, 0, len(s.scans))
+	for _, scan := range s.scans {
+		isActive := containsStatus(activeStatuses, scan.Status)
+		if (active == "true" && !isActive) || (active == "false" && isActive) {
+			continue
+		}
+		scans = append(scans, scan)
+	}
+	s.lock.Unlock()
+
+	sort.Slice(scans, func(left, right int) bool {
+		return less(scans[left], scans[right])
+	})
+	number, size, err := paging(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	start, end, pageInfo := pageBounds(number, size, len(scans))
+	writeJSON(w, http.StatusOK, pagedResponse{Resources: scans[start:end], Page: pageInfo})
+}
+
+func (s *Server) getScan(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid scan id")
+		return
+	}
+	s.lock.Lock()
+	scan, ok := s.scans[id]
+	s.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("scan %d not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, scan)
+}
+
+func (s *Server) getSite(w http.ResponseWriter, rawID string) {
+	site, ok := s.site(w, rawID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, site)
+}
+
+func (s *Server) listSiteTags(w http.ResponseWriter, r *http.Request, rawID string) {
+	site, ok := s.site(w, rawID)
+	if !ok {
+		return
+	}
+	number, size, err := paging(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tags := site.Tags
+	if tags == nil {
+		tags = []Tag{}
+	}
+	start, end, pageInfo := pageBounds(number, size, len(tags))
+	writeJSON(w, http.StatusOK, pagedResponse{Resources: tags[start:end], Page: pageInfo})
+}
+
+// site looks up a site by its ID, writing an error response if it does not exist.
+func (s *Server) site(w http.ResponseWriter, rawID string) (Site, bool) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid site id")
+		return Site{}, false
+	}
+	s.lock.Lock()
+	site, ok := s.sites[id]
+	s.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("site %d not found", id))
+		return Site{}, false
+	}
+	return site, true
+}
+
+// listAssets lists every asset, or only the assets of a site when rawSiteID is set.
+func (s *Server) listAssets(w http.ResponseWriter, r *http.Request, rawSiteID string) {
+	siteID := 0
+	if rawSiteID != "" {
+		site, ok := s.site(w, rawSiteID)
+		if !ok {
+			return
+		}
+		siteID = site.ID
+	}
+	number, size, err := paging(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	assets := make([]Asset, 0, len(s.assets))
+	for _, asset := range s.assets {
+		if siteID == 0 || asset.SiteID == siteID {
+			assets = append(assets, asset)
+		}
+	}
+	s.lock.Unlock()
+
+	sort.Slice(assets, func(left, right int) bool {
+		return assets[left].ID < assets[right].ID
+	})
+	start, end, pageInfo := pageBounds(number, size, len(assets))
+	writeJSON(w, http.StatusOK, pagedResponse{Resources: assets[start:end], Page: pageInfo})
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid asset id")
+		return
+	}
+	s.lock.Lock()
+	asset, ok := s.assets[id]
+	s.lock.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("asset %d not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// scanOrder returns a comparison of scans for the Nexpose sort criteria, each in
+// the format property[,ASC|DESC]. Ties are broken by ascending scan ID, so that
+// paging is stable.
+func scanOrder(criteria []string) (func(Scan, Scan) bool, error) {
+	type comparison func(left Scan, right Scan) int
+	var comparisons []comparison
+	for _, criterion := range criteria {
+		parts := strings.Split(criterion, ",")
+		descending := false
+		if len(parts) == 2 {
+			switch strings.ToUpper(parts[1]) {
+			case "ASC":
+			case "DESC":
+				descending = true
+			default:
+				return nil, fmt.Errorf("invalid sort direction %s", parts[1])
+			}
+		} else if len(parts) != 1 {
+			return nil, fmt.Errorf("invalid sort criteria %s", criterion)
+		}
+
+		var compare comparison
+		switch parts[0] {
+		case "id":
+			compare = func(left Scan, right Scan) int { return compareInts(left.ID, right.ID) }
+		case "siteId":
+			compare = func(left Scan, right Scan) int { return compareInts(left.SiteID, right.SiteID) }
+		case "scanName":
+			compare = func(left Scan, right Scan) int { return strings.Compare(left.Name, right.Name) }
+		case "scanType":
+			compare = func(left Scan, right Scan) int { return strings.Compare(left.Type, right.Type) }
+		case "status":
+			compare = func(left Scan, right Scan) int { return strings.Compare(left.Status, right.Status) }
+		case "startTime":
+			compare = func(left Scan, right Scan) int { return compareTimes(left.StartTime, right.StartTime) }
+		case "endTime":
+			compare = func(left Scan, right Scan) int { return compareTimes(left.EndTime, right.EndTime) }
+		default:
+			return nil, fmt.Errorf("invalid sort property %s", parts[0])
+		}
+		if descending {
+			ascending := compare
+			compare = func(left Scan, right Scan) int { return -ascending(left, right) }
+		}
+		comparisons = append(comparisons, compare)
+	}
+
+	return func(left Scan, right Scan) bool {
+		for _, compare := range comparisons {
+			if result := compare(left, right); result != 0 {
+				return result < 0
+			}
+		}
+		return left.ID < right.ID
+	}, nil
+}
+
+func compareInts(left int, right int) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(left time.Time, right time.Time) int {
+	switch {
+	case left.Before(right):
+		return -1
+	case left.After(right):
+		return 1
+	}
+	return 0
+}
+
+// paging returns the requested page number and size.
+func paging(r *http.Request) (int, int, error) {
+	number, size := 0, defaultPageSize
+	var err error
+	if raw := r.URL.Query().Get("page"); raw != "" {
+		if number, err = strconv.Atoi(raw); err != nil || number < 0 {
+			return 0, 0, fmt.Errorf("invalid page %s", raw)
+		}
+	}
+	if raw := r.URL.Query().Get("size"); raw != "" {
+		if size, err = strconv.Atoi(raw); err != nil || size < 1 || size > maxPageSize {
+			return 0, 0, fmt.Errorf("invalid size %s", raw)
+		}
+	}
+	return number, size, nil
+}
+
+// pageBounds returns the range of resources on a page, and the page details.
+func pageBounds(number int, size int, total int) (int, int, page) {
+	start := number * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return start, end, page{
+		Number:         number,
+		Size:           size,
+		TotalResources: total,
+		TotalPages:     (total + size - 1) / size,
+	}
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Status: strconv.Itoa(status), Message: message})
+}
@@ -0,0 +1,316 @@
+package nexposetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/scanfetcher"
+	"github.com/stretchr/testify/require"
+)
+
+type scansResponse struct {
+	Resources []scanResource `json:"resources"`
+	Page      page           `json:"page"`
+}
+
+func getJSON(t *testing.T, server *httptest.Server, path string, v interface{}) int {
+	res, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+	return res.StatusCode
+}
+
+func scanIDs(resources []scanResource) []int {
+	ids := make([]int, 0, len(resources))
+	for _, resource := range resources {
+		ids = append(ids, resource.ID)
+	}
+	return ids
+}
+
+func TestServerScans(t *testing.T) {
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := New()
+	fake.AddScans(GenerateScans(1, 5, 2, end, time.Hour)...)
+	fake.AddScans(Scan{ID: 6, SiteID: 1, Name: "Running", Status: StatusRunning, StartTime: end})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tc := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int
+		expectedPage   page
+	}{
+		{
+			name:           "defaults",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{1, 2, 3, 4, 5, 6},
+			expectedPage:   page{Number: 0, Size: 10, TotalResources: 6, TotalPages: 1},
+		},
+		{
+			name:           "completed newest first",
+			query:          "?active=false&sort=endTime,DESC&size=2&page=1",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{3, 2},
+			expectedPage:   page{Number: 1, Size: 2, TotalResources: 5, TotalPages: 3},
+		},
+		{
+			name:           "active",
+			query:          "?active=true",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{6},
+			expectedPage:   page{Number: 0, Size: 10, TotalResources: 1, TotalPages: 1},
+		},
+		{
+			name:           "multiple sorts",
+			query:          "?active=false&sort=siteId,DESC&sort=id,DESC",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{4, 2, 5, 3, 1},
+			expectedPage:   page{Number: 0, Size: 10, TotalResources: 5, TotalPages: 1},
+		},
+		{
+			name:           "past the last page",
+			query:          "?size=5&page=3",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int{},
+			expectedPage:   page{Number: 3, Size: 5, TotalResources: 6, TotalPages: 2},
+		},
+		{
+			name:           "invalid sort property",
+			query:          "?sort=color",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid sort direction",
+			query:          "?sort=id,UP",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "page size too large",
+			query:          "?size=501",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var response scansResponse
+			status := getJSON(t, server, "/api/3/scans"+tt.query, &response)
+			require.Equal(t, tt.expectedStatus, status)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, tt.expectedIDs, scanIDs(response.Resources))
+			require.Equal(t, tt.expectedPage, response.Page)
+		})
+	}
+}
+
+func TestServerScan(t *testing.T) {
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := New()
+	fake.AddScans(Scan{ID: 1, SiteID: 2, Name: "Scan", Type: "Manual", Status: StatusRunning, StartTime: end})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var resource scanResource
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/scans/1", &resource))
+	require.Equal(t, scanResource{ID: 1, SiteID: 2, Name: "Scan", Type: "Manual", Status: StatusRunning,
+		StartTime: "2019-06-01T12:00:00Z"}, resource)
+
+	require.True(t, fake.SetScanStatus(1, StatusFinished, end.Add(time.Hour)))
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/scans/1", &resource))
+	require.Equal(t, StatusFinished, resource.Status)
+	require.Equal(t, "2019-06-01T13:00:00Z", resource.EndTime)
+
+	require.True(t, fake.RemoveScan(1))
+	require.False(t, fake.RemoveScan(1))
+	require.False(t, fake.SetScanStatus(1, StatusFinished, end))
+	var errResponse errorResponse
+	require.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/3/scans/1", &errResponse))
+	require.Equal(t, "404", errResponse.Status)
+}
+
+func TestServerSitesAndAssets(t *testing.T) {
+	fake := New()
+	fake.AddSites(Site{ID: 1, Name: "Site", Description: "A site", Importance: "high",
+		Tags: []Tag{{ID: 1, Name: "prod", Type: "custom"}, {ID: 2, Name: "pci", Type: "custom"}}})
+	fake.AddAssets(Asset{ID: 2, SiteID: 1, IP: "10.0.0.2"}, Asset{ID: 1, SiteID: 1, IP: "10.0.0.1"},
+		Asset{ID: 3, SiteID: 2, IP: "10.0.0.3"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var site Site
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/sites/1", &site))
+	require.Equal(t, Site{ID: 1, Name: "Site", Description: "A site", Importance: "high"}, site)
+
+	var tags struct {
+		Resources []Tag `json:"resources"`
+		Page      page  `json:"page"`
+	}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/sites/1/tags?size=1&page=1", &tags))
+	require.Equal(t, []Tag{{ID: 2, Name: "pci", Type: "custom"}}, tags.Resources)
+	require.Equal(t, page{Number: 1, Size: 1, TotalResources: 2, TotalPages: 2}, tags.Page)
+
+	var assets struct {
+		Resources []Asset `json:"resources"`
+		Page      page    `json:"page"`
+	}
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/sites/1/assets", &assets))
+	require.Equal(t, []Asset{{ID: 1, IP: "10.0.0.1"}, {ID: 2, IP: "10.0.0.2"}}, assets.Resources)
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/assets", &assets))
+	require.Len(t, assets.Resources, 3)
+
+	var asset Asset
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/assets/3", &asset))
+	require.Equal(t, "10.0.0.3", asset.IP)
+
+	var errResponse errorResponse
+	require.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/3/sites/2", &errResponse))
+	require.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/3/sites/2/assets", &errResponse))
+	require.Equal(t, http.StatusNotFound, getJSON(t, server, "/api/3/assets/4", &errResponse))
+}
+
+func TestServerFailures(t *testing.T) {
+	fake := New()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var response interface{}
+	fake.FailRequests("/api/3/scans", http.StatusServiceUnavailable, 2)
+	require.Equal(t, http.StatusServiceUnavailable, getJSON(t, server, "/api/3/scans", &response))
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3", &response))
+	require.Equal(t, http.StatusServiceUnavailable, getJSON(t, server, "/api/3/scans?page=1", &response))
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3/scans", &response))
+	require.Equal(t, 3, fake.Requests("/api/3/scans"))
+	require.Equal(t, 4, fake.Requests("/api/3"))
+
+	fake.FailRequests("/api/3", http.StatusInternalServerError, 0)
+	require.Equal(t, http.StatusInternalServerError, getJSON(t, server, "/api/3", &response))
+	require.Equal(t, http.StatusInternalServerError, getJSON(t, server, "/api/3", &response))
+	fake.ClearFailures()
+	require.Equal(t, http.StatusOK, getJSON(t, server, "/api/3", &response))
+
+	fake.RequireBasicAuth("user", "password")
+	require.Equal(t, http.StatusUnauthorized, getJSON(t, server, "/api/3", &response))
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/3", http.NoBody)
+	req.SetBasicAuth("user", "password")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestServerLatency(t *testing.T) {
+	fake := New()
+	fake.SetLatency(time.Second)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/3", http.NoBody)
+	_, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.Error(t, err)
+}
+
+func TestLoadFixtures(t *testing.T) {
+	tc := []struct {
+		name        string
+		fixtures    string
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			fixtures: `{
+				"scans": [{"id": 1, "siteId": 1, "scanName": "Scan", "scanType": "Scheduled", "status": "finished",
+					"startTime": "2019-06-01T11:00:00Z", "endTime": "2019-06-01T12:00:00Z"}],
+				"sites": [{"id": 1, "name": "Site", "tags": [{"id": 1, "name": "prod", "type": "custom"}]}],
+				"assets": [{"id": 1, "siteId": 1, "ip": "10.0.0.1"}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name:        "unknown field",
+			fixtures:    `{"scan": []}`,
+			expectedErr: true,
+		},
+		{
+			name:        "malformed",
+			fixtures:    `{`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := New()
+			err := fake.LoadFixtures(strings.NewReader(tt.fixtures))
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, fake.scans, 1)
+			require.Equal(t, time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC), fake.scans[1].EndTime)
+			require.Equal(t, []Tag{{ID: 1, Name: "prod", Type: "custom"}}, fake.sites[1].Tags)
+			require.Equal(t, 1, fake.assets[1].SiteID)
+		})
+	}
+}
+
+// TestNexposeClient crawls the fake server with the client used by the notifier.
+func TestNexposeClient(t *testing.T) {
+	end := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := New()
+	fake.AddScans(GenerateScans(1, 25, 3, end, time.Hour)...)
+	fake.AddScans(Scan{ID: 26, SiteID: 1, Name: "Running", Status: StatusRunning, StartTime: end})
+	fake.AddSites(Site{ID: 1, Name: "Site", Tags: []Tag{{ID: 1, Name: "prod"}}})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL)
+	client := &scanfetcher.NexposeClient{
+		Client:        http.DefaultClient,
+		Endpoint:      endpoint,
+		Console:       endpoint.Host,
+		PageSize:      4,
+		ScanBlocklist: container.NewStringContainer([]string{"Scan 25"}),
+		Parallelism:   2,
+		LogFn:         domain.LoggerFromContext,
+		StatFn:        domain.StatFromContext,
+	}
+	require.NoError(t, client.CheckDependencies(context.Background()))
+
+	result, err := client.FetchScans(context.Background(), domain.ScanQuery{Since: end.Add(-10 * time.Hour)})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(result.Scans))
+	for _, scan := range result.Scans {
+		ids = append(ids, scan.ScanID)
+	}
+	require.ElementsMatch(t, []string{"16", "17", "18", "19", "20", "21", "22", "23", "24"}, ids)
+
+	site, err := client.FetchSite(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, domain.Site{Name: "Site", Tags: []string{"prod"}}, site)
+	_, err = client.FetchSite(context.Background(), "2")
+	require.IsType(t, domain.SiteNotFound{}, err)
+
+	fake.FailRequests("/api/3/scans", http.StatusServiceUnavailable, 1)
+	_, err = client.FetchScans(context.Background(), domain.ScanQuery{Since: end})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("%d", http.StatusServiceUnavailable))
+}
@@ -0,0 +1,105 @@
+package nexposetest
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a Nexpose scan.
+const (
+	StatusRunning     = "running"
+	StatusDispatched  = "dispatched"
+	StatusPaused      = "paused"
+	StatusIntegrating = "integrating"
+	StatusFinished    = "finished"
+	StatusStopped     = "stopped"
+	StatusAborted     = "aborted"
+	StatusError       = "error"
+	StatusUnknown     = "unknown"
+)
+
+// activeStatuses are the statuses of scans returned when requesting active scans.
+var activeStatuses = []string{StatusRunning, StatusDispatched, StatusPaused}
+
+// Scan is a scan served by the fake Nexpose server. A zero StartTime or EndTime is
+// served as an empty string.
+type Scan struct {
+	ID        int       `json:"id"`
+	SiteID    int       `json:"siteId"`
+	Name      string    `json:"scanName"`
+	Type      string    `json:"scanType"`
+	Status    string    `json:"status"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// MarshalJSON formats the scan as it is returned by the Nexpose API.
+func (s Scan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(scanResource{
+		ID:        s.ID,
+		SiteID:    s.SiteID,
+		Name:      s.Name,
+		Type:      s.Type,
+		Status:    s.Status,
+		StartTime: formatTime(s.StartTime),
+		EndTime:   formatTime(s.EndTime),
+	})
+}
+
+type scanResource struct {
+	ID        int    `json:"id"`
+	SiteID    int    `json:"siteId"`
+	Name      string `json:"scanName"`
+	Type      string `json:"scanType"`
+	Status    string `json:"status"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// Site is a site served by the fake Nexpose server.
+type Site struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Importance  string `json:"importance"`
+	Tags        []Tag  `json:"-"`
+}
+
+// Tag is a tag on a site served by the fake Nexpose server.
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Asset is an asset served by the fake Nexpose server, belonging to a single site.
+type Asset struct {
+	ID       int    `json:"id"`
+	SiteID   int    `json:"-"`
+	IP       string `json:"ip"`
+	HostName string `json:"hostName"`
+}
+
+type page struct {
+	Number         int `json:"number"`
+	Size           int `json:"size"`
+	TotalResources int `json:"totalResources"`
+	TotalPages     int `json:"totalPages"`
+}
+
+type pagedResponse struct {
+	Resources interface{} `json:"resources"`
+	Page      page        `json:"page"`
+}
+
+type errorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -2,13 +2,17 @@ package producer
 
 import (
 	"context"
+	"net/http"
 	"net/url"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 )
 
 // ProducerConfig holds configuration required to send Nexpose assets
 // to a queue via an HTTP Producer
 type ProducerConfig struct {
-	Endpoint string
+	Endpoint       string
+	CircuitBreaker *circuitbreaker.Config
 }
 
 // Name is used by the settings library and will add a "HTTPPRODUCER"
@@ -22,7 +26,9 @@ func (c *ProducerConfig) Name() string {
 type ProducerComponent struct{}
 
 // Settings can be used to populate default values if there are any
-func (*ProducerComponent) Settings() *ProducerConfig { return &ProducerConfig{} }
+func (*ProducerComponent) Settings() *ProducerConfig {
+	return &ProducerConfig{CircuitBreaker: circuitbreaker.DefaultConfig()}
+}
 
 // New constructs a HTTP from a config.
 func (*ProducerComponent) New(_ context.Context, c *ProducerConfig) (*HTTP, error) {
@@ -31,5 +37,14 @@ func (*ProducerComponent) New(_ context.Context, c *ProducerConfig) (*HTTP, erro
 		return nil, err
 	}
 
-	return &HTTP{Endpoint: endpoint}, nil
+	breaker, err := circuitbreaker.New("producer", c.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTP{
+		Client:         &http.Client{Transport: circuitbreaker.Wrap(http.DefaultTransport, breaker)},
+		CircuitBreaker: breaker,
+		Endpoint:       endpoint,
+	}, nil
 }
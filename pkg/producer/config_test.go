@@ -2,8 +2,12 @@ package producer
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/settings"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,9 +25,32 @@ func TestProducerComponent_New(t *testing.T) {
 	producer, err := producerComponent.New(context.Background(), c)
 
 	require.Equal(t, "http://localhost", producer.Endpoint.String())
+	require.Nil(t, producer.CircuitBreaker)
+	require.Equal(t, http.DefaultTransport, producer.Client.Transport)
 	require.Nil(t, err)
 }
 
+func TestProducerComponent_New_CircuitBreaker(t *testing.T) {
+	producerComponent := ProducerComponent{}
+	c := producerComponent.Settings()
+	c.Endpoint = "http://localhost"
+	producer, err := producerComponent.New(context.Background(), c)
+
+	require.Nil(t, err)
+	require.Equal(t, "producer", producer.CircuitBreaker.Dependency)
+	require.IsType(t, &circuitbreaker.Transport{}, producer.Client.Transport)
+}
+
+func TestProducerComponent_New_InvalidCircuitBreaker(t *testing.T) {
+	producerComponent := ProducerComponent{}
+	c := &ProducerConfig{
+		Endpoint:       "http://localhost",
+		CircuitBreaker: &circuitbreaker.Config{FailureThreshold: -1},
+	}
+	_, err := producerComponent.New(context.Background(), c)
+	require.Error(t, err)
+}
+
 func TestProducerComponent_New_InvalidHost(t *testing.T) {
 	producerComponent := ProducerComponent{}
 	c := &ProducerConfig{
@@ -32,3 +59,76 @@ func TestProducerComponent_New_InvalidHost(t *testing.T) {
 	_, err := producerComponent.New(context.Background(), c)
 	require.Error(t, err)
 }
+
+func TestOutputComponent(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:     "default",
+			output:   map[string]interface{}{},
+			expected: &HTTP{},
+		},
+		{
+			name:     "stdout",
+			output:   map[string]interface{}{"type": "stdout"},
+			expected: &NDJSON{},
+		},
+		{
+			name:     "file",
+			output:   map[string]interface{}{"type": OutputTypeFile, "file": map[string]interface{}{"path": "scans.ndjson"}},
+			expected: &NDJSON{},
+		},
+		{
+			name:        "file without path",
+			output:      map[string]interface{}{"type": OutputTypeFile},
+			expectedErr: true,
+		},
+		{
+			name:        "file with negative max size",
+			output:      map[string]interface{}{"type": OutputTypeFile, "file": map[string]interface{}{"path": "scans.ndjson", "maxsize": -1}},
+			expectedErr: true,
+		},
+		{
+			name:        "file with negative max files",
+			output:      map[string]interface{}{"type": OutputTypeFile, "file": map[string]interface{}{"path": "scans.ndjson", "maxfiles": -1}},
+			expectedErr: true,
+		},
+		{
+			name:        "unknown",
+			output:      map[string]interface{}{"type": "QUEUE"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := settings.NewMapSource(map[string]interface{}{
+				"output":       tt.output,
+				"httpproducer": map[string]interface{}{"endpoint": "http://localhost"},
+			})
+			component := &OutputComponent{Source: source}
+			var output domain.Producer
+			err := settings.NewComponent(context.Background(), source, component, &output)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tt.expected, output)
+		})
+	}
+}
+
+func TestOutputComponent_File(t *testing.T) {
+	component := &OutputComponent{}
+	c := component.Settings()
+	c.Type = OutputTypeFile
+	c.File.Path = "scans.ndjson"
+	output, err := component.New(context.Background(), c)
+	require.NoError(t, err)
+	require.Equal(t, &RotatingFile{Path: "scans.ndjson", MaxSize: 100 * 1024 * 1024, MaxFiles: 5}, output.(*NDJSON).Writer)
+}
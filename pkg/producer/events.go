@@ -0,0 +1,61 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// EventsConfig holds configuration for where events other than completed scans,
+// such as alerts about the health of the notifier, are produced.
+type EventsConfig struct {
+	Endpoint       string `description:"An HTTP endpoint to which events other than completed scans are produced. Defaults to the scan output."`
+	CircuitBreaker *circuitbreaker.Config
+}
+
+// Name is used by the settings library and will add a "EVENTS_"
+// prefix to EventsConfig environment variables
+func (c *EventsConfig) Name() string {
+	return "Events"
+}
+
+// EventsComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function. Events
+// are produced to Output unless a separate endpoint is configured.
+type EventsComponent struct {
+	Output domain.Producer
+}
+
+// Settings can be used to populate default values if there are any
+func (*EventsComponent) Settings() *EventsConfig {
+	return &EventsConfig{CircuitBreaker: circuitbreaker.DefaultConfig()}
+}
+
+// New constructs the configured event producer.
+func (e *EventsComponent) New(_ context.Context, c *EventsConfig) (domain.EventProducer, error) {
+	if c.Endpoint == "" {
+		eventProducer, ok := e.Output.(domain.EventProducer)
+		if !ok {
+			return nil, fmt.Errorf("the scan output cannot produce events, so an events endpoint must be set")
+		}
+		return eventProducer, nil
+	}
+
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	breaker, err := circuitbreaker.New("events", c.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTP{
+		Client:         &http.Client{Transport: circuitbreaker.Wrap(http.DefaultTransport, breaker)},
+		CircuitBreaker: breaker,
+		Endpoint:       endpoint,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsComponent(t *testing.T) {
+	output := &NDJSON{}
+	component := &EventsComponent{Output: output}
+	c := component.Settings()
+	require.Equal(t, "Events", c.Name())
+
+	eventProducer, err := component.New(context.Background(), c)
+	require.NoError(t, err)
+	require.Equal(t, output, eventProducer)
+
+	c.Endpoint = "http://localhost/events"
+	eventProducer, err = component.New(context.Background(), c)
+	require.NoError(t, err)
+	require.IsType(t, &HTTP{}, eventProducer)
+	require.Equal(t, "http://localhost/events", eventProducer.(*HTTP).Endpoint.String())
+	require.Equal(t, "events", eventProducer.(*HTTP).CircuitBreaker.Dependency)
+
+	c.CircuitBreaker = &circuitbreaker.Config{FailureThreshold: -1}
+	_, err = component.New(context.Background(), c)
+	require.Error(t, err)
+
+	c.Endpoint = "~!@#$%^&*()_+:?><!@#$%^&*())_:"
+	_, err = component.New(context.Background(), c)
+	require.Error(t, err)
+
+	component = &EventsComponent{Output: &Router{}}
+	_, err = component.New(context.Background(), component.Settings())
+	require.Error(t, err)
+}
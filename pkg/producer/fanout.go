@@ -0,0 +1,108 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// FanOutConfig holds configuration for additional destinations which receive every
+// scan, each with its own delivery state.
+type FanOutConfig struct {
+	Destinations string        `description:"Comma separated name=endpoint pairs of additional destinations which receive every scan."`
+	Attempts     int           `description:"Attempts to produce a scan to an additional destination before giving up on it."`
+	Backoff      time.Duration `description:"Wait before the first retry to an additional destination, doubled for each later retry."`
+	Timeout      time.Duration `description:"The maximum time to wait for each attempt to produce a scan to an additional destination."`
+	DeadLetter   bool          `description:"Store scans which could not be produced to an additional destination, and move on to the next scan."`
+}
+
+// Name is used by the settings library and will add a "FANOUT_"
+// prefix to FanOutConfig environment variables
+func (c *FanOutConfig) Name() string {
+	return "FanOut"
+}
+
+// FanOut holds the additional destinations which receive every scan.
+type FanOut struct {
+	Destinations    []*Retrier
+	DeadLetter      bool
+	CircuitBreakers []*circuitbreaker.Breaker
+}
+
+// FanOutComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type FanOutComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*FanOutComponent) Settings() *FanOutConfig {
+	return &FanOutConfig{
+		Attempts:   3,
+		Backoff:    time.Second,
+		Timeout:    10 * time.Second,
+		DeadLetter: true,
+	}
+}
+
+// New constructs a FanOut from a config. When dead letters are enabled, the
+// DeadLetterStorer of each destination must be set before use.
+func (*FanOutComponent) New(_ context.Context, c *FanOutConfig) (*FanOut, error) {
+	if c.Attempts < 1 {
+		return nil, fmt.Errorf("fan out attempts must be at least 1, got %d", c.Attempts)
+	}
+	if c.Backoff < 0 {
+		return nil, fmt.Errorf("fan out backoff must not be negative, got %s", c.Backoff)
+	}
+	if c.Timeout <= 0 {
+		return nil, fmt.Errorf("fan out timeout must be positive, got %s", c.Timeout)
+	}
+
+	fanOut := &FanOut{DeadLetter: c.DeadLetter}
+	if strings.TrimSpace(c.Destinations) == "" {
+		return fanOut, nil
+	}
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(c.Destinations, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("fan out destination must be a name=endpoint pair, got %q", pair)
+		}
+		name := parts[0]
+		if name == DefaultDestination || seen[name] {
+			return nil, fmt.Errorf("fan out destination name %s is already in use", name)
+		}
+		seen[name] = true
+		endpoint, err := url.Parse(parts[1])
+		if err != nil || !endpoint.IsAbs() {
+			return nil, fmt.Errorf("fan out destination %s has an invalid endpoint: %q", name, parts[1])
+		}
+
+		breaker, err := circuitbreaker.New("fanout-"+name, circuitbreaker.DefaultConfig())
+		if err != nil {
+			return nil, err
+		}
+		fanOut.CircuitBreakers = append(fanOut.CircuitBreakers, breaker)
+		fanOut.Destinations = append(fanOut.Destinations, &Retrier{
+			Destination: name,
+			Wrapped: &HTTP{
+				Client: &http.Client{
+					Transport: circuitbreaker.Wrap(http.DefaultTransport, breaker),
+					Timeout:   c.Timeout,
+				},
+				CircuitBreaker: breaker,
+				Endpoint:       endpoint,
+			},
+			CircuitBreaker: breaker,
+			Attempts:       c.Attempts,
+			Backoff:        c.Backoff,
+			LogFn:          domain.LoggerFromContext,
+			StatFn:         domain.StatFromContext,
+		})
+	}
+	return fanOut, nil
+}
@@ -0,0 +1,107 @@
+package producer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutConfigName(t *testing.T) {
+	config := FanOutConfig{}
+	require.Equal(t, "FanOut", config.Name())
+}
+
+func TestFanOutComponentDefaultConfig(t *testing.T) {
+	component := &FanOutComponent{}
+	config := component.Settings()
+	require.Empty(t, config.Destinations)
+	require.Equal(t, 3, config.Attempts)
+	require.Equal(t, time.Second, config.Backoff)
+	require.Equal(t, 10*time.Second, config.Timeout)
+	require.True(t, config.DeadLetter)
+
+	fanOut, err := component.New(context.Background(), config)
+	require.NoError(t, err)
+	require.Empty(t, fanOut.Destinations)
+	require.True(t, fanOut.DeadLetter)
+}
+
+func TestFanOutComponentNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *FanOutConfig
+		expectedDest []string
+		expectErr    bool
+	}{
+		{
+			name: "destinations",
+			config: &FanOutConfig{
+				Destinations: "vulns=http://localhost/vulns, datalake=http://localhost/datalake",
+				Attempts:     2,
+				Backoff:      time.Second,
+				Timeout:      time.Second,
+			},
+			expectedDest: []string{"vulns", "datalake"},
+		},
+		{
+			name:      "no attempts",
+			config:    &FanOutConfig{Attempts: 0},
+			expectErr: true,
+		},
+		{
+			name:      "negative backoff",
+			config:    &FanOutConfig{Attempts: 1, Backoff: -time.Second, Timeout: time.Second},
+			expectErr: true,
+		},
+		{
+			name:      "no timeout",
+			config:    &FanOutConfig{Attempts: 1},
+			expectErr: true,
+		},
+		{
+			name:      "missing endpoint",
+			config:    &FanOutConfig{Destinations: "datalake", Attempts: 1, Timeout: time.Second},
+			expectErr: true,
+		},
+		{
+			name:      "invalid endpoint",
+			config:    &FanOutConfig{Destinations: "datalake=datalake", Attempts: 1, Timeout: time.Second},
+			expectErr: true,
+		},
+		{
+			name:      "duplicate name",
+			config:    &FanOutConfig{Destinations: "datalake=http://localhost/a,datalake=http://localhost/b", Attempts: 1, Timeout: time.Second},
+			expectErr: true,
+		},
+		{
+			name:      "reserved name",
+			config:    &FanOutConfig{Destinations: "default=http://localhost", Attempts: 1, Timeout: time.Second},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component := &FanOutComponent{}
+			fanOut, err := component.New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, destination := range fanOut.Destinations {
+				names = append(names, destination.Destination)
+				require.Equal(t, tt.config.Attempts, destination.Attempts)
+				require.Equal(t, tt.config.Backoff, destination.Backoff)
+				require.IsType(t, &HTTP{}, destination.Wrapped)
+				require.Equal(t, tt.config.Timeout, destination.Wrapped.(*HTTP).Client.Timeout)
+				require.Equal(t, destination.Wrapped.(*HTTP).CircuitBreaker, destination.CircuitBreaker)
+			}
+			require.Equal(t, tt.expectedDest, names)
+			require.Len(t, fanOut.CircuitBreakers, len(tt.expectedDest))
+		})
+	}
+}
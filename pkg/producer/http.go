@@ -4,44 +4,108 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 )
 
+// producerDependency names the producer endpoint in the domain errors returned by HTTP.
+const producerDependency = "producer"
+
 // HTTP holds configuration for producing completed scan events to an HTTP endpoint
 type HTTP struct {
-	Client   *http.Client
-	Endpoint *url.URL
+	Client         *http.Client
+	CircuitBreaker *circuitbreaker.Breaker
+	Endpoint       *url.URL
 }
 
 type scanPayload struct {
-	ScanID    string `json:"scanID,omitempty"`
-	SiteID    string `json:"siteID,omitempty"`
-	ScanType  string `json:"scanType,omitempty"`
-	StartTime string `json:"startTime,omitempty"`
-	EndTime   string `json:"endTime,omitempty"`
+	EventID         string   `json:"eventID,omitempty"`
+	ScanID          string   `json:"scanID,omitempty"`
+	SiteID          string   `json:"siteID,omitempty"`
+	ScanType        string   `json:"scanType,omitempty"`
+	ScanName        string   `json:"scanName,omitempty"`
+	StartTime       string   `json:"startTime,omitempty"`
+	EndTime         string   `json:"endTime,omitempty"`
+	SiteName        string   `json:"siteName,omitempty"`
+	SiteDescription string   `json:"siteDescription,omitempty"`
+	SiteImportance  string   `json:"siteImportance,omitempty"`
+	SiteTags        []string `json:"siteTags,omitempty"`
+	SiteUnavailable bool     `json:"siteUnavailable,omitempty"`
 }
 
-// Produce sends the completed scan event to an HTTP endpoint
-func (p *HTTP) Produce(ctx context.Context, scan domain.CompletedScan) error {
-	payload := scanPayload{
+// eventPayload is produced for events other than completed scans, which are told
+// apart from scans by their event type.
+type eventPayload struct {
+	EventID   string      `json:"eventID"`
+	EventType string      `json:"eventType"`
+	Time      string      `json:"time"`
+	Detail    interface{} `json:"detail,omitempty"`
+}
+
+func newEventPayload(event domain.Event) eventPayload {
+	return eventPayload{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Time:      event.Time.Format(time.RFC3339Nano),
+		Detail:    event.Detail,
+	}
+}
+
+func newScanPayload(scan domain.CompletedScan) scanPayload {
+	return scanPayload{
+		EventID:   scan.EventID(),
 		ScanID:    scan.ScanID,
 		SiteID:    scan.SiteID,
 		ScanType:  scan.ScanType,
+		ScanName:  scan.ScanName,
 		StartTime: scan.StartTime.Format(time.RFC3339Nano),
-		EndTime:   scan.StartTime.Format(time.RFC3339Nano),
+		EndTime:   scan.EndTime.Format(time.RFC3339Nano),
+
+		SiteName:        scan.Site.Name,
+		SiteDescription: scan.Site.Description,
+		SiteImportance:  scan.Site.Importance,
+		SiteTags:        scan.Site.Tags,
+		SiteUnavailable: scan.SiteUnavailable,
+	}
+}
+
+// Produce sends the completed scan event to an HTTP endpoint. The event ID is included
+// in the payload and sent as the Idempotency-Key header, so that the endpoint can
+// de-duplicate scans which are produced more than once.
+func (p *HTTP) Produce(ctx context.Context, scan domain.CompletedScan) error {
+	payload := newScanPayload(scan)
+	return p.post(ctx, payload.EventID, payload)
+}
+
+// ProduceEvent sends an event other than a completed scan to the HTTP endpoint. The
+// event ID is sent as the Idempotency-Key header, as for scans.
+func (p *HTTP) ProduceEvent(ctx context.Context, event domain.Event) error {
+	return p.post(ctx, event.ID, newEventPayload(event))
+}
+
+// post sends a JSON payload to the HTTP endpoint.
+func (p *HTTP) post(ctx context.Context, eventID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
 	}
-	body, _ := json.Marshal(payload)
 	req, _ := http.NewRequest(http.MethodPost, p.Endpoint.String(), bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", eventID)
 	res, err := p.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return err
+		}
+		return domain.UpstreamUnavailable{Dependency: producerDependency, Reason: err.Error()}
 	}
 	defer res.Body.Close()
 
@@ -51,8 +115,31 @@ func (p *HTTP) Produce(ctx context.Context, scan domain.CompletedScan) error {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected response from http producer: %d %s",
-			res.StatusCode, string(resBody))
+		return responseError(res, resBody)
 	}
 	return nil
 }
+
+// responseError returns the domain error matching the status code of an
+// unsuccessful response from the producer endpoint. Statuses with no specific
+// meaning, such as the endpoint rejecting the scan, are returned as an untyped error.
+func responseError(res *http.Response, body []byte) error {
+	reason := fmt.Sprintf("unexpected response from http producer: %d %s", res.StatusCode, string(body))
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return domain.AuthenticationFailure{Dependency: producerDependency, Reason: reason}
+	case http.StatusTooManyRequests:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return domain.RateLimited{Dependency: producerDependency, Reason: reason, RetryAfter: retryAfter}
+	case http.StatusConflict:
+		return domain.Conflict{Dependency: producerDependency, Reason: reason}
+	case http.StatusNotFound:
+		return domain.InvalidConfiguration{Dependency: producerDependency, Reason: reason}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return domain.UpstreamUnavailable{Dependency: producerDependency, Reason: reason}
+	}
+	return errors.New(reason)
+}
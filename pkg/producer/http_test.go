@@ -9,7 +9,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	"github.com/golang/mock/gomock"
@@ -95,3 +97,302 @@ func TestHTTP_Produce(t *testing.T) {
 	}
 
 }
+
+func TestHTTP_ProduceSitePayload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+
+	tests := []struct {
+		name        string
+		site        domain.Site
+		unavailable bool
+		expected    map[string]interface{}
+	}{
+		{
+			name: "enriched",
+			site: domain.Site{
+				Name:        "Site 2",
+				Description: "the second site",
+				Importance:  "high",
+				Tags:        []string{"production", "pci"},
+			},
+			expected: map[string]interface{}{
+				"siteName":        "Site 2",
+				"siteDescription": "the second site",
+				"siteImportance":  "high",
+				"siteTags":        []interface{}{"production", "pci"},
+			},
+		},
+		{
+			name:     "not enriched",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:        "site unavailable",
+			unavailable: true,
+			expected:    map[string]interface{}{"siteUnavailable": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := domain.CompletedScan{ScanID: "1", SiteID: "2", Site: tt.site, SiteUnavailable: tt.unavailable}
+			mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				var payload map[string]interface{}
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+				for _, key := range []string{"siteName", "siteDescription", "siteImportance", "siteTags", "siteUnavailable"} {
+					value, ok := tt.expected[key]
+					if !ok {
+						require.NotContains(t, payload, key)
+						continue
+					}
+					require.Equal(t, value, payload[key])
+				}
+				return &http.Response{
+					Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+					StatusCode: http.StatusOK,
+				}, nil
+			})
+			require.NoError(t, producer.Produce(context.Background(), scan))
+		})
+	}
+}
+
+func TestHTTP_ProducePayload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+	startTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	scan := domain.CompletedScan{
+		ScanID:    "1",
+		SiteID:    "2",
+		ScanType:  "Scheduled",
+		ScanName:  "weekly",
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Hour),
+	}
+
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		require.Equal(t, map[string]interface{}{
+			"eventID":   scan.EventID(),
+			"scanID":    "1",
+			"siteID":    "2",
+			"scanType":  "Scheduled",
+			"scanName":  "weekly",
+			"startTime": "2019-06-01T00:00:00Z",
+			"endTime":   "2019-06-01T01:00:00Z",
+		}, payload)
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+	require.NoError(t, producer.Produce(context.Background(), scan))
+}
+
+func TestHTTP_ProduceEndTime(t *testing.T) {
+	startTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		name     string
+		endTime  time.Time
+		expected string
+	}{
+		{
+			name:     "end time differs from the start time",
+			endTime:  startTime.Add(90 * time.Minute),
+			expected: "2019-06-01T01:30:00Z",
+		},
+		{
+			name:     "end time with fractional seconds",
+			endTime:  startTime.Add(time.Hour + 500*time.Millisecond),
+			expected: "2019-06-01T01:00:00.5Z",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRT := NewMockRoundTripper(ctrl)
+
+			endpoint, _ := url.Parse("http://localhost")
+			producer := &HTTP{
+				Client:   &http.Client{Transport: mockRT},
+				Endpoint: endpoint,
+			}
+			scan := domain.CompletedScan{ScanID: "1", StartTime: startTime, EndTime: tt.endTime}
+
+			mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				var payload scanPayload
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+				require.Equal(t, "2019-06-01T00:00:00Z", payload.StartTime)
+				require.Equal(t, tt.expected, payload.EndTime)
+				return &http.Response{
+					Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+					StatusCode: http.StatusOK,
+				}, nil
+			})
+			require.NoError(t, producer.Produce(context.Background(), scan))
+		})
+	}
+}
+
+func TestHTTP_ProduceIdempotencyKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+	scan := domain.CompletedScan{Console: "nexpose", ScanID: "1", SiteID: "2", Status: "finished"}
+
+	var keys []string
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		require.Equal(t, scan.EventID(), payload["eventID"])
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			StatusCode: http.StatusOK,
+		}, nil
+	}).Times(2)
+
+	// producing the same scan again sends the same key
+	require.NoError(t, producer.Produce(context.Background(), scan))
+	require.NoError(t, producer.Produce(context.Background(), scan))
+	require.Equal(t, []string{scan.EventID(), scan.EventID()}, keys)
+}
+
+func TestHTTP_ProduceEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+	event := domain.Event{
+		ID:     "id",
+		Type:   "notifier.recovered",
+		Time:   time.Date(2019, 6, 1, 11, 0, 0, 0, time.UTC),
+		Detail: map[string]string{"degradedSince": "2019-06-01T10:00:00Z"},
+	}
+
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"eventID": "id", "eventType": "notifier.recovered", "time": "2019-06-01T11:00:00Z",
+			"detail": {"degradedSince": "2019-06-01T10:00:00Z"}}`, string(body))
+		require.Equal(t, "id", req.Header.Get("Idempotency-Key"))
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("unavailable"))),
+			StatusCode: http.StatusServiceUnavailable,
+		}, nil
+	})
+	require.IsType(t, domain.UpstreamUnavailable{}, producer.ProduceEvent(context.Background(), event))
+}
+
+func TestHTTP_ProduceErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		expected   error
+	}{
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			expected:   domain.AuthenticationFailure{Dependency: "producer", Reason: "unexpected response from http producer: 401 rejected"},
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: "5",
+			expected:   domain.RateLimited{Dependency: "producer", Reason: "unexpected response from http producer: 429 rejected", RetryAfter: 5 * time.Second},
+		},
+		{
+			name:       "conflict",
+			statusCode: http.StatusConflict,
+			expected:   domain.Conflict{Dependency: "producer", Reason: "unexpected response from http producer: 409 rejected"},
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			expected:   domain.InvalidConfiguration{Dependency: "producer", Reason: "unexpected response from http producer: 404 rejected"},
+		},
+		{
+			name:       "unavailable",
+			statusCode: http.StatusBadGateway,
+			expected:   domain.UpstreamUnavailable{Dependency: "producer", Reason: "unexpected response from http producer: 502 rejected"},
+		},
+		{
+			name:       "rejected",
+			statusCode: http.StatusBadRequest,
+			expected:   errors.New("unexpected response from http producer: 400 rejected"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.retryAfter != "" {
+				header.Set("Retry-After", tt.retryAfter)
+			}
+			mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{
+				Body:       ioutil.NopCloser(strings.NewReader("rejected")),
+				Header:     header,
+				StatusCode: tt.statusCode,
+			}, nil)
+			require.Equal(t, tt.expected, producer.Produce(context.Background(), domain.CompletedScan{ScanID: "1"}))
+		})
+	}
+}
+
+func TestHTTP_ProduceRequestError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+
+	endpoint, _ := url.Parse("http://localhost")
+	producer := &HTTP{
+		Client:   &http.Client{Transport: mockRT},
+		Endpoint: endpoint,
+	}
+
+	mockRT.EXPECT().RoundTrip(gomock.Any()).Return(nil, errors.New("connection refused"))
+	err := producer.Produce(context.Background(), domain.CompletedScan{ScanID: "1"})
+	require.IsType(t, domain.UpstreamUnavailable{}, err)
+	require.Equal(t, "producer", err.(domain.UpstreamUnavailable).Dependency)
+}
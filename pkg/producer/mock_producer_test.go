@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: Producer,DeadLetterStorer)
+
+// Package producer is a generated GoMock package.
+package producer
+
+import (
+	context "context"
+	domain "github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockProducer is a mock of Producer interface
+type MockProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockProducerMockRecorder
+}
+
+// MockProducerMockRecorder is the mock recorder for MockProducer
+type MockProducerMockRecorder struct {
+	mock *MockProducer
+}
+
+// NewMockProducer creates a new mock instance
+func NewMockProducer(ctrl *gomock.Controller) *MockProducer {
+	mock := &MockProducer{ctrl: ctrl}
+	mock.recorder = &MockProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockProducer) EXPECT() *MockProducerMockRecorder {
+	return m.recorder
+}
+
+// Produce mocks base method
+func (m *MockProducer) Produce(arg0 context.Context, arg1 domain.CompletedScan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Produce", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Produce indicates an expected call of Produce
+func (mr *MockProducerMockRecorder) Produce(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Produce", reflect.TypeOf((*MockProducer)(nil).Produce), arg0, arg1)
+}
+
+// MockDeadLetterStorer is a mock of DeadLetterStorer interface
+type MockDeadLetterStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterStorerMockRecorder
+}
+
+// MockDeadLetterStorerMockRecorder is the mock recorder for MockDeadLetterStorer
+type MockDeadLetterStorerMockRecorder struct {
+	mock *MockDeadLetterStorer
+}
+
+// NewMockDeadLetterStorer creates a new mock instance
+func NewMockDeadLetterStorer(ctrl *gomock.Controller) *MockDeadLetterStorer {
+	mock := &MockDeadLetterStorer{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockDeadLetterStorer) EXPECT() *MockDeadLetterStorerMockRecorder {
+	return m.recorder
+}
+
+// StoreDeadLetter mocks base method
+func (m *MockDeadLetterStorer) StoreDeadLetter(arg0 context.Context, arg1 domain.DeadLetter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreDeadLetter", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreDeadLetter indicates an expected call of StoreDeadLetter
+func (mr *MockDeadLetterStorerMockRecorder) StoreDeadLetter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreDeadLetter", reflect.TypeOf((*MockDeadLetterStorer)(nil).StoreDeadLetter), arg0, arg1)
+}
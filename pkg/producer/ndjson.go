@@ -0,0 +1,135 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// NDJSON produces completed scan and other events as newline delimited JSON, one
+// event per line, to a writer such as stdout or a RotatingFile.
+type NDJSON struct {
+	Writer io.Writer
+	lock   sync.Mutex
+}
+
+// Produce writes the completed scan event as a single line.
+func (p *NDJSON) Produce(_ context.Context, scan domain.CompletedScan) error {
+	return p.write(newScanPayload(scan))
+}
+
+// ProduceEvent writes an event other than a completed scan as a single line.
+func (p *NDJSON) ProduceEvent(_ context.Context, event domain.Event) error {
+	return p.write(newEventPayload(event))
+}
+
+func (p *NDJSON) write(payload interface{}) error {
+	line, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// write each line with a single call, so that a rotating file never splits a line
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, err = p.Writer.Write(append(line, '\n'))
+	return err
+}
+
+// RotatingFile is a writer which appends to a file, renaming it with a numbered suffix
+// once it reaches a maximum size and starting a new file. Only the newest rotated
+// files are kept.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64 // zero never rotates
+	MaxFiles int   // the number of rotated files to keep
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Write appends p to the file, first rotating the file if p would take it past the
+// maximum size. A write larger than the maximum size is never split.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size = f.size + int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file to Path.1, shifting older files up by one and
+// removing any beyond MaxFiles, then opens a new file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.MaxFiles < 1 {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return f.open()
+	}
+	if err := os.Remove(rotatedPath(f.Path, f.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := f.MaxFiles - 1; n >= 1; n = n - 1 {
+		if err := os.Rename(rotatedPath(f.Path, n), rotatedPath(f.Path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.Path, rotatedPath(f.Path, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,123 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestNDJSON_Produce(t *testing.T) {
+	start := time.Date(2019, 6, 1, 11, 0, 0, 0, time.UTC)
+	scan := domain.CompletedScan{
+		Console:   "nexpose",
+		ScanID:    "1",
+		SiteID:    "2",
+		ScanType:  "Scheduled",
+		ScanName:  "Scan",
+		Status:    "finished",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Site:      domain.Site{Name: "Site", Tags: []string{"prod"}},
+	}
+	buffer := &bytes.Buffer{}
+	p := &NDJSON{Writer: buffer}
+
+	require.NoError(t, p.Produce(context.Background(), scan))
+	require.NoError(t, p.Produce(context.Background(), scan))
+	line := `{"eventID":"` + scan.EventID() + `","scanID":"1","siteID":"2","scanType":"Scheduled",` +
+		`"scanName":"Scan","startTime":"2019-06-01T11:00:00Z","endTime":"2019-06-01T12:00:00Z",` +
+		`"siteName":"Site","siteTags":["prod"]}` + "\n"
+	require.Equal(t, line+line, buffer.String())
+
+	p = &NDJSON{Writer: failingWriter{}}
+	require.Error(t, p.Produce(context.Background(), scan))
+}
+
+func TestNDJSON_ProduceEvent(t *testing.T) {
+	event := domain.Event{
+		ID:     "id",
+		Type:   "notifier.degraded",
+		Time:   time.Date(2019, 6, 1, 11, 0, 0, 0, time.UTC),
+		Detail: map[string]int{"consecutiveFailures": 3},
+	}
+	buffer := &bytes.Buffer{}
+	p := &NDJSON{Writer: buffer}
+
+	require.NoError(t, p.ProduceEvent(context.Background(), event))
+	require.Equal(t, `{"eventID":"id","eventType":"notifier.degraded","time":"2019-06-01T11:00:00Z",`+
+		`"detail":{"consecutiveFailures":3}}`+"\n", buffer.String())
+
+	p = &NDJSON{Writer: failingWriter{}}
+	require.Error(t, p.ProduceEvent(context.Background(), event))
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scans.ndjson")
+	require.NoError(t, ioutil.WriteFile(path, []byte("aaaa\n"), 0644))
+
+	f := &RotatingFile{Path: path, MaxSize: 10, MaxFiles: 2}
+	defer f.Close()
+	for _, line := range []string{"bbbb\n", "cccc\n", "dddddddddddd\n", "eeee\n"} {
+		n, err := f.Write([]byte(line))
+		require.NoError(t, err)
+		require.Equal(t, len(line), n)
+	}
+
+	// existing content counts towards the first file, a line is never split, and
+	// only the newest rotated files are kept
+	expected := map[string]string{
+		path:        "eeee\n",
+		path + ".1": "dddddddddddd\n",
+		path + ".2": "cccc\n",
+	}
+	for file, content := range expected {
+		actual, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, content, string(actual), file)
+	}
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRotatingFile_NoRotatedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scans.ndjson")
+
+	f := &RotatingFile{Path: path, MaxSize: 5}
+	defer f.Close()
+	for _, line := range []string{"aaaa\n", "bbbb\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	actual, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "bbbb\n", string(actual))
+	_, err = os.Stat(path + ".1")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRotatingFile_InvalidPath(t *testing.T) {
+	f := &RotatingFile{Path: filepath.Join("does", "not", "exist", "scans.ndjson")}
+	_, err := f.Write([]byte("aaaa\n"))
+	require.Error(t, err)
+	require.NoError(t, f.Close())
+}
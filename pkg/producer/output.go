@@ -0,0 +1,95 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/settings"
+)
+
+const (
+	// OutputTypeHTTP produces scans to the HTTP producer endpoint.
+	OutputTypeHTTP = "HTTP"
+	// OutputTypeStdout writes scans to stdout as newline delimited JSON.
+	OutputTypeStdout = "STDOUT"
+	// OutputTypeFile writes scans to a rotating file as newline delimited JSON.
+	OutputTypeFile = "FILE"
+)
+
+// FileConfig holds configuration for writing produced scans to a rotating file.
+type FileConfig struct {
+	Path     string `description:"The file to which produced scans are appended."`
+	MaxSize  int64  `description:"The size in bytes at which the file is rotated, or 0 to never rotate."`
+	MaxFiles int    `description:"The number of rotated files to keep."`
+}
+
+// Name is used by the settings library and will add a "FILE_"
+// prefix to FileConfig environment variables
+func (c *FileConfig) Name() string {
+	return "File"
+}
+
+// OutputConfig holds configuration for choosing where the default destination
+// produces scans.
+type OutputConfig struct {
+	Type string `description:"Where the default destination produces scans. One of HTTP, STDOUT, FILE."`
+	File *FileConfig
+}
+
+// Name is used by the settings library and will add a "OUTPUT_"
+// prefix to OutputConfig environment variables
+func (c *OutputConfig) Name() string {
+	return "Output"
+}
+
+// OutputComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function. The
+// source is used to load the HTTP producer configuration.
+type OutputComponent struct {
+	Source settings.Source
+}
+
+// Settings can be used to populate default values if there are any
+func (*OutputComponent) Settings() *OutputConfig {
+	return &OutputConfig{
+		Type: OutputTypeHTTP,
+		File: &FileConfig{
+			MaxSize:  100 * 1024 * 1024,
+			MaxFiles: 5,
+		},
+	}
+}
+
+// New constructs the configured producer for the default destination.
+func (o *OutputComponent) New(ctx context.Context, c *OutputConfig) (domain.Producer, error) {
+	switch strings.ToUpper(c.Type) {
+	case OutputTypeHTTP:
+		httpProducer := new(HTTP)
+		if err := settings.NewComponent(ctx, o.Source, &ProducerComponent{}, httpProducer); err != nil {
+			return nil, err
+		}
+		return httpProducer, nil
+	case OutputTypeStdout:
+		return &NDJSON{Writer: os.Stdout}, nil
+	case OutputTypeFile:
+		if c.File.Path == "" {
+			return nil, fmt.Errorf("output file path must be set when using the %s output", OutputTypeFile)
+		}
+		if c.File.MaxSize < 0 {
+			return nil, fmt.Errorf("output file max size must not be negative, got %d", c.File.MaxSize)
+		}
+		if c.File.MaxFiles < 0 {
+			return nil, fmt.Errorf("output file max files must not be negative, got %d", c.File.MaxFiles)
+		}
+		return &NDJSON{Writer: &RotatingFile{
+			Path:     c.File.Path,
+			MaxSize:  c.File.MaxSize,
+			MaxFiles: c.File.MaxFiles,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown output type %s", c.Type)
+	}
+}
@@ -0,0 +1,78 @@
+package producer
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+// Retrier retries producing a scan to a destination, waiting Backoff before the
+// first retry and doubling the wait before each retry after that. Once every
+// attempt has failed, the scan is stored as a dead letter when a DeadLetterStorer
+// is set, so that delivery to the destination can continue with the next scan.
+//
+// While the destination's CircuitBreaker is open, scans fail without being
+// attempted, retried or stored as dead letters, since every scan would fail.
+type Retrier struct {
+	Destination      string
+	Wrapped          domain.Producer
+	CircuitBreaker   *circuitbreaker.Breaker
+	Attempts         int
+	Backoff          time.Duration
+	DeadLetterStorer domain.DeadLetterStorer
+	LogFn            domain.LogFn
+	StatFn           domain.StatFn
+}
+
+// Produce sends the completed scan event to the wrapped producer. A
+// domain.ScanDeadLettered error is returned if every attempt fails and the scan
+// was stored as a dead letter instead.
+func (r *Retrier) Produce(ctx context.Context, scan domain.CompletedScan) error {
+	var err error
+	wait := r.Backoff
+	attempt := 1
+	for ; ; attempt = attempt + 1 {
+		if r.CircuitBreaker != nil && r.CircuitBreaker.Rejecting() {
+			return domain.UpstreamUnavailable{Dependency: r.Destination, Reason: "circuit breaker is open"}
+		}
+		if err = r.Wrapped.Produce(ctx, scan); err == nil {
+			return nil
+		}
+		if attempt >= r.Attempts {
+			break
+		}
+		r.StatFn(ctx).Count("producer.retried", 1, "destination:"+r.Destination)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = wait * 2
+	}
+
+	if r.DeadLetterStorer == nil {
+		return err
+	}
+	deadLetter := domain.DeadLetter{
+		Destination: r.Destination,
+		Scan:        scan,
+		Reason:      err.Error(),
+		Attempts:    attempt,
+		FailedAt:    time.Now(),
+	}
+	if storeErr := r.DeadLetterStorer.StoreDeadLetter(ctx, deadLetter); storeErr != nil {
+		r.LogFn(ctx).Error(logs.StorageFailure{Reason: storeErr.Error()})
+		return err
+	}
+	r.LogFn(ctx).Warn(logs.ScanDeadLettered{
+		ScanID:      scan.ScanID,
+		Destination: r.Destination,
+		Attempts:    attempt,
+		Reason:      err.Error(),
+	})
+	r.StatFn(ctx).Count("producer.deadlettered", 1, "destination:"+r.Destination)
+	return domain.ScanDeadLettered{Destination: r.Destination, ScanID: scan.ScanID, Reason: err.Error()}
+}
@@ -0,0 +1,155 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrier_Produce(t *testing.T) {
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "2"}
+	produceErr := errors.New("produce error")
+
+	tests := []struct {
+		name          string
+		produceErrs   []error
+		deadLetter    bool
+		deadLetterErr error
+		deadLettered  bool
+		expectErr     bool
+	}{
+		{
+			name:        "first attempt succeeds",
+			produceErrs: []error{nil},
+		},
+		{
+			name:        "retry succeeds",
+			produceErrs: []error{produceErr, produceErr, nil},
+		},
+		{
+			name:        "every attempt fails without dead letters",
+			produceErrs: []error{produceErr, produceErr, produceErr},
+			expectErr:   true,
+		},
+		{
+			name:         "every attempt fails with dead letters",
+			produceErrs:  []error{produceErr, produceErr, produceErr},
+			deadLetter:   true,
+			deadLettered: true,
+		},
+		{
+			name:          "dead letter error",
+			produceErrs:   []error{produceErr, produceErr, produceErr},
+			deadLetter:    true,
+			deadLetterErr: errors.New("storage error"),
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockProducer := NewMockProducer(ctrl)
+
+			retrier := &Retrier{
+				Destination: "datalake",
+				Wrapped:     mockProducer,
+				Attempts:    3,
+				Backoff:     time.Millisecond,
+				LogFn:       testLogFn,
+				StatFn:      testStatFn,
+			}
+			var calls []*gomock.Call
+			for _, err := range tt.produceErrs {
+				calls = append(calls, mockProducer.EXPECT().Produce(gomock.Any(), scan).Return(err))
+			}
+			gomock.InOrder(calls...)
+			if tt.deadLetter {
+				mockDeadLetterStorer := NewMockDeadLetterStorer(ctrl)
+				mockDeadLetterStorer.EXPECT().StoreDeadLetter(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, deadLetter domain.DeadLetter) error {
+						require.Equal(t, "datalake", deadLetter.Destination)
+						require.Equal(t, scan, deadLetter.Scan)
+						require.Equal(t, produceErr.Error(), deadLetter.Reason)
+						require.Equal(t, 3, deadLetter.Attempts)
+						return tt.deadLetterErr
+					})
+				retrier.DeadLetterStorer = mockDeadLetterStorer
+			}
+
+			err := retrier.Produce(context.Background(), scan)
+			if tt.expectErr {
+				require.Equal(t, produceErr, err)
+				return
+			}
+			if tt.deadLettered {
+				require.Equal(t, domain.ScanDeadLettered{Destination: "datalake", ScanID: "1", Reason: produceErr.Error()}, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRetrier_ProduceCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockProducer := NewMockProducer(ctrl)
+	mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).Return(errors.New("produce error"))
+
+	retrier := &Retrier{
+		Destination: "datalake",
+		Wrapped:     mockProducer,
+		Attempts:    3,
+		Backoff:     time.Hour,
+		LogFn:       testLogFn,
+		StatFn:      testStatFn,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, retrier.Produce(ctx, domain.CompletedScan{ScanID: "1"}))
+}
+
+func TestRetrier_ProduceCircuitBreakerOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockProducer := NewMockProducer(ctrl)
+	mockDeadLetterStorer := NewMockDeadLetterStorer(ctrl)
+
+	breaker, err := circuitbreaker.New("fanout-datalake", &circuitbreaker.Config{FailureThreshold: 1, OpenTimeout: time.Hour})
+	require.NoError(t, err)
+	breaker.LogFn = testLogFn
+	breaker.StatFn = testStatFn
+	retrier := &Retrier{
+		Destination:      "datalake",
+		Wrapped:          mockProducer,
+		CircuitBreaker:   breaker,
+		Attempts:         3,
+		Backoff:          time.Millisecond,
+		DeadLetterStorer: mockDeadLetterStorer,
+		LogFn:            testLogFn,
+		StatFn:           testStatFn,
+	}
+
+	// the breaker opens during the first attempt, so the scan is neither retried
+	// nor stored as a dead letter
+	mockProducer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ domain.CompletedScan) error {
+			require.NoError(t, breaker.Allow(ctx))
+			breaker.Record(ctx, false)
+			return errors.New("produce error")
+		})
+	err = retrier.Produce(context.Background(), domain.CompletedScan{ScanID: "1"})
+	require.Equal(t, domain.UpstreamUnavailable{Dependency: "datalake", Reason: "circuit breaker is open"}, err)
+
+	// later scans are not attempted while it is open
+	err = retrier.Produce(context.Background(), domain.CompletedScan{ScanID: "2"})
+	require.IsType(t, domain.UpstreamUnavailable{}, err)
+}
@@ -0,0 +1,226 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+// DefaultDestination is the name of the destination served by the HTTPProducer
+// endpoint, which receives every scan when no routing rules are configured.
+const DefaultDestination = "default"
+
+// maxPartialDeliveries is the number of scans which failed for some destinations
+// and not others whose successful destinations are remembered.
+const maxPartialDeliveries = 1000
+
+// Rule selects the destinations for the scans it matches. Each populated condition
+// must match the scan, and a condition matches if any of its values do. A rule with
+// no conditions matches every scan.
+type Rule struct {
+	SiteIDs      []string
+	SiteTags     []string
+	ScanTypes    []string
+	ScanNames    *regexp.Regexp
+	Destinations []string
+}
+
+// Matches returns true if the scan satisfies every condition of the rule.
+func (r Rule) Matches(scan domain.CompletedScan) bool {
+	if len(r.SiteIDs) > 0 && !containsString(r.SiteIDs, scan.SiteID, false) {
+		return false
+	}
+	if len(r.SiteTags) > 0 && !containsAnyString(r.SiteTags, scan.Site.Tags) {
+		return false
+	}
+	if len(r.ScanTypes) > 0 && !containsString(r.ScanTypes, scan.ScanType, true) {
+		return false
+	}
+	if r.ScanNames != nil && !r.ScanNames.MatchString(scan.ScanName) {
+		return false
+	}
+	return true
+}
+
+// Router produces each scan to the named destinations of every rule matching the
+// scan, or to the default destinations when no rule matches. Scans with no
+// destinations are dropped.
+//
+// When any rule matches by site tag, a scan whose site could not be looked up
+// fails rather than being routed without its tags, so that it is retried.
+//
+// When a scan fails for some of its destinations, the destinations which received
+// it are remembered, so that retrying the scan only produces it to those which
+// failed. This is kept in memory for up to maxPartialDeliveries scans, so a retry
+// after a restart produces the scan to every destination again, relying on the
+// Idempotency-Key of the scan for destinations to discard the duplicates.
+type Router struct {
+	Rules               []Rule
+	DefaultDestinations []string
+	Destinations        map[string]domain.Producer
+	CircuitBreakers     []*circuitbreaker.Breaker
+	LogFn               domain.LogFn
+	StatFn              domain.StatFn
+
+	lock      sync.Mutex
+	delivered map[string][]string
+}
+
+// Produce sends the completed scan event to each of its destinations which has
+// not already received it. Every destination is attempted, and an error is
+// returned if any of them fail.
+func (r *Router) Produce(ctx context.Context, scan domain.CompletedScan) error {
+	if scan.SiteUnavailable && r.RoutesBySiteTags() {
+		return domain.UpstreamUnavailable{
+			Dependency: "nexpose",
+			Reason:     fmt.Sprintf("site %s of scan %s could not be looked up to route by its tags", scan.SiteID, scan.ScanID),
+		}
+	}
+	destinations := r.route(scan)
+	if len(destinations) == 0 {
+		r.StatFn(ctx).Count("producer.unrouted", 1)
+	}
+	eventID := scan.EventID()
+	delivered := r.deliveredTo(eventID)
+	var failed []string
+	var firstErr error
+	for _, name := range destinations {
+		if containsString(delivered, name, false) {
+			continue
+		}
+		if err := r.Destinations[name].Produce(ctx, scan); err != nil {
+			r.LogFn(ctx).Error(logs.RouteFailure{
+				ScanID:      scan.ScanID,
+				Destination: name,
+				Reason:      err.Error(),
+			})
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed = append(failed, name)
+			continue
+		}
+		r.StatFn(ctx).Count("producer.routed", 1, "destination:"+name)
+		delivered = append(delivered, name)
+	}
+	r.recordDelivered(eventID, delivered, len(failed) > 0)
+	if len(failed) == 0 {
+		return nil
+	}
+	// keep the domain error of a single failed destination, so that the reason
+	// it failed is not lost
+	if len(failed) == 1 {
+		if err, ok := destinationError(firstErr, failed[0]); ok {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to produce scan %s to destinations: %s",
+		scan.ScanID, strings.Join(failed, ", "))
+}
+
+// destinationError names the destination as the dependency of a domain error
+// returned by its producer, reporting false for any other error.
+func destinationError(err error, destination string) (error, bool) {
+	switch e := err.(type) {
+	case domain.AuthenticationFailure:
+		e.Dependency = destination
+		return e, true
+	case domain.UpstreamUnavailable:
+		e.Dependency = destination
+		return e, true
+	case domain.RateLimited:
+		e.Dependency = destination
+		return e, true
+	case domain.Conflict:
+		e.Dependency = destination
+		return e, true
+	case domain.InvalidConfiguration:
+		e.Dependency = destination
+		return e, true
+	}
+	return err, false
+}
+
+// deliveredTo returns the destinations which already received a scan that failed
+// for other destinations.
+func (r *Router) deliveredTo(eventID string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.delivered[eventID]...)
+}
+
+// recordDelivered remembers the destinations which received a scan while it is
+// partially delivered, and forgets them once it has been delivered everywhere.
+func (r *Router) recordDelivered(eventID string, destinations []string, partial bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !partial {
+		delete(r.delivered, eventID)
+		return
+	}
+	if r.delivered == nil {
+		r.delivered = make(map[string][]string)
+	}
+	if _, ok := r.delivered[eventID]; !ok && len(r.delivered) >= maxPartialDeliveries {
+		// forget another scan, which is produced everywhere again if it is retried
+		for other := range r.delivered {
+			delete(r.delivered, other)
+			break
+		}
+	}
+	r.delivered[eventID] = destinations
+}
+
+// RoutesBySiteTags returns true if any rule matches scans by site tag, which
+// requires scans to be enriched with the details of their site.
+func (r *Router) RoutesBySiteTags() bool {
+	for _, rule := range r.Rules {
+		if len(rule.SiteTags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// route returns the names of the destinations for a scan, without duplicates.
+func (r *Router) route(scan domain.CompletedScan) []string {
+	var destinations []string
+	for _, rule := range r.Rules {
+		if !rule.Matches(scan) {
+			continue
+		}
+		for _, name := range rule.Destinations {
+			if !containsString(destinations, name, false) {
+				destinations = append(destinations, name)
+			}
+		}
+	}
+	if len(destinations) == 0 {
+		return r.DefaultDestinations
+	}
+	return destinations
+}
+
+func containsString(values []string, value string, ignoreCase bool) bool {
+	for _, v := range values {
+		if v == value || (ignoreCase && strings.EqualFold(v, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyString(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate, true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// RoutingConfig holds configuration for routing scans to different destinations.
+type RoutingConfig struct {
+	File string `description:"Path to a JSON file of routing rules. Every scan is sent to the HTTPProducer endpoint when empty."`
+}
+
+// Name is used by the settings library and will add a "ROUTING_"
+// prefix to RoutingConfig environment variables
+func (c *RoutingConfig) Name() string {
+	return "Routing"
+}
+
+// routingFile is the format of the routing rules file.
+type routingFile struct {
+	Destinations        map[string]destinationConfig `json:"destinations"`
+	Rules               []ruleConfig                 `json:"rules"`
+	DefaultDestinations []string                     `json:"defaultDestinations"`
+}
+
+type destinationConfig struct {
+	Endpoint string `json:"endpoint"`
+}
+
+type ruleConfig struct {
+	SiteIDs         []string `json:"siteIDs"`
+	SiteTags        []string `json:"siteTags"`
+	ScanTypes       []string `json:"scanTypes"`
+	ScanNamePattern string   `json:"scanNamePattern"`
+	Destinations    []string `json:"destinations"`
+}
+
+// RoutingComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type RoutingComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*RoutingComponent) Settings() *RoutingConfig {
+	return &RoutingConfig{}
+}
+
+// New constructs a Router from a config. The producer for the DefaultDestination
+// must be added to the Router's destinations before use.
+func (*RoutingComponent) New(_ context.Context, c *RoutingConfig) (*Router, error) {
+	router := &Router{
+		DefaultDestinations: []string{DefaultDestination},
+		Destinations:        map[string]domain.Producer{},
+		LogFn:               domain.LoggerFromContext,
+		StatFn:              domain.StatFromContext,
+	}
+	if c.File == "" {
+		return router, nil
+	}
+
+	file, err := os.Open(c.File)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var rf routingFile
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&rf); err != nil {
+		return nil, fmt.Errorf("invalid routing file %s: %s", c.File, err.Error())
+	}
+
+	for name, destination := range rf.Destinations {
+		if name == DefaultDestination {
+			return nil, fmt.Errorf("routing destination %s is reserved for the HTTPProducer endpoint", name)
+		}
+		endpoint, err := url.Parse(destination.Endpoint)
+		if err != nil || !endpoint.IsAbs() {
+			return nil, fmt.Errorf("routing destination %s has an invalid endpoint: %q", name, destination.Endpoint)
+		}
+		breaker, err := circuitbreaker.New("route-"+name, circuitbreaker.DefaultConfig())
+		if err != nil {
+			return nil, err
+		}
+		router.Destinations[name] = &HTTP{
+			Client:         &http.Client{Transport: circuitbreaker.Wrap(http.DefaultTransport, breaker)},
+			CircuitBreaker: breaker,
+			Endpoint:       endpoint,
+		}
+		router.CircuitBreakers = append(router.CircuitBreakers, breaker)
+	}
+	known := func(name string) bool {
+		_, ok := rf.Destinations[name]
+		return ok || name == DefaultDestination
+	}
+
+	for offset, rc := range rf.Rules {
+		if len(rc.Destinations) == 0 {
+			return nil, fmt.Errorf("routing rule %d has no destinations", offset)
+		}
+		for _, name := range rc.Destinations {
+			if !known(name) {
+				return nil, fmt.Errorf("routing rule %d has an unknown destination: %s", offset, name)
+			}
+		}
+		rule := Rule{
+			SiteIDs:      rc.SiteIDs,
+			SiteTags:     rc.SiteTags,
+			ScanTypes:    rc.ScanTypes,
+			Destinations: rc.Destinations,
+		}
+		if rc.ScanNamePattern != "" {
+			if rule.ScanNames, err = regexp.Compile(rc.ScanNamePattern); err != nil {
+				return nil, fmt.Errorf("routing rule %d has an invalid scan name pattern: %s", offset, err.Error())
+			}
+		}
+		router.Rules = append(router.Rules, rule)
+	}
+
+	if rf.DefaultDestinations != nil {
+		for _, name := range rf.DefaultDestinations {
+			if !known(name) {
+				return nil, fmt.Errorf("unknown default routing destination: %s", name)
+			}
+		}
+		router.DefaultDestinations = rf.DefaultDestinations
+	}
+	return router, nil
+}
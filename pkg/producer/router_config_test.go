@@ -0,0 +1,118 @@
+package producer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingConfigName(t *testing.T) {
+	config := RoutingConfig{}
+	require.Equal(t, "Routing", config.Name())
+}
+
+func TestRoutingComponentWithoutFile(t *testing.T) {
+	component := &RoutingComponent{}
+	router, err := component.New(context.Background(), component.Settings())
+	require.NoError(t, err)
+	require.Empty(t, router.Rules)
+	require.Equal(t, []string{DefaultDestination}, router.DefaultDestinations)
+	require.Empty(t, router.Destinations)
+	require.Empty(t, router.CircuitBreakers)
+}
+
+func TestRoutingComponentWithFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  string
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			contents: `{
+				"destinations": {
+					"pci": {"endpoint": "http://localhost/pci"},
+					"agents": {"endpoint": "http://localhost/agents"}
+				},
+				"rules": [
+					{"siteTags": ["pci"], "destinations": ["pci"]},
+					{"scanTypes": ["Agent"], "scanNamePattern": "^Agent", "destinations": ["agents", "default"]}
+				],
+				"defaultDestinations": ["default"]
+			}`,
+		},
+		{
+			name:      "invalid json",
+			contents:  `{`,
+			expectErr: true,
+		},
+		{
+			name:      "unknown field",
+			contents:  `{"routes": []}`,
+			expectErr: true,
+		},
+		{
+			name:      "reserved destination",
+			contents:  `{"destinations": {"default": {"endpoint": "http://localhost"}}}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid endpoint",
+			contents:  `{"destinations": {"pci": {"endpoint": "pci"}}}`,
+			expectErr: true,
+		},
+		{
+			name:      "rule without destinations",
+			contents:  `{"rules": [{"siteIDs": ["1"]}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "rule with unknown destination",
+			contents:  `{"rules": [{"siteIDs": ["1"], "destinations": ["pci"]}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "rule with invalid scan name pattern",
+			contents:  `{"rules": [{"scanNamePattern": "(", "destinations": ["default"]}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "unknown default destination",
+			contents:  `{"defaultDestinations": ["pci"]}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ioutil.TempFile("", "routing-")
+			require.NoError(t, err)
+			defer os.Remove(file.Name())
+			_, err = file.WriteString(tt.contents)
+			require.NoError(t, err)
+			require.NoError(t, file.Close())
+
+			component := &RoutingComponent{}
+			router, err := component.New(context.Background(), &RoutingConfig{File: file.Name()})
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, router.Rules, 2)
+			require.True(t, router.Rules[1].ScanNames.MatchString("Agent Scan"))
+			require.Equal(t, []string{DefaultDestination}, router.DefaultDestinations)
+			require.Len(t, router.Destinations, 2)
+			require.Len(t, router.CircuitBreakers, 2)
+		})
+	}
+}
+
+func TestRoutingComponentWithMissingFile(t *testing.T) {
+	component := &RoutingComponent{}
+	_, err := component.New(context.Background(), &RoutingConfig{File: "/does/not/exist.json"})
+	require.Error(t, err)
+}
@@ -0,0 +1,278 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_Matches(t *testing.T) {
+	scan := domain.CompletedScan{
+		ScanID:   "1",
+		SiteID:   "2",
+		ScanType: "Agent",
+		ScanName: "Weekly PCI Scan",
+		Site:     domain.Site{Tags: []string{"production", "PCI"}},
+	}
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		expected bool
+	}{
+		{
+			name:     "no conditions",
+			rule:     Rule{},
+			expected: true,
+		},
+		{
+			name:     "site ID",
+			rule:     Rule{SiteIDs: []string{"1", "2"}},
+			expected: true,
+		},
+		{
+			name:     "other site ID",
+			rule:     Rule{SiteIDs: []string{"1"}},
+			expected: false,
+		},
+		{
+			name:     "site tag ignoring case",
+			rule:     Rule{SiteTags: []string{"pci"}},
+			expected: true,
+		},
+		{
+			name:     "other site tag",
+			rule:     Rule{SiteTags: []string{"staging"}},
+			expected: false,
+		},
+		{
+			name:     "scan type ignoring case",
+			rule:     Rule{ScanTypes: []string{"agent"}},
+			expected: true,
+		},
+		{
+			name:     "other scan type",
+			rule:     Rule{ScanTypes: []string{"Scheduled", "Manual"}},
+			expected: false,
+		},
+		{
+			name:     "scan name",
+			rule:     Rule{ScanNames: regexp.MustCompile("PCI")},
+			expected: true,
+		},
+		{
+			name:     "other scan name",
+			rule:     Rule{ScanNames: regexp.MustCompile("^Daily")},
+			expected: false,
+		},
+		{
+			name:     "every condition",
+			rule:     Rule{SiteIDs: []string{"2"}, SiteTags: []string{"pci"}, ScanTypes: []string{"Agent"}},
+			expected: true,
+		},
+		{
+			name:     "one condition fails",
+			rule:     Rule{SiteIDs: []string{"2"}, SiteTags: []string{"pci"}, ScanTypes: []string{"Manual"}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.rule.Matches(scan))
+		})
+	}
+}
+
+func TestRouter_Produce(t *testing.T) {
+	pciScan := domain.CompletedScan{ScanID: "1", SiteID: "1", Site: domain.Site{Tags: []string{"pci"}}}
+	agentPCIScan := domain.CompletedScan{ScanID: "2", SiteID: "1", ScanType: "Agent", Site: domain.Site{Tags: []string{"pci"}}}
+	otherScan := domain.CompletedScan{ScanID: "3", SiteID: "2", ScanType: "Scheduled"}
+	rules := []Rule{
+		{SiteTags: []string{"pci"}, Destinations: []string{"pci"}},
+		{ScanTypes: []string{"Agent"}, Destinations: []string{"agents", "pci"}},
+	}
+
+	tests := []struct {
+		name                string
+		scan                domain.CompletedScan
+		defaultDestinations []string
+		expected            []string
+		failing             string
+		expectErr           bool
+	}{
+		{
+			name:                "one matching rule",
+			scan:                pciScan,
+			defaultDestinations: []string{DefaultDestination},
+			expected:            []string{"pci"},
+		},
+		{
+			name:                "several matching rules",
+			scan:                agentPCIScan,
+			defaultDestinations: []string{DefaultDestination},
+			expected:            []string{"pci", "agents"},
+		},
+		{
+			name:                "default route",
+			scan:                otherScan,
+			defaultDestinations: []string{DefaultDestination},
+			expected:            []string{DefaultDestination},
+		},
+		{
+			name:                "no default route",
+			scan:                otherScan,
+			defaultDestinations: []string{},
+			expected:            []string{},
+		},
+		{
+			name:                "site which could not be looked up",
+			scan:                domain.CompletedScan{ScanID: "4", SiteID: "1", SiteUnavailable: true},
+			defaultDestinations: []string{DefaultDestination},
+			expected:            []string{},
+			expectErr:           true,
+		},
+		{
+			name:                "every destination is attempted",
+			scan:                agentPCIScan,
+			defaultDestinations: []string{DefaultDestination},
+			expected:            []string{"pci", "agents"},
+			failing:             "pci",
+			expectErr:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			destinations := map[string]domain.Producer{}
+			for _, name := range []string{DefaultDestination, "pci", "agents"} {
+				destinations[name] = NewMockProducer(ctrl)
+			}
+			for _, name := range tt.expected {
+				var err error
+				if name == tt.failing {
+					err = errors.New("produce error")
+				}
+				destinations[name].(*MockProducer).EXPECT().Produce(gomock.Any(), tt.scan).Return(err)
+			}
+
+			router := &Router{
+				Rules:               rules,
+				DefaultDestinations: tt.defaultDestinations,
+				Destinations:        destinations,
+				LogFn:               testLogFn,
+				StatFn:              testStatFn,
+			}
+			err := router.Produce(context.Background(), tt.scan)
+			if tt.expectErr {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.failing)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRouter_RoutesBySiteTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "2", ScanType: "Agent", SiteUnavailable: true}
+	agents := NewMockProducer(ctrl)
+	router := &Router{
+		Rules:        []Rule{{ScanTypes: []string{"Agent"}, Destinations: []string{"agents"}}},
+		Destinations: map[string]domain.Producer{"agents": agents},
+		LogFn:        testLogFn,
+		StatFn:       testStatFn,
+	}
+	require.False(t, router.RoutesBySiteTags())
+
+	// a scan whose site could not be looked up is routed when no rule needs its tags
+	agents.EXPECT().Produce(gomock.Any(), scan).Return(nil)
+	require.NoError(t, router.Produce(context.Background(), scan))
+
+	router.Rules = append(router.Rules, Rule{SiteTags: []string{"pci"}, Destinations: []string{"agents"}})
+	require.True(t, router.RoutesBySiteTags())
+	err := router.Produce(context.Background(), scan)
+	require.IsType(t, domain.UpstreamUnavailable{}, err)
+}
+
+func TestRouter_ProduceDomainError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "2", ScanType: "Agent"}
+	pci := NewMockProducer(ctrl)
+	agents := NewMockProducer(ctrl)
+	router := &Router{
+		Rules:        []Rule{{ScanTypes: []string{"Agent"}, Destinations: []string{"pci", "agents"}}},
+		Destinations: map[string]domain.Producer{"pci": pci, "agents": agents},
+		LogFn:        testLogFn,
+		StatFn:       testStatFn,
+	}
+
+	// a single failed destination keeps its domain error, naming the destination
+	unavailable := domain.UpstreamUnavailable{Dependency: "producer", Reason: "503"}
+	pci.EXPECT().Produce(gomock.Any(), scan).Return(unavailable)
+	agents.EXPECT().Produce(gomock.Any(), scan).Return(nil)
+	err := router.Produce(context.Background(), scan)
+	require.Equal(t, domain.UpstreamUnavailable{Dependency: "pci", Reason: "503"}, err)
+
+	// several failed destinations are reported together
+	other := domain.CompletedScan{ScanID: "2", SiteID: "2", ScanType: "Agent"}
+	pci.EXPECT().Produce(gomock.Any(), other).Return(unavailable)
+	agents.EXPECT().Produce(gomock.Any(), other).Return(domain.RateLimited{Dependency: "producer", Reason: "429"})
+	err = router.Produce(context.Background(), other)
+	require.EqualError(t, err, "failed to produce scan 2 to destinations: pci, agents")
+}
+
+func TestRouter_ProduceRetriesFailedDestinations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "2", ScanType: "Agent"}
+	pci := NewMockProducer(ctrl)
+	agents := NewMockProducer(ctrl)
+	router := &Router{
+		Rules:        []Rule{{ScanTypes: []string{"Agent"}, Destinations: []string{"pci", "agents"}}},
+		Destinations: map[string]domain.Producer{"pci": pci, "agents": agents},
+		LogFn:        testLogFn,
+		StatFn:       testStatFn,
+	}
+
+	unavailable := domain.UpstreamUnavailable{Dependency: "producer", Reason: "503"}
+	gomock.InOrder(
+		pci.EXPECT().Produce(gomock.Any(), scan).Return(nil),
+		agents.EXPECT().Produce(gomock.Any(), scan).Return(unavailable),
+		// the retry is only produced to the destination which failed
+		agents.EXPECT().Produce(gomock.Any(), scan).Return(unavailable),
+		agents.EXPECT().Produce(gomock.Any(), scan).Return(nil),
+		// once delivered everywhere, the scan is no longer remembered
+		pci.EXPECT().Produce(gomock.Any(), scan).Return(nil),
+		agents.EXPECT().Produce(gomock.Any(), scan).Return(nil),
+	)
+	require.Error(t, router.Produce(context.Background(), scan))
+	require.Error(t, router.Produce(context.Background(), scan))
+	require.NoError(t, router.Produce(context.Background(), scan))
+	require.Empty(t, router.delivered)
+	require.NoError(t, router.Produce(context.Background(), scan))
+}
+
+func TestRouter_PartialDeliveriesBounded(t *testing.T) {
+	router := &Router{}
+	for offset := 0; offset < maxPartialDeliveries+10; offset = offset + 1 {
+		router.recordDelivered(strconv.Itoa(offset), []string{"pci"}, true)
+	}
+	require.Len(t, router.delivered, maxPartialDeliveries)
+	require.Equal(t, []string{"pci"}, router.deliveredTo(strconv.Itoa(maxPartialDeliveries+9)))
+}
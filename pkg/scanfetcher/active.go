@@ -0,0 +1,77 @@
+package scanfetcher
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// FetchActiveScans fetches every scan which Nexpose reports as active and which may
+// still finish, such as those which are running or paused. Scans which have not
+// started yet have no start time and are skipped. Scans with an unparsable start
+// time are quarantined like malformed completed scans.
+func (n *NexposeClient) FetchActiveScans(ctx context.Context) ([]domain.ActiveScan, error) {
+	seen := make(map[int]bool)
+	var scans []domain.ActiveScan
+	for curPage, pages := 0, 1; curPage < pages; curPage = curPage + 1 {
+		q := url.Values{}
+		q.Set(activeQueryParam, "true")
+		q.Set(pageQueryParam, strconv.Itoa(curPage))
+		q.Set(sizeQueryParam, strconv.Itoa(n.PageSize))
+		scanResp, err := n.makeScanPageRequest(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		pages = scanResp.Page.TotalPages
+
+		for _, resource := range scanResp.Resources {
+			// scans which finish while paging shift the remaining scans between pages
+			if seen[resource.ScanID] || !isInFlightScanStatus(resource.Status) || resource.StartTime == "" {
+				continue
+			}
+			seen[resource.ScanID] = true
+
+			startTime, err := time.Parse(time.RFC3339Nano, resource.StartTime)
+			if err != nil {
+				malformedErr := malformedScanError{
+					ScanID:   strconv.Itoa(resource.ScanID),
+					ScanName: resource.ScanName,
+					SiteID:   strconv.Itoa(resource.SiteID),
+					Field:    "startTime",
+					Reason:   err.Error(),
+				}
+				if err := n.quarantineScan(ctx, resource, malformedErr); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			scans = append(scans, domain.ActiveScan{
+				Console:   n.Console,
+				ScanID:    strconv.Itoa(resource.ScanID),
+				SiteID:    strconv.Itoa(resource.SiteID),
+				ScanType:  resource.ScanType,
+				ScanName:  resource.ScanName,
+				Status:    resource.Status,
+				StartTime: startTime,
+			})
+		}
+	}
+	return scans, nil
+}
+
+// IsScanActive looks up a single scan, reporting whether Nexpose still reports it as
+// active. A scan which no longer exists is not active.
+func (n *NexposeClient) IsScanActive(ctx context.Context, scanID string) (bool, error) {
+	resource, err := n.makeNexposeScanRequest(ctx, scanID)
+	switch err.(type) {
+	case nil:
+		return isInFlightScanStatus(resource.Status), nil
+	case scanNotFoundError:
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,177 @@
+package scanfetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNexposeClient_FetchActiveScans(t *testing.T) {
+	startTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	firstPage := `{
+		"resources": [
+			{"id": 1, "siteId": 10, "scanType": "Scheduled", "scanName": "Running", "status": "running", "startTime": "2019-06-01T00:00:00Z"},
+			{"id": 2, "siteId": 10, "scanType": "Manual", "scanName": "Dispatched", "status": "dispatched", "startTime": ""},
+			{"id": 3, "siteId": 11, "scanType": "Scheduled", "scanName": "Finished", "status": "finished", "startTime": "2019-06-01T00:00:00Z"}
+		],
+		"page": {"number": 0, "size": 3, "totalResources": 5, "totalPages": 2}
+	}`
+	secondPage := `{
+		"resources": [
+			{"id": 1, "siteId": 10, "scanType": "Scheduled", "scanName": "Running", "status": "running", "startTime": "2019-06-01T00:00:00Z"},
+			{"id": 4, "siteId": 12, "scanType": "Manual", "scanName": "Paused", "status": "paused", "startTime": "2019-06-01T00:00:00Z"},
+			{"id": 5, "siteId": 12, "scanType": "Manual", "scanName": "Malformed", "status": "running", "startTime": "yesterday"}
+		],
+		"page": {"number": 1, "size": 3, "totalResources": 5, "totalPages": 2}
+	}`
+	response := func(body string) *http.Response {
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: http.StatusOK,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		strict    bool
+		responses []*http.Response
+		errs      []error
+		expected  []domain.ActiveScan
+		expectErr bool
+	}{
+		{
+			name:      "success",
+			responses: []*http.Response{response(firstPage), response(secondPage)},
+			errs:      []error{nil, nil},
+			expected: []domain.ActiveScan{
+				{Console: "console", ScanID: "1", SiteID: "10", ScanType: "Scheduled", ScanName: "Running", Status: "running", StartTime: startTime},
+				{Console: "console", ScanID: "4", SiteID: "12", ScanType: "Manual", ScanName: "Paused", Status: "paused", StartTime: startTime},
+			},
+		},
+		{
+			name:      "malformed scan fails in strict mode",
+			strict:    true,
+			responses: []*http.Response{response(firstPage), response(secondPage)},
+			errs:      []error{nil, nil},
+			expectErr: true,
+		},
+		{
+			name:      "request error",
+			responses: []*http.Response{response(firstPage), nil},
+			errs:      []error{nil, errors.New("connection refused")},
+			expectErr: true,
+		},
+		{
+			name: "unexpected status",
+			responses: []*http.Response{{
+				Body:       ioutil.NopCloser(bytes.NewBufferString("unavailable")),
+				StatusCode: http.StatusServiceUnavailable,
+			}},
+			errs:      []error{nil},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRT := NewMockRoundTripper(ctrl)
+			for offset := range tt.responses {
+				page := offset
+				res, err := tt.responses[offset], tt.errs[offset]
+				mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+					require.Equal(t, "/api/3/scans", req.URL.Path)
+					require.Equal(t, "true", req.URL.Query().Get(activeQueryParam))
+					require.Equal(t, strconv.Itoa(page), req.URL.Query().Get(pageQueryParam))
+					return res, err
+				})
+			}
+
+			endpoint, _ := url.Parse("http://localhost")
+			nexposeClient := &NexposeClient{
+				Client:   &http.Client{Transport: mockRT},
+				Endpoint: endpoint,
+				Console:  "console",
+				PageSize: 3,
+				Strict:   tt.strict,
+				LogFn:    testLogFn,
+				StatFn:   testStatFn,
+			}
+			actual, err := nexposeClient.FetchActiveScans(context.Background())
+			require.Equal(t, tt.expectErr, err != nil)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestNexposeClient_IsScanActive(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  *http.Response
+		err       error
+		expected  bool
+		expectErr bool
+	}{
+		{
+			name: "paused",
+			response: &http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 1, "siteId": 10, "status": "paused"}`)),
+				StatusCode: http.StatusOK,
+			},
+			expected: true,
+		},
+		{
+			name: "finished",
+			response: &http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": 1, "siteId": 10, "status": "finished"}`)),
+				StatusCode: http.StatusOK,
+			},
+		},
+		{
+			name: "not found",
+			response: &http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBufferString("not found")),
+				StatusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name:      "request error",
+			err:       errors.New("connection refused"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRT := NewMockRoundTripper(ctrl)
+			mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				require.Equal(t, "/api/3/scans/1", req.URL.Path)
+				return tt.response, tt.err
+			})
+
+			endpoint, _ := url.Parse("http://localhost")
+			nexposeClient := &NexposeClient{
+				Client:   &http.Client{Transport: mockRT},
+				Endpoint: endpoint,
+				LogFn:    testLogFn,
+				StatFn:   testStatFn,
+			}
+			actual, err := nexposeClient.IsScanActive(context.Background(), "1")
+			require.Equal(t, tt.expectErr, err != nil)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
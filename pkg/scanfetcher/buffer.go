@@ -0,0 +1,234 @@
+package scanfetcher
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// scanBuffer collects the completed scans found while crawling. When a limit is set,
+// only the most recently added scans are retained; since scans are crawled in
+// descending order by end time, these are the oldest scans.
+//
+// The timestamp stored after a run excludes every scan which ended at or before it,
+// so a limit never separates scans which ended at the same time: any scans which were
+// not retained but ended at the same time as the most recent scan retained are
+// returned as well, even though this exceeds the limit.
+type scanBuffer interface {
+	// Add records a completed scan.
+	Add(scan domain.CompletedScan) error
+	// Result returns a result containing the retained scans along with the extra
+	// scans provided. Once a result is returned, closing the reader of the result
+	// closes the buffer.
+	Result(extra []domain.CompletedScan) (domain.ScanResult, error)
+	// Dropped returns the number of scans which were added but not retained.
+	Dropped() int
+	// Close releases any resources held by the buffer.
+	Close() error
+}
+
+// newScanBuffer returns a buffer which spills to a temporary file in directory when one
+// is provided, otherwise a buffer held in memory. A limit of 0 retains every scan.
+func newScanBuffer(limit int, directory string) (scanBuffer, error) {
+	if directory == "" {
+		return &memoryScanBuffer{limit: limit}, nil
+	}
+	file, err := ioutil.TempFile(directory, "nexpose-scans-")
+	if err != nil {
+		return nil, err
+	}
+	return &fileScanBuffer{limit: limit, file: file}, nil
+}
+
+// memoryScanBuffer retains scans in memory, using a ring of at most limit scans.
+// Evicted holds the most recently evicted scans which ended at the same time.
+type memoryScanBuffer struct {
+	limit   int
+	scans   []domain.CompletedScan
+	next    int
+	added   int
+	evicted []domain.CompletedScan
+}
+
+func (b *memoryScanBuffer) Add(scan domain.CompletedScan) error {
+	b.added = b.added + 1
+	if b.limit <= 0 || len(b.scans) < b.limit {
+		b.scans = append(b.scans, scan)
+		return nil
+	}
+	evicted := b.scans[b.next]
+	if len(b.evicted) > 0 && !b.evicted[0].EndTime.Equal(evicted.EndTime) {
+		b.evicted = b.evicted[:0]
+	}
+	b.evicted = append(b.evicted, evicted)
+	b.scans[b.next] = scan
+	b.next = (b.next + 1) % b.limit
+	return nil
+}
+
+func (b *memoryScanBuffer) Result(extra []domain.CompletedScan) (domain.ScanResult, error) {
+	var scans []domain.CompletedScan
+	scans = append(scans, b.tied()...)
+	scans = append(scans, b.scans[b.next:]...)
+	scans = append(scans, b.scans[:b.next]...)
+	return domain.ScanResult{Scans: append(scans, extra...)}, nil
+}
+
+func (b *memoryScanBuffer) Dropped() int {
+	return b.added - len(b.scans) - len(b.tied())
+}
+
+// tied returns the evicted scans which ended at the same time as the most recent
+// scan retained.
+func (b *memoryScanBuffer) tied() []domain.CompletedScan {
+	if len(b.evicted) == 0 {
+		return nil
+	}
+	var latest time.Time
+	for _, scan := range b.scans {
+		if scan.EndTime.After(latest) {
+			latest = scan.EndTime
+		}
+	}
+	if !latest.Equal(b.evicted[0].EndTime) {
+		return nil
+	}
+	return b.evicted
+}
+
+func (b *memoryScanBuffer) Close() error {
+	return nil
+}
+
+// spilledScan locates a scan written to the file of a fileScanBuffer.
+type spilledScan struct {
+	endTime time.Time
+	offset  int64
+	length  int
+}
+
+// fileScanBuffer writes every scan to a temporary file, so that memory use does not
+// grow with the size of the scans. Only the end time and location of each retained
+// scan is kept in memory, and the scans are read back from the file one at a time.
+// Evicted holds the most recently evicted scans which ended at the same time.
+type fileScanBuffer struct {
+	limit   int
+	file    *os.File
+	index   []spilledScan
+	size    int64
+	added   int
+	evicted []spilledScan
+}
+
+func (b *fileScanBuffer) Add(scan domain.CompletedScan) error {
+	record, err := json.Marshal(scan)
+	if err != nil {
+		return err
+	}
+	if _, err := b.file.Write(record); err != nil {
+		return err
+	}
+	b.added = b.added + 1
+	if b.limit > 0 && len(b.index) == b.limit {
+		evicted := b.index[0]
+		if len(b.evicted) > 0 && !b.evicted[0].endTime.Equal(evicted.endTime) {
+			b.evicted = b.evicted[:0]
+		}
+		b.evicted = append(b.evicted, evicted)
+		b.index = b.index[1:]
+	}
+	b.index = append(b.index, spilledScan{endTime: scan.EndTime, offset: b.size, length: len(record)})
+	b.size = b.size + int64(len(record))
+	return nil
+}
+
+func (b *fileScanBuffer) Result(extra []domain.CompletedScan) (domain.ScanResult, error) {
+	index := append([]spilledScan(nil), b.tied()...)
+	index = append(index, b.index...)
+	sort.SliceStable(index, func(left, right int) bool {
+		return index[left].endTime.Before(index[right].endTime)
+	})
+	extra = append([]domain.CompletedScan(nil), extra...)
+	sort.SliceStable(extra, func(left, right int) bool {
+		return extra[left].EndTime.Before(extra[right].EndTime)
+	})
+	return domain.ScanResult{Spilled: &fileScanReader{buffer: b, index: index, extra: extra}}, nil
+}
+
+func (b *fileScanBuffer) Dropped() int {
+	return b.added - len(b.index) - len(b.tied())
+}
+
+// tied returns the evicted scans which ended at the same time as the most recent
+// scan retained.
+func (b *fileScanBuffer) tied() []spilledScan {
+	if len(b.evicted) == 0 {
+		return nil
+	}
+	var latest time.Time
+	for _, spilled := range b.index {
+		if spilled.endTime.After(latest) {
+			latest = spilled.endTime
+		}
+	}
+	if !latest.Equal(b.evicted[0].endTime) {
+		return nil
+	}
+	return b.evicted
+}
+
+func (b *fileScanBuffer) Close() error {
+	closeErr := b.file.Close()
+	if err := os.Remove(b.file.Name()); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// fileScanReader reads the scans retained by a fileScanBuffer from its file, merged
+// with extra scans held in memory, in ascending order by end time.
+type fileScanReader struct {
+	buffer *fileScanBuffer
+	index  []spilledScan
+	extra  []domain.CompletedScan
+	record []byte
+}
+
+func (r *fileScanReader) Len() int {
+	return len(r.index) + len(r.extra)
+}
+
+func (r *fileScanReader) Next() (domain.CompletedScan, error) {
+	if len(r.index) == 0 && len(r.extra) == 0 {
+		return domain.CompletedScan{}, io.EOF
+	}
+	if len(r.index) == 0 || (len(r.extra) > 0 && r.extra[0].EndTime.Before(r.index[0].endTime)) {
+		scan := r.extra[0]
+		r.extra = r.extra[1:]
+		return scan, nil
+	}
+
+	spilled := r.index[0]
+	r.index = r.index[1:]
+	if cap(r.record) < spilled.length {
+		r.record = make([]byte, spilled.length)
+	}
+	record := r.record[:spilled.length]
+	if _, err := r.buffer.file.ReadAt(record, spilled.offset); err != nil {
+		return domain.CompletedScan{}, err
+	}
+	var scan domain.CompletedScan
+	if err := json.Unmarshal(record, &scan); err != nil {
+		return domain.CompletedScan{}, err
+	}
+	return scan, nil
+}
+
+func (r *fileScanReader) Close() error {
+	return r.buffer.Close()
+}
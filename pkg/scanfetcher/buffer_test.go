@@ -0,0 +1,202 @@
+package scanfetcher
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanBuffer(t *testing.T) {
+	directory, err := ioutil.TempDir("", "scanbuffer")
+	require.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	tests := []struct {
+		name        string
+		limit       int
+		directory   string
+		added       int
+		expectedIDs []string
+		dropped     int
+	}{
+		{
+			name:        "memory without limit",
+			added:       4,
+			expectedIDs: []string{"3", "2", "1", "0"},
+		},
+		{
+			name:        "memory under limit",
+			limit:       5,
+			added:       4,
+			expectedIDs: []string{"3", "2", "1", "0"},
+		},
+		{
+			name:        "memory over limit",
+			limit:       3,
+			added:       7,
+			expectedIDs: []string{"6", "5", "4"},
+			dropped:     4,
+		},
+		{
+			name:        "memory at limit",
+			limit:       3,
+			added:       6,
+			expectedIDs: []string{"5", "4", "3"},
+			dropped:     3,
+		},
+		{
+			name:        "file without limit",
+			directory:   directory,
+			added:       4,
+			expectedIDs: []string{"3", "2", "1", "0"},
+		},
+		{
+			name:        "file over limit",
+			limit:       3,
+			directory:   directory,
+			added:       7,
+			expectedIDs: []string{"6", "5", "4"},
+			dropped:     4,
+		},
+		{
+			name:      "empty",
+			limit:     3,
+			directory: directory,
+		},
+	}
+
+	endTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer, err := newScanBuffer(tt.limit, tt.directory)
+			require.Nil(t, err)
+			// scans are added in descending order by end time
+			for offset := 0; offset < tt.added; offset = offset + 1 {
+				scan := domain.CompletedScan{ScanID: strconv.Itoa(offset), EndTime: endTime.Add(time.Duration(-offset) * time.Hour)}
+				require.Nil(t, buffer.Add(scan))
+			}
+			require.Equal(t, tt.dropped, buffer.Dropped())
+			result, err := buffer.Result(nil)
+			require.Nil(t, err)
+			require.Equal(t, len(tt.expectedIDs), result.Reader().Len())
+			scans, err := domain.ReadScans(result.Reader())
+			require.Nil(t, err)
+
+			var actualIDs []string
+			for _, scan := range scans {
+				actualIDs = append(actualIDs, scan.ScanID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs)
+		})
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	require.Nil(t, err)
+	require.Empty(t, files)
+}
+
+func TestScanBufferExtraScans(t *testing.T) {
+	directory, err := ioutil.TempDir("", "scanbuffer")
+	require.Nil(t, err)
+	defer os.RemoveAll(directory)
+	endTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	scan := func(id string, hours int) domain.CompletedScan {
+		return domain.CompletedScan{ScanID: id, SiteID: "1", EndTime: endTime.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	for _, dir := range []string{"", directory} {
+		buffer, err := newScanBuffer(3, dir)
+		require.Nil(t, err)
+		// scans are added in descending order by end time, and only the oldest are retained
+		for _, added := range []domain.CompletedScan{scan("5", 5), scan("4", 4), scan("3", 3), scan("2", 2)} {
+			require.Nil(t, buffer.Add(added))
+		}
+		result, err := buffer.Result([]domain.CompletedScan{scan("6", 3), scan("1", 0)})
+		require.Nil(t, err)
+		require.Equal(t, dir != "", result.Spilled != nil)
+
+		// scans are read in ascending order by end time, with retained scans first on ties
+		scans, err := domain.ReadScans(result.Reader())
+		require.Nil(t, err)
+		var actualIDs []string
+		for _, scan := range scans {
+			actualIDs = append(actualIDs, scan.ScanID)
+		}
+		require.Equal(t, []string{"1", "2", "3", "6", "4"}, actualIDs)
+		require.Equal(t, scan("4", 4), scans[4])
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	require.Nil(t, err)
+	require.Empty(t, files)
+}
+
+func TestScanBufferTiedScans(t *testing.T) {
+	directory, err := ioutil.TempDir("", "scanbuffer")
+	require.Nil(t, err)
+	defer os.RemoveAll(directory)
+	endTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		hours       []int
+		expectedIDs []string
+		dropped     int
+	}{
+		{
+			name:        "evicted scans tied with the most recent scan retained",
+			hours:       []int{4, 3, 2, 2, 2, 1},
+			expectedIDs: []string{"5", "2", "3", "4"},
+			dropped:     2,
+		},
+		{
+			name:        "limit reached by tied scans",
+			hours:       []int{3, 3, 3, 3, 3},
+			expectedIDs: []string{"0", "1", "2", "3", "4"},
+		},
+		{
+			name:        "evicted scans tied with each other only",
+			hours:       []int{4, 3, 3, 2, 1},
+			expectedIDs: []string{"4", "3"},
+			dropped:     3,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, dir := range []string{"", directory} {
+			t.Run(tt.name, func(t *testing.T) {
+				buffer, err := newScanBuffer(2, dir)
+				require.Nil(t, err)
+				for offset, hours := range tt.hours {
+					scan := domain.CompletedScan{ScanID: strconv.Itoa(offset), EndTime: endTime.Add(time.Duration(hours) * time.Hour)}
+					require.Nil(t, buffer.Add(scan))
+				}
+				require.Equal(t, tt.dropped, buffer.Dropped())
+				result, err := buffer.Result(nil)
+				require.Nil(t, err)
+				scans, err := domain.ReadScans(result.Reader())
+				require.Nil(t, err)
+
+				var actualIDs []string
+				for _, scan := range scans {
+					actualIDs = append(actualIDs, scan.ScanID)
+				}
+				require.Equal(t, tt.expectedIDs, actualIDs)
+			})
+		}
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	require.Nil(t, err)
+	require.Empty(t, files)
+}
+
+func TestScanBufferInvalidDirectory(t *testing.T) {
+	_, err := newScanBuffer(0, "/does/not/exist")
+	require.Error(t, err)
+}
@@ -3,18 +3,34 @@ package scanfetcher
 import (
 	"context"
 	"encoding/csv"
+	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 )
 
 // NexposeConfig holds configuration to connect to Nexpose
 // and make a call to the fetch scans API
 type NexposeConfig struct {
-	Endpoint      string `description:"The scheme and host of a Nexpose instance."`
-	PageSize      int    `description:"The number of scans that should be returned from the Nexpose API at one time."`
-	ScanBlocklist string `description:"CSV-formatted list of scan names to discard."`
+	Endpoint         string        `description:"The scheme and host of a Nexpose instance."`
+	Console          string        `description:"An identifier for the Nexpose console, used to derive event IDs. Defaults to the endpoint host."`
+	PageSize         int           `description:"The number of scans that should be returned from the Nexpose API at one time."`
+	ScanBlocklist    string        `description:"CSV-formatted list of scan names to discard."`
+	SettleWindow     time.Duration `description:"How long after a scan ends before it is eligible to be produced."`
+	Parallelism      int           `description:"The number of scan pages to request concurrently after the first page."`
+	RateLimit        float64       `description:"The maximum number of requests per second to make to Nexpose, or 0 for no limit."`
+	RateBurst        int           `description:"The number of requests which may be made to Nexpose in a burst before the rate limit applies."`
+	MaxScans         int           `description:"The maximum number of scans to return from a single run, keeping the oldest, or 0 for no limit."`
+	SpillDirectory   string        `description:"A directory in which to buffer scans on disk while crawling, instead of in memory."`
+	CircuitBreaker   *circuitbreaker.Config
+	Strict           bool `description:"Fail on malformed scan records instead of quarantining them."`
+	StoreQuarantined bool `description:"Persist quarantined scan records to storage for later inspection."`
 }
 
 // Name is used by the settings library and will add a "NEXPOSE_"
@@ -30,27 +46,82 @@ type NexposeComponent struct{}
 // Settings can be used to populate default values if there are any
 func (*NexposeComponent) Settings() *NexposeConfig {
 	return &NexposeConfig{
-		PageSize:      100,
-		ScanBlocklist: "",
+		PageSize:       100,
+		ScanBlocklist:  "",
+		Parallelism:    1,
+		RateBurst:      1,
+		CircuitBreaker: circuitbreaker.DefaultConfig(),
 	}
 }
 
 // New constructs a NexposeClient from a config.
 func (*NexposeComponent) New(_ context.Context, c *NexposeConfig) (*NexposeClient, error) {
+	if c.Parallelism < 0 {
+		return nil, fmt.Errorf("nexpose parallelism must not be negative, got %d", c.Parallelism)
+	}
+	if c.RateLimit < 0 {
+		return nil, fmt.Errorf("nexpose rate limit must not be negative, got %v", c.RateLimit)
+	}
+	if c.MaxScans < 0 {
+		return nil, fmt.Errorf("nexpose max scans must not be negative, got %d", c.MaxScans)
+	}
+	if c.SpillDirectory != "" {
+		info, err := os.Stat(c.SpillDirectory)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("nexpose spill directory %s is not a directory", c.SpillDirectory)
+		}
+	}
+
 	endpoint, err := url.Parse(c.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 
+	console := c.Console
+	if console == "" {
+		console = endpoint.Host
+	}
+
 	csvReader := csv.NewReader(strings.NewReader(c.ScanBlocklist))
 	scanBlockList, err := csvReader.Read()
 	if err != nil {
 		return nil, err
 	}
 
+	breaker, err := circuitbreaker.New("nexpose", c.CircuitBreaker)
+	if err != nil {
+		return nil, err
+	}
+
+	// every call to Nexpose shares the client, and so shares the rate limit and
+	// circuit breaker; calls rejected by the circuit breaker do not wait for the rate limit
+	var transport http.RoundTripper = http.DefaultTransport
+	if c.RateLimit > 0 {
+		transport = &rateLimitedTransport{
+			Wrapped: transport,
+			Bucket:  newTokenBucket(c.RateLimit, c.RateBurst),
+			StatFn:  domain.StatFromContext,
+		}
+	}
+	transport = circuitbreaker.Wrap(transport, breaker)
+
 	return &NexposeClient{
-		Endpoint:      endpoint,
-		PageSize:      c.PageSize,
-		ScanBlocklist: container.NewStringContainer(scanBlockList),
+		Client:           &http.Client{Transport: transport},
+		CircuitBreaker:   breaker,
+		Endpoint:         endpoint,
+		Console:          console,
+		PageSize:         c.PageSize,
+		ScanBlocklist:    container.NewStringContainer(scanBlockList),
+		SettleWindow:     c.SettleWindow,
+		Parallelism:      c.Parallelism,
+		MaxScans:         c.MaxScans,
+		SpillDirectory:   c.SpillDirectory,
+		Strict:           c.Strict,
+		StoreQuarantined: c.StoreQuarantined,
+		LogFn:            domain.LoggerFromContext,
+		StatFn:           domain.StatFromContext,
 	}, nil
 }
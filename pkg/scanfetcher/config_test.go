@@ -2,8 +2,12 @@ package scanfetcher
 
 import (
 	"context"
+	"net/http"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
 	"github.com/stretchr/testify/require"
 )
@@ -19,26 +23,60 @@ func TestComponentDefaultConfig(t *testing.T) {
 	require.Empty(t, config.Endpoint)
 	require.Equal(t, config.PageSize, 100)
 	require.Equal(t, config.ScanBlocklist, "")
+	require.Zero(t, config.SettleWindow)
+	require.Equal(t, 1, config.Parallelism)
+	require.Zero(t, config.RateLimit)
+	require.Equal(t, 1, config.RateBurst)
+	require.Equal(t, circuitbreaker.DefaultConfig(), config.CircuitBreaker)
+	require.Zero(t, config.MaxScans)
+	require.Empty(t, config.SpillDirectory)
+	require.False(t, config.Strict)
+	require.False(t, config.StoreQuarantined)
 }
 
 func TestNexposeClientConfigWithValues(t *testing.T) {
 	nexposeComponent := NexposeComponent{}
 	config := &NexposeConfig{
-		Endpoint:      "http://localhost",
-		PageSize:      5,
-		ScanBlocklist: "BadScan1,\"Bad Scan, the Second\"",
+		Endpoint:       "http://localhost",
+		PageSize:       5,
+		ScanBlocklist:  "BadScan1,\"Bad Scan, the Second\"",
+		SettleWindow:   5 * time.Minute,
+		Parallelism:    4,
+		MaxScans:       50,
+		SpillDirectory: os.TempDir(),
+		Strict:         true,
 	}
 	nexposeClient, err := nexposeComponent.New(context.Background(), config)
 
 	require.Equal(t, "http://localhost", nexposeClient.Endpoint.String())
+	require.Equal(t, "localhost", nexposeClient.Console)
 	require.Equal(t, 5, nexposeClient.PageSize)
 	require.Equal(t, &container.StringContainer{
 		"Bad Scan, the Second": struct{}{},
 		"BadScan1":             struct{}{},
 	}, nexposeClient.ScanBlocklist)
+	require.Equal(t, 5*time.Minute, nexposeClient.SettleWindow)
+	require.Equal(t, 4, nexposeClient.Parallelism)
+	require.Equal(t, 50, nexposeClient.MaxScans)
+	require.Equal(t, os.TempDir(), nexposeClient.SpillDirectory)
+	require.True(t, nexposeClient.Strict)
+	require.Equal(t, http.DefaultTransport, nexposeClient.Client.Transport)
+	require.NotNil(t, nexposeClient.LogFn)
+	require.NotNil(t, nexposeClient.StatFn)
 	require.Nil(t, err)
 }
 
+func TestNexposeClientConfigWithConsole(t *testing.T) {
+	nexposeComponent := NexposeComponent{}
+	config := nexposeComponent.Settings()
+	config.Endpoint = "http://gateway-outbound:8080"
+	config.ScanBlocklist = "BadScan1"
+	config.Console = "nexpose.example.com"
+	nexposeClient, err := nexposeComponent.New(context.Background(), config)
+	require.NoError(t, err)
+	require.Equal(t, "nexpose.example.com", nexposeClient.Console)
+}
+
 func TestNexposeClientConfigWithInvalidEndpoint(t *testing.T) {
 	nexposeComponent := NexposeComponent{}
 	config := &NexposeConfig{Endpoint: "~!@#$%^&*()_+:?><!@#$%^&*())_:", ScanBlocklist: ""}
@@ -47,6 +85,61 @@ func TestNexposeClientConfigWithInvalidEndpoint(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestNexposeClientConfigWithInvalidPaging(t *testing.T) {
+	nexposeComponent := NexposeComponent{}
+	for _, config := range []*NexposeConfig{
+		{Endpoint: "http://localhost", Parallelism: -1},
+		{Endpoint: "http://localhost", Parallelism: 1, MaxScans: -1},
+		{Endpoint: "http://localhost", Parallelism: 1, RateLimit: -1},
+		{Endpoint: "http://localhost", Parallelism: 1, SpillDirectory: "/does/not/exist"},
+		{Endpoint: "http://localhost", Parallelism: 1, SpillDirectory: "config_test.go"},
+	} {
+		_, err := nexposeComponent.New(context.Background(), config)
+		require.Error(t, err)
+	}
+}
+
+func TestNexposeClientConfigWithRateLimit(t *testing.T) {
+	nexposeComponent := NexposeComponent{}
+	config := &NexposeConfig{Endpoint: "http://localhost", ScanBlocklist: "BadScan1", RateLimit: 5, RateBurst: 10}
+	nexposeClient, err := nexposeComponent.New(context.Background(), config)
+
+	require.Nil(t, err)
+	transport, ok := nexposeClient.Client.Transport.(*rateLimitedTransport)
+	require.True(t, ok)
+	require.Equal(t, http.DefaultTransport, transport.Wrapped)
+	require.Equal(t, 5.0, transport.Bucket.rate)
+	require.Equal(t, 10.0, transport.Bucket.burst)
+}
+
+func TestNexposeClientConfigWithCircuitBreaker(t *testing.T) {
+	nexposeComponent := NexposeComponent{}
+	config := &NexposeConfig{
+		Endpoint:       "http://localhost",
+		ScanBlocklist:  "BadScan1",
+		RateLimit:      5,
+		CircuitBreaker: circuitbreaker.DefaultConfig(),
+	}
+	nexposeClient, err := nexposeComponent.New(context.Background(), config)
+
+	require.Nil(t, err)
+	require.Equal(t, "nexpose", nexposeClient.CircuitBreaker.Dependency)
+	transport, ok := nexposeClient.Client.Transport.(*circuitbreaker.Transport)
+	require.True(t, ok)
+	require.IsType(t, &rateLimitedTransport{}, transport.Wrapped)
+}
+
+func TestNexposeClientConfigWithInvalidCircuitBreaker(t *testing.T) {
+	nexposeComponent := NexposeComponent{}
+	config := &NexposeConfig{
+		Endpoint:       "http://localhost",
+		ScanBlocklist:  "BadScan1",
+		CircuitBreaker: &circuitbreaker.Config{FailureThreshold: 5},
+	}
+	_, err := nexposeComponent.New(context.Background(), config)
+	require.Error(t, err)
+}
+
 // Note: The [CSV spec](https://tools.ietf.org/html/rfc4180) is vague on this, but Go will
 // throw a csv.ParseError for quoted fields with leading spaces, so this is invalid.
 func TestNexposeClientConfigWithInvalidBlocklist(t *testing.T) {
@@ -0,0 +1,117 @@
+package scanfetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+type cachedSite struct {
+	site    domain.Site
+	expires time.Time
+}
+
+// SiteEnricher decorates a domain.ScanFetcher, adding the details and tags of each
+// scan's site to the scans it returns. Site details are cached for CacheTTL so that
+// sites with many scans are only looked up once.
+//
+// A site which cannot be looked up does not fail the run, since the scans have
+// already been fetched; the scans are returned without site details and marked as
+// SiteUnavailable instead, leaving producers which need the details to fail them.
+type SiteEnricher struct {
+	ScanFetcher domain.ScanFetcher
+	SiteFetcher domain.SiteFetcher
+	Enabled     bool
+	CacheTTL    time.Duration
+	LogFn       domain.LogFn
+	StatFn      domain.StatFn
+
+	lock  sync.Mutex
+	cache map[string]cachedSite
+}
+
+// FetchScans fetches scans from the wrapped domain.ScanFetcher and, when enabled,
+// adds the details of each scan's site.
+func (e *SiteEnricher) FetchScans(ctx context.Context, query domain.ScanQuery) (domain.ScanResult, error) {
+	result, err := e.ScanFetcher.FetchScans(ctx, query)
+	if err != nil || !e.Enabled {
+		return result, err
+	}
+
+	if result.Spilled != nil {
+		// spilled scans are enriched one at a time as they are read
+		result.Spilled = &enrichingScanReader{ScanReader: result.Spilled, ctx: ctx, enricher: e}
+		return result, nil
+	}
+	for offset := range result.Scans {
+		result.Scans[offset] = e.enrich(ctx, result.Scans[offset])
+	}
+	return result, nil
+}
+
+// enrich adds the details of a scan's site to the scan, returning the scan without
+// them when the site cannot be looked up.
+func (e *SiteEnricher) enrich(ctx context.Context, scan domain.CompletedScan) domain.CompletedScan {
+	site, err := e.fetchSite(ctx, scan.SiteID)
+	if err != nil {
+		e.LogFn(ctx).Warn(logs.SiteEnrichmentFailure{
+			ScanID: scan.ScanID,
+			SiteID: scan.SiteID,
+			Reason: err.Error(),
+		})
+		e.StatFn(ctx).Count("scanfetcher.enrichment.failed", 1)
+		scan.SiteUnavailable = true
+		return scan
+	}
+	scan.Site = site
+	return scan
+}
+
+// enrichingScanReader adds the details of each scan's site as it is read.
+type enrichingScanReader struct {
+	domain.ScanReader
+	ctx      context.Context
+	enricher *SiteEnricher
+}
+
+func (r *enrichingScanReader) Next() (domain.CompletedScan, error) {
+	scan, err := r.ScanReader.Next()
+	if err != nil {
+		return scan, err
+	}
+	return r.enricher.enrich(r.ctx, scan), nil
+}
+
+// fetchSite returns the cached details of a site, looking them up if they are not
+// cached or have expired. Sites which do not exist are cached as empty details. The
+// cache is not locked while looking up a site, so concurrent lookups of the same
+// site may both be made.
+func (e *SiteEnricher) fetchSite(ctx context.Context, siteID string) (domain.Site, error) {
+	e.lock.Lock()
+	cached, ok := e.cache[siteID]
+	e.lock.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.site, nil
+	}
+
+	site, err := e.SiteFetcher.FetchSite(ctx, siteID)
+	switch err.(type) {
+	case nil:
+	case domain.SiteNotFound:
+		// sites may be deleted after their scans complete
+		site = domain.Site{}
+	default:
+		return domain.Site{}, err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.cache == nil {
+		e.cache = make(map[string]cachedSite)
+	}
+	e.cache[siteID] = cachedSite{site: site, expires: time.Now().Add(e.CacheTTL)}
+	return site, nil
+}
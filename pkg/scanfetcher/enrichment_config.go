@@ -0,0 +1,46 @@
+package scanfetcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// EnrichmentConfig holds configuration for adding site details to scans.
+type EnrichmentConfig struct {
+	Enabled  bool          `description:"Add the details and tags of each scan's site to produced scans."`
+	CacheTTL time.Duration `description:"How long to cache the details of a site before looking them up again."`
+}
+
+// Name is used by the settings library and will add a "ENRICHMENT_"
+// prefix to EnrichmentConfig environment variables
+func (c *EnrichmentConfig) Name() string {
+	return "Enrichment"
+}
+
+// EnrichmentComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type EnrichmentComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*EnrichmentComponent) Settings() *EnrichmentConfig {
+	return &EnrichmentConfig{
+		CacheTTL: time.Hour,
+	}
+}
+
+// New constructs a SiteEnricher from a config. The scan and site fetchers must be
+// set on the result before use.
+func (*EnrichmentComponent) New(_ context.Context, c *EnrichmentConfig) (*SiteEnricher, error) {
+	if c.CacheTTL < 0 {
+		return nil, fmt.Errorf("enrichment cache ttl must not be negative, got %s", c.CacheTTL)
+	}
+	return &SiteEnricher{
+		Enabled:  c.Enabled,
+		CacheTTL: c.CacheTTL,
+		LogFn:    domain.LoggerFromContext,
+		StatFn:   domain.StatFromContext,
+	}, nil
+}
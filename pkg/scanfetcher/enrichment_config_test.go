@@ -0,0 +1,34 @@
+package scanfetcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrichmentConfigName(t *testing.T) {
+	config := EnrichmentConfig{}
+	require.Equal(t, "Enrichment", config.Name())
+}
+
+func TestEnrichmentComponentDefaultConfig(t *testing.T) {
+	component := &EnrichmentComponent{}
+	config := component.Settings()
+	require.False(t, config.Enabled)
+	require.Equal(t, time.Hour, config.CacheTTL)
+}
+
+func TestEnrichmentComponentNew(t *testing.T) {
+	component := &EnrichmentComponent{}
+	enricher, err := component.New(context.Background(), &EnrichmentConfig{Enabled: true, CacheTTL: time.Minute})
+	require.NoError(t, err)
+	require.True(t, enricher.Enabled)
+	require.Equal(t, time.Minute, enricher.CacheTTL)
+	require.NotNil(t, enricher.LogFn)
+	require.NotNil(t, enricher.StatFn)
+
+	_, err = component.New(context.Background(), &EnrichmentConfig{CacheTTL: -time.Minute})
+	require.Error(t, err)
+}
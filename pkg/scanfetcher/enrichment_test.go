@@ -0,0 +1,222 @@
+package scanfetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteEnricher_FetchScans(t *testing.T) {
+	site1 := domain.Site{Name: "Site 1", Importance: "high", Tags: []string{"production"}}
+	site2 := domain.Site{Name: "Site 2", Importance: "low"}
+	scans := func() []domain.CompletedScan {
+		return []domain.CompletedScan{
+			{ScanID: "1", SiteID: "1"},
+			{ScanID: "2", SiteID: "2"},
+			{ScanID: "3", SiteID: "1"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		spilled   bool
+		fetchErr  error
+		sites     map[string]domain.Site
+		siteErrs  map[string]error
+		expected  []domain.CompletedScan
+		expectErr bool
+	}{
+		{
+			name:    "sites are looked up once per site",
+			enabled: true,
+			sites:   map[string]domain.Site{"1": site1, "2": site2},
+			expected: []domain.CompletedScan{
+				{ScanID: "1", SiteID: "1", Site: site1},
+				{ScanID: "2", SiteID: "2", Site: site2},
+				{ScanID: "3", SiteID: "1", Site: site1},
+			},
+		},
+		{
+			name:    "spilled scans are enriched as they are read",
+			enabled: true,
+			spilled: true,
+			sites:   map[string]domain.Site{"1": site1, "2": site2},
+			expected: []domain.CompletedScan{
+				{ScanID: "1", SiteID: "1", Site: site1},
+				{ScanID: "2", SiteID: "2", Site: site2},
+				{ScanID: "3", SiteID: "1", Site: site1},
+			},
+		},
+		{
+			name:     "disabled",
+			enabled:  false,
+			expected: scans(),
+		},
+		{
+			name:      "fetch error",
+			enabled:   true,
+			fetchErr:  errors.New("fetch error"),
+			expectErr: true,
+		},
+		{
+			name:     "missing site",
+			enabled:  true,
+			sites:    map[string]domain.Site{"2": site2},
+			siteErrs: map[string]error{"1": domain.SiteNotFound{SiteID: "1"}},
+			expected: []domain.CompletedScan{
+				{ScanID: "1", SiteID: "1"},
+				{ScanID: "2", SiteID: "2", Site: site2},
+				{ScanID: "3", SiteID: "1"},
+			},
+		},
+		{
+			name:     "site error leaves scans unenriched",
+			enabled:  true,
+			sites:    map[string]domain.Site{"2": site2},
+			siteErrs: map[string]error{"1": errors.New("site error")},
+			expected: []domain.CompletedScan{
+				{ScanID: "1", SiteID: "1", SiteUnavailable: true},
+				{ScanID: "2", SiteID: "2", Site: site2},
+				{ScanID: "3", SiteID: "1", SiteUnavailable: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockScanFetcher := NewMockScanFetcher(ctrl)
+			mockSiteFetcher := NewMockSiteFetcher(ctrl)
+
+			query := domain.ScanQuery{Since: time.Now()}
+			if tt.fetchErr != nil {
+				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), query).Return(domain.ScanResult{}, tt.fetchErr)
+			} else if tt.spilled {
+				spilled := domain.ScanResult{Scans: scans()}.Reader()
+				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), query).Return(domain.ScanResult{Spilled: spilled}, nil)
+			} else {
+				mockScanFetcher.EXPECT().FetchScans(gomock.Any(), query).Return(domain.ScanResult{Scans: scans()}, nil)
+			}
+			for siteID, site := range tt.sites {
+				mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), siteID).Return(site, nil)
+			}
+			for siteID, err := range tt.siteErrs {
+				// errors other than a missing site are not cached, so the site is looked up again
+				times := 2
+				if _, ok := err.(domain.SiteNotFound); ok {
+					times = 1
+				}
+				mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), siteID).Return(domain.Site{}, err).Times(times)
+			}
+
+			enricher := &SiteEnricher{
+				ScanFetcher: mockScanFetcher,
+				SiteFetcher: mockSiteFetcher,
+				Enabled:     tt.enabled,
+				CacheTTL:    time.Hour,
+				LogFn:       testLogFn,
+				StatFn:      testStatFn,
+			}
+			result, err := enricher.FetchScans(context.Background(), query)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.spilled, result.Spilled != nil)
+			actual, err := domain.ReadScans(result.Reader())
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSiteEnricher_CacheTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockScanFetcher := NewMockScanFetcher(ctrl)
+	mockSiteFetcher := NewMockSiteFetcher(ctrl)
+
+	scan := domain.CompletedScan{ScanID: "1", SiteID: "1"}
+	mockScanFetcher.EXPECT().FetchScans(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, domain.ScanQuery) (domain.ScanResult, error) {
+			return domain.ScanResult{Scans: []domain.CompletedScan{scan}}, nil
+		}).Times(3)
+	gomock.InOrder(
+		mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), "1").Return(domain.Site{Name: "before"}, nil),
+		mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), "1").Return(domain.Site{Name: "after"}, nil),
+	)
+
+	enricher := &SiteEnricher{
+		ScanFetcher: mockScanFetcher,
+		SiteFetcher: mockSiteFetcher,
+		Enabled:     true,
+		CacheTTL:    50 * time.Millisecond,
+		LogFn:       testLogFn,
+		StatFn:      testStatFn,
+	}
+	ctx := context.Background()
+
+	result, err := enricher.FetchScans(ctx, domain.ScanQuery{})
+	require.NoError(t, err)
+	require.Equal(t, "before", result.Scans[0].Site.Name)
+
+	result, err = enricher.FetchScans(ctx, domain.ScanQuery{})
+	require.NoError(t, err)
+	require.Equal(t, "before", result.Scans[0].Site.Name)
+
+	time.Sleep(100 * time.Millisecond)
+	result, err = enricher.FetchScans(ctx, domain.ScanQuery{})
+	require.NoError(t, err)
+	require.Equal(t, "after", result.Scans[0].Site.Name)
+}
+
+func TestSiteEnricher_LookupDoesNotBlockCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSiteFetcher := NewMockSiteFetcher(ctrl)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), "2").Return(domain.Site{Name: "Site 2"}, nil)
+	mockSiteFetcher.EXPECT().FetchSite(gomock.Any(), "1").DoAndReturn(
+		func(context.Context, string) (domain.Site, error) {
+			close(started)
+			<-release
+			return domain.Site{Name: "Site 1"}, nil
+		})
+
+	enricher := &SiteEnricher{
+		SiteFetcher: mockSiteFetcher,
+		Enabled:     true,
+		CacheTTL:    time.Hour,
+		LogFn:       testLogFn,
+		StatFn:      testStatFn,
+	}
+	ctx := context.Background()
+	_, err := enricher.fetchSite(ctx, "2")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		site, err := enricher.fetchSite(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "Site 1", site.Name)
+	}()
+
+	// cached sites are returned while another site is being looked up
+	<-started
+	site, err := enricher.fetchSite(ctx, "2")
+	require.NoError(t, err)
+	require.Equal(t, "Site 2", site.Name)
+	close(release)
+	<-done
+}
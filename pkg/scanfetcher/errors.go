@@ -1,12 +1,22 @@
 package scanfetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 )
 
+// nexposeDependency names Nexpose in the domain errors returned by the client.
+const nexposeDependency = "nexpose"
+
 // outOfRangeError is an error indicating the scan time was outside the valid range.
 type outOfRangeError struct {
 	ScanID   string
@@ -47,3 +57,81 @@ func (e scanNameInBlocklistError) Error() string {
 	return fmt.Sprintf("scan %s (\"%s\") for site %s in blocklist: %s",
 		e.ScanID, e.ScanName, e.SiteID, e.Blocklist)
 }
+
+// scanNotSettledError is an error indicating the scan ended within the settle window,
+// and should be left for a later run.
+type scanNotSettledError struct {
+	ScanID   string
+	ScanName string
+	SiteID   string
+	Cutoff   time.Time
+	ScanTime time.Time
+}
+
+func (e scanNotSettledError) Error() string {
+	return fmt.Sprintf("scan %s (\"%s\") for site %s ended after settle cutoff %s: %s",
+		e.ScanID, e.ScanName, e.SiteID, e.Cutoff.Format(time.RFC3339Nano), e.ScanTime.Format(time.RFC3339Nano))
+}
+
+// scanNotFoundError is an error indicating a scan requested by ID does not exist.
+type scanNotFoundError struct {
+	ScanID string
+}
+
+func (e scanNotFoundError) Error() string {
+	return fmt.Sprintf("scan %s not found", e.ScanID)
+}
+
+// malformedScanError is an error indicating a field of the scan resource could not be parsed.
+type malformedScanError struct {
+	ScanID   string
+	ScanName string
+	SiteID   string
+	Field    string
+	Reason   string
+}
+
+func (e malformedScanError) Error() string {
+	return fmt.Sprintf("scan %s (\"%s\") for site %s has malformed %s: %s",
+		e.ScanID, e.ScanName, e.SiteID, e.Field, e.Reason)
+}
+
+// requestError converts an error making a request to Nexpose into
+// domain.UpstreamUnavailable, unless the request failed because ctx is done.
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return domain.UpstreamUnavailable{Dependency: nexposeDependency, Reason: err.Error()}
+}
+
+// responseError reads the body of an unsuccessful response from a Nexpose api, and
+// returns the domain error matching its status code. Statuses with no specific
+// meaning are returned as an untyped error.
+func responseError(api string, res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	reason := fmt.Sprintf("unexpected response from nexpose %s api: %d %s", api, res.StatusCode, string(body))
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return domain.AuthenticationFailure{Dependency: nexposeDependency, Reason: reason}
+	case http.StatusTooManyRequests:
+		return domain.RateLimited{Dependency: nexposeDependency, Reason: reason, RetryAfter: retryAfter(res.Header)}
+	case http.StatusConflict:
+		return domain.Conflict{Dependency: nexposeDependency, Reason: reason}
+	case http.StatusNotFound:
+		return domain.InvalidConfiguration{Dependency: nexposeDependency, Reason: reason}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return domain.UpstreamUnavailable{Dependency: nexposeDependency, Reason: reason}
+	}
+	return errors.New(reason)
+}
+
+// retryAfter returns the wait requested by a Retry-After header given in seconds,
+// or zero if there is none.
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
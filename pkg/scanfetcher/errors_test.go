@@ -1,11 +1,17 @@
 package scanfetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,3 +31,96 @@ func TestScanNameInBlocklistError(t *testing.T) {
 	e := scanNameInBlocklistError{ScanID: "1", ScanName: "Test", SiteID: "1", Blocklist: container.NewStringContainer([]string{"Bad Scan"})}
 	require.Equal(t, e.Error(), "scan 1 (\"Test\") for site 1 in blocklist: [Bad Scan]")
 }
+
+func TestScanNotSettledError(t *testing.T) {
+	now := time.Now()
+	e := scanNotSettledError{ScanID: "1", ScanName: "Test", SiteID: "1", Cutoff: now, ScanTime: now.Add(1 * time.Minute)}
+	require.Equal(t, e.Error(), fmt.Sprintf("scan 1 (\"Test\") for site 1 ended after settle cutoff %s: %s",
+		now.Format(time.RFC3339Nano), now.Add(1*time.Minute).Format(time.RFC3339Nano)))
+}
+
+func TestScanNotFoundError(t *testing.T) {
+	e := scanNotFoundError{ScanID: "1"}
+	require.Equal(t, e.Error(), "scan 1 not found")
+}
+
+func TestMalformedScanError(t *testing.T) {
+	e := malformedScanError{ScanID: "1", ScanName: "Test", SiteID: "1", Field: "endTime", Reason: "bad value"}
+	require.Equal(t, e.Error(), "scan 1 (\"Test\") for site 1 has malformed endTime: bad value")
+}
+
+func TestRequestError(t *testing.T) {
+	err := requestError(context.Background(), errors.New("connection refused"))
+	require.Equal(t, domain.UpstreamUnavailable{Dependency: "nexpose", Reason: "connection refused"}, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = requestError(ctx, context.Canceled)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestResponseError(t *testing.T) {
+	reason := "unexpected response from nexpose scans api: %d failed"
+	tc := []struct {
+		Name       string
+		StatusCode int
+		RetryAfter string
+		Expected   error
+	}{
+		{
+			Name:       "unauthorized",
+			StatusCode: http.StatusUnauthorized,
+			Expected:   domain.AuthenticationFailure{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 401)},
+		},
+		{
+			Name:       "forbidden",
+			StatusCode: http.StatusForbidden,
+			Expected:   domain.AuthenticationFailure{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 403)},
+		},
+		{
+			Name:       "rate limited",
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "30",
+			Expected:   domain.RateLimited{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 429), RetryAfter: 30 * time.Second},
+		},
+		{
+			Name:       "rate limited with date",
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: "Wed, 21 Oct 2015 07:28:00 GMT",
+			Expected:   domain.RateLimited{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 429)},
+		},
+		{
+			Name:       "conflict",
+			StatusCode: http.StatusConflict,
+			Expected:   domain.Conflict{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 409)},
+		},
+		{
+			Name:       "not found",
+			StatusCode: http.StatusNotFound,
+			Expected:   domain.InvalidConfiguration{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 404)},
+		},
+		{
+			Name:       "unavailable",
+			StatusCode: http.StatusServiceUnavailable,
+			Expected:   domain.UpstreamUnavailable{Dependency: "nexpose", Reason: fmt.Sprintf(reason, 503)},
+		},
+		{
+			Name:       "bad request",
+			StatusCode: http.StatusBadRequest,
+			Expected:   errors.New(fmt.Sprintf(reason, 400)),
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			res := &http.Response{
+				StatusCode: tt.StatusCode,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("failed")),
+			}
+			if tt.RetryAfter != "" {
+				res.Header.Set("Retry-After", tt.RetryAfter)
+			}
+			require.Equal(t, tt.Expected, responseError("scans", res))
+		})
+	}
+}
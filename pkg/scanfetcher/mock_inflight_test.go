@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: InFlightScanFetcher)
+
+// Package scanfetcher is a generated GoMock package.
+package scanfetcher
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockInFlightScanFetcher is a mock of InFlightScanFetcher interface
+type MockInFlightScanFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockInFlightScanFetcherMockRecorder
+}
+
+// MockInFlightScanFetcherMockRecorder is the mock recorder for MockInFlightScanFetcher
+type MockInFlightScanFetcherMockRecorder struct {
+	mock *MockInFlightScanFetcher
+}
+
+// NewMockInFlightScanFetcher creates a new mock instance
+func NewMockInFlightScanFetcher(ctrl *gomock.Controller) *MockInFlightScanFetcher {
+	mock := &MockInFlightScanFetcher{ctrl: ctrl}
+	mock.recorder = &MockInFlightScanFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockInFlightScanFetcher) EXPECT() *MockInFlightScanFetcherMockRecorder {
+	return m.recorder
+}
+
+// FetchInFlightScans mocks base method
+func (m *MockInFlightScanFetcher) FetchInFlightScans(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchInFlightScans", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchInFlightScans indicates an expected call of FetchInFlightScans
+func (mr *MockInFlightScanFetcherMockRecorder) FetchInFlightScans(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchInFlightScans", reflect.TypeOf((*MockInFlightScanFetcher)(nil).FetchInFlightScans), arg0)
+}
@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/asecurityteam/nexpose-scan-notifier/pkg/domain (interfaces: ScanQuarantiner)
+
+// Package scanfetcher is a generated GoMock package.
+package scanfetcher
+
+import (
+	context "context"
+	domain "github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockScanQuarantiner is a mock of ScanQuarantiner interface
+type MockScanQuarantiner struct {
+	ctrl     *gomock.Controller
+	recorder *MockScanQuarantinerMockRecorder
+}
+
+// MockScanQuarantinerMockRecorder is the mock recorder for MockScanQuarantiner
+type MockScanQuarantinerMockRecorder struct {
+	mock *MockScanQuarantiner
+}
+
+// NewMockScanQuarantiner creates a new mock instance
+func NewMockScanQuarantiner(ctrl *gomock.Controller) *MockScanQuarantiner {
+	mock := &MockScanQuarantiner{ctrl: ctrl}
+	mock.recorder = &MockScanQuarantinerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockScanQuarantiner) EXPECT() *MockScanQuarantinerMockRecorder {
+	return m.recorder
+}
+
+// QuarantineScan mocks base method
+func (m *MockScanQuarantiner) QuarantineScan(arg0 context.Context, arg1 domain.QuarantinedScan) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantineScan", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QuarantineScan indicates an expected call of QuarantineScan
+func (mr *MockScanQuarantinerMockRecorder) QuarantineScan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantineScan", reflect.TypeOf((*MockScanQuarantiner)(nil).QuarantineScan), arg0, arg1)
+}
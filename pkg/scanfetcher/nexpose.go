@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
 )
 
 const (
@@ -24,8 +28,15 @@ const (
 	sortQueryValue   = "endTime,DESC" // Return scans in descending order start with most recently completed.
 
 	finishedScanStatus = "finished" // Status for scans which have completed successfully.
+
+	maxErrorBodySize = 4096 // The number of bytes of an unexpected response body to include in errors.
+	maxDriftRetries  = 3    // The number of times to re-request a page after scans are removed while crawling.
 )
 
+// inFlightScanStatuses are the non-terminal statuses of scans which may still
+// reach a status of "finished".
+var inFlightScanStatuses = []string{"running", "paused", "dispatched", "integrating"}
+
 type page struct {
 	Number         int `json:"number"`
 	Size           int `json:"size"`
@@ -50,109 +61,613 @@ type nexposeScanResponse struct {
 
 // NexposeClient implements the interfaces to fetch scans from Nexpose
 type NexposeClient struct {
-	Client        *http.Client
-	Endpoint      *url.URL
-	PageSize      int
-	ScanBlocklist *container.StringContainer
+	Client         *http.Client
+	CircuitBreaker *circuitbreaker.Breaker
+	Endpoint       *url.URL
+	Console        string
+	PageSize       int
+	ScanBlocklist  *container.StringContainer
+	SettleWindow   time.Duration
+	Parallelism    int
+	MaxScans       int
+	SpillDirectory string
+
+	InFlightScanFetcher domain.InFlightScanFetcher
+
+	Strict           bool
+	StoreQuarantined bool
+	ScanQuarantiner  domain.ScanQuarantiner
+	LogFn            domain.LogFn
+	StatFn           domain.StatFn
 }
 
-// FetchScans fetches Nexpose scans, filters out running scans, and returns all completed scans
-// after the provided timestamp.
-func (n *NexposeClient) FetchScans(ctx context.Context, ts time.Time) ([]domain.CompletedScan, error) {
-	var completedScans []domain.CompletedScan
+// ScanLimit returns the maximum number of scans returned for a query, which is the
+// lower of the configured and requested maximums, or 0 when neither is set.
+func (n *NexposeClient) ScanLimit(query domain.ScanQuery) int {
+	limit := n.MaxScans
+	if query.Limit > 0 && (limit == 0 || query.Limit < limit) {
+		limit = query.Limit
+	}
+	return limit
+}
 
-	scanResp, err := n.makePagedNexposeScanRequest(0)
+// FetchScans fetches Nexpose scans, filters out running scans, and returns all completed scans
+// after the provided timestamp. When a settle window is configured, scans which ended within
+// the window are left for a later run, giving Nexpose time to finish recording them.
+//
+// When in-flight tracking is configured, scans seen in a non-terminal status such as
+// "integrating" are remembered between runs and re-checked individually, regardless of the
+// provided timestamp, until they reach a terminal status. Those which finish are returned
+// along with the other completed scans, even when they would exceed the maximum number of
+// scans to return. The scans still in flight are returned rather than stored, so that the
+// caller only stops tracking scans which finished late once it has produced them.
+//
+// When a maximum number of scans is configured or requested, the lower of the two applies;
+// only the oldest scans after the provided timestamp are returned, the result is marked as
+// truncated, and the rest are left for a later run. Scans are buffered in a
+// temporary file while crawling when a spill directory is configured, and are then
+// returned as a reader of that file rather than being read back into memory.
+//
+// Pages after the first are requested concurrently when parallelism is configured, but are
+// always processed in order, and no further pages are requested once a page is found to
+// contain scans at or before the provided timestamp.
+//
+// When the query has a deadline, no further pages are requested once it has passed. The
+// result is then marked as incomplete and contains no scans, and any scans in flight which
+// were not yet re-checked remain tracked.
+//
+// When the query names the scans to re-check, those are re-checked instead of the
+// tracked scans, and no scans in flight are returned.
+func (n *NexposeClient) FetchScans(ctx context.Context, query domain.ScanQuery) (domain.ScanResult, error) {
+	ts := query.Since
+	buffer, err := newScanBuffer(n.ScanLimit(query), n.SpillDirectory)
 	if err != nil {
-		return nil, err
+		return domain.ScanResult{}, err
 	}
-
-	pages := scanResp.Page.TotalPages
-	for _, resource := range scanResp.Resources {
-		completedScan, err := n.scanResourceToCompletedScan(resource, ts)
-		switch err.(type) {
-		case nil:
-			completedScans = append(completedScans, completedScan)
-		case scanNotFinishedError:
-			// skip scans without a status of "finished"
-		case scanNameInBlocklistError:
-			//skip scans included by name in the blocklist
-		case outOfRangeError:
-			// since scans are returned in descending order by scan time, return
-			// the list of completed scans after finding the first scan outside
-			// the valid time range
-			return completedScans, nil
-		default:
-			return nil, err
+	// the buffer is closed with the reader of the result once one is returned
+	closeBuffer := true
+	defer func() {
+		if closeBuffer {
+			buffer.Close()
 		}
+	}()
+
+	var cutoff time.Time
+	if n.SettleWindow > 0 {
+		cutoff = time.Now().Add(-1 * n.SettleWindow)
 	}
 
-	for curPage := 1; curPage < pages; curPage = curPage + 1 {
-		scanResp, err := n.makePagedNexposeScanRequest(curPage)
-		if err != nil {
-			return nil, err
+	tracking := n.InFlightScanFetcher != nil && query.Recheck == nil
+	tracked := query.Recheck
+	if tracking {
+		var err error
+		if tracked, err = n.InFlightScanFetcher.FetchInFlightScans(ctx); err != nil {
+			return domain.ScanResult{}, err
 		}
+	}
+	produced := make(map[string]bool)
+	inFlight := make(map[string]bool)
+
+	scanResp, err := n.makePagedNexposeScanRequest(ctx, 0)
+	if err != nil {
+		return domain.ScanResult{}, err
+	}
+	pages := scanResp.Page.TotalPages
+	if n.crossesTimestamp(scanResp, ts, cutoff) {
+		pages = 1
+	}
 
-		for _, resource := range scanResp.Resources {
-			completedScan, err := n.scanResourceToCompletedScan(resource, ts)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	window := make(chan struct{}, n.parallelism())
+	window <- struct{}{} // the first page is already being processed
+	results := n.fetchPages(ctx, pages, window, ts, cutoff)
+
+	// scans are identified by ID while crawling, since scans which finish or are removed
+	// during the crawl shift the remaining scans between pages
+	seen := make(map[int]bool)
+	var skipped domain.SkippedScans
+	var fetched int
+	processResources := func(resources []resource) (bool, error) {
+		fetched = fetched + 1
+		for _, resource := range resources {
+			if seen[resource.ScanID] {
+				continue
+			}
+			seen[resource.ScanID] = true
+
+			completedScan, err := n.scanResourceToCompletedScan(resource, ts, cutoff)
 			switch err.(type) {
 			case nil:
-				completedScans = append(completedScans, completedScan)
+				if err := buffer.Add(completedScan); err != nil {
+					return false, err
+				}
+				produced[completedScan.ScanID] = true
 			case scanNotFinishedError:
-				// skip scans without a status of "finished"
+				// skip scans without a status of "finished", remembering those
+				// which may still finish so they can be re-checked later
+				skipped.NotFinished = skipped.NotFinished + 1
+				if isInFlightScanStatus(resource.Status) {
+					inFlight[strconv.Itoa(resource.ScanID)] = true
+				}
 			case scanNameInBlocklistError:
 				//skip scans included by name in the blocklist
+				skipped.Blocklisted = skipped.Blocklisted + 1
+			case scanNotSettledError:
+				// skip scans which ended within the settle window
+				skipped.NotSettled = skipped.NotSettled + 1
+			case malformedScanError:
+				// skip scans which cannot be parsed, unless failing fast
+				if err := n.quarantineScan(ctx, resource, err.(malformedScanError)); err != nil {
+					return false, err
+				}
+				skipped.Malformed = skipped.Malformed + 1
 			case outOfRangeError:
-				// since scans are returned in descending order by scan time, return
-				// the list of completed scans after finding the first scan outside
-				// the valid time range
-				return completedScans, nil
+				// since scans are returned in descending order by scan time, stop
+				// crawling after finding the first scan outside the valid time range
+				skipped.OutOfRange = skipped.OutOfRange + 1
+				return true, nil
 			default:
-				return nil, err
+				return false, err
 			}
 		}
+		return false, nil
 	}
 
-	return completedScans, nil
+	totalResources := scanResp.Page.TotalResources
+crawl:
+	for curPage := 0; curPage < pages; curPage = curPage + 1 {
+		if curPage > 0 {
+			if deadlineExceeded(query.Deadline) {
+				// the earliest scans have not been reached, so none can be returned
+				n.LogFn(ctx).Info(logs.CrawlDeadlineExceeded{Page: curPage, Pages: pages})
+				n.StatFn(ctx).Count("scanfetcher.deadlineexceeded", 1)
+				return domain.ScanResult{Incomplete: true, Pages: fetched, Skipped: skipped}, nil
+			}
+			if scanResp, err = n.nextPage(ctx, curPage, results); err != nil {
+				return domain.ScanResult{}, err
+			}
+			if scanResp.Page.Number < 0 {
+				// skipped, since an earlier page crossed the provided timestamp
+				break crawl
+			}
+
+			// scans removed since the previous page was requested shift later scans onto
+			// earlier pages, so re-request the previous page to find any which were missed
+			drift := scanResp.Page.TotalResources - totalResources
+			if drift != 0 || overlaps(scanResp.Resources, seen) {
+				n.logPaginationDrift(ctx, curPage, totalResources, scanResp)
+			}
+			for retry := 0; drift < 0 && retry < maxDriftRetries; retry = retry + 1 {
+				previous, err := n.makePagedNexposeScanRequest(ctx, curPage-1)
+				if err != nil {
+					return domain.ScanResult{}, err
+				}
+				if stop, err := processResources(previous.Resources); err != nil || stop {
+					if err != nil {
+						return domain.ScanResult{}, err
+					}
+					break crawl
+				}
+				drift = previous.Page.TotalResources - scanResp.Page.TotalResources
+			}
+			totalResources = scanResp.Page.TotalResources
+
+			// scans which finish during the crawl can push the oldest scans onto a
+			// page which did not exist when the crawl began
+			if scanResp.Page.TotalPages > pages {
+				pages = scanResp.Page.TotalPages
+			}
+		}
+
+		stop, err := processResources(scanResp.Resources)
+		if err != nil {
+			return domain.ScanResult{}, err
+		}
+		if stop {
+			break crawl
+		}
+		if curPage < len(results) {
+			// allow another page to be requested now that this one is processed
+			<-window
+		}
+	}
+
+	truncated := buffer.Dropped() > 0
+	if truncated {
+		// the most recent scans are left for a later run
+		n.StatFn(ctx).Count("scanfetcher.deferred", float64(buffer.Dropped()))
+	}
+
+	// re-check scans which were in flight during previous runs, and were neither
+	// produced nor seen in flight again while crawling
+	var finished []domain.CompletedScan
+	for _, scanID := range tracked {
+		if produced[scanID] || inFlight[scanID] {
+			continue
+		}
+		if deadlineExceeded(query.Deadline) {
+			// scans which cannot be re-checked in time are re-checked by a later run
+			inFlight[scanID] = true
+			continue
+		}
+		resource, err := n.makeNexposeScanRequest(ctx, scanID)
+		switch err.(type) {
+		case nil:
+		case scanNotFoundError:
+			// stop tracking scans which no longer exist
+			continue
+		default:
+			return domain.ScanResult{}, err
+		}
+
+		// the scan ended before it finished, so it may be older than the provided
+		// timestamp; only the settle window applies
+		completedScan, err := n.scanResourceToCompletedScan(resource, time.Time{}, cutoff)
+		switch err.(type) {
+		case nil:
+			finished = append(finished, completedScan)
+		case scanNotFinishedError:
+			if isInFlightScanStatus(resource.Status) {
+				inFlight[scanID] = true
+			}
+		case scanNotSettledError:
+			inFlight[scanID] = true
+		case scanNameInBlocklistError:
+			// stop tracking scans included by name in the blocklist
+			skipped.Blocklisted = skipped.Blocklisted + 1
+		case malformedScanError:
+			// stop tracking scans which cannot be parsed, unless failing fast
+			if err := n.quarantineScan(ctx, resource, err.(malformedScanError)); err != nil {
+				return domain.ScanResult{}, err
+			}
+			skipped.Malformed = skipped.Malformed + 1
+		default:
+			return domain.ScanResult{}, err
+		}
+	}
+
+	result, err := buffer.Result(finished)
+	if err != nil {
+		return domain.ScanResult{}, err
+	}
+	closeBuffer = false
+	result.Truncated = truncated
+	result.Pages = fetched
+	result.Skipped = skipped
+	if tracking {
+		result.InFlight = pendingInFlightScans(tracked, inFlight)
+	}
+	return result, nil
 }
 
-func (n *NexposeClient) makePagedNexposeScanRequest(page int) (nexposeScanResponse, error) {
-	u, _ := url.Parse(n.Endpoint.String())
-	u.Path = path.Join(u.Path, "api", "3", "scans")
+// pageResult is the outcome of requesting a single page of scans.
+type pageResult struct {
+	scanResp nexposeScanResponse
+	err      error
+	skipped  bool
+}
+
+// fetchPages requests pages 1 through pages-1 of scans in the background, using up to
+// Parallelism concurrent requests, which are subject to the client's rate limit.
+// Each page is delivered on its own channel so that pages may be processed in order
+// regardless of which request completes first. A page may only be requested once a
+// slot is available in window, which the caller frees after processing each page,
+// bounding how far ahead of processing the requests may run.
+//
+// Once a page contains a scan outside the valid time range, no later page can contain
+// scans to produce, so later pages are not requested and are delivered as skipped.
+// Requests stop when ctx is cancelled.
+func (n *NexposeClient) fetchPages(ctx context.Context, pages int, window chan struct{},
+	start time.Time, cutoff time.Time) []chan pageResult {
+	results := make([]chan pageResult, pages)
+	for curPage := range results {
+		results[curPage] = make(chan pageResult, 1)
+	}
+	if pages < 2 {
+		return results
+	}
 
-	q := u.Query()
+	lastPage := int64(pages)
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for curPage := 1; curPage < pages; curPage = curPage + 1 {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- curPage:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for worker := 0; worker < n.parallelism(); worker = worker + 1 {
+		go func() {
+			for curPage := range jobs {
+				if int64(curPage) >= atomic.LoadInt64(&lastPage) {
+					results[curPage] <- pageResult{skipped: true}
+					continue
+				}
+				scanResp, err := n.makePagedNexposeScanRequest(ctx, curPage)
+				if err == nil && n.crossesTimestamp(scanResp, start, cutoff) {
+					lowerLastPage(&lastPage, int64(curPage+1))
+				}
+				results[curPage] <- pageResult{scanResp: scanResp, err: err}
+			}
+		}()
+	}
+	return results
+}
+
+// nextPage returns the requested page, either from the pages fetched in the background
+// or, for pages which did not exist when the crawl began, by requesting it directly.
+// Pages which were skipped are returned with a negative page number.
+func (n *NexposeClient) nextPage(ctx context.Context, curPage int, results []chan pageResult) (nexposeScanResponse, error) {
+	if curPage >= len(results) {
+		return n.makePagedNexposeScanRequest(ctx, curPage)
+	}
+
+	var result pageResult
+	select {
+	case result = <-results[curPage]:
+	case <-ctx.Done():
+		return nexposeScanResponse{}, ctx.Err()
+	}
+	if result.skipped {
+		return nexposeScanResponse{Page: page{Number: -1}}, nil
+	}
+	return result.scanResp, result.err
+}
+
+// logPaginationDrift records that the scans changed between requesting two pages.
+func (n *NexposeClient) logPaginationDrift(ctx context.Context, curPage int, previousTotal int,
+	scanResp nexposeScanResponse) {
+	n.LogFn(ctx).Info(logs.PaginationDrift{
+		Page:                   curPage,
+		PreviousTotalResources: previousTotal,
+		TotalResources:         scanResp.Page.TotalResources,
+	})
+	n.StatFn(ctx).Count("scanfetcher.pagedrift", 1)
+}
+
+// overlaps reports whether any of the resources have already been seen on an earlier page.
+func overlaps(resources []resource, seen map[int]bool) bool {
+	for _, resource := range resources {
+		if seen[resource.ScanID] {
+			return true
+		}
+	}
+	return false
+}
+
+// crossesTimestamp reports whether a page contains a scan outside the valid time range,
+// using the same checks as the crawl so that the crawl would stop within the page.
+func (n *NexposeClient) crossesTimestamp(scanResp nexposeScanResponse, start time.Time, cutoff time.Time) bool {
+	for _, resource := range scanResp.Resources {
+		if _, err := n.scanResourceToCompletedScan(resource, start, cutoff); err != nil {
+			if _, ok := err.(outOfRangeError); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *NexposeClient) parallelism() int {
+	if n.Parallelism < 1 {
+		return 1
+	}
+	return n.Parallelism
+}
+
+// deadlineExceeded reports whether a deadline is set and has passed.
+func deadlineExceeded(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// lowerLastPage atomically sets lastPage to page if page is lower.
+func lowerLastPage(lastPage *int64, page int64) {
+	for {
+		current := atomic.LoadInt64(lastPage)
+		if page >= current || atomic.CompareAndSwapInt64(lastPage, current, page) {
+			return
+		}
+	}
+}
+
+// pendingInFlightScans returns the sorted IDs of the scans which are still in flight,
+// or nil when they are the same as those tracked before this run.
+func pendingInFlightScans(tracked []string, inFlight map[string]bool) []string {
+	scanIDs := make([]string, 0, len(inFlight))
+	for scanID := range inFlight {
+		scanIDs = append(scanIDs, scanID)
+	}
+	sort.Strings(scanIDs)
+
+	previous := append([]string(nil), tracked...)
+	sort.Strings(previous)
+	if len(previous) != len(scanIDs) {
+		return scanIDs
+	}
+	for offset := range previous {
+		if previous[offset] != scanIDs[offset] {
+			return scanIDs
+		}
+	}
+	return nil
+}
+
+// quarantineScan records a scan resource which could not be parsed, so that a single
+// malformed record does not prevent all other scans from being produced. The original
+// error is returned instead when running in strict mode.
+func (n *NexposeClient) quarantineScan(ctx context.Context, resource resource, malformedErr malformedScanError) error {
+	if n.Strict {
+		return malformedErr
+	}
+
+	record, _ := json.Marshal(resource)
+	n.LogFn(ctx).Warn(logs.MalformedScanQuarantined{
+		ScanID:   malformedErr.ScanID,
+		ScanName: malformedErr.ScanName,
+		SiteID:   malformedErr.SiteID,
+		Field:    malformedErr.Field,
+		Reason:   malformedErr.Reason,
+		Record:   string(record),
+	})
+	n.StatFn(ctx).Count("scanfetcher.quarantined", 1)
+
+	if !n.StoreQuarantined || n.ScanQuarantiner == nil {
+		return nil
+	}
+	return n.ScanQuarantiner.QuarantineScan(ctx, domain.QuarantinedScan{
+		ScanID:        malformedErr.ScanID,
+		SiteID:        malformedErr.SiteID,
+		Reason:        malformedErr.Error(),
+		Record:        string(record),
+		QuarantinedAt: time.Now(),
+	})
+}
+
+func isInFlightScanStatus(status string) bool {
+	for _, inFlightStatus := range inFlightScanStatuses {
+		if strings.EqualFold(status, inFlightStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *NexposeClient) makePagedNexposeScanRequest(ctx context.Context, page int) (nexposeScanResponse, error) {
+	q := url.Values{}
 	q.Set(activeQueryParam, "false")
 	q.Set(pageQueryParam, strconv.Itoa(page))
 	q.Set(sizeQueryParam, strconv.Itoa(n.PageSize))
 	q.Set(sortQueryParam, sortQueryValue)
-	u.RawQuery = q.Encode()
+	return n.makeScanPageRequest(ctx, q)
+}
+
+func (n *NexposeClient) makeScanPageRequest(ctx context.Context, q url.Values) (nexposeScanResponse, error) {
+	u, _ := url.Parse(n.Endpoint.String())
+	u.Path = path.Join(u.Path, "api", "3", "scans")
+	query := u.Query()
+	for key, values := range q {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
 
 	req, _ := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
 	req.Header.Set("Content-Type", "application/json")
-	res, err := n.Client.Do(req)
+	res, err := n.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return nexposeScanResponse{}, err
+		return nexposeScanResponse{}, requestError(ctx, err)
 	}
 	defer res.Body.Close()
 
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nexposeScanResponse{}, err
-	}
-
 	if res.StatusCode != http.StatusOK {
-		return nexposeScanResponse{}, fmt.Errorf("unexpected response from nexpose scans api: %d %s",
-			res.StatusCode, string(body))
+		return nexposeScanResponse{}, responseError("scans", res)
 	}
 
+	return decodeScanResponse(res.Body)
+}
+
+// decodeScanResponse decodes a page of scans from body one resource at a time, so
+// that only a single resource of the raw response is held in memory at once rather
+// than the entire page.
+func decodeScanResponse(body io.Reader) (nexposeScanResponse, error) {
 	var scanResp nexposeScanResponse
-	err = json.Unmarshal(body, &scanResp)
-	if err != nil {
+	decoder := json.NewDecoder(body)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nexposeScanResponse{}, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nexposeScanResponse{}, err
+		}
+		switch token {
+		case "page":
+			err = decoder.Decode(&scanResp.Page)
+		case "resources":
+			scanResp.Resources, err = decodeResources(decoder)
+		default:
+			// skip fields such as links without decoding them
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return nexposeScanResponse{}, err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
 		return nexposeScanResponse{}, err
 	}
 	return scanResp, nil
 }
 
-func (n *NexposeClient) scanResourceToCompletedScan(resource resource, start time.Time) (domain.CompletedScan, error) {
+func decodeResources(decoder *json.Decoder) ([]resource, error) {
+	token, err := decoder.Token()
+	if err != nil || token == nil {
+		// a null list of resources is empty
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected resources to be an array, found %v", token)
+	}
+	var resources []resource
+	for decoder.More() {
+		var resource resource
+		if err := decoder.Decode(&resource); err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, expected json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %v in scans response, found %v", expected, token)
+	}
+	return nil
+}
+
+func (n *NexposeClient) makeNexposeScanRequest(ctx context.Context, scanID string) (resource, error) {
+	u, _ := url.Parse(n.Endpoint.String())
+	u.Path = path.Join(u.Path, "api", "3", "scans", scanID)
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return resource{}, requestError(ctx, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return resource{}, scanNotFoundError{ScanID: scanID}
+	}
+	if res.StatusCode != http.StatusOK {
+		return resource{}, responseError("scan", res)
+	}
+
+	var scan resource
+	if err := json.NewDecoder(res.Body).Decode(&scan); err != nil {
+		return resource{}, err
+	}
+	return scan, nil
+}
+
+func (n *NexposeClient) scanResourceToCompletedScan(resource resource, start time.Time,
+	cutoff time.Time) (domain.CompletedScan, error) {
 	// skip scans that have not finished
 	if !strings.EqualFold(resource.Status, finishedScanStatus) {
 		return domain.CompletedScan{}, scanNotFinishedError{
@@ -176,7 +691,25 @@ func (n *NexposeClient) scanResourceToCompletedScan(resource resource, start tim
 	// extract scan end time from scan resource
 	endTime, err := time.Parse(time.RFC3339Nano, resource.EndTime)
 	if err != nil {
-		return domain.CompletedScan{}, err
+		return domain.CompletedScan{}, malformedScanError{
+			ScanID:   strconv.Itoa(resource.ScanID),
+			ScanName: resource.ScanName,
+			SiteID:   strconv.Itoa(resource.SiteID),
+			Field:    "endTime",
+			Reason:   err.Error(),
+		}
+	}
+
+	// skip scans which ended too recently for Nexpose to have finished
+	// recording them; a zero cutoff means no settle window is configured
+	if !cutoff.IsZero() && endTime.After(cutoff) {
+		return domain.CompletedScan{}, scanNotSettledError{
+			ScanID:   strconv.Itoa(resource.ScanID),
+			ScanName: resource.ScanName,
+			SiteID:   strconv.Itoa(resource.SiteID),
+			Cutoff:   cutoff,
+			ScanTime: endTime,
+		}
 	}
 
 	// scans are fetched sorted by end time in descending order,
@@ -195,13 +728,22 @@ func (n *NexposeClient) scanResourceToCompletedScan(resource resource, start tim
 	// extract scan end time from scan resource
 	startTime, err := time.Parse(time.RFC3339Nano, resource.StartTime)
 	if err != nil {
-		return domain.CompletedScan{}, err
+		return domain.CompletedScan{}, malformedScanError{
+			ScanID:   strconv.Itoa(resource.ScanID),
+			ScanName: resource.ScanName,
+			SiteID:   strconv.Itoa(resource.SiteID),
+			Field:    "startTime",
+			Reason:   err.Error(),
+		}
 	}
 
 	return domain.CompletedScan{
+		Console:   n.Console,
 		SiteID:    strconv.Itoa(resource.SiteID),
 		ScanID:    strconv.Itoa(resource.ScanID),
 		ScanType:  resource.ScanType,
+		ScanName:  resource.ScanName,
+		Status:    finishedScanStatus,
 		StartTime: startTime,
 		EndTime:   endTime,
 	}, nil
@@ -216,11 +758,11 @@ func (n *NexposeClient) CheckDependencies(ctx context.Context) error {
 	req, _ := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
 	res, err := n.Client.Do(req)
 	if err != nil {
-		return err
+		return requestError(ctx, err)
 	}
 	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("Nexpose unexpectedly returned non-200 response code: %d attempting to GET: %s. ", res.StatusCode, u.String())
+	if res.StatusCode != http.StatusOK {
+		return responseError("dependency check", res)
 	}
 
 	return nil
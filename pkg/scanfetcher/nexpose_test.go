@@ -3,14 +3,19 @@ package scanfetcher
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	http "net/http"
 	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/circuitbreaker"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	gomock "github.com/golang/mock/gomock"
@@ -34,17 +39,17 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 					"startTime": "%s",
 					"endTime": "%s",
 					"scanType": "Scheduled",
-					"id": 1001,
-					"scanName": "%s",
+					"id": 100%[5]d,
+					"scanName": "%[3]s",
 					"siteId": 1,
-					"status": "%s"
+					"status": "%[4]s"
 				}
 			],
 			"page": {
-				"number": %d,
+				"number": %[5]d,
 				"size": 1,
 				"totalResources": 3,
-				"totalPages": %d
+				"totalPages": %[6]d
 			}
 		}`
 
@@ -71,7 +76,9 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 					StartTime: afterTimestamp.Add(time.Second * -10),
 					EndTime:   afterTimestamp,
 					ScanType:  "Scheduled",
-					ScanID:    "1001",
+					ScanName:  "Allowed Scan",
+					Status:    finishedScanStatus,
+					ScanID:    "1000",
 					SiteID:    "1",
 				},
 			},
@@ -105,6 +112,8 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 					StartTime: afterTimestamp.Add(time.Second * -10),
 					EndTime:   afterTimestamp,
 					ScanType:  "Scheduled",
+					ScanName:  "Allowed Scan",
+					Status:    finishedScanStatus,
 					ScanID:    "1001",
 					SiteID:    "1",
 				},
@@ -139,6 +148,8 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 					StartTime: afterTimestamp.Add(time.Second * -10),
 					EndTime:   afterTimestamp,
 					ScanType:  "Scheduled",
+					ScanName:  "Allowed Scan",
+					Status:    finishedScanStatus,
 					ScanID:    "1001",
 					SiteID:    "1",
 				},
@@ -173,7 +184,9 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 					StartTime: afterTimestamp.Add(time.Second * -10),
 					EndTime:   afterTimestamp,
 					ScanType:  "Scheduled",
-					ScanID:    "1001",
+					ScanName:  "Allowed Scan",
+					Status:    finishedScanStatus,
+					ScanID:    "1000",
 					SiteID:    "1",
 				},
 			},
@@ -288,11 +301,15 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(tt.responses[offset], tt.responseErrs[offset])
 			}
 			nexposeClient := &NexposeClient{
+				LogFn:         testLogFn,
+				StatFn:        testStatFn,
 				Client:        &http.Client{Transport: mockRT},
 				Endpoint:      endpoint,
 				ScanBlocklist: &container.StringContainer{"Blocked Scan": struct{}{}},
+				Strict:        true,
 			}
-			actual, err := nexposeClient.FetchScans(context.Background(), timestamp)
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+			actual := result.Scans
 			require.Equal(t, tt.expected, actual)
 			if tt.expectErr {
 				require.Error(t, err)
@@ -303,6 +320,643 @@ func TestNexposeClient_FetchScans(t *testing.T) {
 	}
 }
 
+func TestNexposeClient_FetchScansSettleWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	now := time.Now()
+	timestamp := now.Add(-1 * time.Hour)
+	testScanResponse := `
+		{
+			"resources": [
+				{
+					"startTime": "%s",
+					"endTime": "%s",
+					"scanType": "Scheduled",
+					"id": %d,
+					"scanName": "Allowed Scan",
+					"siteId": 1,
+					"status": "finished"
+				}
+			],
+			"page": {
+				"number": %d,
+				"size": 1,
+				"totalResources": 3,
+				"totalPages": 3
+			}
+		}`
+	endTimes := []time.Time{
+		now.Add(-1 * time.Minute),
+		now.Add(-20 * time.Minute),
+		timestamp.Add(-1 * time.Minute),
+	}
+
+	mockRT := NewMockRoundTripper(ctrl)
+	for offset, endTime := range endTimes {
+		mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{
+			Body: ioutil.NopCloser(bytes.NewBuffer([]byte(fmt.Sprintf(testScanResponse,
+				endTime.Add(time.Second*-10).Format(time.RFC3339Nano),
+				endTime.Format(time.RFC3339Nano), 1000+offset, offset)))),
+			StatusCode: http.StatusOK,
+		}, nil)
+	}
+	nexposeClient := &NexposeClient{
+		LogFn:         testLogFn,
+		StatFn:        testStatFn,
+		Client:        &http.Client{Transport: mockRT},
+		Endpoint:      endpoint,
+		ScanBlocklist: &container.StringContainer{},
+		SettleWindow:  10 * time.Minute,
+	}
+	result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+	actual := result.Scans
+	require.Nil(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "1001", actual[0].ScanID)
+	require.True(t, actual[0].EndTime.Equal(endTimes[1]))
+	require.Equal(t, 3, result.Pages)
+	require.Equal(t, domain.SkippedScans{NotSettled: 1, OutOfRange: 1}, result.Skipped)
+}
+
+func TestNexposeClient_FetchScansSkipped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	now := time.Now()
+	timestamp := now.Add(-1 * time.Hour)
+	scan := `{
+		"startTime": "%s",
+		"endTime": "%s",
+		"scanType": "Scheduled",
+		"id": %d,
+		"scanName": "%s",
+		"siteId": 1,
+		"status": "%s"
+	}`
+	resources := []string{
+		fmt.Sprintf(scan, now.Add(-2*time.Minute).Format(time.RFC3339Nano), now.Add(-1*time.Minute).Format(time.RFC3339Nano), 1, "Allowed Scan", "running"),
+		fmt.Sprintf(scan, now.Add(-3*time.Minute).Format(time.RFC3339Nano), now.Add(-2*time.Minute).Format(time.RFC3339Nano), 2, "BadScan1", "finished"),
+		fmt.Sprintf(scan, now.Add(-4*time.Minute).Format(time.RFC3339Nano), "not a time", 3, "Allowed Scan", "finished"),
+		fmt.Sprintf(scan, now.Add(-5*time.Minute).Format(time.RFC3339Nano), now.Add(-4*time.Minute).Format(time.RFC3339Nano), 4, "Allowed Scan", "finished"),
+		fmt.Sprintf(scan, timestamp.Add(-2*time.Minute).Format(time.RFC3339Nano), timestamp.Add(-1*time.Minute).Format(time.RFC3339Nano), 5, "Allowed Scan", "finished"),
+	}
+	body := fmt.Sprintf(`{"resources": [%s], "page": {"number": 0, "size": 5, "totalResources": 5, "totalPages": 1}}`,
+		strings.Join(resources, ","))
+
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	nexposeClient := &NexposeClient{
+		LogFn:         testLogFn,
+		StatFn:        testStatFn,
+		Client:        &http.Client{Transport: mockRT},
+		Endpoint:      endpoint,
+		ScanBlocklist: container.NewStringContainer([]string{"BadScan1"}),
+	}
+	result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+	require.NoError(t, err)
+	require.Len(t, result.Scans, 1)
+	require.Equal(t, "4", result.Scans[0].ScanID)
+	require.Equal(t, 1, result.Pages)
+	require.Equal(t, domain.SkippedScans{
+		NotFinished: 1,
+		Blocklisted: 1,
+		Malformed:   1,
+		OutOfRange:  1,
+	}, result.Skipped)
+}
+
+func TestNexposeClient_FetchScansParallel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	testScanResponse := `
+		{
+			"resources": [
+				{
+					"startTime": "%s",
+					"endTime": "%s",
+					"scanType": "Scheduled",
+					"id": %d,
+					"scanName": "Allowed Scan",
+					"siteId": 1,
+					"status": "finished"
+				}
+			],
+			"page": {
+				"number": %d,
+				"size": 1,
+				"totalResources": %d,
+				"totalPages": %d
+			}
+		}`
+
+	tests := []struct {
+		name          string
+		pages         int
+		crossingPage  int
+		failingPage   int
+		expectedIDs   []string
+		expectedPages []int
+		expectErr     bool
+	}{
+		{
+			name:         "pages merged in order",
+			pages:        6,
+			crossingPage: 4,
+			failingPage:  -1,
+			expectedIDs:  []string{"1000", "1001", "1002", "1003"},
+		},
+		{
+			name:         "all pages within range",
+			pages:        5,
+			crossingPage: -1,
+			failingPage:  -1,
+			expectedIDs:  []string{"1000", "1001", "1002", "1003", "1004"},
+		},
+		{
+			name:          "first page crosses timestamp",
+			pages:         6,
+			crossingPage:  0,
+			failingPage:   -1,
+			expectedPages: []int{0},
+		},
+		{
+			name:         "error fetching a later page",
+			pages:        6,
+			crossingPage: -1,
+			failingPage:  2,
+			expectErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var requested []int
+			mockRT := NewMockRoundTripper(ctrl)
+			mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				curPage, _ := strconv.Atoi(req.URL.Query().Get(pageQueryParam))
+				lock.Lock()
+				requested = append(requested, curPage)
+				lock.Unlock()
+
+				// later pages respond first, so that responses arrive out of order
+				time.Sleep(time.Duration(tt.pages-curPage) * time.Millisecond)
+				if curPage == tt.failingPage {
+					return &http.Response{
+						Body:       ioutil.NopCloser(bytes.NewBufferString("unavailable")),
+						StatusCode: http.StatusServiceUnavailable,
+					}, nil
+				}
+				endTime := timestamp.Add(time.Duration(tt.pages-curPage) * time.Hour)
+				if tt.crossingPage >= 0 && curPage >= tt.crossingPage {
+					endTime = timestamp.Add(time.Duration(-1-curPage) * time.Hour)
+				}
+				return &http.Response{
+					Body: ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf(testScanResponse,
+						endTime.Add(-1*time.Minute).Format(time.RFC3339Nano), endTime.Format(time.RFC3339Nano),
+						1000+curPage, curPage, tt.pages, tt.pages))),
+					StatusCode: http.StatusOK,
+				}, nil
+			}).AnyTimes()
+
+			nexposeClient := &NexposeClient{
+				LogFn:         testLogFn,
+				StatFn:        testStatFn,
+				Client:        &http.Client{Transport: mockRT},
+				Endpoint:      endpoint,
+				ScanBlocklist: &container.StringContainer{},
+				Parallelism:   3,
+				Strict:        true,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+			actual := result.Scans
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+
+			var actualIDs []string
+			for _, scan := range actual {
+				actualIDs = append(actualIDs, scan.ScanID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs)
+			if tt.expectedPages != nil {
+				lock.Lock()
+				require.Equal(t, tt.expectedPages, requested)
+				lock.Unlock()
+			}
+		})
+	}
+}
+
+// finishedTransport reports each round trip once the wrapped transport has returned.
+type finishedTransport struct {
+	Wrapped  http.RoundTripper
+	Finished chan int
+}
+
+func (t *finishedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Wrapped.RoundTrip(req)
+	curPage, _ := strconv.Atoi(req.URL.Query().Get(pageQueryParam))
+	t.Finished <- curPage
+	return res, err
+}
+
+func TestNexposeClient_FetchScansParallelStopsEarly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	scanPage := func(number int, endTime time.Time) *http.Response {
+		body, _ := json.Marshal(nexposeScanResponse{
+			Page: page{Number: number, Size: 1, TotalResources: 3, TotalPages: 3},
+			Resources: []resource{{
+				ScanID:    1000 + number,
+				SiteID:    1,
+				ScanType:  "Scheduled",
+				StartTime: endTime.Add(-1 * time.Minute).Format(time.RFC3339Nano),
+				EndTime:   endTime.Format(time.RFC3339Nano),
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+			}},
+		})
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewBuffer(body)), StatusCode: http.StatusOK}
+	}
+
+	// the last page is still being requested when the second page crosses the timestamp
+	lastPageRequested := make(chan struct{})
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		switch curPage, _ := strconv.Atoi(req.URL.Query().Get(pageQueryParam)); curPage {
+		case 0:
+			return scanPage(0, timestamp.Add(time.Hour)), nil
+		case 1:
+			<-lastPageRequested
+			return scanPage(1, timestamp.Add(-1*time.Hour)), nil
+		default:
+			close(lastPageRequested)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+	}).Times(3)
+
+	breaker := &circuitbreaker.Breaker{
+		Dependency:       "nexpose",
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+		LogFn:            testLogFn,
+		StatFn:           testStatFn,
+	}
+	transport := &finishedTransport{Wrapped: circuitbreaker.Wrap(mockRT, breaker), Finished: make(chan int, 3)}
+	nexposeClient := &NexposeClient{
+		LogFn:          testLogFn,
+		StatFn:         testStatFn,
+		Client:         &http.Client{Transport: transport},
+		CircuitBreaker: breaker,
+		Endpoint:       endpoint,
+		ScanBlocklist:  &container.StringContainer{},
+		Parallelism:    2,
+	}
+	result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+	require.Nil(t, err)
+	require.Len(t, result.Scans, 1)
+
+	// the request cancelled when the crawl stopped is not a failure of Nexpose
+	for finished := 0; finished < 3; finished = finished + 1 {
+		<-transport.Finished
+	}
+	require.Equal(t, circuitbreaker.StateClosed, breaker.CircuitBreakerState().State)
+}
+
+func TestNexposeClient_FetchScansPaginationDrift(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	// scans are numbered so that higher IDs ended more recently, and IDs up
+	// to 1002 ended after the timestamp
+	scanPage := func(number int, total int, ids ...int) *http.Response {
+		scanResp := nexposeScanResponse{
+			Page: page{Number: number, Size: 2, TotalResources: total, TotalPages: (total + 1) / 2},
+		}
+		for _, id := range ids {
+			endTime := timestamp.Add(time.Duration(id-1001) * time.Hour)
+			scanResp.Resources = append(scanResp.Resources, resource{
+				ScanID:    id,
+				SiteID:    1,
+				ScanType:  "Scheduled",
+				StartTime: endTime.Add(-1 * time.Minute).Format(time.RFC3339Nano),
+				EndTime:   endTime.Format(time.RFC3339Nano),
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+			})
+		}
+		body, _ := json.Marshal(scanResp)
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewBuffer(body)), StatusCode: http.StatusOK}
+	}
+
+	tests := []struct {
+		name         string
+		responses    []*http.Response
+		responseErrs []error
+		expectedIDs  []string
+		expectErr    bool
+	}{
+		{
+			name: "no drift",
+			responses: []*http.Response{
+				scanPage(0, 6, 1005, 1004),
+				scanPage(1, 6, 1003, 1002),
+				scanPage(2, 6, 1001, 1000),
+			},
+			responseErrs: []error{nil, nil, nil},
+			expectedIDs:  []string{"1005", "1004", "1003", "1002"},
+		},
+		{
+			name: "scan finished while crawling",
+			responses: []*http.Response{
+				scanPage(0, 4, 1005, 1004),
+				scanPage(1, 5, 1004, 1003),
+				scanPage(2, 5, 1002),
+			},
+			responseErrs: []error{nil, nil, nil},
+			expectedIDs:  []string{"1005", "1004", "1003", "1002"},
+		},
+		{
+			name: "scan removed while crawling",
+			responses: []*http.Response{
+				scanPage(0, 5, 1005, 1004),
+				scanPage(1, 4, 1002, 1001),
+				scanPage(0, 4, 1004, 1003),
+			},
+			responseErrs: []error{nil, nil, nil},
+			expectedIDs:  []string{"1005", "1004", "1003", "1002"},
+		},
+		{
+			name: "error re-requesting page",
+			responses: []*http.Response{
+				scanPage(0, 5, 1005, 1004),
+				scanPage(1, 4, 1002, 1001),
+				nil,
+			},
+			responseErrs: []error{nil, nil, fmt.Errorf("HTTPError")},
+			expectErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			var calls []*gomock.Call
+			for offset := range tt.responses {
+				calls = append(calls, mockRT.EXPECT().RoundTrip(gomock.Any()).Return(tt.responses[offset], tt.responseErrs[offset]))
+			}
+			gomock.InOrder(calls...)
+			nexposeClient := &NexposeClient{
+				Client:        &http.Client{Transport: mockRT},
+				Endpoint:      endpoint,
+				ScanBlocklist: &container.StringContainer{},
+				LogFn:         testLogFn,
+				StatFn:        testStatFn,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+			actual := result.Scans
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+
+			var actualIDs []string
+			for _, scan := range actual {
+				actualIDs = append(actualIDs, scan.ScanID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs)
+		})
+	}
+}
+
+func TestNexposeClient_FetchScansMaxScans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	directory, err := ioutil.TempDir("", "nexpose")
+	require.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	scanPage := func(number int, ids ...int) *http.Response {
+		scanResp := nexposeScanResponse{
+			Page: page{Number: number, Size: 2, TotalResources: 6, TotalPages: 3},
+		}
+		for _, id := range ids {
+			endTime := timestamp.Add(time.Duration(id-1001) * time.Hour)
+			scanResp.Resources = append(scanResp.Resources, resource{
+				ScanID:    id,
+				SiteID:    1,
+				ScanType:  "Scheduled",
+				StartTime: endTime.Add(-1 * time.Minute).Format(time.RFC3339Nano),
+				EndTime:   endTime.Format(time.RFC3339Nano),
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+			})
+		}
+		body, _ := json.Marshal(scanResp)
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewBuffer(body)), StatusCode: http.StatusOK}
+	}
+
+	tests := []struct {
+		name        string
+		maxScans    int
+		limit       int
+		directory   string
+		expectedIDs []string
+		truncated   bool
+	}{
+		{
+			name:        "no limit",
+			expectedIDs: []string{"1002", "1003", "1004", "1005", "1006"},
+		},
+		{
+			name:        "oldest scans in memory",
+			maxScans:    3,
+			expectedIDs: []string{"1002", "1003", "1004"},
+			truncated:   true,
+		},
+		{
+			name:        "oldest scans on disk",
+			maxScans:    3,
+			directory:   directory,
+			expectedIDs: []string{"1002", "1003", "1004"},
+			truncated:   true,
+		},
+		{
+			name:        "requested limit",
+			limit:       2,
+			expectedIDs: []string{"1002", "1003"},
+			truncated:   true,
+		},
+		{
+			name:        "requested limit above configured maximum",
+			maxScans:    3,
+			limit:       4,
+			expectedIDs: []string{"1002", "1003", "1004"},
+			truncated:   true,
+		},
+		{
+			name:        "limit not reached",
+			maxScans:    5,
+			expectedIDs: []string{"1002", "1003", "1004", "1005", "1006"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			gomock.InOrder(
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(scanPage(0, 1006, 1005), nil),
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(scanPage(1, 1004, 1003), nil),
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(scanPage(2, 1002, 1001), nil),
+			)
+			nexposeClient := &NexposeClient{
+				Client:         &http.Client{Transport: mockRT},
+				Endpoint:       endpoint,
+				ScanBlocklist:  &container.StringContainer{},
+				MaxScans:       tt.maxScans,
+				SpillDirectory: tt.directory,
+				LogFn:          testLogFn,
+				StatFn:         testStatFn,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp, Limit: tt.limit})
+			require.Nil(t, err)
+			require.Equal(t, tt.directory != "", result.Spilled != nil)
+			actual, err := domain.ReadScans(result.Reader())
+			require.Nil(t, err)
+
+			var actualIDs []string
+			for _, scan := range actual {
+				actualIDs = append(actualIDs, scan.ScanID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs)
+			require.Equal(t, tt.truncated, result.Truncated)
+		})
+	}
+}
+
+func TestNexposeClient_FetchScansDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	scanPage := func(number int, ids ...int) *http.Response {
+		scanResp := nexposeScanResponse{
+			Page: page{Number: number, Size: 2, TotalResources: 4, TotalPages: 2},
+		}
+		for _, id := range ids {
+			endTime := timestamp.Add(time.Duration(id-1000) * time.Hour)
+			scanResp.Resources = append(scanResp.Resources, resource{
+				ScanID:    id,
+				SiteID:    1,
+				ScanType:  "Scheduled",
+				StartTime: endTime.Add(-1 * time.Minute).Format(time.RFC3339Nano),
+				EndTime:   endTime.Format(time.RFC3339Nano),
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+			})
+		}
+		body, _ := json.Marshal(scanResp)
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewBuffer(body)), StatusCode: http.StatusOK}
+	}
+
+	tests := []struct {
+		name        string
+		deadline    time.Time
+		pages       int
+		expectedIDs []string
+		inFlight    []string
+		incomplete  bool
+	}{
+		{
+			name:        "no deadline",
+			pages:       2,
+			expectedIDs: []string{"1001", "1002", "1003", "1004"},
+			inFlight:    []string{},
+		},
+		{
+			name:        "deadline not reached",
+			deadline:    time.Now().Add(time.Hour),
+			pages:       2,
+			expectedIDs: []string{"1001", "1002", "1003", "1004"},
+			inFlight:    []string{},
+		},
+		{
+			name:       "deadline passed while crawling",
+			deadline:   time.Now(),
+			pages:      1,
+			incomplete: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			calls := []*gomock.Call{
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(scanPage(0, 1004, 1003), nil),
+			}
+			if tt.pages > 1 {
+				calls = append(calls, mockRT.EXPECT().RoundTrip(gomock.Any()).Return(scanPage(1, 1002, 1001), nil))
+			}
+			gomock.InOrder(calls...)
+			// the scan in flight stays tracked unless the crawl completes
+			mockInFlight := NewMockInFlightScanFetcher(ctrl)
+			mockInFlight.EXPECT().FetchInFlightScans(gomock.Any()).Return([]string{"1002"}, nil)
+			nexposeClient := &NexposeClient{
+				Client:              &http.Client{Transport: mockRT},
+				Endpoint:            endpoint,
+				ScanBlocklist:       &container.StringContainer{},
+				InFlightScanFetcher: mockInFlight,
+				LogFn:               testLogFn,
+				StatFn:              testStatFn,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp, Deadline: tt.deadline})
+			require.Nil(t, err)
+			actual, err := domain.ReadScans(result.Reader())
+			require.Nil(t, err)
+
+			var actualIDs []string
+			for _, scan := range actual {
+				actualIDs = append(actualIDs, scan.ScanID)
+			}
+			require.Equal(t, tt.expectedIDs, actualIDs)
+			require.Equal(t, tt.incomplete, result.Incomplete)
+			require.Equal(t, tt.pages, result.Pages)
+			require.Equal(t, tt.inFlight, result.InFlight)
+		})
+	}
+}
+
+func TestLowerLastPage(t *testing.T) {
+	lastPage := int64(5)
+	lowerLastPage(&lastPage, 3)
+	require.Equal(t, int64(3), lastPage)
+	lowerLastPage(&lastPage, 4)
+	require.Equal(t, int64(3), lastPage)
+}
+
 type errReader struct {
 	Error error
 }
@@ -412,7 +1066,7 @@ func TestNexposeClient_makePagedNexposeScanRequest(t *testing.T) {
 				Endpoint:      endpoint,
 				ScanBlocklist: &container.StringContainer{"": struct{}{}},
 			}
-			actual, err := nexposeClient.makePagedNexposeScanRequest(0)
+			actual, err := nexposeClient.makePagedNexposeScanRequest(context.Background(), 0)
 			require.Equal(t, tt.expected, actual)
 			if tt.expectErr {
 				require.Error(t, err)
@@ -423,6 +1077,61 @@ func TestNexposeClient_makePagedNexposeScanRequest(t *testing.T) {
 	}
 }
 
+func TestDecodeScanResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		expected  nexposeScanResponse
+		expectErr bool
+	}{
+		{
+			name: "resources before page with links",
+			body: `{"links": [{"href": "http://localhost", "rel": "self"}],
+				"resources": [{"id": 1, "siteId": 10, "status": "finished"}, {"id": 2, "siteId": 10, "status": "running"}],
+				"page": {"number": 0, "size": 2, "totalResources": 2, "totalPages": 1}}`,
+			expected: nexposeScanResponse{
+				Page:      page{Number: 0, Size: 2, TotalResources: 2, TotalPages: 1},
+				Resources: []resource{{ScanID: 1, SiteID: 10, Status: "finished"}, {ScanID: 2, SiteID: 10, Status: "running"}},
+			},
+		},
+		{
+			name:     "null resources",
+			body:     `{"resources": null, "page": {"number": 0, "size": 2}}`,
+			expected: nexposeScanResponse{Page: page{Number: 0, Size: 2}},
+		},
+		{
+			name:      "resources not an array",
+			body:      `{"resources": {"id": 1}}`,
+			expectErr: true,
+		},
+		{
+			name:      "not an object",
+			body:      `[]`,
+			expectErr: true,
+		},
+		{
+			name:      "malformed resource",
+			body:      `{"resources": [{"id": "one"}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "truncated",
+			body:      `{"resources": [{"id": 1}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := decodeScanResponse(bytes.NewBufferString(tt.body))
+			require.Equal(t, tt.expectErr, err != nil)
+			if !tt.expectErr {
+				require.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
 func TestScanResourceToCompletedScan(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -435,6 +1144,7 @@ func TestScanResourceToCompletedScan(t *testing.T) {
 	tests := []struct {
 		name     string
 		resource resource
+		cutoff   time.Time
 		expected domain.CompletedScan
 		err      error
 	}{
@@ -446,12 +1156,15 @@ func TestScanResourceToCompletedScan(t *testing.T) {
 				ScanID:    1001,
 				SiteID:    1,
 				ScanType:  "Agent",
+				ScanName:  "Agent Scan",
 				Status:    finishedScanStatus,
 			},
 			expected: domain.CompletedScan{
 				SiteID:    strconv.Itoa(1),
 				ScanID:    strconv.Itoa(1001),
 				ScanType:  "Agent",
+				ScanName:  "Agent Scan",
+				Status:    finishedScanStatus,
 				StartTime: afterStart.Add(time.Second * -10),
 				EndTime:   afterStart,
 			},
@@ -535,6 +1248,41 @@ func TestScanResourceToCompletedScan(t *testing.T) {
 			expected: domain.CompletedScan{},
 			err:      scanNameInBlocklistError{},
 		},
+		{
+			name: "scan within settle window",
+			resource: resource{
+				StartTime: afterStart.Add(time.Second * -10).Format(time.RFC3339Nano),
+				EndTime:   afterStart.Format(time.RFC3339Nano),
+				ScanID:    1001,
+				ScanType:  "Agent",
+				SiteID:    1,
+				Status:    finishedScanStatus,
+			},
+			cutoff:   afterStart.Add(time.Second * -1),
+			expected: domain.CompletedScan{},
+			err:      scanNotSettledError{},
+		},
+		{
+			name: "scan outside settle window",
+			resource: resource{
+				StartTime: afterStart.Add(time.Second * -10).Format(time.RFC3339Nano),
+				EndTime:   afterStart.Format(time.RFC3339Nano),
+				ScanID:    1001,
+				ScanType:  "Agent",
+				SiteID:    1,
+				Status:    finishedScanStatus,
+			},
+			cutoff: afterStart,
+			expected: domain.CompletedScan{
+				SiteID:    strconv.Itoa(1),
+				ScanID:    strconv.Itoa(1001),
+				ScanType:  "Agent",
+				Status:    finishedScanStatus,
+				StartTime: afterStart.Add(time.Second * -10),
+				EndTime:   afterStart,
+			},
+			err: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -545,7 +1293,7 @@ func TestScanResourceToCompletedScan(t *testing.T) {
 				Endpoint:      endpoint,
 				ScanBlocklist: &container.StringContainer{"Blocked Scan": struct{}{}},
 			}
-			actual, err := nexposeClient.scanResourceToCompletedScan(tt.resource, start)
+			actual, err := nexposeClient.scanResourceToCompletedScan(tt.resource, start, tt.cutoff)
 			require.Equal(t, tt.expected, actual)
 			if tt.err != nil {
 				require.Error(t, err)
@@ -591,3 +1339,301 @@ func TestNexposeDependencyCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestNexposeClient_FetchScansInFlight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	afterTimestamp := time.Date(2019, 05, 25, 00, 00, 00, 00, time.UTC)
+	beforeTimestamp := time.Date(2019, 05, 23, 00, 00, 00, 00, time.UTC)
+	testScan := `
+				{
+					"startTime": "%s",
+					"endTime": "%s",
+					"scanType": "Scheduled",
+					"id": %d,
+					"scanName": "Allowed Scan",
+					"siteId": 1,
+					"status": "%s"
+				}`
+	testScanResponse := `
+		{
+			"resources": [%s],
+			"page": {
+				"number": %d,
+				"size": 1,
+				"totalResources": 2,
+				"totalPages": 2
+			}
+		}`
+	scanJSON := func(id int, endTime time.Time, status string) string {
+		return fmt.Sprintf(testScan, endTime.Add(time.Second*-10).Format(time.RFC3339Nano),
+			endTime.Format(time.RFC3339Nano), id, status)
+	}
+	response := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer([]byte(body))),
+			StatusCode: statusCode,
+		}
+	}
+	crawled := domain.CompletedScan{
+		ScanID:    "1001",
+		SiteID:    "1",
+		ScanType:  "Scheduled",
+		ScanName:  "Allowed Scan",
+		Status:    finishedScanStatus,
+		StartTime: afterTimestamp.Add(time.Second * -10),
+		EndTime:   afterTimestamp,
+	}
+
+	tests := []struct {
+		name           string
+		tracked        []string
+		trackedErr     error
+		recheck        []string
+		rechecks       []*http.Response
+		expectInFlight []string
+		expected       []domain.CompletedScan
+		expectErr      bool
+	}{
+		{
+			name:           "new in flight scan is tracked",
+			tracked:        []string{},
+			expectInFlight: []string{"1002"},
+			expected:       []domain.CompletedScan{crawled},
+		},
+		{
+			name:     "tracked scan seen in flight again is unchanged",
+			tracked:  []string{"1002"},
+			expected: []domain.CompletedScan{crawled},
+		},
+		{
+			name:    "tracked scan which finished before the timestamp is produced",
+			tracked: []string{"1002", "900"},
+			rechecks: []*http.Response{
+				response(http.StatusOK, scanJSON(900, beforeTimestamp.Add(-1*time.Hour), "finished")),
+			},
+			expectInFlight: []string{"1002"},
+			expected: []domain.CompletedScan{crawled, {
+				ScanID:    "900",
+				SiteID:    "1",
+				ScanType:  "Scheduled",
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+				StartTime: beforeTimestamp.Add(-1 * time.Hour).Add(time.Second * -10),
+				EndTime:   beforeTimestamp.Add(-1 * time.Hour),
+			}},
+		},
+		{
+			name:    "tracked scan still in flight remains tracked",
+			tracked: []string{"1002", "900"},
+			rechecks: []*http.Response{
+				response(http.StatusOK, scanJSON(900, beforeTimestamp, "dispatched")),
+			},
+			expected: []domain.CompletedScan{crawled},
+		},
+		{
+			name:    "tracked scan which was aborted is no longer tracked",
+			tracked: []string{"1002", "900"},
+			rechecks: []*http.Response{
+				response(http.StatusOK, scanJSON(900, beforeTimestamp, "aborted")),
+			},
+			expectInFlight: []string{"1002"},
+			expected:       []domain.CompletedScan{crawled},
+		},
+		{
+			name:    "tracked scan which no longer exists is no longer tracked",
+			tracked: []string{"1002", "900"},
+			rechecks: []*http.Response{
+				response(http.StatusNotFound, `{"status": 404}`),
+			},
+			expectInFlight: []string{"1002"},
+			expected:       []domain.CompletedScan{crawled},
+		},
+		{
+			name:    "error re-checking tracked scan",
+			tracked: []string{"1002", "900"},
+			rechecks: []*http.Response{
+				response(http.StatusInternalServerError, `{"status": 500}`),
+			},
+			expected:  nil,
+			expectErr: true,
+		},
+		{
+			name:    "scans named by the query are re-checked instead of the tracked scans",
+			recheck: []string{"900"},
+			rechecks: []*http.Response{
+				response(http.StatusOK, scanJSON(900, beforeTimestamp.Add(-1*time.Hour), "finished")),
+			},
+			expected: []domain.CompletedScan{crawled, {
+				ScanID:    "900",
+				SiteID:    "1",
+				ScanType:  "Scheduled",
+				ScanName:  "Allowed Scan",
+				Status:    finishedScanStatus,
+				StartTime: beforeTimestamp.Add(-1 * time.Hour).Add(time.Second * -10),
+				EndTime:   beforeTimestamp.Add(-1 * time.Hour),
+			}},
+		},
+		{
+			name:       "error fetching tracked scans",
+			trackedErr: fmt.Errorf("storage error"),
+			expected:   nil,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			mockFetcher := NewMockInFlightScanFetcher(ctrl)
+			if tt.recheck == nil {
+				mockFetcher.EXPECT().FetchInFlightScans(gomock.Any()).Return(tt.tracked, tt.trackedErr)
+			}
+			if tt.trackedErr == nil {
+				// the crawl always sees one finished scan and one integrating scan
+				// after the timestamp, before stopping on the second page
+				pages := []*http.Response{
+					response(http.StatusOK, fmt.Sprintf(testScanResponse, scanJSON(1001, afterTimestamp, "finished")+","+
+						scanJSON(1002, afterTimestamp.Add(-1*time.Hour), "integrating"), 0)),
+					response(http.StatusOK, fmt.Sprintf(testScanResponse, scanJSON(1000, beforeTimestamp, "finished"), 1)),
+				}
+				for _, res := range append(pages, tt.rechecks...) {
+					mockRT.EXPECT().RoundTrip(gomock.Any()).Return(res, nil)
+				}
+			}
+			nexposeClient := &NexposeClient{
+				LogFn:               testLogFn,
+				StatFn:              testStatFn,
+				Client:              &http.Client{Transport: mockRT},
+				Endpoint:            endpoint,
+				ScanBlocklist:       &container.StringContainer{},
+				InFlightScanFetcher: mockFetcher,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp, Recheck: tt.recheck})
+			actual := result.Scans
+			require.Equal(t, tt.expected, actual)
+			require.Equal(t, tt.expectErr, err != nil)
+			// the scans still in flight are only returned when they have changed
+			require.Equal(t, tt.expectInFlight, result.InFlight)
+		})
+	}
+}
+
+func TestNexposeClient_FetchScansQuarantine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint, _ := url.Parse("http://localhost")
+	timestamp := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	afterTimestamp := time.Date(2019, 05, 25, 00, 00, 00, 00, time.UTC)
+	testScanResponse := `
+		{
+			"resources": [
+				{
+					"startTime": "%s",
+					"endTime": "%s",
+					"scanType": "Scheduled",
+					"id": 1001,
+					"scanName": "Malformed Scan",
+					"siteId": 1,
+					"status": "finished"
+				},
+				{
+					"startTime": "%s",
+					"endTime": "%s",
+					"scanType": "Scheduled",
+					"id": 1002,
+					"scanName": "Allowed Scan",
+					"siteId": 1,
+					"status": "finished"
+				}
+			],
+			"page": {
+				"number": 0,
+				"size": 2,
+				"totalResources": 2,
+				"totalPages": 1
+			}
+		}`
+	body := fmt.Sprintf(testScanResponse,
+		afterTimestamp.Add(time.Second*-10).Format(time.RFC3339Nano), "",
+		afterTimestamp.Add(time.Second*-20).Format(time.RFC3339Nano),
+		afterTimestamp.Add(time.Second*-10).Format(time.RFC3339Nano))
+	valid := domain.CompletedScan{
+		ScanID:    "1002",
+		SiteID:    "1",
+		ScanType:  "Scheduled",
+		ScanName:  "Allowed Scan",
+		Status:    finishedScanStatus,
+		StartTime: afterTimestamp.Add(time.Second * -20),
+		EndTime:   afterTimestamp.Add(time.Second * -10),
+	}
+
+	tests := []struct {
+		name             string
+		strict           bool
+		storeQuarantined bool
+		quarantineErr    error
+		expected         []domain.CompletedScan
+		expectErr        bool
+	}{
+		{
+			name:     "malformed scan is skipped",
+			expected: []domain.CompletedScan{valid},
+		},
+		{
+			name:             "malformed scan is stored",
+			storeQuarantined: true,
+			expected:         []domain.CompletedScan{valid},
+		},
+		{
+			name:             "error storing malformed scan",
+			storeQuarantined: true,
+			quarantineErr:    fmt.Errorf("storage error"),
+			expected:         nil,
+			expectErr:        true,
+		},
+		{
+			name:      "malformed scan fails in strict mode",
+			strict:    true,
+			expected:  nil,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{
+				Body:       ioutil.NopCloser(bytes.NewBuffer([]byte(body))),
+				StatusCode: http.StatusOK,
+			}, nil)
+			mockQuarantiner := NewMockScanQuarantiner(ctrl)
+			if tt.storeQuarantined {
+				mockQuarantiner.EXPECT().QuarantineScan(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, scan domain.QuarantinedScan) error {
+						require.Equal(t, "1001", scan.ScanID)
+						require.Contains(t, scan.Record, "Malformed Scan")
+						return tt.quarantineErr
+					})
+			}
+			nexposeClient := &NexposeClient{
+				Client:           &http.Client{Transport: mockRT},
+				Endpoint:         endpoint,
+				ScanBlocklist:    &container.StringContainer{},
+				Strict:           tt.strict,
+				StoreQuarantined: tt.storeQuarantined,
+				ScanQuarantiner:  mockQuarantiner,
+				LogFn:            testLogFn,
+				StatFn:           testStatFn,
+			}
+			result, err := nexposeClient.FetchScans(context.Background(), domain.ScanQuery{Since: timestamp})
+			actual := result.Scans
+			require.Equal(t, tt.expected, actual)
+			require.Equal(t, tt.expectErr, err != nil)
+		})
+	}
+}
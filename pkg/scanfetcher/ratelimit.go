@@ -0,0 +1,93 @@
+package scanfetcher
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// tokenBucket allows requests at a steady rate, while permitting bursts of up to
+// burst requests after a quiet period.
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, returning how long the caller must wait before the token
+// may be used.
+func (b *tokenBucket) reserve() time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens = b.tokens - 1
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-1 * b.tokens / b.rate * float64(time.Second))
+}
+
+// release returns a token which was reserved but not used.
+func (b *tokenBucket) release() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// Wait blocks until a token is available or ctx is cancelled, returning how long
+// the caller waited.
+func (b *tokenBucket) Wait(ctx context.Context) (time.Duration, error) {
+	delay := b.reserve()
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	started := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		b.release()
+		return time.Since(started), ctx.Err()
+	}
+}
+
+// rateLimitedTransport limits the rate of requests made through the wrapped transport,
+// recording the time each request spends waiting.
+type rateLimitedTransport struct {
+	Wrapped http.RoundTripper
+	Bucket  *tokenBucket
+	StatFn  domain.StatFn
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	waited, err := t.Bucket.Wait(req.Context())
+	t.StatFn(req.Context()).Timing("nexpose.ratelimit.wait", waited)
+	if err != nil {
+		return nil, err
+	}
+	return t.Wrapped.RoundTrip(req)
+}
@@ -0,0 +1,97 @@
+package scanfetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := newTokenBucket(20, 2)
+
+	// the burst is available immediately
+	for offset := 0; offset < 2; offset = offset + 1 {
+		waited, err := bucket.Wait(context.Background())
+		require.Nil(t, err)
+		require.Zero(t, waited)
+	}
+
+	// further requests wait for a token at the configured rate
+	started := time.Now()
+	waited, err := bucket.Wait(context.Background())
+	require.Nil(t, err)
+	require.True(t, waited > 0)
+	require.True(t, time.Since(started) >= 40*time.Millisecond)
+}
+
+func TestTokenBucketWaitCancelled(t *testing.T) {
+	bucket := newTokenBucket(0.1, 1)
+	_, err := bucket.Wait(context.Background())
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = bucket.Wait(ctx)
+	require.Error(t, err)
+
+	// the token reserved by the cancelled request is returned
+	require.True(t, bucket.tokens > -1)
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name      string
+		ctx       func() context.Context
+		expectRT  bool
+		rtErr     error
+		expectErr bool
+	}{
+		{
+			name:     "success",
+			ctx:      context.Background,
+			expectRT: true,
+		},
+		{
+			name:      "wrapped transport error",
+			ctx:       context.Background,
+			expectRT:  true,
+			rtErr:     fmt.Errorf("HTTPError"),
+			expectErr: true,
+		},
+		{
+			name: "cancelled while waiting",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := NewMockRoundTripper(ctrl)
+			if tt.expectRT {
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK}, tt.rtErr)
+			}
+			bucket := newTokenBucket(0.1, 1)
+			if !tt.expectRT {
+				// use up the burst so that the request must wait
+				bucket.reserve()
+			}
+			transport := &rateLimitedTransport{Wrapped: mockRT, Bucket: bucket, StatFn: testStatFn}
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost", http.NoBody)
+			_, err := transport.RoundTrip(req.WithContext(tt.ctx()))
+			require.Equal(t, tt.expectErr, err != nil)
+		})
+	}
+}
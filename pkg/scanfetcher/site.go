@@ -0,0 +1,144 @@
+package scanfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+const (
+	siteTagsPageSize = 500 // The number of tags to request per page of a site's tags.
+	sitesPageSize    = 500 // The number of sites to request per page of the site list.
+)
+
+type siteResource struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Importance  string `json:"importance"`
+}
+
+type tagResource struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type siteTagsResponse struct {
+	Page      page          `json:"page"`
+	Resources []tagResource `json:"resources"`
+}
+
+type sitesResponse struct {
+	Page      page           `json:"page"`
+	Resources []siteResource `json:"resources"`
+}
+
+// ListSites fetches every page of the Nexpose site list, returning the ID of each
+// site once.
+func (n *NexposeClient) ListSites(ctx context.Context) ([]string, error) {
+	u, _ := url.Parse(n.Endpoint.String())
+	u.Path = path.Join(u.Path, "api", "3", "sites")
+	seen := make(map[int]bool)
+	siteIDs := make([]string, 0)
+	for curPage := 0; ; curPage = curPage + 1 {
+		q := u.Query()
+		q.Set(pageQueryParam, strconv.Itoa(curPage))
+		q.Set(sizeQueryParam, strconv.Itoa(sitesPageSize))
+		u.RawQuery = q.Encode()
+
+		sites, err := n.makeSitesPageRequest(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+
+		// sites created while paging shift the remaining sites between pages
+		for _, site := range sites.Resources {
+			if !seen[site.ID] {
+				seen[site.ID] = true
+				siteIDs = append(siteIDs, strconv.Itoa(site.ID))
+			}
+		}
+		if curPage+1 >= sites.Page.TotalPages || len(sites.Resources) == 0 {
+			return siteIDs, nil
+		}
+	}
+}
+
+// FetchSite fetches the details and every page of the tags of a Nexpose site.
+func (n *NexposeClient) FetchSite(ctx context.Context, siteID string) (domain.Site, error) {
+	u, _ := url.Parse(n.Endpoint.String())
+	u.Path = path.Join(u.Path, "api", "3", "sites", siteID)
+	var site siteResource
+	if err := n.getSiteResource(ctx, siteID, u, &site); err != nil {
+		return domain.Site{}, err
+	}
+
+	// sites may have more tags than fit on a single page
+	u.Path = path.Join(u.Path, "tags")
+	tagNames := make([]string, 0)
+	for curPage := 0; ; curPage = curPage + 1 {
+		q := u.Query()
+		q.Set(pageQueryParam, strconv.Itoa(curPage))
+		q.Set(sizeQueryParam, strconv.Itoa(siteTagsPageSize))
+		u.RawQuery = q.Encode()
+		var tags siteTagsResponse
+		if err := n.getSiteResource(ctx, siteID, u, &tags); err != nil {
+			return domain.Site{}, err
+		}
+		for _, tag := range tags.Resources {
+			tagNames = append(tagNames, tag.Name)
+		}
+		if curPage+1 >= tags.Page.TotalPages || len(tags.Resources) == 0 {
+			break
+		}
+	}
+	return domain.Site{
+		Name:        site.Name,
+		Description: site.Description,
+		Importance:  site.Importance,
+		Tags:        tagNames,
+	}, nil
+}
+
+func (n *NexposeClient) makeSitesPageRequest(ctx context.Context, u *url.URL) (sitesResponse, error) {
+	req, _ := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return sitesResponse{}, requestError(ctx, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return sitesResponse{}, responseError("sites", res)
+	}
+	var sites sitesResponse
+	err = json.NewDecoder(res.Body).Decode(&sites)
+	return sites, err
+}
+
+// getSiteResource decodes a site resource from Nexpose into v, returning
+// domain.SiteNotFound if the site does not exist.
+func (n *NexposeClient) getSiteResource(ctx context.Context, siteID string, u *url.URL, v interface{}) error {
+	req, _ := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return requestError(ctx, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return domain.SiteNotFound{SiteID: siteID}
+	}
+	if res.StatusCode != http.StatusOK {
+		return responseError("site", res)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,253 @@
+package scanfetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNexposeClient_FetchSite(t *testing.T) {
+	siteJSON := `{"id": 1, "name": "Site 1", "description": "the first site", "importance": "high"}`
+	tagsJSON := `{"resources": [{"id": 1, "name": "production", "type": "custom"}, {"id": 2, "name": "pci", "type": "custom"}],
+		"page": {"number": 0, "size": 500, "totalPages": 1, "totalResources": 2}}`
+	firstTagsJSON := `{"resources": [{"id": 1, "name": "production", "type": "custom"}],
+		"page": {"number": 0, "size": 500, "totalPages": 2, "totalResources": 501}}`
+	lastTagsJSON := `{"resources": [{"id": 2, "name": "pci", "type": "custom"}],
+		"page": {"number": 1, "size": 500, "totalPages": 2, "totalResources": 501}}`
+	response := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: statusCode,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		siteRes     *http.Response
+		siteErr     error
+		tagsRes     *http.Response
+		tagsErr     error
+		moreTagsRes *http.Response
+		expected    domain.Site
+		expectedErr error
+		expectErr   bool
+	}{
+		{
+			name:    "success",
+			siteRes: response(http.StatusOK, siteJSON),
+			tagsRes: response(http.StatusOK, tagsJSON),
+			expected: domain.Site{
+				Name:        "Site 1",
+				Description: "the first site",
+				Importance:  "high",
+				Tags:        []string{"production", "pci"},
+			},
+		},
+		{
+			name:        "tags across pages",
+			siteRes:     response(http.StatusOK, siteJSON),
+			tagsRes:     response(http.StatusOK, firstTagsJSON),
+			moreTagsRes: response(http.StatusOK, lastTagsJSON),
+			expected: domain.Site{
+				Name:        "Site 1",
+				Description: "the first site",
+				Importance:  "high",
+				Tags:        []string{"production", "pci"},
+			},
+		},
+		{
+			name:    "site without tags",
+			siteRes: response(http.StatusOK, siteJSON),
+			tagsRes: response(http.StatusOK, `{"resources": []}`),
+			expected: domain.Site{
+				Name:        "Site 1",
+				Description: "the first site",
+				Importance:  "high",
+				Tags:        []string{},
+			},
+		},
+		{
+			name:        "site not found",
+			siteRes:     response(http.StatusNotFound, `{"status": "404"}`),
+			expectedErr: domain.SiteNotFound{SiteID: "1"},
+			expectErr:   true,
+		},
+		{
+			name:      "site request error",
+			siteErr:   errors.New("HTTPError"),
+			expectErr: true,
+		},
+		{
+			name:      "site unexpected status",
+			siteRes:   response(http.StatusInternalServerError, "oops"),
+			expectErr: true,
+		},
+		{
+			name:      "site invalid json",
+			siteRes:   response(http.StatusOK, "{"),
+			expectErr: true,
+		},
+		{
+			name:      "tags request error",
+			siteRes:   response(http.StatusOK, siteJSON),
+			tagsErr:   errors.New("HTTPError"),
+			expectErr: true,
+		},
+		{
+			name:        "later tags page error",
+			siteRes:     response(http.StatusOK, siteJSON),
+			tagsRes:     response(http.StatusOK, firstTagsJSON),
+			moreTagsRes: response(http.StatusBadGateway, "oops"),
+			expectErr:   true,
+		},
+		{
+			name:      "tags unexpected status",
+			siteRes:   response(http.StatusOK, siteJSON),
+			tagsRes:   response(http.StatusBadGateway, "oops"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRT := NewMockRoundTripper(ctrl)
+
+			endpoint, _ := url.Parse("http://localhost")
+			client := &NexposeClient{
+				Client:   &http.Client{Transport: mockRT},
+				Endpoint: endpoint,
+			}
+
+			mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				require.Equal(t, "/api/3/sites/1", req.URL.Path)
+				return tt.siteRes, tt.siteErr
+			})
+			if tt.tagsRes != nil || tt.tagsErr != nil {
+				mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+					require.Equal(t, "/api/3/sites/1/tags", req.URL.Path)
+					require.Equal(t, "0", req.URL.Query().Get(pageQueryParam))
+					require.Equal(t, "500", req.URL.Query().Get(sizeQueryParam))
+					return tt.tagsRes, tt.tagsErr
+				})
+			}
+			if tt.moreTagsRes != nil {
+				mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+					require.Equal(t, "/api/3/sites/1/tags", req.URL.Path)
+					require.Equal(t, "1", req.URL.Query().Get(pageQueryParam))
+					return tt.moreTagsRes, nil
+				})
+			}
+
+			site, err := client.FetchSite(context.Background(), "1")
+			if tt.expectErr {
+				require.Error(t, err)
+				if tt.expectedErr != nil {
+					require.Equal(t, tt.expectedErr, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, site)
+		})
+	}
+}
+
+func TestNexposeClient_ListSites(t *testing.T) {
+	sitesJSON := `{"resources": [{"id": 1, "name": "Site 1"}, {"id": 2, "name": "Site 2"}],
+		"page": {"number": 0, "size": 500, "totalPages": 1, "totalResources": 2}}`
+	firstSitesJSON := `{"resources": [{"id": 1, "name": "Site 1"}, {"id": 2, "name": "Site 2"}],
+		"page": {"number": 0, "size": 500, "totalPages": 2, "totalResources": 501}}`
+	lastSitesJSON := `{"resources": [{"id": 2, "name": "Site 2"}, {"id": 3, "name": "Site 3"}],
+		"page": {"number": 1, "size": 500, "totalPages": 2, "totalResources": 501}}`
+	response := func(statusCode int, body string) *http.Response {
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: statusCode,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		responses []*http.Response
+		err       error
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:      "success",
+			responses: []*http.Response{response(http.StatusOK, sitesJSON)},
+			expected:  []string{"1", "2"},
+		},
+		{
+			name:      "sites shifted between pages",
+			responses: []*http.Response{response(http.StatusOK, firstSitesJSON), response(http.StatusOK, lastSitesJSON)},
+			expected:  []string{"1", "2", "3"},
+		},
+		{
+			name:      "no sites",
+			responses: []*http.Response{response(http.StatusOK, `{"resources": []}`)},
+			expected:  []string{},
+		},
+		{
+			name:      "request error",
+			err:       errors.New("HTTPError"),
+			expectErr: true,
+		},
+		{
+			name:      "later page unexpected status",
+			responses: []*http.Response{response(http.StatusOK, firstSitesJSON), response(http.StatusBadGateway, "oops")},
+			expectErr: true,
+		},
+		{
+			name:      "invalid json",
+			responses: []*http.Response{response(http.StatusOK, "{")},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRT := NewMockRoundTripper(ctrl)
+
+			endpoint, _ := url.Parse("http://localhost")
+			client := &NexposeClient{
+				Client:   &http.Client{Transport: mockRT},
+				Endpoint: endpoint,
+			}
+
+			if tt.err != nil {
+				mockRT.EXPECT().RoundTrip(gomock.Any()).Return(nil, tt.err)
+			}
+			for offset, res := range tt.responses {
+				offset, res := offset, res
+				mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+					require.Equal(t, "/api/3/sites", req.URL.Path)
+					require.Equal(t, strconv.Itoa(offset), req.URL.Query().Get(pageQueryParam))
+					require.Equal(t, "500", req.URL.Query().Get(sizeQueryParam))
+					return res, nil
+				})
+			}
+
+			siteIDs, err := client.ListSites(context.Background())
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, siteIDs)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+// Package service builds the components of the notifier from settings, so that the
+// HTTP service and the command line tool are configured identically.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	v1 "github.com/asecurityteam/nexpose-scan-notifier/pkg/handlers/v1"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/producer"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/scanfetcher"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/storage"
+	"github.com/asecurityteam/serverfull"
+	"github.com/asecurityteam/settings"
+)
+
+const (
+	// RunSourceHTTP names runs triggered through the HTTP service in the run history.
+	RunSourceHTTP = "http"
+	// RunSourceCLI names runs triggered by the command line tool in the run history.
+	RunSourceCLI = "notifierctl"
+)
+
+// Service holds the configured components of the notifier.
+type Service struct {
+	NexposeClient    *scanfetcher.NexposeClient
+	ScanFetcher      *scanfetcher.SiteEnricher
+	Storage          storage.Storage
+	TimestampFetcher *storage.BootstrapTimestampFetcher
+	Router           *producer.Router
+	Destinations     []v1.Destination
+	Events           domain.EventProducer
+	CircuitBreakers  []domain.CircuitBreakerStater
+
+	NotificationHandler    *v1.NotificationHandler
+	DependencyCheckHandler *v1.DependencyCheckHandler
+	WatermarkHandler       *v1.WatermarkHandler
+	RunsHandler            *v1.RunsHandler
+	SiteScanHandler        *v1.SiteScanHandler
+}
+
+// New configures every component of the notifier from a settings source.
+func New(ctx context.Context, source settings.Source) (*Service, error) {
+	// configure Nexpose scan fetcher
+	nexposeComponent := &scanfetcher.NexposeComponent{}
+	nexposeClient := new(scanfetcher.NexposeClient)
+	if err := settings.NewComponent(ctx, source, nexposeComponent, nexposeClient); err != nil {
+		return nil, err
+	}
+
+	// configure the scan event producer, which is the HTTP producer unless scans
+	// are written to stdout or a file for local runs
+	outputComponent := &producer.OutputComponent{Source: source}
+	var output domain.Producer
+	if err := settings.NewComponent(ctx, source, outputComponent, &output); err != nil {
+		return nil, err
+	}
+
+	// route scans to destinations by their site, type and name, sending every scan
+	// to the configured producer when no routing rules are configured
+	routingComponent := &producer.RoutingComponent{}
+	router := new(producer.Router)
+	if err := settings.NewComponent(ctx, source, routingComponent, router); err != nil {
+		return nil, err
+	}
+	router.Destinations[producer.DefaultDestination] = output
+
+	// create the timestamp fetcher/storer, which is DynamoDB unless everything is
+	// kept in memory for local runs
+	storageComponent := &storage.StorageComponent{Source: source}
+	var store storage.Storage
+	if err := settings.NewComponent(ctx, source, storageComponent, &store); err != nil {
+		return nil, err
+	}
+
+	// track scans which were not yet finished when last seen, so that they can be
+	// produced once they finish even if their end time is before the stored timestamp;
+	// the notification handler stores them once each run has produced its scans
+	nexposeClient.InFlightScanFetcher = store
+	nexposeClient.ScanQuarantiner = store
+
+	// optionally add the details and tags of each scan's site to produced scans
+	enrichmentComponent := &scanfetcher.EnrichmentComponent{}
+	siteEnricher := new(scanfetcher.SiteEnricher)
+	if err := settings.NewComponent(ctx, source, enrichmentComponent, siteEnricher); err != nil {
+		return nil, err
+	}
+	siteEnricher.ScanFetcher = nexposeClient
+	siteEnricher.SiteFetcher = nexposeClient
+
+	// routing rules which match site tags cannot match scans without their site's tags
+	if router.RoutesBySiteTags() && !siteEnricher.Enabled {
+		return nil, fmt.Errorf("routing rules which match site tags require site enrichment to be enabled")
+	}
+
+	// apply the initial timestamp policy when no timestamp has been stored yet
+	bootstrapComponent := &storage.BootstrapComponent{}
+	bootstrapTimestampFetcher := new(storage.BootstrapTimestampFetcher)
+	if err := settings.NewComponent(ctx, source, bootstrapComponent, bootstrapTimestampFetcher); err != nil {
+		return nil, err
+	}
+	bootstrapTimestampFetcher.Wrapped = store
+	bootstrapTimestampFetcher.Storer = store
+
+	// deliver every scan to any additional destinations, each storing its own
+	// timestamp and the scans it missed under a separate partition so that it
+	// progresses independently
+	fanOutComponent := &producer.FanOutComponent{}
+	fanOut := new(producer.FanOut)
+	if err := settings.NewComponent(ctx, source, fanOutComponent, fanOut); err != nil {
+		return nil, err
+	}
+	destinations := make([]v1.Destination, 0, len(fanOut.Destinations))
+	for _, destination := range fanOut.Destinations {
+		if fanOut.DeadLetter {
+			destination.DeadLetterStorer = store
+		}
+		partition := store.DestinationPartition(destination.Destination)
+		partitionTimestampFetcher := *bootstrapTimestampFetcher
+		partitionTimestampFetcher.Wrapped = partition
+		partitionTimestampFetcher.Storer = partition
+		destinations = append(destinations, v1.Destination{
+			Name:                destination.Destination,
+			Producer:            destination,
+			TimestampFetcher:    &partitionTimestampFetcher,
+			TimestampStorer:     partition,
+			InFlightScanFetcher: partition,
+			InFlightScanStorer:  partition,
+		})
+	}
+
+	// configure the notification handler, including its per-run budget
+	notificationComponent := &v1.NotificationComponent{}
+	notificationHandler := new(v1.NotificationHandler)
+	if err := settings.NewComponent(ctx, source, notificationComponent, notificationHandler); err != nil {
+		return nil, err
+	}
+	notificationHandler.TimestampFetcher = bootstrapTimestampFetcher
+	notificationHandler.TimestampStorer = store
+	notificationHandler.InFlightScanStorer = store
+	notificationHandler.ScanFetcher = siteEnricher
+	notificationHandler.Producer = router
+	notificationHandler.Destinations = destinations
+
+	// record the history of every run, naming the HTTP service as its source unless
+	// changed by the command line tool
+	notificationHandler.RunRecorder = store
+	notificationHandler.Source = RunSourceHTTP
+
+	// produce an event when regular runs keep failing or the stored timestamp becomes
+	// too old, through the scan output unless a separate events endpoint is configured
+	eventsComponent := &producer.EventsComponent{Output: output}
+	var events domain.EventProducer
+	if err := settings.NewComponent(ctx, source, eventsComponent, &events); err != nil {
+		return nil, err
+	}
+	healthComponent := &v1.HealthComponent{}
+	healthMonitor := new(v1.HealthMonitor)
+	if err := settings.NewComponent(ctx, source, healthComponent, healthMonitor); err != nil {
+		return nil, err
+	}
+	healthMonitor.HealthFetcher = store
+	healthMonitor.HealthStorer = store
+	healthMonitor.TimestampFetcher = store
+	healthMonitor.EventProducer = events
+	notificationHandler.HealthMonitor = healthMonitor
+
+	// record the last completed scan of each site from regular runs, and produce an
+	// event when a site goes too long without one
+	notificationHandler.SiteScanRecorder = store
+	siteScanComponent := &v1.SiteScanComponent{}
+	siteScanHandler := new(v1.SiteScanHandler)
+	if err := settings.NewComponent(ctx, source, siteScanComponent, siteScanHandler); err != nil {
+		return nil, err
+	}
+	siteScanHandler.SiteLister = nexposeClient
+	siteScanHandler.SiteScanFetcher = store
+	siteScanHandler.SiteScanStorer = store
+	siteScanHandler.EventProducer = events
+
+	// produce an event when an active scan runs for longer than allowed, checked
+	// after every regular run when any maximum runtime is configured
+	stalledScanComponent := &v1.StalledScanComponent{}
+	stalledScanMonitor := new(v1.StalledScanMonitor)
+	if err := settings.NewComponent(ctx, source, stalledScanComponent, stalledScanMonitor); err != nil {
+		return nil, err
+	}
+	stalledScanMonitor.ActiveScanFetcher = nexposeClient
+	stalledScanMonitor.ActiveScanChecker = nexposeClient
+	stalledScanMonitor.StalledScanFetcher = store
+	stalledScanMonitor.StalledScanStorer = store
+	stalledScanMonitor.EventProducer = events
+	notificationHandler.StalledScanMonitor = stalledScanMonitor
+
+	// targeted and dry runs must not change storage, including by recording
+	// in-flight scans or storing a bootstrapped timestamp
+	readOnlyTimestampFetcher := *bootstrapTimestampFetcher
+	readOnlyTimestampFetcher.Storer = discardTimestamp{}
+	notificationHandler.ReadOnlyTimestampFetcher = &readOnlyTimestampFetcher
+	notificationHandler.ReadOnlyScanFetcher = readOnlyScanFetcher(nexposeClient, siteEnricher)
+
+	// report the state of each enabled circuit breaker in the dependency check
+	var circuitBreakers []domain.CircuitBreakerStater
+	if nexposeClient.CircuitBreaker != nil {
+		circuitBreakers = append(circuitBreakers, nexposeClient.CircuitBreaker)
+	}
+	if httpProducer, ok := output.(*producer.HTTP); ok && httpProducer.CircuitBreaker != nil {
+		circuitBreakers = append(circuitBreakers, httpProducer.CircuitBreaker)
+	}
+	if httpProducer, ok := events.(*producer.HTTP); ok && httpProducer != output && httpProducer.CircuitBreaker != nil {
+		circuitBreakers = append(circuitBreakers, httpProducer.CircuitBreaker)
+	}
+	for _, breaker := range router.CircuitBreakers {
+		circuitBreakers = append(circuitBreakers, breaker)
+	}
+	for _, breaker := range fanOut.CircuitBreakers {
+		circuitBreakers = append(circuitBreakers, breaker)
+	}
+
+	dependencyCheckHandler := &v1.DependencyCheckHandler{
+		NexposeClientDependencyChecker: nexposeClient,
+		DynamoDBDependencyChecker:      store,
+		CircuitBreakers:                circuitBreakers,
+	}
+
+	watermarkHandler := &v1.WatermarkHandler{
+		TimestampFetcher:        store,
+		TimestampStorer:         store,
+		TimestampResetter:       store,
+		WatermarkChangeRecorder: store,
+		LogFn:                   domain.LoggerFromContext,
+	}
+
+	runsHandler := &v1.RunsHandler{
+		RunFetcher: store,
+		LogFn:      domain.LoggerFromContext,
+	}
+
+	return &Service{
+		NexposeClient:          nexposeClient,
+		ScanFetcher:            siteEnricher,
+		Storage:                store,
+		TimestampFetcher:       bootstrapTimestampFetcher,
+		Router:                 router,
+		Destinations:           destinations,
+		Events:                 events,
+		CircuitBreakers:        circuitBreakers,
+		NotificationHandler:    notificationHandler,
+		DependencyCheckHandler: dependencyCheckHandler,
+		WatermarkHandler:       watermarkHandler,
+		RunsHandler:            runsHandler,
+		SiteScanHandler:        siteScanHandler,
+	}, nil
+}
+
+// Handlers returns the functions served by the HTTP service. When built as an HTTP
+// service, the failures of notification runs are returned as responses naming the
+// stage which failed, for the gateway to pass through; a Lambda build returns them
+// as Lambda errors.
+func (s *Service) Handlers() map[string]serverfull.Function {
+	notification := serverfull.NewFunction(s.NotificationHandler.Handle)
+	if strings.EqualFold(serverfull.BuildMode, serverfull.BuildModeHTTP) {
+		notification = stagedFunction{Function: notification}
+	}
+	return map[string]serverfull.Function{
+		"notification":    notification,
+		"dependencycheck": serverfull.NewFunction(s.DependencyCheckHandler.Handle),
+		"watermark":       serverfull.NewFunction(s.WatermarkHandler.Fetch),
+		"watermarkset":    serverfull.NewFunction(s.WatermarkHandler.Set),
+		"watermarkreset":  serverfull.NewFunction(s.WatermarkHandler.Reset),
+		"runs":            serverfull.NewFunction(s.RunsHandler.Handle),
+		"sitescans":       serverfull.NewFunction(s.SiteScanHandler.Fetch),
+		"sitescancheck":   serverfull.NewFunction(s.SiteScanHandler.Check),
+	}
+}
+
+// stagedFunction returns the failures of a function as an ErrorResponse, since the
+// error of a Lambda function only carries its message and type, which would leave
+// the gateway to parse the failed stage from the message.
+type stagedFunction struct {
+	serverfull.Function
+}
+
+// Invoke runs the function, returning a failure as a response.
+func (f stagedFunction) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	response, err := f.Function.Invoke(ctx, payload)
+	if err == nil {
+		return response, nil
+	}
+	return json.Marshal(v1.NewErrorResponse(err))
+}
+
+// ReadOnlyScanFetcher returns a scan fetcher with the same configuration as the
+// notification handler's, which does not record in-flight or quarantined scans, so
+// that scans can be inspected without changing what later runs produce.
+func (s *Service) ReadOnlyScanFetcher() domain.ScanFetcher {
+	return readOnlyScanFetcher(s.NexposeClient, s.ScanFetcher)
+}
+
+func readOnlyScanFetcher(client *scanfetcher.NexposeClient, enricher *scanfetcher.SiteEnricher) domain.ScanFetcher {
+	readOnlyClient := *client
+	readOnlyClient.InFlightScanFetcher = nil
+	readOnlyClient.ScanQuarantiner = nil
+	readOnlyClient.StoreQuarantined = false
+	return &scanfetcher.SiteEnricher{
+		ScanFetcher: &readOnlyClient,
+		SiteFetcher: client,
+		Enabled:     enricher.Enabled,
+		CacheTTL:    enricher.CacheTTL,
+		LogFn:       enricher.LogFn,
+		StatFn:      enricher.StatFn,
+	}
+}
+
+// discardTimestamp is a domain.TimestampStorer which stores nothing.
+type discardTimestamp struct{}
+
+func (discardTimestamp) StoreTimestamp(context.Context, time.Time) error {
+	return nil
+}
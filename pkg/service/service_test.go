@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	v1 "github.com/asecurityteam/nexpose-scan-notifier/pkg/handlers/v1"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/nexposetest"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/producer"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/storage"
+	"github.com/asecurityteam/serverfull"
+	"github.com/asecurityteam/settings"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingProducer struct {
+	scans []domain.CompletedScan
+}
+
+func (p *recordingProducer) Produce(_ context.Context, scan domain.CompletedScan) error {
+	p.scans = append(p.scans, scan)
+	return nil
+}
+
+func newSource(endpoint string, values map[string]interface{}) settings.Source {
+	m := map[string]interface{}{
+		"nexpose": map[string]interface{}{"endpoint": endpoint, "scanblocklist": "BadScan1"},
+		"storage": map[string]interface{}{"type": storage.StorageTypeMemory},
+		"output":  map[string]interface{}{"type": producer.OutputTypeStdout},
+	}
+	for key, value := range values {
+		m[key] = value
+	}
+	return settings.NewMapSource(m)
+}
+
+func TestNew(t *testing.T) {
+	svc, err := New(context.Background(), newSource("http://localhost", nil))
+	require.NoError(t, err)
+	require.IsType(t, &storage.MemoryStorage{}, svc.Storage)
+	require.IsType(t, &producer.NDJSON{}, svc.Router.Destinations[producer.DefaultDestination])
+	require.Equal(t, svc.Router, svc.NotificationHandler.Producer)
+	require.Equal(t, svc.ScanFetcher, svc.NotificationHandler.ScanFetcher)
+	require.Empty(t, svc.Destinations)
+	require.Len(t, svc.CircuitBreakers, 1)
+	require.Equal(t, svc.Router.Destinations[producer.DefaultDestination], svc.Events)
+	require.Equal(t, svc.Events, svc.NotificationHandler.HealthMonitor.EventProducer)
+	require.Equal(t, 3, svc.NotificationHandler.HealthMonitor.MaxConsecutiveFailures)
+	require.Equal(t, svc.Storage, svc.NotificationHandler.SiteScanRecorder)
+	require.Equal(t, svc.Events, svc.SiteScanHandler.EventProducer)
+	require.Equal(t, svc.NexposeClient, svc.NotificationHandler.StalledScanMonitor.ActiveScanFetcher)
+	require.Equal(t, svc.Events, svc.NotificationHandler.StalledScanMonitor.EventProducer)
+
+	handlers := svc.Handlers()
+	for _, name := range []string{"notification", "dependencycheck", "watermark", "watermarkset", "watermarkreset", "runs", "sitescans", "sitescancheck"} {
+		require.Contains(t, handlers, name)
+	}
+	require.IsType(t, stagedFunction{}, handlers["notification"])
+}
+
+func TestStagedFunction(t *testing.T) {
+	tc := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "success",
+			expected: `"ok"`,
+		},
+		{
+			name:     "failure of a run stage",
+			err:      domain.UpstreamUnavailable{Stage: "produce", Dependency: "producer", Reason: "503"},
+			expected: `{"errorMessage":"produce failed: producer is unavailable: 503","errorType":"UpstreamUnavailable","stage":"produce"}`,
+		},
+		{
+			name:     "failure outside of a run",
+			err:      domain.InvalidInput{Field: "since", Reason: "invalid"},
+			expected: `{"errorMessage":"` + domain.InvalidInput{Field: "since", Reason: "invalid"}.Error() + `","errorType":"InvalidInput","stage":null}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := stagedFunction{Function: serverfull.NewFunction(func(context.Context) (string, error) {
+				if tt.err != nil {
+					return "", tt.err
+				}
+				return "ok", nil
+			})}
+			response, err := fn.Invoke(context.Background(), []byte(`{}`))
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expected, string(response))
+		})
+	}
+}
+
+func TestNew_Destinations(t *testing.T) {
+	svc, err := New(context.Background(), newSource("http://localhost", map[string]interface{}{
+		"output":  map[string]interface{}{"type": producer.OutputTypeHTTP},
+		"fanout":  map[string]interface{}{"destinations": "datalake=http://localhost/datalake"},
+		"storage": map[string]interface{}{"type": storage.StorageTypeMemory},
+	}))
+	require.NoError(t, err)
+	require.Len(t, svc.Destinations, 1)
+	require.Equal(t, "datalake", svc.Destinations[0].Name)
+	// nexpose, the http producer and the fan out destination
+	require.Len(t, svc.CircuitBreakers, 3)
+}
+
+func TestNew_Events(t *testing.T) {
+	svc, err := New(context.Background(), newSource("http://localhost", map[string]interface{}{
+		"output": map[string]interface{}{"type": producer.OutputTypeHTTP},
+		"events": map[string]interface{}{"endpoint": "http://localhost/events"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost/events", svc.Events.(*producer.HTTP).Endpoint.String())
+	// nexpose, the http producer and the events producer
+	require.Len(t, svc.CircuitBreakers, 3)
+}
+
+func TestNew_InvalidSettings(t *testing.T) {
+	routingFile, err := ioutil.TempFile("", "routing")
+	require.NoError(t, err)
+	defer os.Remove(routingFile.Name())
+	_, err = routingFile.WriteString(`{"rules": [{"siteTags": ["pci"], "destinations": ["default"]}]}`)
+	require.NoError(t, err)
+	require.NoError(t, routingFile.Close())
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{
+			name:   "storage",
+			values: map[string]interface{}{"storage": map[string]interface{}{"type": "FILE"}},
+		},
+		{
+			name:   "output",
+			values: map[string]interface{}{"output": map[string]interface{}{"type": "QUEUE"}},
+		},
+		{
+			name:   "health",
+			values: map[string]interface{}{"health": map[string]interface{}{"maxconsecutivefailures": -1}},
+		},
+		{
+			name:   "sitescan",
+			values: map[string]interface{}{"sitescan": map[string]interface{}{"maxintervals": "1=weekly"}},
+		},
+		{
+			name:   "stalledscan",
+			values: map[string]interface{}{"stalledscan": map[string]interface{}{"sitemaxruntimes": "12"}},
+		},
+		{
+			name:   "site tag routing without enrichment",
+			values: map[string]interface{}{"routing": map[string]interface{}{"file": routingFile.Name()}},
+		},
+		{
+			name:   "bootstrap",
+			values: map[string]interface{}{"bootstrap": map[string]interface{}{"policy": "LATER"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(context.Background(), newSource("http://localhost", tt.values))
+			require.Error(t, err)
+		})
+	}
+
+	// site tag routing is valid once enrichment adds the tags
+	_, err = New(context.Background(), newSource("http://localhost", map[string]interface{}{
+		"routing":    map[string]interface{}{"file": routingFile.Name()},
+		"enrichment": map[string]interface{}{"enabled": true},
+	}))
+	require.NoError(t, err)
+}
+
+func TestNotificationHandler_DryRun(t *testing.T) {
+	ctx := logevent.NewContext(context.Background(), logevent.New(logevent.Config{Level: "ERROR"}))
+	end := time.Now().Add(-time.Hour).UTC()
+	fake := nexposetest.New()
+	fake.AddScans(nexposetest.GenerateScans(1, 3, 1, end, time.Minute)...)
+	fake.AddScans(nexposetest.Scan{ID: 4, SiteID: 1, Status: nexposetest.StatusIntegrating, StartTime: end})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	svc, err := New(ctx, newSource(server.URL, map[string]interface{}{
+		"bootstrap": map[string]interface{}{"policy": storage.BootstrapPolicyLookback, "lookback": "24h"},
+	}))
+	require.NoError(t, err)
+	recorder := &recordingProducer{}
+	svc.Router.Destinations[producer.DefaultDestination] = recorder
+
+	output, err := svc.NotificationHandler.Handle(ctx, v1.NotificationInput{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, output.Response, 3)
+	require.Empty(t, recorder.scans)
+
+	// neither the bootstrapped timestamp, the produced scans nor the in-flight scan are stored
+	_, err = svc.Storage.FetchTimestamp(ctx)
+	require.IsType(t, domain.TimestampNotFound{}, err)
+	inFlight, err := svc.Storage.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, inFlight)
+
+	// a regular run stores both
+	output, err = svc.NotificationHandler.Handle(ctx, v1.NotificationInput{})
+	require.NoError(t, err)
+	require.Len(t, output.Response, 3)
+	require.Len(t, recorder.scans, 3)
+	_, err = svc.Storage.FetchTimestamp(ctx)
+	require.NoError(t, err)
+	inFlight, err = svc.Storage.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"4"}, inFlight)
+
+	// both runs are recorded in the run history, most recent first
+	runs, err := svc.RunsHandler.Handle(ctx, v1.RunsInput{})
+	require.NoError(t, err)
+	require.Len(t, runs.Runs, 2)
+	require.False(t, runs.Runs[0].DryRun)
+	require.True(t, runs.Runs[1].DryRun)
+	require.Equal(t, RunSourceHTTP, runs.Runs[0].Source)
+}
+
+type recordingEventProducer struct {
+	events []domain.Event
+}
+
+func (p *recordingEventProducer) ProduceEvent(_ context.Context, event domain.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestNotificationHandler_StalledScans(t *testing.T) {
+	ctx := logevent.NewContext(context.Background(), logevent.New(logevent.Config{Level: "ERROR"}))
+	now := time.Now().UTC()
+	fake := nexposetest.New()
+	fake.AddScans(nexposetest.GenerateScans(1, 1, 1, now.Add(-time.Hour), time.Minute)...)
+	fake.AddScans(
+		nexposetest.Scan{ID: 2, SiteID: 1, Type: "Scheduled", Status: nexposetest.StatusRunning, StartTime: now.Add(-3 * time.Hour)},
+		nexposetest.Scan{ID: 3, SiteID: 2, Type: "Scheduled", Status: nexposetest.StatusRunning, StartTime: now.Add(-30 * time.Minute)},
+		nexposetest.Scan{ID: 4, SiteID: 1, Type: "Scheduled", Status: nexposetest.StatusPaused, StartTime: now.Add(-3 * time.Hour)},
+	)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	svc, err := New(ctx, newSource(server.URL, map[string]interface{}{
+		"bootstrap":   map[string]interface{}{"policy": storage.BootstrapPolicyLookback, "lookback": "24h"},
+		"stalledscan": map[string]interface{}{"defaultmaxruntime": "1h"},
+	}))
+	require.NoError(t, err)
+	svc.Router.Destinations[producer.DefaultDestination] = &recordingProducer{}
+	events := &recordingEventProducer{}
+	svc.NotificationHandler.StalledScanMonitor.EventProducer = events
+
+	// the stalled scan produces a single event across runs
+	for run := 0; run < 2; run = run + 1 {
+		_, err = svc.NotificationHandler.Handle(ctx, v1.NotificationInput{})
+		require.NoError(t, err)
+	}
+	require.Len(t, events.events, 1)
+	require.Equal(t, "scan.stalled", events.events[0].Type)
+	stalled, err := svc.Storage.FetchStalledScans(ctx)
+	require.NoError(t, err)
+	require.Len(t, stalled, 1)
+	require.Equal(t, "2", stalled[0].ScanID)
+
+	// the scan is forgotten once it finishes
+	require.True(t, fake.SetScanStatus(2, nexposetest.StatusFinished, time.Now().UTC()))
+	_, err = svc.NotificationHandler.Handle(ctx, v1.NotificationInput{})
+	require.NoError(t, err)
+	stalled, err = svc.Storage.FetchStalledScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, stalled)
+}
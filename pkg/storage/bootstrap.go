@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/logs"
+)
+
+const (
+	// BootstrapPolicyNow starts from the time of the first run, ignoring all earlier scans.
+	BootstrapPolicyNow = "NOW"
+	// BootstrapPolicyLookback starts from a fixed duration before the time of the first run.
+	BootstrapPolicyLookback = "LOOKBACK"
+	// BootstrapPolicyTimestamp starts from an explicitly configured timestamp.
+	BootstrapPolicyTimestamp = "TIMESTAMP"
+	// BootstrapPolicyAll starts from the beginning of time, producing every scan Nexpose has ever run.
+	BootstrapPolicyAll = "ALL"
+)
+
+// BootstrapConfig holds configuration for choosing the initial timestamp when none
+// exists in storage.
+type BootstrapConfig struct {
+	Policy    string        `description:"Where to start when no timestamp is stored. One of NOW, LOOKBACK, TIMESTAMP, ALL."`
+	Lookback  time.Duration `description:"How far before the first run to start when using the LOOKBACK policy."`
+	Timestamp time.Time     `description:"The RFC3339 timestamp to start from when using the TIMESTAMP policy."`
+}
+
+// Name is used by the settings library and will add a "BOOTSTRAP_"
+// prefix to BootstrapConfig environment variables
+func (c *BootstrapConfig) Name() string {
+	return "Bootstrap"
+}
+
+// BootstrapComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function.
+type BootstrapComponent struct{}
+
+// Settings can be used to populate default values if there are any
+func (*BootstrapComponent) Settings() *BootstrapConfig {
+	return &BootstrapConfig{
+		Policy: BootstrapPolicyAll,
+	}
+}
+
+// New constructs a BootstrapTimestampFetcher from a config. The storage to wrap must
+// be set on the result before use.
+func (*BootstrapComponent) New(_ context.Context, c *BootstrapConfig) (*BootstrapTimestampFetcher, error) {
+	policy := strings.ToUpper(c.Policy)
+	switch policy {
+	case BootstrapPolicyNow, BootstrapPolicyAll:
+	case BootstrapPolicyLookback:
+		if c.Lookback <= 0 {
+			return nil, fmt.Errorf("bootstrap lookback must be positive when using the %s policy", policy)
+		}
+	case BootstrapPolicyTimestamp:
+		if c.Timestamp.IsZero() {
+			return nil, fmt.Errorf("bootstrap timestamp must be set when using the %s policy", policy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bootstrap policy %s", c.Policy)
+	}
+
+	return &BootstrapTimestampFetcher{
+		Policy:    policy,
+		Lookback:  c.Lookback,
+		Timestamp: c.Timestamp,
+		LogFn:     domain.LoggerFromContext,
+	}, nil
+}
+
+// BootstrapTimestampFetcher wraps a TimestampFetcher and applies a configured policy
+// when no timestamp exists in storage. Any timestamp chosen by the policy is stored
+// immediately, so that subsequent runs continue from it rather than re-applying the
+// policy until a scan is produced.
+type BootstrapTimestampFetcher struct {
+	Wrapped   domain.TimestampFetcher
+	Storer    domain.TimestampStorer
+	Policy    string
+	Lookback  time.Duration
+	Timestamp time.Time
+	LogFn     domain.LogFn
+}
+
+// FetchTimestamp returns the stored timestamp if one exists, otherwise the initial
+// timestamp chosen by the bootstrap policy.
+func (f *BootstrapTimestampFetcher) FetchTimestamp(ctx context.Context) (time.Time, error) {
+	ts, err := f.Wrapped.FetchTimestamp(ctx)
+	if _, ok := err.(domain.TimestampNotFound); !ok {
+		return ts, err
+	}
+
+	var start time.Time
+	switch f.Policy {
+	case BootstrapPolicyNow:
+		start = time.Now()
+	case BootstrapPolicyLookback:
+		start = time.Now().Add(-1 * f.Lookback)
+	case BootstrapPolicyTimestamp:
+		start = f.Timestamp
+	default:
+		// full history, preserve the not found result so that every scan is fetched
+		return ts, err
+	}
+
+	if err := f.Storer.StoreTimestamp(ctx, start); err != nil {
+		return time.Time{}, err
+	}
+	f.LogFn(ctx).Info(logs.WatermarkBootstrapped{
+		Policy:    f.Policy,
+		Timestamp: start.Format(time.RFC3339Nano),
+	})
+	return start, nil
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrapName(t *testing.T) {
+	bootstrapConfig := BootstrapConfig{}
+	require.Equal(t, "Bootstrap", bootstrapConfig.Name())
+}
+
+func TestBootstrapComponentDefaultConfig(t *testing.T) {
+	component := &BootstrapComponent{}
+	config := component.Settings()
+	require.Equal(t, BootstrapPolicyAll, config.Policy)
+	require.Zero(t, config.Lookback)
+	require.True(t, config.Timestamp.IsZero())
+}
+
+func TestBootstrapComponentNew(t *testing.T) {
+	ts := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	tests := []struct {
+		name      string
+		config    *BootstrapConfig
+		policy    string
+		expectErr bool
+	}{
+		{
+			name:   "now",
+			config: &BootstrapConfig{Policy: "now"},
+			policy: BootstrapPolicyNow,
+		},
+		{
+			name:   "all",
+			config: &BootstrapConfig{Policy: BootstrapPolicyAll},
+			policy: BootstrapPolicyAll,
+		},
+		{
+			name:   "lookback",
+			config: &BootstrapConfig{Policy: BootstrapPolicyLookback, Lookback: time.Hour},
+			policy: BootstrapPolicyLookback,
+		},
+		{
+			name:      "lookback without duration",
+			config:    &BootstrapConfig{Policy: BootstrapPolicyLookback},
+			expectErr: true,
+		},
+		{
+			name:   "timestamp",
+			config: &BootstrapConfig{Policy: BootstrapPolicyTimestamp, Timestamp: ts},
+			policy: BootstrapPolicyTimestamp,
+		},
+		{
+			name:      "timestamp without value",
+			config:    &BootstrapConfig{Policy: BootstrapPolicyTimestamp},
+			expectErr: true,
+		},
+		{
+			name:      "unknown policy",
+			config:    &BootstrapConfig{Policy: "SOMETIMES"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher, err := (&BootstrapComponent{}).New(context.Background(), tt.config)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.policy, fetcher.Policy)
+		})
+	}
+}
+
+func TestBootstrapTimestampFetcher_FetchTimestamp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stored := time.Date(2019, 05, 24, 00, 00, 00, 00, time.UTC)
+	configured := time.Date(2019, 01, 01, 00, 00, 00, 00, time.UTC)
+	tests := []struct {
+		name        string
+		policy      string
+		fetched     time.Time
+		fetchErr    error
+		expectStore bool
+		storeErr    error
+		expected    func(t *testing.T, ts time.Time)
+		expectErr   bool
+	}{
+		{
+			name:     "stored timestamp is returned unchanged",
+			policy:   BootstrapPolicyNow,
+			fetched:  stored,
+			expected: func(t *testing.T, ts time.Time) { require.Equal(t, stored, ts) },
+		},
+		{
+			name:      "storage errors are returned unchanged",
+			policy:    BootstrapPolicyNow,
+			fetchErr:  fmt.Errorf("storage error"),
+			expected:  func(t *testing.T, ts time.Time) { require.True(t, ts.IsZero()) },
+			expectErr: true,
+		},
+		{
+			name:        "now policy",
+			policy:      BootstrapPolicyNow,
+			fetchErr:    domain.TimestampNotFound{},
+			expectStore: true,
+			expected: func(t *testing.T, ts time.Time) {
+				require.WithinDuration(t, time.Now(), ts, time.Minute)
+			},
+		},
+		{
+			name:        "lookback policy",
+			policy:      BootstrapPolicyLookback,
+			fetchErr:    domain.TimestampNotFound{},
+			expectStore: true,
+			expected: func(t *testing.T, ts time.Time) {
+				require.WithinDuration(t, time.Now().Add(-24*time.Hour), ts, time.Minute)
+			},
+		},
+		{
+			name:        "timestamp policy",
+			policy:      BootstrapPolicyTimestamp,
+			fetchErr:    domain.TimestampNotFound{},
+			expectStore: true,
+			expected:    func(t *testing.T, ts time.Time) { require.Equal(t, configured, ts) },
+		},
+		{
+			name:      "all policy",
+			policy:    BootstrapPolicyAll,
+			fetchErr:  domain.TimestampNotFound{},
+			expected:  func(t *testing.T, ts time.Time) { require.True(t, ts.IsZero()) },
+			expectErr: true,
+		},
+		{
+			name:        "error storing bootstrap timestamp",
+			policy:      BootstrapPolicyTimestamp,
+			fetchErr:    domain.TimestampNotFound{},
+			expectStore: true,
+			storeErr:    fmt.Errorf("storage error"),
+			expected:    func(t *testing.T, ts time.Time) { require.True(t, ts.IsZero()) },
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFetcher := NewMockTimestampFetcher(ctrl)
+			mockStorer := NewMockTimestampStorer(ctrl)
+			mockFetcher.EXPECT().FetchTimestamp(gomock.Any()).Return(tt.fetched, tt.fetchErr)
+			if tt.expectStore {
+				mockStorer.EXPECT().StoreTimestamp(gomock.Any(), gomock.Any()).Return(tt.storeErr)
+			}
+			fetcher := &BootstrapTimestampFetcher{
+				Wrapped:   mockFetcher,
+				Storer:    mockStorer,
+				Policy:    tt.policy,
+				Lookback:  24 * time.Hour,
+				Timestamp: configured,
+				LogFn:     testLogFn,
+			}
+			ts, err := fetcher.FetchTimestamp(context.Background())
+			tt.expected(t, ts)
+			require.Equal(t, tt.expectErr, err != nil)
+		})
+	}
+}
@@ -13,17 +13,37 @@ const (
 	defaultDynamoDBPartitionKeyName        = "partitionkey"
 	defaultDynamoDBLastProcessedPartionKey = "lastProcessed"
 	defaultDynamoDBTimestampKeyName        = "timestamp"
+	defaultDynamoDBInFlightPartitionKey    = "inFlight"
+	defaultDynamoDBInFlightKeyName         = "scans"
+	defaultDynamoDBQuarantineKeyPrefix     = "quarantine-"
+	defaultDynamoDBDeadLetterKeyPrefix     = "deadLetter-"
+	defaultDynamoDBRunHistoryKeyPrefix     = "runHistory-"
+	defaultDynamoDBExpiresAtKeyName        = "expiresAt"
+	defaultDynamoDBHealthKeyValue          = "health"
+	defaultDynamoDBSiteScansKeyValue       = "siteScans"
+	defaultDynamoDBStalledScansKeyValue    = "stalledScans"
+	defaultDynamoDBWatermarkAuditKeyPrefix = "watermarkAudit-"
 )
 
 // DynamoDBTimestampStorageConfig holds configuration required to send Nexpose assets
 // to a queue via an HTTP Producer
 type DynamoDBTimestampStorageConfig struct {
-	TableName         string
-	PartitionKeyName  string
-	PartitionKeyValue string
-	TimestampKeyName  string
-	Region            string
-	Endpoint          string
+	TableName               string
+	PartitionKeyName        string
+	PartitionKeyValue       string
+	TimestampKeyName        string
+	InFlightKeyValue        string
+	InFlightKeyName         string
+	QuarantineKeyPrefix     string
+	DeadLetterKeyPrefix     string
+	RunHistoryKeyPrefix     string
+	ExpiresAtKeyName        string
+	HealthKeyValue          string
+	SiteScansKeyValue       string
+	StalledScansKeyValue    string
+	WatermarkAuditKeyPrefix string
+	Region                  string
+	Endpoint                string
 }
 
 // Name is used by the settings library and will add a "DYNAMODB"
@@ -39,10 +59,20 @@ type DynamoDBTimestampStorageComponent struct{}
 // Settings can be used to populate default values if there are any
 func (*DynamoDBTimestampStorageComponent) Settings() *DynamoDBTimestampStorageConfig {
 	return &DynamoDBTimestampStorageConfig{
-		TableName:         defaultDynamoDBTableName,
-		PartitionKeyName:  defaultDynamoDBPartitionKeyName,
-		PartitionKeyValue: defaultDynamoDBLastProcessedPartionKey,
-		TimestampKeyName:  defaultDynamoDBTimestampKeyName,
+		TableName:               defaultDynamoDBTableName,
+		PartitionKeyName:        defaultDynamoDBPartitionKeyName,
+		PartitionKeyValue:       defaultDynamoDBLastProcessedPartionKey,
+		TimestampKeyName:        defaultDynamoDBTimestampKeyName,
+		InFlightKeyValue:        defaultDynamoDBInFlightPartitionKey,
+		InFlightKeyName:         defaultDynamoDBInFlightKeyName,
+		QuarantineKeyPrefix:     defaultDynamoDBQuarantineKeyPrefix,
+		DeadLetterKeyPrefix:     defaultDynamoDBDeadLetterKeyPrefix,
+		RunHistoryKeyPrefix:     defaultDynamoDBRunHistoryKeyPrefix,
+		ExpiresAtKeyName:        defaultDynamoDBExpiresAtKeyName,
+		HealthKeyValue:          defaultDynamoDBHealthKeyValue,
+		SiteScansKeyValue:       defaultDynamoDBSiteScansKeyValue,
+		StalledScansKeyValue:    defaultDynamoDBStalledScansKeyValue,
+		WatermarkAuditKeyPrefix: defaultDynamoDBWatermarkAuditKeyPrefix,
 	}
 }
 
@@ -58,10 +88,20 @@ func (*DynamoDBTimestampStorageComponent) New(_ context.Context, c *DynamoDBTime
 
 	db := dynamodb.New(awsSession)
 	return &DynamoDBTimestampStorage{
-		db:                db,
-		tableName:         c.TableName,
-		partitionKeyName:  c.PartitionKeyName,
-		partitionKeyValue: c.PartitionKeyValue,
-		timestampKeyName:  c.TimestampKeyName,
+		db:                      db,
+		tableName:               c.TableName,
+		partitionKeyName:        c.PartitionKeyName,
+		partitionKeyValue:       c.PartitionKeyValue,
+		timestampKeyName:        c.TimestampKeyName,
+		inFlightKeyValue:        c.InFlightKeyValue,
+		inFlightKeyName:         c.InFlightKeyName,
+		quarantineKeyPrefix:     c.QuarantineKeyPrefix,
+		deadLetterKeyPrefix:     c.DeadLetterKeyPrefix,
+		runHistoryKeyPrefix:     c.RunHistoryKeyPrefix,
+		expiresAtKeyName:        c.ExpiresAtKeyName,
+		healthKeyValue:          c.HealthKeyValue,
+		siteScansKeyValue:       c.SiteScansKeyValue,
+		stalledScansKeyValue:    c.StalledScansKeyValue,
+		watermarkAuditKeyPrefix: c.WatermarkAuditKeyPrefix,
 	}, nil
 }
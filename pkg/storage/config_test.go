@@ -3,7 +3,9 @@ package storage
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/asecurityteam/settings"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,6 +23,13 @@ func TestComponentDefaultConfig(t *testing.T) {
 	require.Equal(t, config.PartitionKeyName, defaultDynamoDBPartitionKeyName)
 	require.Equal(t, config.PartitionKeyValue, defaultDynamoDBLastProcessedPartionKey)
 	require.Equal(t, config.TimestampKeyName, defaultDynamoDBTimestampKeyName)
+	require.Equal(t, config.InFlightKeyValue, defaultDynamoDBInFlightPartitionKey)
+	require.Equal(t, config.InFlightKeyName, defaultDynamoDBInFlightKeyName)
+	require.Equal(t, config.QuarantineKeyPrefix, defaultDynamoDBQuarantineKeyPrefix)
+	require.Equal(t, config.DeadLetterKeyPrefix, defaultDynamoDBDeadLetterKeyPrefix)
+	require.Equal(t, config.HealthKeyValue, defaultDynamoDBHealthKeyValue)
+	require.Equal(t, config.SiteScansKeyValue, defaultDynamoDBSiteScansKeyValue)
+	require.Equal(t, config.StalledScansKeyValue, defaultDynamoDBStalledScansKeyValue)
 }
 
 func TestNexposeClientConfigWithValues(t *testing.T) {
@@ -32,6 +41,8 @@ func TestNexposeClientConfigWithValues(t *testing.T) {
 		PartitionKeyName:  "partitionKeyName",
 		PartitionKeyValue: "partitionKeyValue",
 		TimestampKeyName:  "timestampKeyName",
+		InFlightKeyValue:  "inFlightKeyValue",
+		InFlightKeyName:   "inFlightKeyName",
 	}
 	dynamoDBTimestampStorage, err := component.New(context.Background(), config)
 
@@ -39,5 +50,80 @@ func TestNexposeClientConfigWithValues(t *testing.T) {
 	require.Equal(t, "partitionKeyName", dynamoDBTimestampStorage.partitionKeyName)
 	require.Equal(t, "partitionKeyValue", dynamoDBTimestampStorage.partitionKeyValue)
 	require.Equal(t, "timestampKeyName", dynamoDBTimestampStorage.timestampKeyName)
+	require.Equal(t, "inFlightKeyValue", dynamoDBTimestampStorage.inFlightKeyValue)
+	require.Equal(t, "inFlightKeyName", dynamoDBTimestampStorage.inFlightKeyName)
 	require.Nil(t, err)
 }
+
+func TestStorageComponent(t *testing.T) {
+	tests := []struct {
+		name        string
+		storageType string
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:        "dynamodb",
+			storageType: StorageTypeDynamoDB,
+			expected:    &DynamoDBTimestampStorage{},
+		},
+		{
+			name:        "memory",
+			storageType: "memory",
+			expected:    &MemoryStorage{},
+		},
+		{
+			name:        "unknown",
+			storageType: "FILE",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := settings.NewMapSource(map[string]interface{}{
+				"storage": map[string]interface{}{"type": tt.storageType},
+			})
+			component := &StorageComponent{Source: source}
+			var store Storage
+			err := settings.NewComponent(context.Background(), source, component, &store)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tt.expected, store)
+		})
+	}
+}
+
+func TestStorageComponent_DynamoDBSettings(t *testing.T) {
+	source := settings.NewMapSource(map[string]interface{}{
+		"dynamodb": map[string]interface{}{"tablename": "tableName"},
+	})
+	component := &StorageComponent{Source: source}
+	store, err := component.New(context.Background(), component.Settings())
+	require.NoError(t, err)
+	require.Equal(t, "tableName", store.(*DynamoDBTimestampStorage).tableName)
+}
+
+func TestStorageComponent_HistoryTTL(t *testing.T) {
+	component := &StorageComponent{Source: settings.NewMapSource(map[string]interface{}{})}
+	conf := component.Settings()
+	require.Equal(t, 30*24*time.Hour, conf.HistoryTTL)
+
+	conf.HistoryTTL = time.Hour
+	store, err := component.New(context.Background(), conf)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, store.(*DynamoDBTimestampStorage).historyTTL)
+	require.Equal(t, defaultDynamoDBRunHistoryKeyPrefix, store.(*DynamoDBTimestampStorage).runHistoryKeyPrefix)
+
+	conf.Type = StorageTypeMemory
+	store, err = component.New(context.Background(), conf)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, store.(*MemoryStorage).state.historyTTL)
+
+	conf.HistoryTTL = -1 * time.Hour
+	_, err = component.New(context.Background(), conf)
+	require.Error(t, err)
+}
@@ -2,15 +2,21 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
+// dynamoDBDependency names DynamoDB in the domain errors returned by storage.
+const dynamoDBDependency = "dynamodb"
+
 type dynamoResult struct {
 	PartitionKey string `json:"partitionKey"`
 	Timestamp    string `json:"timestamp"`
@@ -19,11 +25,33 @@ type dynamoResult struct {
 // DynamoDBTimestampStorage provides persistence and retrieval of last processed scan timestamps from
 // a DynamoDB table.
 type DynamoDBTimestampStorage struct {
-	db                dynamodbiface.DynamoDBAPI
-	tableName         string
-	partitionKeyName  string
-	partitionKeyValue string
-	timestampKeyName  string
+	db                      dynamodbiface.DynamoDBAPI
+	tableName               string
+	partitionKeyName        string
+	partitionKeyValue       string
+	timestampKeyName        string
+	inFlightKeyValue        string
+	inFlightKeyName         string
+	quarantineKeyPrefix     string
+	deadLetterKeyPrefix     string
+	runHistoryKeyPrefix     string
+	expiresAtKeyName        string
+	healthKeyValue          string
+	siteScansKeyValue       string
+	stalledScansKeyValue    string
+	watermarkAuditKeyPrefix string
+	historyTTL              time.Duration
+}
+
+// DestinationPartition returns storage for the timestamp of the last scan processed by
+// a single destination, and the scans which finished late that it missed. It shares
+// the same table, using the configured partition key values suffixed with the
+// destination name, so that each destination tracks its own progress.
+func (s *DynamoDBTimestampStorage) DestinationPartition(destination string) Storage {
+	partition := *s
+	partition.partitionKeyValue = s.partitionKeyValue + "-" + destination
+	partition.inFlightKeyValue = s.inFlightKeyValue + "-" + destination
+	return &partition
 }
 
 // FetchTimestamp queries a DynamoDB table with a static partition key for the last processed timestamp.
@@ -37,7 +65,7 @@ func (s *DynamoDBTimestampStorage) FetchTimestamp(ctx context.Context) (time.Tim
 		},
 	})
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, dynamoDBError(err)
 	}
 
 	var result dynamoResult
@@ -71,15 +99,559 @@ func (s *DynamoDBTimestampStorage) StoreTimestamp(ctx context.Context, ts time.T
 			},
 		},
 	})
+	if err != nil {
+		return dynamoDBError(err)
+	}
+
+	return nil
+}
+
+// ResetTimestamp removes the last processed timestamp from a DynamoDB table with a static partition key.
+func (s *DynamoDBTimestampStorage) ResetTimestamp(ctx context.Context) error {
+	_, err := s.db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.partitionKeyValue),
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
+
+// RecordWatermarkChange stores the audit record of a manual change to the timestamp
+// in a DynamoDB table, using the timestamp's partition key value and the time of the
+// change with a static prefix as the partition key.
+func (s *DynamoDBTimestampStorage) RecordWatermarkChange(ctx context.Context, change domain.WatermarkChange) error {
+	item := map[string]*dynamodb.AttributeValue{
+		s.partitionKeyName: {
+			S: aws.String(s.watermarkAuditKeyPrefix + s.partitionKeyValue + "-" + change.ChangedAt.Format(time.RFC3339Nano)),
+		},
+		"action": {
+			S: aws.String(change.Action),
+		},
+		"actor": {
+			S: aws.String(change.Actor),
+		},
+		"reason": {
+			S: aws.String(change.Reason),
+		},
+		"changedAt": {
+			S: aws.String(change.ChangedAt.Format(time.RFC3339Nano)),
+		},
+	}
+	if !change.Previous.IsZero() {
+		item["previous"] = &dynamodb.AttributeValue{S: aws.String(change.Previous.Format(time.RFC3339Nano))}
+	}
+	if !change.Current.IsZero() {
+		item["current"] = &dynamodb.AttributeValue{S: aws.String(change.Current.Format(time.RFC3339Nano))}
+	}
+	_, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      item,
+	})
+	return dynamoDBError(err)
+}
+
+// FetchInFlightScans queries a DynamoDB table with a static partition key for the IDs of
+// scans which were in flight during the last run.
+func (s *DynamoDBTimestampStorage) FetchInFlightScans(ctx context.Context) ([]string, error) {
+	item, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.inFlightKeyValue),
+			},
+		},
+	})
+	if err != nil {
+		return nil, dynamoDBError(err)
+	}
+
+	scanIDs := []string{}
+	if value, ok := item.Item[s.inFlightKeyName]; ok {
+		for _, scanID := range value.L {
+			scanIDs = append(scanIDs, aws.StringValue(scanID.S))
+		}
+	}
+	return scanIDs, nil
+}
+
+// StoreInFlightScans upserts the IDs of scans which are in flight to a DynamoDB table
+// with a static partition key.
+func (s *DynamoDBTimestampStorage) StoreInFlightScans(ctx context.Context, scanIDs []string) error {
+	values := make([]*dynamodb.AttributeValue, 0, len(scanIDs))
+	for _, scanID := range scanIDs {
+		values = append(values, &dynamodb.AttributeValue{S: aws.String(scanID)})
+	}
+	_, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.inFlightKeyValue),
+			},
+			s.inFlightKeyName: {
+				L: values,
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
+
+// QuarantineScan stores a scan record which could not be parsed in a DynamoDB table, using
+// the scan ID with a static prefix as the partition key.
+func (s *DynamoDBTimestampStorage) QuarantineScan(ctx context.Context, scan domain.QuarantinedScan) error {
+	_, err := s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.quarantineKeyPrefix + scan.ScanID),
+			},
+			"siteID": {
+				S: aws.String(scan.SiteID),
+			},
+			"reason": {
+				S: aws.String(scan.Reason),
+			},
+			"record": {
+				S: aws.String(scan.Record),
+			},
+			"quarantinedAt": {
+				S: aws.String(scan.QuarantinedAt.Format(time.RFC3339Nano)),
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
+
+// StoreDeadLetter stores a scan which could not be produced to a destination in a DynamoDB
+// table, using the destination and scan ID with a static prefix as the partition key.
+func (s *DynamoDBTimestampStorage) StoreDeadLetter(ctx context.Context, deadLetter domain.DeadLetter) error {
+	record, err := json.Marshal(deadLetter.Scan)
 	if err != nil {
 		return err
 	}
+	_, err = s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.deadLetterKeyPrefix + deadLetter.Destination + "-" + deadLetter.Scan.ScanID),
+			},
+			"destination": {
+				S: aws.String(deadLetter.Destination),
+			},
+			"reason": {
+				S: aws.String(deadLetter.Reason),
+			},
+			"record": {
+				S: aws.String(string(record)),
+			},
+			"attempts": {
+				N: aws.String(strconv.Itoa(deadLetter.Attempts)),
+			},
+			"failedAt": {
+				S: aws.String(deadLetter.FailedAt.Format(time.RFC3339Nano)),
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
 
+// runHistoryBucket is the period of the runs stored under a single partition key.
+const runHistoryBucket = time.Hour
+
+// RecordRun appends the history of a run to a DynamoDB table, which keeps the runs
+// started within each hour in a list, using the hour with a static prefix as the
+// partition key. When a history TTL is set, each hour records the time its last run
+// expires, so that DynamoDB TTL can remove it when enabled on the table.
+func (s *DynamoDBTimestampStorage) RecordRun(ctx context.Context, run domain.RunRecord) error {
+	record, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	bucket := run.StartTime.UTC().Truncate(runHistoryBucket)
+	update := "SET #runs = list_append(if_not_exists(#runs, :empty), :run)"
+	names := map[string]*string{"#runs": aws.String("runs")}
+	values := map[string]*dynamodb.AttributeValue{
+		":empty": {L: []*dynamodb.AttributeValue{}},
+		":run":   {L: []*dynamodb.AttributeValue{{S: aws.String(string(record))}}},
+	}
+	if s.historyTTL > 0 {
+		update = update + ", #expiresAt = :expiresAt"
+		names["#expiresAt"] = aws.String(s.expiresAtKeyName)
+		expiresAt := bucket.Add(runHistoryBucket).Add(s.historyTTL).Unix()
+		values[":expiresAt"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt, 10))}
+	}
+	_, err = s.db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.runHistoryKey(bucket)),
+			},
+		},
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return dynamoDBError(err)
+}
+
+// FetchRuns returns the runs matching a query from a DynamoDB table, most recent first.
+// The hours in the range are fetched one at a time, most recent first, until the
+// page is full. Runs which started before the history TTL are excluded, even if
+// DynamoDB has not yet removed them.
+func (s *DynamoDBTimestampStorage) FetchRuns(ctx context.Context, query domain.RunQuery) (domain.RunPage, error) {
+	cursor, err := decodeRunCursor(query.Cursor)
+	if err != nil {
+		return domain.RunPage{}, err
+	}
+	since, until := runQueryRange(query, s.historyTTL)
+	if !since.Before(until) {
+		return domain.RunPage{Runs: []domain.RunRecord{}}, nil
+	}
+	last := until.Add(-1 * time.Nanosecond)
+	if cursor != nil && cursor.startTime.Before(last) {
+		last = cursor.startTime
+	}
+
+	runs := []domain.RunRecord{}
+	first := since.UTC().Truncate(runHistoryBucket)
+	for bucket := last.UTC().Truncate(runHistoryBucket); !bucket.Before(first); bucket = bucket.Add(-1 * runHistoryBucket) {
+		bucketRuns, err := s.fetchRunHistoryBucket(ctx, bucket)
+		if err != nil {
+			return domain.RunPage{}, err
+		}
+		matched := make([]domain.RunRecord, 0, len(bucketRuns))
+		for _, run := range bucketRuns {
+			if matchesRun(run, since, until, cursor) {
+				matched = append(matched, run)
+			}
+		}
+		sortRuns(matched)
+		runs = append(runs, matched...)
+		if query.Limit > 0 && len(runs) > query.Limit {
+			break
+		}
+	}
+	return pageRuns(runs, query.Limit), nil
+}
+
+// fetchRunHistoryBucket returns the runs started within the hour beginning at bucket.
+func (s *DynamoDBTimestampStorage) fetchRunHistoryBucket(ctx context.Context, bucket time.Time) ([]domain.RunRecord, error) {
+	item, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.runHistoryKey(bucket)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, dynamoDBError(err)
+	}
+
+	value, ok := item.Item["runs"]
+	if !ok {
+		return nil, nil
+	}
+	runs := make([]domain.RunRecord, 0, len(value.L))
+	for _, record := range value.L {
+		var run domain.RunRecord
+		if err := json.Unmarshal([]byte(aws.StringValue(record.S)), &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *DynamoDBTimestampStorage) runHistoryKey(bucket time.Time) string {
+	return s.runHistoryKeyPrefix + bucket.Format("2006-01-02T15")
+}
+
+// FetchHealth queries a DynamoDB table with a static partition key for the health of
+// the notifier, returning the zero value when none is stored.
+func (s *DynamoDBTimestampStorage) FetchHealth(ctx context.Context) (domain.NotifierHealth, error) {
+	item, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.healthKeyValue),
+			},
+		},
+	})
+	if err != nil {
+		return domain.NotifierHealth{}, dynamoDBError(err)
+	}
+
+	var health domain.NotifierHealth
+	if value, ok := item.Item["health"]; ok {
+		if err := json.Unmarshal([]byte(aws.StringValue(value.S)), &health); err != nil {
+			return domain.NotifierHealth{}, err
+		}
+	}
+	return health, nil
+}
+
+// StoreHealth upserts the health of the notifier to a DynamoDB table with a static
+// partition key.
+func (s *DynamoDBTimestampStorage) StoreHealth(ctx context.Context, health domain.NotifierHealth) error {
+	record, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.healthKeyValue),
+			},
+			"health": {
+				S: aws.String(string(record)),
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
+
+// FetchStalledScans queries a DynamoDB table with a static partition key for the
+// scans which were stalled as of the last check.
+func (s *DynamoDBTimestampStorage) FetchStalledScans(ctx context.Context) ([]domain.StalledScan, error) {
+	item, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.stalledScansKeyValue),
+			},
+		},
+	})
+	if err != nil {
+		return nil, dynamoDBError(err)
+	}
+
+	var scans []domain.StalledScan
+	if value, ok := item.Item["scans"]; ok {
+		if err := json.Unmarshal([]byte(aws.StringValue(value.S)), &scans); err != nil {
+			return nil, err
+		}
+	}
+	return scans, nil
+}
+
+// StoreStalledScans upserts the scans which are stalled to a DynamoDB table with a
+// static partition key.
+func (s *DynamoDBTimestampStorage) StoreStalledScans(ctx context.Context, scans []domain.StalledScan) error {
+	if scans == nil {
+		scans = []domain.StalledScan{}
+	}
+	record, err := json.Marshal(scans)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.stalledScansKeyValue),
+			},
+			"scans": {
+				S: aws.String(string(record)),
+			},
+		},
+	})
+	return dynamoDBError(err)
+}
+
+// siteScansAttempts is the number of times the tracked state of a site is read and
+// written before giving up, when another run changes it concurrently.
+const siteScansAttempts = 3
+
+// RecordSiteScans updates the most recent completed scan of each site in a DynamoDB
+// table, which keeps the state of each site in its own item, with the site scans
+// partition key value suffixed with the site ID.
+func (s *DynamoDBTimestampStorage) RecordSiteScans(ctx context.Context, scans []domain.CompletedScan) error {
+	bySite := map[string][]domain.CompletedScan{}
+	for _, scan := range scans {
+		bySite[scan.SiteID] = append(bySite[scan.SiteID], scan)
+	}
+	now := time.Now()
+	for _, siteID := range sortedSiteIDs(bySite) {
+		siteScans := bySite[siteID]
+		err := s.updateSiteScan(ctx, siteID, func(states map[string]domain.SiteScanState) {
+			recordSiteScans(states, siteScans, now)
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// FetchSiteScans scans a DynamoDB table for the items which hold the tracked state of
+// each site, returning them ordered by site ID.
+func (s *DynamoDBTimestampStorage) FetchSiteScans(ctx context.Context) ([]domain.SiteScanState, error) {
+	states := map[string]domain.SiteScanState{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		output, err := s.db.ScanWithContext(ctx, &dynamodb.ScanInput{
+			TableName:                aws.String(s.tableName),
+			FilterExpression:         aws.String("begins_with(#key, :prefix)"),
+			ExpressionAttributeNames: map[string]*string{"#key": aws.String(s.partitionKeyName)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":prefix": {S: aws.String(s.siteScanKey(""))},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, dynamoDBError(err)
+		}
+		for _, item := range output.Items {
+			state, ok, err := siteScanState(item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				states[state.SiteID] = state
+			}
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			return sortedSiteScans(states), nil
+		}
+		startKey = output.LastEvaluatedKey
+	}
+}
+
+// StoreSiteScans replaces the tracked state of sites in a DynamoDB table, one item
+// per site.
+func (s *DynamoDBTimestampStorage) StoreSiteScans(ctx context.Context, updates []domain.SiteScanState) error {
+	for _, update := range updates {
+		update := update
+		err := s.updateSiteScan(ctx, update.SiteID, func(states map[string]domain.SiteScanState) {
+			storeSiteScans(states, []domain.SiteScanState{update})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DynamoDBTimestampStorage) siteScanKey(siteID string) string {
+	return s.siteScansKeyValue + "-" + siteID
+}
+
+// fetchSiteScan returns the tracked state of a single site, if it is tracked, along
+// with the version of the item which holds it.
+func (s *DynamoDBTimestampStorage) fetchSiteScan(ctx context.Context, siteID string) (map[string]domain.SiteScanState, int, error) {
+	item, err := s.db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			s.partitionKeyName: {
+				S: aws.String(s.siteScanKey(siteID)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, 0, dynamoDBError(err)
+	}
+
+	states := map[string]domain.SiteScanState{}
+	state, ok, err := siteScanState(item.Item)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ok {
+		states[siteID] = state
+	}
+	var version int
+	if value, ok := item.Item["version"]; ok {
+		if version, err = strconv.Atoi(aws.StringValue(value.N)); err != nil {
+			return nil, 0, err
+		}
+	}
+	return states, version, nil
+}
+
+// siteScanState decodes the tracked state of a site from the item which holds it.
+func siteScanState(item map[string]*dynamodb.AttributeValue) (domain.SiteScanState, bool, error) {
+	value, ok := item["site"]
+	if !ok {
+		return domain.SiteScanState{}, false, nil
+	}
+	var state domain.SiteScanState
+	if err := json.Unmarshal([]byte(aws.StringValue(value.S)), &state); err != nil {
+		return domain.SiteScanState{}, false, err
+	}
+	return state, true, nil
+}
+
+// updateSiteScan applies update to the tracked state of a single site, writing it
+// only if no other run has changed the site since it was read, and trying again if
+// one has.
+func (s *DynamoDBTimestampStorage) updateSiteScan(ctx context.Context, siteID string, update func(map[string]domain.SiteScanState)) error {
+	for attempt := 1; ; attempt = attempt + 1 {
+		states, version, err := s.fetchSiteScan(ctx, siteID)
+		if err != nil {
+			return err
+		}
+		update(states)
+		record, err := json.Marshal(states[siteID])
+		if err != nil {
+			return err
+		}
+		_, err = s.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(s.tableName),
+			Item: map[string]*dynamodb.AttributeValue{
+				s.partitionKeyName: {
+					S: aws.String(s.siteScanKey(siteID)),
+				},
+				"site": {
+					S: aws.String(string(record)),
+				},
+				"version": {
+					N: aws.String(strconv.Itoa(version + 1)),
+				},
+			},
+			ConditionExpression:      aws.String("attribute_not_exists(#version) OR #version = :version"),
+			ExpressionAttributeNames: map[string]*string{"#version": aws.String("version")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":version": {N: aws.String(strconv.Itoa(version))},
+			},
+		})
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException && attempt < siteScansAttempts {
+			continue
+		}
+		return dynamoDBError(err)
+	}
+}
+
 // CheckDependencies tries to communicate to the DB by trying to retrieve its tables
 func (s *DynamoDBTimestampStorage) CheckDependencies(ctx context.Context) error {
 	_, err := s.db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(s.tableName)})
+	return dynamoDBError(err)
+}
+
+// dynamoDBError converts an error returned by DynamoDB into the matching domain error.
+// Errors with no specific meaning are returned unchanged.
+func dynamoDBError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	reason := awsErr.Error()
+	switch awsErr.Code() {
+	case "UnrecognizedClientException", "InvalidSignatureException", "MissingAuthenticationTokenException",
+		"ExpiredTokenException", "AccessDeniedException":
+		return domain.AuthenticationFailure{Dependency: dynamoDBDependency, Reason: reason}
+	case dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeRequestLimitExceeded,
+		"ThrottlingException":
+		return domain.RateLimited{Dependency: dynamoDBDependency, Reason: reason}
+	case dynamodb.ErrCodeConditionalCheckFailedException, dynamodb.ErrCodeTransactionConflictException:
+		return domain.Conflict{Dependency: dynamoDBDependency, Reason: reason}
+	case dynamodb.ErrCodeResourceNotFoundException, "ValidationException":
+		return domain.InvalidConfiguration{Dependency: dynamoDBDependency, Reason: reason}
+	case dynamodb.ErrCodeInternalServerError, "ServiceUnavailable", "RequestError":
+		return domain.UpstreamUnavailable{Dependency: dynamoDBDependency, Reason: reason}
+	}
 	return err
 }
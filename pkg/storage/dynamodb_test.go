@@ -2,12 +2,16 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -154,6 +158,421 @@ func TestDynamoDBTimestampStorage_StoreTimestamp(t *testing.T) {
 	}
 }
 
+func TestDynamoDBTimestampStorage_ResetTimestamp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                mockDB,
+		tableName:         defaultDynamoDBTableName,
+		partitionKeyName:  defaultDynamoDBPartitionKeyName,
+		partitionKeyValue: defaultDynamoDBLastProcessedPartionKey,
+		timestampKeyName:  defaultDynamoDBTimestampKeyName,
+	}
+
+	deleteItemInput := &dynamodb.DeleteItemInput{
+		TableName: aws.String(defaultDynamoDBTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {
+				S: aws.String(defaultDynamoDBLastProcessedPartionKey),
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "success",
+			err:  nil,
+		},
+		{
+			name: "error deleting timestamp",
+			err:  fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().DeleteItemWithContext(gomock.Any(), deleteItemInput).Return(&dynamodb.DeleteItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.ResetTimestamp(context.Background())
+			if tt.err != nil {
+				require.Error(t, actual)
+				return
+			}
+			require.Nil(t, actual)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_FetchInFlightScans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:               mockDB,
+		tableName:        defaultDynamoDBTableName,
+		partitionKeyName: defaultDynamoDBPartitionKeyName,
+		inFlightKeyValue: defaultDynamoDBInFlightPartitionKey,
+		inFlightKeyName:  defaultDynamoDBInFlightKeyName,
+	}
+
+	tests := []struct {
+		name        string
+		response    *dynamodb.GetItemOutput
+		responseErr error
+		expected    []string
+		errExpected bool
+	}{
+		{
+			name: "success",
+			response: &dynamodb.GetItemOutput{
+				Item: map[string]*dynamodb.AttributeValue{
+					defaultDynamoDBPartitionKeyName: {
+						S: aws.String(defaultDynamoDBInFlightPartitionKey),
+					},
+					defaultDynamoDBInFlightKeyName: {
+						L: []*dynamodb.AttributeValue{{S: aws.String("1")}, {S: aws.String("2")}},
+					},
+				},
+			},
+			expected: []string{"1", "2"},
+		},
+		{
+			name:     "no scans stored",
+			response: &dynamodb.GetItemOutput{},
+			expected: []string{},
+		},
+		{
+			name:        "error fetching scans",
+			response:    &dynamodb.GetItemOutput{},
+			responseErr: fmt.Errorf("get item error"),
+			expected:    nil,
+			errExpected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().GetItemWithContext(gomock.Any(), &dynamodb.GetItemInput{
+				TableName: aws.String(defaultDynamoDBTableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					defaultDynamoDBPartitionKeyName: {
+						S: aws.String(defaultDynamoDBInFlightPartitionKey),
+					},
+				},
+			}).Return(tt.response, tt.responseErr)
+			actual, err := dynamoTimestampStorage.FetchInFlightScans(context.Background())
+			require.Equal(t, tt.expected, actual)
+			require.Equal(t, tt.errExpected, err != nil)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_StoreInFlightScans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:               mockDB,
+		tableName:        defaultDynamoDBTableName,
+		partitionKeyName: defaultDynamoDBPartitionKeyName,
+		inFlightKeyValue: defaultDynamoDBInFlightPartitionKey,
+		inFlightKeyName:  defaultDynamoDBInFlightKeyName,
+	}
+
+	tests := []struct {
+		name    string
+		scanIDs []string
+		values  []*dynamodb.AttributeValue
+		err     error
+	}{
+		{
+			name:    "success",
+			scanIDs: []string{"1", "2"},
+			values:  []*dynamodb.AttributeValue{{S: aws.String("1")}, {S: aws.String("2")}},
+			err:     nil,
+		},
+		{
+			name:    "success with no scans",
+			scanIDs: []string{},
+			values:  []*dynamodb.AttributeValue{},
+			err:     nil,
+		},
+		{
+			name:    "error storing scans",
+			scanIDs: []string{"1"},
+			values:  []*dynamodb.AttributeValue{{S: aws.String("1")}},
+			err:     fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+				TableName: aws.String(defaultDynamoDBTableName),
+				Item: map[string]*dynamodb.AttributeValue{
+					defaultDynamoDBPartitionKeyName: {
+						S: aws.String(defaultDynamoDBInFlightPartitionKey),
+					},
+					defaultDynamoDBInFlightKeyName: {
+						L: tt.values,
+					},
+				},
+			}).Return(&dynamodb.PutItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.StoreInFlightScans(context.Background(), tt.scanIDs)
+			require.Equal(t, tt.err != nil, actual != nil)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_QuarantineScan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                  mockDB,
+		tableName:           defaultDynamoDBTableName,
+		partitionKeyName:    defaultDynamoDBPartitionKeyName,
+		quarantineKeyPrefix: defaultDynamoDBQuarantineKeyPrefix,
+	}
+
+	ts := time.Now()
+	scan := domain.QuarantinedScan{
+		ScanID:        "1",
+		SiteID:        "2",
+		Reason:        "bad end time",
+		Record:        `{"id": 1}`,
+		QuarantinedAt: ts,
+	}
+	putItemInput := &dynamodb.PutItemInput{
+		TableName: aws.String(defaultDynamoDBTableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {
+				S: aws.String(defaultDynamoDBQuarantineKeyPrefix + "1"),
+			},
+			"siteID": {
+				S: aws.String("2"),
+			},
+			"reason": {
+				S: aws.String("bad end time"),
+			},
+			"record": {
+				S: aws.String(`{"id": 1}`),
+			},
+			"quarantinedAt": {
+				S: aws.String(ts.Format(time.RFC3339Nano)),
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "success",
+			err:  nil,
+		},
+		{
+			name: "error storing scan",
+			err:  fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItemInput).Return(&dynamodb.PutItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.QuarantineScan(context.Background(), scan)
+			require.Equal(t, tt.err != nil, actual != nil)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_StoreDeadLetter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                  mockDB,
+		tableName:           defaultDynamoDBTableName,
+		partitionKeyName:    defaultDynamoDBPartitionKeyName,
+		deadLetterKeyPrefix: defaultDynamoDBDeadLetterKeyPrefix,
+	}
+
+	ts := time.Now()
+	deadLetter := domain.DeadLetter{
+		Destination: "datalake",
+		Scan:        domain.CompletedScan{ScanID: "1", SiteID: "2"},
+		Reason:      "producer error",
+		Attempts:    3,
+		FailedAt:    ts,
+	}
+	record, _ := json.Marshal(deadLetter.Scan)
+	putItemInput := &dynamodb.PutItemInput{
+		TableName: aws.String(defaultDynamoDBTableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {
+				S: aws.String(defaultDynamoDBDeadLetterKeyPrefix + "datalake-1"),
+			},
+			"destination": {
+				S: aws.String("datalake"),
+			},
+			"reason": {
+				S: aws.String("producer error"),
+			},
+			"record": {
+				S: aws.String(string(record)),
+			},
+			"attempts": {
+				N: aws.String("3"),
+			},
+			"failedAt": {
+				S: aws.String(ts.Format(time.RFC3339Nano)),
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "success",
+			err:  nil,
+		},
+		{
+			name: "error storing dead letter",
+			err:  fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItemInput).Return(&dynamodb.PutItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.StoreDeadLetter(context.Background(), deadLetter)
+			require.Equal(t, tt.err != nil, actual != nil)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_RecordWatermarkChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                      mockDB,
+		tableName:               defaultDynamoDBTableName,
+		partitionKeyName:        defaultDynamoDBPartitionKeyName,
+		partitionKeyValue:       defaultDynamoDBLastProcessedPartionKey,
+		watermarkAuditKeyPrefix: defaultDynamoDBWatermarkAuditKeyPrefix,
+	}
+
+	changedAt := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	previous := changedAt.Add(-time.Hour)
+	tests := []struct {
+		name   string
+		change domain.WatermarkChange
+		item   map[string]*dynamodb.AttributeValue
+		err    error
+	}{
+		{
+			name: "set",
+			change: domain.WatermarkChange{
+				Action:    "set",
+				Actor:     "ops-automation",
+				Reason:    "rewind",
+				Previous:  previous,
+				Current:   changedAt.Add(-2 * time.Hour),
+				ChangedAt: changedAt,
+			},
+			item: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {S: aws.String("watermarkAudit-lastProcessed-2019-06-01T12:00:00Z")},
+				"action":                        {S: aws.String("set")},
+				"actor":                         {S: aws.String("ops-automation")},
+				"reason":                        {S: aws.String("rewind")},
+				"previous":                      {S: aws.String("2019-06-01T11:00:00Z")},
+				"current":                       {S: aws.String("2019-06-01T10:00:00Z")},
+				"changedAt":                     {S: aws.String("2019-06-01T12:00:00Z")},
+			},
+		},
+		{
+			name: "reset with no previous timestamp",
+			change: domain.WatermarkChange{
+				Action:    "reset",
+				Actor:     "ops-automation",
+				Reason:    "start over",
+				ChangedAt: changedAt,
+			},
+			item: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {S: aws.String("watermarkAudit-lastProcessed-2019-06-01T12:00:00Z")},
+				"action":                        {S: aws.String("reset")},
+				"actor":                         {S: aws.String("ops-automation")},
+				"reason":                        {S: aws.String("start over")},
+				"changedAt":                     {S: aws.String("2019-06-01T12:00:00Z")},
+			},
+			err: fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+				TableName: aws.String(defaultDynamoDBTableName),
+				Item:      tt.item,
+			}).Return(&dynamodb.PutItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.RecordWatermarkChange(context.Background(), tt.change)
+			require.Equal(t, tt.err != nil, actual != nil)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_DestinationPartition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                mockDB,
+		tableName:         defaultDynamoDBTableName,
+		partitionKeyName:  defaultDynamoDBPartitionKeyName,
+		partitionKeyValue: defaultDynamoDBLastProcessedPartionKey,
+		timestampKeyName:  defaultDynamoDBTimestampKeyName,
+		inFlightKeyValue:  defaultDynamoDBInFlightPartitionKey,
+		inFlightKeyName:   defaultDynamoDBInFlightKeyName,
+	}
+
+	partition := dynamoTimestampStorage.DestinationPartition("datalake")
+	require.Equal(t, defaultDynamoDBLastProcessedPartionKey, dynamoTimestampStorage.partitionKeyValue)
+
+	ts := time.Now()
+	mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+		TableName: aws.String(defaultDynamoDBTableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {
+				S: aws.String(defaultDynamoDBLastProcessedPartionKey + "-datalake"),
+			},
+			defaultDynamoDBTimestampKeyName: {
+				S: aws.String(ts.Format(time.RFC3339Nano)),
+			},
+		},
+	}).Return(&dynamodb.PutItemOutput{}, nil)
+	require.NoError(t, partition.StoreTimestamp(context.Background(), ts))
+
+	mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+		TableName: aws.String(defaultDynamoDBTableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {
+				S: aws.String(defaultDynamoDBInFlightPartitionKey + "-datalake"),
+			},
+			defaultDynamoDBInFlightKeyName: {
+				L: []*dynamodb.AttributeValue{{S: aws.String("4")}},
+			},
+		},
+	}).Return(&dynamodb.PutItemOutput{}, nil)
+	require.NoError(t, partition.StoreInFlightScans(context.Background(), []string{"4"}))
+}
+
 func TestDynamoDBDependencyCheck(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -189,3 +608,544 @@ func TestDynamoDBDependencyCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestDynamoDBError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected func(reason string) error
+	}{
+		{
+			name: "authentication failure",
+			err:  awserr.New("UnrecognizedClientException", "invalid token", nil),
+			expected: func(reason string) error {
+				return domain.AuthenticationFailure{Dependency: "dynamodb", Reason: reason}
+			},
+		},
+		{
+			name: "throttled",
+			err:  awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throughput exceeded", nil),
+			expected: func(reason string) error {
+				return domain.RateLimited{Dependency: "dynamodb", Reason: reason}
+			},
+		},
+		{
+			name: "conflict",
+			err:  awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil),
+			expected: func(reason string) error {
+				return domain.Conflict{Dependency: "dynamodb", Reason: reason}
+			},
+		},
+		{
+			name: "missing table",
+			err:  awserr.New(dynamodb.ErrCodeResourceNotFoundException, "no table", nil),
+			expected: func(reason string) error {
+				return domain.InvalidConfiguration{Dependency: "dynamodb", Reason: reason}
+			},
+		},
+		{
+			name: "unavailable",
+			err:  awserr.New("RequestError", "send request failed", nil),
+			expected: func(reason string) error {
+				return domain.UpstreamUnavailable{Dependency: "dynamodb", Reason: reason}
+			},
+		},
+		{
+			name: "other aws error",
+			err:  awserr.New("ItemCollectionSizeLimitExceededException", "too large", nil),
+			expected: func(string) error {
+				return awserr.New("ItemCollectionSizeLimitExceededException", "too large", nil)
+			},
+		},
+		{
+			name: "other error",
+			err:  errors.New("🐖"),
+			expected: func(string) error {
+				return errors.New("🐖")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			ctrl := gomock.NewController(tt)
+			defer ctrl.Finish()
+			mockDB := NewMockDynamoDBAPI(ctrl)
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), gomock.Any()).Return(nil, test.err)
+
+			dynamoTimestampStorage := &DynamoDBTimestampStorage{
+				db:                mockDB,
+				tableName:         defaultDynamoDBTableName,
+				partitionKeyName:  defaultDynamoDBPartitionKeyName,
+				partitionKeyValue: defaultDynamoDBLastProcessedPartionKey,
+				timestampKeyName:  defaultDynamoDBTimestampKeyName,
+			}
+			err := dynamoTimestampStorage.StoreTimestamp(context.Background(), time.Now())
+			require.Equal(tt, test.expected(test.err.Error()), err)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_RecordRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+
+	ts := time.Date(2019, 6, 1, 12, 30, 0, 0, time.UTC)
+	run := domain.RunRecord{RunID: "1", Source: "http", StartTime: ts, EndTime: ts.Add(time.Second), Produced: 2}
+	record, _ := json.Marshal(run)
+	key := map[string]*dynamodb.AttributeValue{
+		defaultDynamoDBPartitionKeyName: {
+			S: aws.String(defaultDynamoDBRunHistoryKeyPrefix + "2019-06-01T12"),
+		},
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":empty": {L: []*dynamodb.AttributeValue{}},
+		":run":   {L: []*dynamodb.AttributeValue{{S: aws.String(string(record))}}},
+	}
+
+	tests := []struct {
+		name       string
+		historyTTL time.Duration
+		input      *dynamodb.UpdateItemInput
+		err        error
+	}{
+		{
+			name: "without ttl",
+			input: &dynamodb.UpdateItemInput{
+				TableName:                 aws.String(defaultDynamoDBTableName),
+				Key:                       key,
+				UpdateExpression:          aws.String("SET #runs = list_append(if_not_exists(#runs, :empty), :run)"),
+				ExpressionAttributeNames:  map[string]*string{"#runs": aws.String("runs")},
+				ExpressionAttributeValues: values,
+			},
+		},
+		{
+			name:       "with ttl",
+			historyTTL: 24 * time.Hour,
+			input: &dynamodb.UpdateItemInput{
+				TableName:        aws.String(defaultDynamoDBTableName),
+				Key:              key,
+				UpdateExpression: aws.String("SET #runs = list_append(if_not_exists(#runs, :empty), :run), #expiresAt = :expiresAt"),
+				ExpressionAttributeNames: map[string]*string{
+					"#runs":      aws.String("runs"),
+					"#expiresAt": aws.String(defaultDynamoDBExpiresAtKeyName),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":empty":     values[":empty"],
+					":run":       values[":run"],
+					":expiresAt": {N: aws.String(strconv.FormatInt(time.Date(2019, 6, 2, 13, 0, 0, 0, time.UTC).Unix(), 10))},
+				},
+			},
+		},
+		{
+			name: "error recording run",
+			input: &dynamodb.UpdateItemInput{
+				TableName:                 aws.String(defaultDynamoDBTableName),
+				Key:                       key,
+				UpdateExpression:          aws.String("SET #runs = list_append(if_not_exists(#runs, :empty), :run)"),
+				ExpressionAttributeNames:  map[string]*string{"#runs": aws.String("runs")},
+				ExpressionAttributeValues: values,
+			},
+			err: fmt.Errorf("dynamodb error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoTimestampStorage := &DynamoDBTimestampStorage{
+				db:                  mockDB,
+				tableName:           defaultDynamoDBTableName,
+				partitionKeyName:    defaultDynamoDBPartitionKeyName,
+				runHistoryKeyPrefix: defaultDynamoDBRunHistoryKeyPrefix,
+				expiresAtKeyName:    defaultDynamoDBExpiresAtKeyName,
+				historyTTL:          tt.historyTTL,
+			}
+			mockDB.EXPECT().UpdateItemWithContext(gomock.Any(), tt.input).Return(&dynamodb.UpdateItemOutput{}, tt.err)
+			actual := dynamoTimestampStorage.RecordRun(context.Background(), run)
+			require.Equal(t, tt.err, actual)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_FetchRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                  mockDB,
+		tableName:           defaultDynamoDBTableName,
+		partitionKeyName:    defaultDynamoDBPartitionKeyName,
+		runHistoryKeyPrefix: defaultDynamoDBRunHistoryKeyPrefix,
+	}
+
+	ts := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	runs := []domain.RunRecord{
+		{RunID: "1", StartTime: ts.Add(10 * time.Minute)},
+		{RunID: "2", StartTime: ts.Add(50 * time.Minute)},
+		{RunID: "3", StartTime: ts.Add(70 * time.Minute)},
+		{RunID: "4", StartTime: ts.Add(130 * time.Minute)},
+	}
+	buckets := map[string][]domain.RunRecord{
+		"2019-06-01T10": {runs[0], runs[1]},
+		"2019-06-01T11": {runs[2]},
+		"2019-06-01T12": {runs[3]},
+	}
+	expectBucket := func(hour string) {
+		item := map[string]*dynamodb.AttributeValue{}
+		if bucketRuns, ok := buckets[hour]; ok {
+			records := []*dynamodb.AttributeValue{}
+			for _, run := range bucketRuns {
+				record, _ := json.Marshal(run)
+				records = append(records, &dynamodb.AttributeValue{S: aws.String(string(record))})
+			}
+			item["runs"] = &dynamodb.AttributeValue{L: records}
+		}
+		mockDB.EXPECT().GetItemWithContext(gomock.Any(), &dynamodb.GetItemInput{
+			TableName: aws.String(defaultDynamoDBTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {
+					S: aws.String(defaultDynamoDBRunHistoryKeyPrefix + hour),
+				},
+			},
+		}).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	}
+
+	// the first page stops fetching hours once it is full
+	expectBucket("2019-06-01T13")
+	expectBucket("2019-06-01T12")
+	expectBucket("2019-06-01T11")
+	query := domain.RunQuery{Since: ts.Add(20 * time.Minute), Until: ts.Add(4 * time.Hour), Limit: 1}
+	page, err := dynamoTimestampStorage.FetchRuns(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, []domain.RunRecord{runs[3]}, page.Runs)
+	require.NotEmpty(t, page.Cursor)
+
+	// the next page continues from the hour of the cursor, and excludes runs before since
+	expectBucket("2019-06-01T12")
+	expectBucket("2019-06-01T11")
+	expectBucket("2019-06-01T10")
+	query.Cursor = page.Cursor
+	query.Limit = 5
+	page, err = dynamoTimestampStorage.FetchRuns(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(page.Runs))
+	require.Equal(t, "3", page.Runs[0].RunID)
+	require.Equal(t, "2", page.Runs[1].RunID)
+	require.Empty(t, page.Cursor)
+
+	mockDB.EXPECT().GetItemWithContext(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("dynamodb error"))
+	_, err = dynamoTimestampStorage.FetchRuns(context.Background(), domain.RunQuery{Since: ts, Until: ts.Add(time.Hour)})
+	require.Error(t, err)
+}
+
+func TestDynamoDBTimestampStorage_FetchHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:               mockDB,
+		tableName:        defaultDynamoDBTableName,
+		partitionKeyName: defaultDynamoDBPartitionKeyName,
+		healthKeyValue:   defaultDynamoDBHealthKeyValue,
+	}
+	since := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	health := domain.NotifierHealth{
+		ConsecutiveFailures: 3,
+		LastError:           "fetchScans failed: nexpose error",
+		DegradedSince:       since,
+		Reasons:             []string{"consecutiveFailures"},
+		Alerted:             true,
+	}
+	record, _ := json.Marshal(health)
+
+	tests := []struct {
+		name     string
+		item     map[string]*dynamodb.AttributeValue
+		dbErr    error
+		expected domain.NotifierHealth
+		err      bool
+	}{
+		{
+			name:     "success",
+			item:     map[string]*dynamodb.AttributeValue{"health": {S: aws.String(string(record))}},
+			expected: health,
+		},
+		{
+			name:     "no health stored",
+			item:     map[string]*dynamodb.AttributeValue{},
+			expected: domain.NotifierHealth{},
+		},
+		{
+			name: "malformed health",
+			item: map[string]*dynamodb.AttributeValue{"health": {S: aws.String("{")}},
+			err:  true,
+		},
+		{
+			name:  "dynamodb error",
+			dbErr: fmt.Errorf("dynamodb error"),
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().GetItemWithContext(gomock.Any(), &dynamodb.GetItemInput{
+				TableName: aws.String(defaultDynamoDBTableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					defaultDynamoDBPartitionKeyName: {
+						S: aws.String(defaultDynamoDBHealthKeyValue),
+					},
+				},
+			}).Return(&dynamodb.GetItemOutput{Item: tt.item}, tt.dbErr)
+			actual, err := dynamoTimestampStorage.FetchHealth(context.Background())
+			require.Equal(t, tt.err, err != nil)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDynamoDBTimestampStorage_StoreHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:               mockDB,
+		tableName:        defaultDynamoDBTableName,
+		partitionKeyName: defaultDynamoDBPartitionKeyName,
+		healthKeyValue:   defaultDynamoDBHealthKeyValue,
+	}
+	health := domain.NotifierHealth{ConsecutiveFailures: 1, LastError: "produce failed"}
+	record, _ := json.Marshal(health)
+
+	for _, dbErr := range []error{nil, fmt.Errorf("dynamodb error")} {
+		mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+			TableName: aws.String(defaultDynamoDBTableName),
+			Item: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {
+					S: aws.String(defaultDynamoDBHealthKeyValue),
+				},
+				"health": {
+					S: aws.String(string(record)),
+				},
+			},
+		}).Return(&dynamodb.PutItemOutput{}, dbErr)
+		err := dynamoTimestampStorage.StoreHealth(context.Background(), health)
+		require.Equal(t, dbErr != nil, err != nil)
+	}
+}
+
+func TestDynamoDBTimestampStorage_StalledScans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := NewMockDynamoDBAPI(ctrl)
+	dynamoTimestampStorage := &DynamoDBTimestampStorage{
+		db:                   mockDB,
+		tableName:            defaultDynamoDBTableName,
+		partitionKeyName:     defaultDynamoDBPartitionKeyName,
+		stalledScansKeyValue: defaultDynamoDBStalledScansKeyValue,
+	}
+	scans := []domain.StalledScan{{ScanID: "1", SiteID: "2", StalledSince: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), Alerted: true}}
+	record, _ := json.Marshal(scans)
+	key := map[string]*dynamodb.AttributeValue{
+		defaultDynamoDBPartitionKeyName: {
+			S: aws.String(defaultDynamoDBStalledScansKeyValue),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		item     map[string]*dynamodb.AttributeValue
+		dbErr    error
+		expected []domain.StalledScan
+		err      bool
+	}{
+		{
+			name:     "success",
+			item:     map[string]*dynamodb.AttributeValue{"scans": {S: aws.String(string(record))}},
+			expected: scans,
+		},
+		{
+			name: "no scans stored",
+			item: map[string]*dynamodb.AttributeValue{},
+		},
+		{
+			name: "malformed scans",
+			item: map[string]*dynamodb.AttributeValue{"scans": {S: aws.String("[")}},
+			err:  true,
+		},
+		{
+			name:  "dynamodb error",
+			dbErr: fmt.Errorf("dynamodb error"),
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB.EXPECT().GetItemWithContext(gomock.Any(), &dynamodb.GetItemInput{
+				TableName: aws.String(defaultDynamoDBTableName),
+				Key:       key,
+			}).Return(&dynamodb.GetItemOutput{Item: tt.item}, tt.dbErr)
+			actual, err := dynamoTimestampStorage.FetchStalledScans(context.Background())
+			require.Equal(t, tt.err, err != nil)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	for _, stored := range []struct {
+		scans  []domain.StalledScan
+		record string
+		dbErr  error
+	}{
+		{scans: scans, record: string(record)},
+		{scans: nil, record: "[]"},
+		{scans: scans, record: string(record), dbErr: fmt.Errorf("dynamodb error")},
+	} {
+		mockDB.EXPECT().PutItemWithContext(gomock.Any(), &dynamodb.PutItemInput{
+			TableName: aws.String(defaultDynamoDBTableName),
+			Item: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {
+					S: aws.String(defaultDynamoDBStalledScansKeyValue),
+				},
+				"scans": {
+					S: aws.String(stored.record),
+				},
+			},
+		}).Return(&dynamodb.PutItemOutput{}, stored.dbErr)
+		err := dynamoTimestampStorage.StoreStalledScans(context.Background(), stored.scans)
+		require.Equal(t, stored.dbErr != nil, err != nil)
+	}
+}
+
+func TestDynamoDBTimestampStorage_SiteScans(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	stored := domain.SiteScanState{SiteID: "1", LastScanID: "1", LastScanEnd: ts, TrackedSince: ts}
+	siteItem := func(state domain.SiteScanState, version string) map[string]*dynamodb.AttributeValue {
+		record, _ := json.Marshal(state)
+		return map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {S: aws.String(defaultDynamoDBSiteScansKeyValue + "-" + state.SiteID)},
+			"site":                          {S: aws.String(string(record))},
+			"version":                       {N: aws.String(version)},
+		}
+	}
+	getItem := func(siteID string) *dynamodb.GetItemInput {
+		return &dynamodb.GetItemInput{
+			TableName: aws.String(defaultDynamoDBTableName),
+			Key: map[string]*dynamodb.AttributeValue{
+				defaultDynamoDBPartitionKeyName: {S: aws.String(defaultDynamoDBSiteScansKeyValue + "-" + siteID)},
+			},
+		}
+	}
+	putItem := func(state domain.SiteScanState, version string, next string) *dynamodb.PutItemInput {
+		return &dynamodb.PutItemInput{
+			TableName:                 aws.String(defaultDynamoDBTableName),
+			Item:                      siteItem(state, next),
+			ConditionExpression:       aws.String("attribute_not_exists(#version) OR #version = :version"),
+			ExpressionAttributeNames:  map[string]*string{"#version": aws.String("version")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":version": {N: aws.String(version)}},
+		}
+	}
+	scan := func(startKey map[string]*dynamodb.AttributeValue) *dynamodb.ScanInput {
+		return &dynamodb.ScanInput{
+			TableName:                aws.String(defaultDynamoDBTableName),
+			FilterExpression:         aws.String("begins_with(#key, :prefix)"),
+			ExpressionAttributeNames: map[string]*string{"#key": aws.String(defaultDynamoDBPartitionKeyName)},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":prefix": {S: aws.String(defaultDynamoDBSiteScansKeyValue + "-")},
+			},
+			ExclusiveStartKey: startKey,
+		}
+	}
+	conflict := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conflict", nil)
+	newStore := func(mockDB *MockDynamoDBAPI) *DynamoDBTimestampStorage {
+		return &DynamoDBTimestampStorage{
+			db:                mockDB,
+			tableName:         defaultDynamoDBTableName,
+			partitionKeyName:  defaultDynamoDBPartitionKeyName,
+			siteScansKeyValue: defaultDynamoDBSiteScansKeyValue,
+		}
+	}
+
+	t.Run("fetch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := NewMockDynamoDBAPI(ctrl)
+		store := newStore(mockDB)
+		other := domain.SiteScanState{SiteID: "0", TrackedSince: ts}
+		lastKey := map[string]*dynamodb.AttributeValue{
+			defaultDynamoDBPartitionKeyName: {S: aws.String(defaultDynamoDBSiteScansKeyValue + "-1")},
+		}
+
+		gomock.InOrder(
+			mockDB.EXPECT().ScanWithContext(gomock.Any(), scan(nil)).
+				Return(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{siteItem(stored, "4")}, LastEvaluatedKey: lastKey}, nil),
+			mockDB.EXPECT().ScanWithContext(gomock.Any(), scan(lastKey)).
+				Return(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{siteItem(other, "1")}}, nil),
+		)
+		states, err := store.FetchSiteScans(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []domain.SiteScanState{other, stored}, states)
+
+		mockDB.EXPECT().ScanWithContext(gomock.Any(), scan(nil)).Return(&dynamodb.ScanOutput{}, nil)
+		states, err = store.FetchSiteScans(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, states)
+
+		mockDB.EXPECT().ScanWithContext(gomock.Any(), scan(nil)).Return(nil, fmt.Errorf("dynamodb error"))
+		_, err = store.FetchSiteScans(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("store", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := NewMockDynamoDBAPI(ctrl)
+		store := newStore(mockDB)
+		update := stored
+		update.OverdueSince = ts.Add(time.Hour)
+		update.Alerted = true
+		added := domain.SiteScanState{SiteID: "2", TrackedSince: ts}
+
+		mockDB.EXPECT().GetItemWithContext(gomock.Any(), getItem("1")).Return(&dynamodb.GetItemOutput{Item: siteItem(stored, "4")}, nil)
+		mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItem(update, "4", "5")).Return(&dynamodb.PutItemOutput{}, nil)
+		mockDB.EXPECT().GetItemWithContext(gomock.Any(), getItem("2")).Return(&dynamodb.GetItemOutput{}, nil)
+		mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItem(added, "0", "1")).Return(&dynamodb.PutItemOutput{}, nil)
+		require.NoError(t, store.StoreSiteScans(context.Background(), []domain.SiteScanState{update, added}))
+	})
+
+	t.Run("record retries conflicts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := NewMockDynamoDBAPI(ctrl)
+		store := newStore(mockDB)
+		scans := []domain.CompletedScan{
+			{ScanID: "2", SiteID: "1", EndTime: ts.Add(time.Hour)},
+			{ScanID: "3", SiteID: "1", EndTime: ts.Add(30 * time.Minute)},
+		}
+		recorded := stored
+		recorded.LastScanID = "2"
+		recorded.LastScanEnd = ts.Add(time.Hour)
+
+		mockDB.EXPECT().GetItemWithContext(gomock.Any(), getItem("1")).Return(&dynamodb.GetItemOutput{Item: siteItem(stored, "4")}, nil).Times(2)
+		gomock.InOrder(
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItem(recorded, "4", "5")).Return(nil, conflict),
+			mockDB.EXPECT().PutItemWithContext(gomock.Any(), putItem(recorded, "4", "5")).Return(&dynamodb.PutItemOutput{}, nil),
+		)
+		require.NoError(t, store.RecordSiteScans(context.Background(), scans))
+
+		// no scans are recorded without reading storage
+		require.NoError(t, store.RecordSiteScans(context.Background(), nil))
+	})
+
+	t.Run("record gives up after repeated conflicts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := NewMockDynamoDBAPI(ctrl)
+		store := newStore(mockDB)
+
+		mockDB.EXPECT().GetItemWithContext(gomock.Any(), getItem("1")).Return(&dynamodb.GetItemOutput{}, nil).Times(siteScansAttempts)
+		mockDB.EXPECT().PutItemWithContext(gomock.Any(), gomock.Any()).Return(nil, conflict).Times(siteScansAttempts)
+		err := store.RecordSiteScans(context.Background(), []domain.CompletedScan{{ScanID: "1", SiteID: "1", EndTime: ts}})
+		require.IsType(t, domain.Conflict{}, err)
+	})
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// memoryState is shared by a MemoryStorage and its destination partitions.
+type memoryState struct {
+	lock        sync.Mutex
+	timestamps  map[string]time.Time
+	changes     []domain.WatermarkChange
+	inFlight    map[string][]string
+	quarantined []domain.QuarantinedScan
+	deadLetters []domain.DeadLetter
+	runs        []domain.RunRecord
+	health      domain.NotifierHealth
+	siteScans   map[string]domain.SiteScanState
+	stalled     []domain.StalledScan
+	historyTTL  time.Duration
+}
+
+// MemoryStorage keeps timestamps and scan records in memory, for local runs which
+// have no database. Everything stored is lost when the process exits.
+type MemoryStorage struct {
+	state     *memoryState
+	partition string
+}
+
+// NewMemoryStorage returns empty in-memory storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		state: &memoryState{
+			timestamps: map[string]time.Time{},
+			inFlight:   map[string][]string{},
+			siteScans:  map[string]domain.SiteScanState{},
+		},
+	}
+}
+
+// DestinationPartition returns storage for the timestamp of the last scan processed by
+// a single destination, and the scans which finished late that it missed, which shares
+// all other records with this storage.
+func (s *MemoryStorage) DestinationPartition(destination string) Storage {
+	return &MemoryStorage{state: s.state, partition: destination}
+}
+
+// FetchTimestamp returns the last processed timestamp.
+func (s *MemoryStorage) FetchTimestamp(_ context.Context) (time.Time, error) {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	ts, ok := s.state.timestamps[s.partition]
+	if !ok {
+		return time.Time{}, domain.TimestampNotFound{}
+	}
+	return ts, nil
+}
+
+// StoreTimestamp replaces the last processed timestamp.
+func (s *MemoryStorage) StoreTimestamp(_ context.Context, ts time.Time) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.timestamps[s.partition] = ts
+	return nil
+}
+
+// ResetTimestamp removes the last processed timestamp.
+func (s *MemoryStorage) ResetTimestamp(_ context.Context) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	delete(s.state.timestamps, s.partition)
+	return nil
+}
+
+// FetchInFlightScans returns the IDs of scans which were in flight during the last run.
+func (s *MemoryStorage) FetchInFlightScans(_ context.Context) ([]string, error) {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return append([]string{}, s.state.inFlight[s.partition]...), nil
+}
+
+// StoreInFlightScans replaces the IDs of scans which are in flight.
+func (s *MemoryStorage) StoreInFlightScans(_ context.Context, scanIDs []string) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.inFlight[s.partition] = append([]string{}, scanIDs...)
+	return nil
+}
+
+// QuarantineScan keeps a scan record which could not be parsed.
+func (s *MemoryStorage) QuarantineScan(_ context.Context, scan domain.QuarantinedScan) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.quarantined = append(s.state.quarantined, scan)
+	return nil
+}
+
+// QuarantinedScans returns every quarantined scan record, oldest first.
+func (s *MemoryStorage) QuarantinedScans() []domain.QuarantinedScan {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return append([]domain.QuarantinedScan{}, s.state.quarantined...)
+}
+
+// RecordWatermarkChange keeps the audit record of a manual change to the timestamp.
+func (s *MemoryStorage) RecordWatermarkChange(_ context.Context, change domain.WatermarkChange) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.changes = append(s.state.changes, change)
+	return nil
+}
+
+// WatermarkChanges returns the audit record of every manual change to a timestamp,
+// oldest first.
+func (s *MemoryStorage) WatermarkChanges() []domain.WatermarkChange {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return append([]domain.WatermarkChange{}, s.state.changes...)
+}
+
+// StoreDeadLetter keeps a scan which could not be produced to a destination.
+func (s *MemoryStorage) StoreDeadLetter(_ context.Context, deadLetter domain.DeadLetter) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.deadLetters = append(s.state.deadLetters, deadLetter)
+	return nil
+}
+
+// DeadLetters returns every dead letter, oldest first.
+func (s *MemoryStorage) DeadLetters() []domain.DeadLetter {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return append([]domain.DeadLetter{}, s.state.deadLetters...)
+}
+
+// RecordRun keeps the history of a notification run.
+func (s *MemoryStorage) RecordRun(_ context.Context, run domain.RunRecord) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.runs = append(s.state.runs, run)
+	return nil
+}
+
+// FetchRuns returns the runs matching a query, most recent first. Runs which started
+// before the history TTL are removed.
+func (s *MemoryStorage) FetchRuns(_ context.Context, query domain.RunQuery) (domain.RunPage, error) {
+	cursor, err := decodeRunCursor(query.Cursor)
+	if err != nil {
+		return domain.RunPage{}, err
+	}
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+
+	if s.state.historyTTL > 0 {
+		oldest := time.Now().Add(-1 * s.state.historyTTL)
+		kept := s.state.runs[:0]
+		for _, run := range s.state.runs {
+			if !run.StartTime.Before(oldest) {
+				kept = append(kept, run)
+			}
+		}
+		s.state.runs = kept
+	}
+
+	since, until := runQueryRange(query, s.state.historyTTL)
+	runs := []domain.RunRecord{}
+	for _, run := range s.state.runs {
+		if matchesRun(run, since, until, cursor) {
+			runs = append(runs, run)
+		}
+	}
+	sortRuns(runs)
+	return pageRuns(runs, query.Limit), nil
+}
+
+// FetchHealth returns the health of the notifier.
+func (s *MemoryStorage) FetchHealth(_ context.Context) (domain.NotifierHealth, error) {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return s.state.health, nil
+}
+
+// StoreHealth replaces the health of the notifier.
+func (s *MemoryStorage) StoreHealth(_ context.Context, health domain.NotifierHealth) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.health = health
+	return nil
+}
+
+// RecordSiteScans updates the most recent completed scan of each site.
+func (s *MemoryStorage) RecordSiteScans(_ context.Context, scans []domain.CompletedScan) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	recordSiteScans(s.state.siteScans, scans, time.Now())
+	return nil
+}
+
+// FetchSiteScans returns the tracked state of every site, ordered by site ID.
+func (s *MemoryStorage) FetchSiteScans(_ context.Context) ([]domain.SiteScanState, error) {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return sortedSiteScans(s.state.siteScans), nil
+}
+
+// StoreSiteScans replaces the tracked state of sites.
+func (s *MemoryStorage) StoreSiteScans(_ context.Context, states []domain.SiteScanState) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	storeSiteScans(s.state.siteScans, states)
+	return nil
+}
+
+// FetchStalledScans returns the scans which were stalled as of the last check.
+func (s *MemoryStorage) FetchStalledScans(_ context.Context) ([]domain.StalledScan, error) {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	return append([]domain.StalledScan(nil), s.state.stalled...), nil
+}
+
+// StoreStalledScans replaces the scans which are stalled.
+func (s *MemoryStorage) StoreStalledScans(_ context.Context, scans []domain.StalledScan) error {
+	s.state.lock.Lock()
+	defer s.state.lock.Unlock()
+	s.state.stalled = append([]domain.StalledScan(nil), scans...)
+	return nil
+}
+
+// CheckDependencies always succeeds, as in-memory storage has no dependencies.
+func (s *MemoryStorage) CheckDependencies(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage_Timestamp(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	partition := store.DestinationPartition("datalake")
+
+	_, err := store.FetchTimestamp(ctx)
+	require.IsType(t, domain.TimestampNotFound{}, err)
+
+	ts := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.StoreTimestamp(ctx, ts))
+	actual, err := store.FetchTimestamp(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ts, actual)
+
+	// each destination partition progresses independently
+	_, err = partition.FetchTimestamp(ctx)
+	require.IsType(t, domain.TimestampNotFound{}, err)
+	require.NoError(t, partition.StoreTimestamp(ctx, ts.Add(-time.Hour)))
+	actual, err = partition.FetchTimestamp(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ts.Add(-time.Hour), actual)
+
+	require.NoError(t, store.ResetTimestamp(ctx))
+	_, err = store.FetchTimestamp(ctx)
+	require.IsType(t, domain.TimestampNotFound{}, err)
+	_, err = partition.FetchTimestamp(ctx)
+	require.NoError(t, err)
+}
+
+func TestMemoryStorage_InFlightScans(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	scanIDs, err := store.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, scanIDs)
+
+	stored := []string{"1", "2"}
+	require.NoError(t, store.StoreInFlightScans(ctx, stored))
+	stored[0] = "3"
+	scanIDs, err = store.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, scanIDs)
+
+	// each destination partition tracks the scans it missed separately
+	partition := store.DestinationPartition("datalake")
+	scanIDs, err = partition.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, scanIDs)
+	require.NoError(t, partition.StoreInFlightScans(ctx, []string{"4"}))
+	scanIDs, err = store.FetchInFlightScans(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, scanIDs)
+}
+
+func TestMemoryStorage_Records(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	partition := store.DestinationPartition("datalake")
+
+	quarantined := domain.QuarantinedScan{ScanID: "1", Reason: "invalid end time"}
+	require.NoError(t, store.QuarantineScan(ctx, quarantined))
+	deadLetter := domain.DeadLetter{Destination: "datalake", Scan: domain.CompletedScan{ScanID: "2"}, Attempts: 3}
+	require.NoError(t, partition.StoreDeadLetter(ctx, deadLetter))
+
+	require.Equal(t, []domain.QuarantinedScan{quarantined}, store.QuarantinedScans())
+	require.Equal(t, []domain.DeadLetter{deadLetter}, store.DeadLetters())
+	change := domain.WatermarkChange{Action: "reset", Actor: "admin", Reason: "start over", ChangedAt: time.Now()}
+	require.NoError(t, store.RecordWatermarkChange(ctx, change))
+	require.Equal(t, []domain.WatermarkChange{change}, store.WatermarkChanges())
+	require.NoError(t, store.CheckDependencies(ctx))
+}
+
+func TestMemoryStorage_Health(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+
+	health, err := store.FetchHealth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, domain.NotifierHealth{}, health)
+
+	stored := domain.NotifierHealth{ConsecutiveFailures: 3, LastError: "fetchScans failed", Alerted: true}
+	require.NoError(t, store.StoreHealth(ctx, stored))
+	health, err = store.DestinationPartition("datalake").FetchHealth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, stored, health)
+}
+
+func TestMemoryStorage_SiteScans(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	states, err := store.FetchSiteScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, states)
+
+	require.NoError(t, store.RecordSiteScans(ctx, []domain.CompletedScan{
+		{ScanID: "3", SiteID: "2", EndTime: ts.Add(time.Hour)},
+		{ScanID: "1", SiteID: "1", EndTime: ts},
+		{ScanID: "2", SiteID: "1", EndTime: ts.Add(-time.Hour)},
+	}))
+	states, err = store.FetchSiteScans(ctx)
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	require.Equal(t, "1", states[0].SiteID)
+	require.Equal(t, "1", states[0].LastScanID)
+	require.Equal(t, ts, states[0].LastScanEnd)
+	require.False(t, states[0].TrackedSince.IsZero())
+	require.Equal(t, "2", states[1].SiteID)
+	require.Equal(t, "3", states[1].LastScanID)
+
+	// a scan recorded after the state was fetched is kept
+	overdue := states[0]
+	overdue.OverdueSince = ts.Add(2 * time.Hour)
+	overdue.Alerted = true
+	require.NoError(t, store.RecordSiteScans(ctx, []domain.CompletedScan{{ScanID: "4", SiteID: "1", EndTime: ts.Add(3 * time.Hour)}}))
+	require.NoError(t, store.StoreSiteScans(ctx, []domain.SiteScanState{overdue, {SiteID: "5", TrackedSince: ts}}))
+	states, err = store.FetchSiteScans(ctx)
+	require.NoError(t, err)
+	require.Len(t, states, 3)
+	require.Equal(t, "4", states[0].LastScanID)
+	require.Equal(t, ts.Add(3*time.Hour), states[0].LastScanEnd)
+	require.True(t, states[0].Alerted)
+	require.Equal(t, domain.SiteScanState{SiteID: "5", TrackedSince: ts}, states[2])
+}
+
+func TestMemoryStorage_StalledScans(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	ts := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	scans, err := store.FetchStalledScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, scans)
+
+	stored := []domain.StalledScan{{ScanID: "1", SiteID: "2", StalledSince: ts, Alerted: true}}
+	require.NoError(t, store.StoreStalledScans(ctx, stored))
+	scans, err = store.DestinationPartition("datalake").FetchStalledScans(ctx)
+	require.NoError(t, err)
+	require.Equal(t, stored, scans)
+
+	require.NoError(t, store.StoreStalledScans(ctx, nil))
+	scans, err = store.FetchStalledScans(ctx)
+	require.NoError(t, err)
+	require.Empty(t, scans)
+}
+
+func TestMemoryStorage_Runs(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	partition := store.DestinationPartition("datalake")
+
+	ts := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+	runs := []domain.RunRecord{
+		{RunID: "a", StartTime: ts, EndTime: ts.Add(time.Second), Produced: 1},
+		{RunID: "c", StartTime: ts.Add(time.Minute), EndTime: ts.Add(time.Minute + time.Second)},
+		{RunID: "b", StartTime: ts.Add(time.Minute), EndTime: ts.Add(time.Minute + time.Second)},
+		{RunID: "d", StartTime: ts.Add(2 * time.Minute), EndTime: ts.Add(2*time.Minute + time.Second), Error: "failed"},
+	}
+	for _, run := range runs {
+		require.NoError(t, partition.RecordRun(ctx, run))
+	}
+
+	// runs are returned most recent first, across pages
+	query := domain.RunQuery{Since: ts, Until: ts.Add(time.Hour), Limit: 3}
+	page, err := store.FetchRuns(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, []domain.RunRecord{runs[3], runs[1], runs[2]}, page.Runs)
+	require.NotEmpty(t, page.Cursor)
+	query.Cursor = page.Cursor
+	page, err = store.FetchRuns(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, []domain.RunRecord{runs[0]}, page.Runs)
+	require.Empty(t, page.Cursor)
+
+	// the range includes runs started at since, and excludes those started at until
+	page, err = store.FetchRuns(ctx, domain.RunQuery{Since: ts.Add(time.Minute), Until: ts.Add(2 * time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []domain.RunRecord{runs[1], runs[2]}, page.Runs)
+
+	_, err = store.FetchRuns(ctx, domain.RunQuery{Since: ts, Until: ts.Add(time.Hour), Cursor: "not a cursor"})
+	require.IsType(t, domain.InvalidInput{}, err)
+}
+
+func TestMemoryStorage_RunsHistoryTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStorage()
+	store.state.historyTTL = time.Hour
+
+	now := time.Now()
+	expired := domain.RunRecord{RunID: "a", StartTime: now.Add(-2 * time.Hour)}
+	kept := domain.RunRecord{RunID: "b", StartTime: now.Add(-1 * time.Minute)}
+	require.NoError(t, store.RecordRun(ctx, expired))
+	require.NoError(t, store.RecordRun(ctx, kept))
+
+	page, err := store.FetchRuns(ctx, domain.RunQuery{Since: now.Add(-24 * time.Hour), Until: now, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []domain.RunRecord{kept}, page.Runs)
+	require.Len(t, store.state.runs, 1)
+}
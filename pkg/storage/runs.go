@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// runCursor is the position of the last run of a page in the run history, which is
+// ordered by start time and then run ID, most recent first.
+type runCursor struct {
+	startTime time.Time
+	runID     string
+}
+
+// encodeRunCursor returns the cursor which continues from after a run.
+func encodeRunCursor(run domain.RunRecord) string {
+	position := run.StartTime.UTC().Format(time.RFC3339Nano) + " " + run.RunID
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// decodeRunCursor parses a cursor returned by encodeRunCursor, returning nil if the
+// cursor is empty.
+func decodeRunCursor(cursor string) (*runCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	invalid := domain.InvalidInput{Field: "cursor", Reason: "must be a cursor returned by a previous page"}
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.SplitN(string(position), " ", 2)
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	return &runCursor{startTime: startTime, runID: parts[1]}, nil
+}
+
+// follows reports whether a run comes after the cursor in the run history.
+func (c *runCursor) follows(run domain.RunRecord) bool {
+	if c == nil || run.StartTime.Before(c.startTime) {
+		return true
+	}
+	return run.StartTime.Equal(c.startTime) && run.RunID < c.runID
+}
+
+// runQueryRange returns the range of start times of the runs matching a query,
+// excluding runs which are older than ttl when it is set.
+func runQueryRange(query domain.RunQuery, ttl time.Duration) (time.Time, time.Time) {
+	since := query.Since
+	if oldest := time.Now().Add(-1 * ttl); ttl > 0 && oldest.After(since) {
+		since = oldest
+	}
+	return since, query.Until
+}
+
+// matchesRun reports whether a run started within a range and comes after the cursor.
+func matchesRun(run domain.RunRecord, since time.Time, until time.Time, cursor *runCursor) bool {
+	return !run.StartTime.Before(since) && run.StartTime.Before(until) && cursor.follows(run)
+}
+
+// sortRuns sorts runs by start time and then run ID, most recent first.
+func sortRuns(runs []domain.RunRecord) {
+	sort.SliceStable(runs, func(left, right int) bool {
+		if runs[left].StartTime.Equal(runs[right].StartTime) {
+			return runs[left].RunID > runs[right].RunID
+		}
+		return runs[left].StartTime.After(runs[right].StartTime)
+	})
+}
+
+// pageRuns returns the first limit runs, which must already be sorted, and a cursor
+// to continue from if any runs remain. A limit of zero returns every run.
+func pageRuns(runs []domain.RunRecord, limit int) domain.RunPage {
+	if limit <= 0 || len(runs) <= limit {
+		return domain.RunPage{Runs: runs}
+	}
+	return domain.RunPage{Runs: runs[:limit], Cursor: encodeRunCursor(runs[limit-1])}
+}
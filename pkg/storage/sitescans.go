@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+)
+
+// recordSiteScans updates the most recent completed scan of each site with scans,
+// tracking sites which have not been seen before from now.
+func recordSiteScans(states map[string]domain.SiteScanState, scans []domain.CompletedScan, now time.Time) {
+	for _, scan := range scans {
+		state, ok := states[scan.SiteID]
+		if !ok {
+			state = domain.SiteScanState{SiteID: scan.SiteID, TrackedSince: now}
+		}
+		if scan.EndTime.After(state.LastScanEnd) {
+			state.LastScanID = scan.ScanID
+			state.LastScanEnd = scan.EndTime
+		}
+		states[scan.SiteID] = state
+	}
+}
+
+// storeSiteScans replaces the state of each updated site, keeping the stored most
+// recent completed scan if it is newer than the update's.
+func storeSiteScans(states map[string]domain.SiteScanState, updates []domain.SiteScanState) {
+	for _, update := range updates {
+		if stored, ok := states[update.SiteID]; ok && stored.LastScanEnd.After(update.LastScanEnd) {
+			update.LastScanID = stored.LastScanID
+			update.LastScanEnd = stored.LastScanEnd
+		}
+		states[update.SiteID] = update
+	}
+}
+
+// sortedSiteScans returns the state of every site, ordered by site ID.
+func sortedSiteScans(states map[string]domain.SiteScanState) []domain.SiteScanState {
+	sorted := make([]domain.SiteScanState, 0, len(states))
+	for _, state := range states {
+		sorted = append(sorted, state)
+	}
+	sort.Slice(sorted, func(left, right int) bool {
+		return sorted[left].SiteID < sorted[right].SiteID
+	})
+	return sorted
+}
+
+// sortedSiteIDs returns the IDs of the sites with completed scans, in order.
+func sortedSiteIDs(scans map[string][]domain.CompletedScan) []string {
+	siteIDs := make([]string, 0, len(scans))
+	for siteID := range scans {
+		siteIDs = append(siteIDs, siteID)
+	}
+	sort.Strings(siteIDs)
+	return siteIDs
+}
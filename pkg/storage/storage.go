@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	"github.com/asecurityteam/settings"
+)
+
+const (
+	// StorageTypeDynamoDB stores timestamps and scan records in a DynamoDB table.
+	StorageTypeDynamoDB = "DYNAMODB"
+	// StorageTypeMemory keeps timestamps and scan records in memory, losing them on exit.
+	StorageTypeMemory = "MEMORY"
+)
+
+// Storage is implemented by each supported storage backend, and provides everything
+// the service persists between runs.
+type Storage interface {
+	domain.TimestampFetcher
+	domain.TimestampStorer
+	domain.TimestampResetter
+	domain.WatermarkChangeRecorder
+	domain.InFlightScanFetcher
+	domain.InFlightScanStorer
+	domain.ScanQuarantiner
+	domain.DeadLetterStorer
+	domain.RunRecorder
+	domain.RunFetcher
+	domain.HealthFetcher
+	domain.HealthStorer
+	domain.SiteScanRecorder
+	domain.SiteScanFetcher
+	domain.SiteScanStorer
+	domain.StalledScanFetcher
+	domain.StalledScanStorer
+	domain.DependencyChecker
+
+	// DestinationPartition returns storage for the timestamp of the last scan
+	// processed by a single destination.
+	DestinationPartition(destination string) Storage
+}
+
+// StorageConfig holds configuration for choosing a storage backend.
+type StorageConfig struct {
+	Type       string        `description:"Where timestamps and scan records are stored. One of DYNAMODB, MEMORY."`
+	HistoryTTL time.Duration `description:"How long the history of each notification run is kept. Zero keeps it forever."`
+}
+
+// Name is used by the settings library and will add a "STORAGE_"
+// prefix to StorageConfig environment variables
+func (c *StorageConfig) Name() string {
+	return "Storage"
+}
+
+// StorageComponent satisfies the settings library Component
+// API, and may be used by the settings.NewComponent function. The
+// source is used to load the configuration of the chosen backend.
+type StorageComponent struct {
+	Source settings.Source
+}
+
+// Settings can be used to populate default values if there are any
+func (*StorageComponent) Settings() *StorageConfig {
+	return &StorageConfig{
+		Type:       StorageTypeDynamoDB,
+		HistoryTTL: 30 * 24 * time.Hour,
+	}
+}
+
+// New constructs the configured storage backend.
+func (s *StorageComponent) New(ctx context.Context, c *StorageConfig) (Storage, error) {
+	if c.HistoryTTL < 0 {
+		return nil, fmt.Errorf("storage history ttl must not be negative, got %s", c.HistoryTTL)
+	}
+	switch strings.ToUpper(c.Type) {
+	case StorageTypeDynamoDB:
+		dynamoDBTimestampStorage := new(DynamoDBTimestampStorage)
+		if err := settings.NewComponent(ctx, s.Source, &DynamoDBTimestampStorageComponent{}, dynamoDBTimestampStorage); err != nil {
+			return nil, err
+		}
+		dynamoDBTimestampStorage.historyTTL = c.HistoryTTL
+		return dynamoDBTimestampStorage, nil
+	case StorageTypeMemory:
+		memoryStorage := NewMemoryStorage()
+		memoryStorage.state.historyTTL = c.HistoryTTL
+		return memoryStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %s", c.Type)
+	}
+}
@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/container"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/domain"
+	v1 "github.com/asecurityteam/nexpose-scan-notifier/pkg/handlers/v1"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/producer"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/scanfetcher"
+	"github.com/asecurityteam/nexpose-scan-notifier/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// outboundGateway serves responses from Nexpose as the outbound gateway does, returning a
+// bad gateway status for those which the specification of the route rejects.
+func outboundGateway(t *testing.T, spec gatewaySpec, path string, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, path, r.URL.Path)
+		if spec.validatesResponses(t, http.MethodGet, path) {
+			if err := spec.validate(spec.responseSchema(t, http.MethodGet, path, http.StatusOK), []byte(body)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestGatewayOutboundScansQuarantine(t *testing.T) {
+	spec := loadGatewaySpec(t, "api-outbound.yaml")
+	since := time.Date(2019, 5, 24, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 5, 25, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		Name      string
+		StartTime string
+		EndTime   string
+		Field     string
+	}{
+		{
+			Name:      "malformed end time",
+			StartTime: end.Add(-time.Hour).Format(time.RFC3339Nano),
+			EndTime:   "yesterday",
+			Field:     "endTime",
+		},
+		{
+			Name:      "missing end time",
+			StartTime: end.Add(-time.Hour).Format(time.RFC3339Nano),
+			EndTime:   "",
+			Field:     "endTime",
+		},
+		{
+			Name:      "malformed start time",
+			StartTime: "2019-05-24 12:00",
+			EndTime:   end.Format(time.RFC3339Nano),
+			Field:     "startTime",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			body := fmt.Sprintf(`{
+				"resources": [
+					{"id": 1001, "siteId": 1, "scanType": "Scheduled", "scanName": "Malformed Scan",
+					"status": "finished", "startTime": %q, "endTime": %q},
+					{"id": 1002, "siteId": 1, "scanType": "Scheduled", "scanName": "Allowed Scan",
+					"status": "finished", "startTime": %q, "endTime": %q}
+				],
+				"page": {"number": 0, "size": 2, "totalResources": 2, "totalPages": 1}
+			}`, tt.StartTime, tt.EndTime, end.Add(-2*time.Hour).Format(time.RFC3339Nano), end.Add(-time.Minute).Format(time.RFC3339Nano))
+			gateway := outboundGateway(t, spec, "/api/3/scans", body)
+			defer gateway.Close()
+
+			endpoint, _ := url.Parse(gateway.URL)
+			store := storage.NewMemoryStorage()
+			client := &scanfetcher.NexposeClient{
+				Client:           gateway.Client(),
+				Endpoint:         endpoint,
+				PageSize:         2,
+				ScanBlocklist:    &container.StringContainer{},
+				StoreQuarantined: true,
+				ScanQuarantiner:  store,
+				LogFn:            testLogFn,
+				StatFn:           testStatFn,
+			}
+			result, err := client.FetchScans(context.Background(), domain.ScanQuery{Since: since})
+			require.NoError(t, err)
+			require.Len(t, result.Scans, 1)
+			require.Equal(t, "1002", result.Scans[0].ScanID)
+
+			quarantined := store.QuarantinedScans()
+			require.Len(t, quarantined, 1)
+			require.Equal(t, "1001", quarantined[0].ScanID)
+			require.Contains(t, quarantined[0].Reason, tt.Field)
+		})
+	}
+}
+
+func TestGatewayOutboundScansInvalidPage(t *testing.T) {
+	spec := loadGatewaySpec(t, "api-outbound.yaml")
+	schema := spec.responseSchema(t, http.MethodGet, "/api/3/scans", http.StatusOK)
+
+	// responses which are not pages of scans are still rejected by the gateway
+	require.Error(t, spec.validate(schema, []byte(`{"page": {"number": 0}}`)))
+	require.Error(t, spec.validate(schema, []byte(`{"page": {"number": 0}, "resources": [{"id": "1001"}]}`)))
+	require.Error(t, spec.validate(schema, []byte(`{"page": {"number": 0}, "resources": [{"status": "done"}]}`)))
+	require.NoError(t, spec.validate(schema, []byte(`{"page": {"number": 0}, "resources": [{"id": 1001, "endTime": ""}]}`)))
+}
+
+func TestGatewayInboundNotificationStage(t *testing.T) {
+	spec := loadGatewaySpec(t, "api-inbound.yaml")
+	failure := func(err error) []byte {
+		body, err := json.Marshal(v1.NewErrorResponse(err))
+		require.NoError(t, err)
+		return body
+	}
+
+	tc := []struct {
+		Name     string
+		Paths    []string
+		Template string
+		Body     []byte
+		Status   int
+		Stage    interface{}
+	}{
+		{
+			Name:     "successful run",
+			Template: "success",
+			Body:     []byte(`{"response": [], "moreRemaining": false, "summary": {"runID": "1"}}`),
+			Status:   http.StatusOK,
+		},
+		{
+			Name:     "failed run names the stage",
+			Template: "success",
+			Body:     failure(domain.RateLimited{Stage: "fetchScans", Dependency: "nexpose", Reason: "429"}),
+			Status:   http.StatusTooManyRequests,
+			Stage:    "fetchScans",
+		},
+		{
+			Name:     "failed run without a dependency names the stage",
+			Template: "success",
+			Body:     failure(domain.RunFailure{Stage: "produce", Reason: "unexpected"}),
+			Status:   http.StatusInternalServerError,
+			Stage:    "produce",
+		},
+		{
+			Name:     "invalid input has no stage",
+			Paths:    []string{"/notification/override"},
+			Template: "success",
+			Body:     failure(domain.InvalidInput{Field: "since", Reason: "invalid"}),
+			Status:   http.StatusBadRequest,
+		},
+		{
+			Name:     "lambda error has no stage",
+			Template: "error",
+			Body:     []byte(`{"errorMessage": "fetchScans failed: unexpected", "errorType": "RunFailure", "stackTrace": []}`),
+			Status:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			paths := tt.Paths
+			if paths == nil {
+				paths = []string{"/notification", "/notification/override"}
+			}
+			for _, path := range paths {
+				tmpl := spec.lambdaTemplate(t, http.MethodPost, path, tt.Template)
+				status, body := renderLambdaTemplate(t, tmpl, tt.Body)
+				require.Equal(t, tt.Status, status)
+				if status == http.StatusOK {
+					continue
+				}
+				require.NoError(t, spec.validate(spec.responseSchema(t, http.MethodPost, path, status), body))
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				require.Equal(t, tt.Stage, response["stage"])
+			}
+		})
+	}
+}
+
+// publishGateway accepts the scans and events published to the outbound gateway,
+// rejecting those which the specification of the route rejects, and records the type
+// of each one it accepts.
+func publishGateway(t *testing.T, spec gatewaySpec, accepted *[]string, rejected *[]string) *httptest.Server {
+	schema := spec.requestSchema(t, http.MethodPost, "/publish")
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/publish", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if err := spec.validate(schema, body); err != nil {
+			*rejected = append(*rejected, err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var payload struct {
+			EventType string `json:"eventType"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		if payload.EventType == "" {
+			payload.EventType = "scan"
+		}
+		*accepted = append(*accepted, payload.EventType)
+	}))
+}
+
+// activeScans returns the same active scans on every check.
+type activeScans []domain.ActiveScan
+
+func (a activeScans) FetchActiveScans(context.Context) ([]domain.ActiveScan, error) {
+	return a, nil
+}
+
+// siteList lists the same sites on every check.
+type siteList []string
+
+func (l siteList) ListSites(context.Context) ([]string, error) {
+	return l, nil
+}
+
+func TestGatewayOutboundPublishEvents(t *testing.T) {
+	spec := loadGatewaySpec(t, "api-outbound.yaml")
+	var accepted, rejected []string
+	server := publishGateway(t, spec, &accepted, &rejected)
+	defer server.Close()
+	endpoint, _ := url.Parse(server.URL + "/publish")
+	// events are produced to the scan output when no events endpoint is configured
+	publisher := &producer.HTTP{Client: server.Client(), Endpoint: endpoint}
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	now := time.Now()
+
+	require.NoError(t, publisher.Produce(ctx, domain.CompletedScan{
+		ScanID:    "1001",
+		SiteID:    "11",
+		ScanType:  "Scheduled",
+		ScanName:  "Weekly Scan",
+		StartTime: now.Add(-time.Hour),
+		EndTime:   now,
+	}))
+
+	health := &v1.HealthMonitor{
+		HealthFetcher:          store,
+		HealthStorer:           store,
+		TimestampFetcher:       store,
+		EventProducer:          publisher,
+		LogFn:                  testLogFn,
+		MaxConsecutiveFailures: 1,
+	}
+	health.Observe(ctx, fmt.Errorf("run failed"))
+	health.Observe(ctx, nil)
+
+	require.NoError(t, store.StoreSiteScans(ctx, []domain.SiteScanState{{SiteID: "11", TrackedSince: now.Add(-2 * time.Hour)}}))
+	sites := &v1.SiteScanHandler{
+		SiteLister:         siteList{"11"},
+		SiteScanFetcher:    store,
+		SiteScanStorer:     store,
+		EventProducer:      publisher,
+		LogFn:              testLogFn,
+		DefaultMaxInterval: time.Hour,
+	}
+	_, err := sites.Check(ctx)
+	require.NoError(t, err)
+
+	stalled := &v1.StalledScanMonitor{
+		ActiveScanFetcher: activeScans{{
+			ScanID:    "1002",
+			SiteID:    "11",
+			ScanType:  "Scheduled",
+			ScanName:  "Weekly Scan",
+			Status:    "running",
+			StartTime: now.Add(-2 * time.Hour),
+		}},
+		StalledScanFetcher: store,
+		StalledScanStorer:  store,
+		EventProducer:      publisher,
+		LogFn:              testLogFn,
+		DefaultMaxRuntime:  time.Hour,
+	}
+	stalled.Check(ctx)
+
+	require.Empty(t, rejected)
+	require.Equal(t, []string{"scan", "notifier.degraded", "notifier.recovered", "site.scan_overdue", "scan.stalled"}, accepted)
+}
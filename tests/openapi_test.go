@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// gatewaySpec is a gateway API specification, loaded to check payloads against the
+// schemas which the gateway validates requests and responses with.
+type gatewaySpec struct {
+	doc map[string]interface{}
+}
+
+// loadGatewaySpec loads an API specification from the root of the repository.
+func loadGatewaySpec(t *testing.T, name string) gatewaySpec {
+	raw, err := ioutil.ReadFile("../" + name)
+	require.NoError(t, err)
+	var doc interface{}
+	require.NoError(t, yaml.Unmarshal(raw, &doc))
+	return gatewaySpec{doc: normalizeYAML(doc).(map[string]interface{})}
+}
+
+// normalizeYAML converts the maps decoded from YAML to maps with string keys,
+// such as those decoded from JSON.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (s gatewaySpec) operation(t *testing.T, method string, path string) map[string]interface{} {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	route, ok := paths[path].(map[string]interface{})
+	require.True(t, ok, "no route for %s", path)
+	op, ok := route[strings.ToLower(method)].(map[string]interface{})
+	require.True(t, ok, "no %s operation for %s", method, path)
+	return op
+}
+
+// requestSchema returns the JSON schema of the body of requests to the route.
+func (s gatewaySpec) requestSchema(t *testing.T, method string, path string) map[string]interface{} {
+	op := s.operation(t, method, path)
+	body, _ := op["requestBody"].(map[string]interface{})
+	return jsonSchema(t, body)
+}
+
+// responseSchema returns the JSON schema of the body of responses from the route with the status.
+func (s gatewaySpec) responseSchema(t *testing.T, method string, path string, status int) map[string]interface{} {
+	op := s.operation(t, method, path)
+	responses, _ := op["responses"].(map[string]interface{})
+	response, _ := responses[fmt.Sprint(status)].(map[string]interface{})
+	return jsonSchema(t, response)
+}
+
+// validatesResponses reports whether the gateway validates the responses of the route.
+func (s gatewaySpec) validatesResponses(t *testing.T, method string, path string) bool {
+	extension, _ := s.operation(t, method, path)["x-transportd"].(map[string]interface{})
+	enabled, _ := extension["enabled"].([]interface{})
+	for _, plugin := range enabled {
+		if plugin == "responsevalidation" {
+			return true
+		}
+	}
+	return false
+}
+
+// lambdaTemplate returns a template with which the gateway maps the response of the
+// lambda function of the route, such as "success" or "error".
+func (s gatewaySpec) lambdaTemplate(t *testing.T, method string, path string, name string) string {
+	extension, _ := s.operation(t, method, path)["x-transportd"].(map[string]interface{})
+	lambda, _ := extension["lambda"].(map[string]interface{})
+	tmpl, ok := lambda[name].(string)
+	require.True(t, ok, "no %s template for %s", name, path)
+	return tmpl
+}
+
+// renderLambdaTemplate maps the body of a lambda response with a template, returning
+// the status and body of the gateway's response.
+func renderLambdaTemplate(t *testing.T, tmpl string, body []byte) (int, []byte) {
+	parsed, err := template.New("lambda").Delims("#!", "!#").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tmpl)
+	require.NoError(t, err)
+	var data struct{ Response struct{ Body interface{} } }
+	require.NoError(t, json.Unmarshal(body, &data.Response.Body))
+	var rendered bytes.Buffer
+	require.NoError(t, parsed.Execute(&rendered, data))
+
+	var response struct {
+		Status          int             `json:"status"`
+		Body            json.RawMessage `json:"body"`
+		BodyPassthrough bool            `json:"bodyPassthrough"`
+	}
+	require.NoError(t, json.Unmarshal(rendered.Bytes(), &response), rendered.String())
+	if response.BodyPassthrough {
+		return response.Status, body
+	}
+	return response.Status, response.Body
+}
+
+func jsonSchema(t *testing.T, body map[string]interface{}) map[string]interface{} {
+	content, _ := body["content"].(map[string]interface{})
+	media, _ := content["application/json"].(map[string]interface{})
+	schema, ok := media["schema"].(map[string]interface{})
+	require.True(t, ok, "no JSON schema")
+	return schema
+}
+
+// validate checks a JSON payload against a schema of the specification, covering the
+// subset of OpenAPI schemas used by the gateway specifications.
+func (s gatewaySpec) validate(schema map[string]interface{}, payload []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return err
+	}
+	return s.validateValue("", schema, value)
+}
+
+func (s gatewaySpec) resolve(schema map[string]interface{}) map[string]interface{} {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	var node interface{} = s.doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node = node.(map[string]interface{})[part]
+	}
+	return s.resolve(node.(map[string]interface{}))
+}
+
+func (s gatewaySpec) validateValue(field string, schema map[string]interface{}, value interface{}) error {
+	schema = s.resolve(schema)
+	if options, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, option := range options {
+			if s.validateValue(field, option.(map[string]interface{}), value) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s matches %d of the schemas, not exactly one", field, matched)
+		}
+		return nil
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is not one of %v", field, enum)
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", field)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s.%s is required", field, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if v, ok := object[name]; ok {
+				if err := s.validateValue(field+"."+name, property.(map[string]interface{}), v); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", field)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range array {
+			if err := s.validateValue(fmt.Sprintf("%s[%d]", field, i), items, item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", field)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Errorf("%s must be a date-time: %s", field, err.Error())
+			}
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s must be an integer", field)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number", field)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", field)
+		}
+	}
+	return nil
+}